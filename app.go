@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -9,8 +13,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"open-dan/internal/agent"
 	"open-dan/internal/channel"
@@ -27,8 +35,20 @@ const (
 	keyringPlaceholder     = "[keyring]"
 	secretNameLLMKey       = "llm_api_key"
 	secretNameTelegramToken = "telegram_token"
+	secretNameHTTPToken     = "http_channel_token"
+	secretNameDiscordToken  = "discord_token"
+	secretNameSlackBot      = "slack_bot_token"
+	secretNameSlackApp      = "slack_app_token"
+	secretNameBraveKey     = "brave_api_key"
+	secretNameTranscriptionKey = "transcription_api_key"
+	secretNameEmbeddingsKey    = "embeddings_api_key"
+	secretNameCookieKey    = "browser_cookie_key"
+	retentionInterval      = 6 * time.Hour
 )
 
+// guiChatID is the conversation of the app's own chat window.
+const guiChatID = "gui"
+
 // App struct holds the application state and exposes methods to the frontend.
 type App struct {
 	ctx       context.Context
@@ -44,8 +64,12 @@ type App struct {
 	sanitizer   *security.Sanitizer
 	browserTool *tool.BrowserTool
 	skillLoader *skill.Loader
-	logsMu      sync.Mutex // protects logs
+	registry    *tool.Registry // the agent's tools, set by initAgent
+	workspace   string          // resolved workspace directory, set by initAgent
+	transcriber llm.Transcriber // for Telegram voice messages; may be nil
+	logsMu      sync.Mutex // protects logs and logSecrets
 	logs        []LogEntry
+	logSecrets  []string // configured secret values, masked in log entries
 }
 
 // LogEntry is a log line exposed to the frontend.
@@ -77,9 +101,13 @@ func (a *App) startup(ctx context.Context) {
 	a.cfgLoader = loader
 
 	cfg, err := loader.Load()
-	if err != nil {
+	if cfg == nil {
 		log.Printf("failed to load config: %v", err)
 		cfg = config.Defaults()
+	} else if err != nil {
+		// Keep the rest of the config; saving it must not fall back to setup
+		log.Printf("invalid config settings were disabled: %v", err)
+		a.addLog("error", "Invalid config settings were disabled: "+err.Error())
 	}
 	a.cfg = cfg
 
@@ -92,30 +120,49 @@ func (a *App) startup(ctx context.Context) {
 
 	// Resolve secrets from Keychain (or migrate plaintext → Keychain)
 	a.resolveSecrets()
+	a.updateLogSecrets()
 
 	// Initialize sanitizer
 	a.sanitizer = security.NewSanitizer(cfg.Security.PIIFiltering)
 
-	// Initialize memory (SQLite)
-	home, err := os.UserHomeDir()
-	if err != nil {
-		log.Printf("failed to get home directory: %v", err)
-		return
-	}
-	dbPath := filepath.Join(home, ".opendan", "memory.db")
-	mem, err := memory.NewSQLiteMemory(dbPath)
-	if err != nil {
-		log.Printf("failed to initialize memory: %v", err)
-		return
+	// Initialize memory (SQLite, or RAM only in privacy mode)
+	if cfg.Memory.Ephemeral {
+		mem := memory.NewInMemory()
+		mem.SetRetention(cfg.Memory)
+		a.mem = mem
+		log.Println("Privacy mode: conversations are kept in memory only")
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Printf("failed to get home directory: %v", err)
+			return
+		}
+		dbPath := filepath.Join(home, ".opendan", "memory.db")
+		// Encrypted once the master password unlocks the vault; see
+		// unlockMemory.
+		mem, err := memory.NewSQLiteMemory(dbPath, nil)
+		if err != nil {
+			log.Printf("failed to initialize memory: %v", err)
+			return
+		}
+		mem.SetRetention(cfg.Memory)
+		a.mem = mem
+		a.loadPIIMappings()
 	}
-	a.mem = mem
+	go a.runRetention(ctx)
 
 	// Initialize channel manager
 	a.chanMgr = channel.NewManager()
+	a.chanMgr.SetSendRetries(cfg.Channels.SendRetries)
 
-	// If setup is completed, initialize the agent
+	// If setup is completed, initialize the agent. With a locked vault that
+	// waits for Unlock, since the secrets aren't readable yet.
 	if cfg.SetupCompleted {
-		a.initAgent()
+		if a.IsLocked() {
+			log.Println("Secret vault is locked; waiting for the master password")
+		} else {
+			a.initAgent()
+		}
 	}
 
 	// Subscribe to events for logging
@@ -125,6 +172,28 @@ func (a *App) startup(ctx context.Context) {
 	a.bus.Subscribe(eventbus.TopicStatusChange, func(e eventbus.Event) {
 		a.addLog("info", e.Payload)
 	})
+
+	// Forward live tool output to the GUI
+	a.bus.Subscribe(eventbus.TopicToolProgress, func(e eventbus.Event) {
+		wailsruntime.EventsEmit(a.ctx, string(eventbus.TopicToolProgress), e.Payload)
+	})
+	// Ask the user to confirm flagged tool calls; see ApproveToolCall
+	a.bus.Subscribe(eventbus.TopicToolApprovalRequest, func(e eventbus.Event) {
+		wailsruntime.EventsEmit(a.ctx, string(eventbus.TopicToolApprovalRequest), e.Payload)
+	})
+	// PII placeholders of a cleared conversation are no longer referenced
+	a.bus.Subscribe(eventbus.TopicConversationCleared, func(e eventbus.Event) {
+		if conv, ok := e.Payload.(string); ok {
+			a.sanitizer.Reset(conv)
+		}
+	})
+	// Show skills' stderr live and in the log panel
+	a.bus.Subscribe(eventbus.TopicSkillLog, func(e eventbus.Event) {
+		wailsruntime.EventsEmit(a.ctx, string(eventbus.TopicSkillLog), e.Payload)
+		if l, ok := e.Payload.(skill.LogLine); ok {
+			a.addLog("skill", l.Skill+": "+l.Line)
+		}
+	})
 }
 
 // shutdown is called when the app is closing.
@@ -143,6 +212,64 @@ func (a *App) shutdown(ctx context.Context) {
 	}
 }
 
+// runRetention prunes old messages at startup and then periodically.
+func (a *App) runRetention(ctx context.Context) {
+	prune := func() {
+		deleted, err := a.mem.Prune(ctx)
+		if err != nil {
+			log.Printf("failed to prune memory: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Pruned %d old messages", deleted)
+		}
+	}
+
+	prune()
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			prune()
+		}
+	}
+}
+
+// buildProvider creates the LLM provider from config, with the fallback
+// provider behind it if one is configured.
+func (a *App) buildProvider() (llm.Provider, error) {
+	provider, err := llm.NewProvider(a.cfg.LLM)
+	if err != nil {
+		return nil, err
+	}
+	if a.cfg.FallbackLLM != nil && a.cfg.FallbackLLM.APIKey != "" {
+		fallback, err := llm.NewProvider(*a.cfg.FallbackLLM)
+		if err != nil {
+			log.Printf("fallback LLM provider disabled: %v", err)
+		} else {
+			provider = llm.NewFallbackProvider(provider, fallback)
+		}
+	}
+	return provider, nil
+}
+
+// applyProvider rebuilds the running agent's provider after an LLM config
+// change, so the next message uses it. a.mu must be held.
+func (a *App) applyProvider() error {
+	if a.agent == nil || a.cfg.LLM.APIKey == "" {
+		return nil
+	}
+	provider, err := a.buildProvider()
+	if err != nil {
+		return fmt.Errorf("config saved, but the provider could not be switched: %w", err)
+	}
+	a.agent.SetProvider(provider)
+	return nil
+}
+
 func (a *App) initAgent() {
 	if a.cfg.LLM.APIKey == "" {
 		log.Println("LLM API key not configured, skipping agent init")
@@ -150,20 +277,12 @@ func (a *App) initAgent() {
 	}
 
 	// Create LLM provider
-	provider, err := llm.NewProvider(a.cfg.LLM)
+	provider, err := a.buildProvider()
 	if err != nil {
 		log.Printf("failed to create LLM provider: %v", err)
 		return
 	}
 
-	// Add fallback if configured
-	if a.cfg.FallbackLLM != nil && a.cfg.FallbackLLM.APIKey != "" {
-		fallback, err := llm.NewProvider(*a.cfg.FallbackLLM)
-		if err == nil {
-			provider = llm.NewFallbackProvider(provider, fallback)
-		}
-	}
-
 	// Create tool registry
 	registry := tool.NewRegistry()
 
@@ -181,19 +300,49 @@ func (a *App) initAgent() {
 		return
 	}
 
-	registry.Register(tool.NewShellTool(tool.ShellConfig{
-		WorkspaceDir:   workspaceDir,
-		TimeoutSecs:    a.cfg.Security.Sandbox.TimeoutSecs,
-		MaxOutputChars: a.cfg.Security.Sandbox.MaxOutputChars,
-		SandboxEnabled: a.cfg.Security.Sandbox.Enabled,
-	}))
-	registry.Register(tool.NewWebSearchTool())
-	registry.Register(tool.NewFilesystemTool(workspaceDir))
+	shellTool, err := tool.NewShellTool(tool.ShellConfig{
+		WorkspaceDir:      workspaceDir,
+		TimeoutSecs:       a.cfg.Security.Sandbox.TimeoutSecs,
+		MaxOutputChars:    a.cfg.Security.Sandbox.MaxOutputChars,
+		SandboxEnabled:    a.cfg.Security.Sandbox.Enabled,
+		AllowedCommands:   a.cfg.Security.Sandbox.AllowedCommands,
+		ExtraDenyPatterns: a.cfg.Security.Sandbox.ExtraDenyPatterns,
+		AllowPatterns:     a.cfg.Security.Sandbox.AllowPatterns,
+	})
+	if err != nil {
+		// Fail closed: a broken sandbox config disables the shell tool.
+		log.Printf("shell tool disabled: %v", err)
+	} else {
+		registry.Register(shellTool)
+	}
+	registry.Register(tool.NewWebSearchTool(a.cfg.WebSearch))
+	registry.Register(tool.NewFetchTool(a.cfg.Fetch))
+	if len(a.cfg.HTTPRequest.AllowedHosts) > 0 {
+		registry.Register(tool.NewHTTPTool(a.cfg.HTTPRequest))
+	}
+	fsTool := tool.NewFilesystemTool(workspaceDir)
+	fsTool.SetMaxFileSize(int64(a.cfg.Security.Sandbox.MaxFileSizeKB) * 1024)
+	registry.Register(fsTool)
+	registry.Register(tool.NewCalculatorTool())
+	registry.Register(tool.NewDateTimeTool(a.cfg.Agent.TimeZone))
+	if a.mem != nil {
+		registry.Register(tool.NewKVTool(a.mem))
+		registry.Register(tool.NewMemorySearchTool(a.mem))
+		if a.cfg.Notes.Enabled {
+			registry.Register(tool.NewNotesTool(a.mem, a.cfg.Notes.MaxKB))
+		}
+	}
 
 	// Browser tool
 	if a.cfg.Browser.Enabled {
 		a.browserTool = tool.NewBrowserTool(a.cfg.Browser)
+		if key, err := a.cookieKey(); err != nil {
+			log.Printf("browser cookie persistence disabled: %v", err)
+		} else if err := a.browserTool.SetCookieStore(filepath.Join(home, ".opendan", "cookies.enc"), key); err != nil {
+			log.Printf("browser cookie persistence disabled: %v", err)
+		}
 		registry.Register(a.browserTool)
+		registry.Register(tool.NewScrapeTool(a.browserTool))
 	}
 
 	// Skills
@@ -205,15 +354,31 @@ func (a *App) initAgent() {
 		if err := os.MkdirAll(skillsDir, 0755); err != nil {
 			log.Printf("failed to create skills directory: %v", err)
 		}
-		a.skillLoader = skill.NewLoader(skillsDir, a.cfg.Plugins.TimeoutSecs, a.cfg.Plugins.SandboxEnabled)
-		skills, err := a.skillLoader.LoadAll(a.cfg.Plugins.EnabledSkills)
+		if a.skillLoader != nil {
+			a.skillLoader.Close()
+		}
+		a.skillLoader = a.newSkillLoader(skillsDir)
+		if mode := a.cfg.Plugins.Isolation; mode != "" {
+			iso, err := skill.NewIsolation(mode, workspaceDir, a.cfg.Plugins.UnisolatedSkills)
+			if err != nil {
+				log.Printf("skill isolation unavailable, skills run without it: %v", err)
+			}
+			a.skillLoader.SetIsolation(iso)
+		}
+		loaded, err := a.skillLoader.Sync(registry, a.cfg.Plugins.EnabledSkills)
 		if err != nil {
 			log.Printf("failed to load skills: %v", err)
 		}
-		for _, s := range skills {
-			registry.Register(s)
+		log.Printf("Loaded %d skills", loaded)
+		// Pick up skills added, edited, or removed while running
+		enabledSkills := func() []string {
+			a.mu.RLock()
+			defer a.mu.RUnlock()
+			return a.cfg.Plugins.EnabledSkills
+		}
+		if err := a.skillLoader.Watch(a.ctx, registry, enabledSkills); err != nil {
+			log.Printf("skills will not reload automatically: %v", err)
 		}
-		log.Printf("Loaded %d skills", len(skills))
 	}
 
 	// Create agent
@@ -225,17 +390,70 @@ func (a *App) initAgent() {
 		a.bus,
 		a.chanMgr,
 	)
+	ag.SetApproval(a.cfg.Security.Approval)
 	a.mu.Lock()
 	a.agent = ag
+	a.registry = registry
 	a.mu.Unlock()
 
-	// Start Telegram if configured
+	// Start configured channels
+	a.workspace = workspaceDir
+	a.transcriber = nil
+	if tc := a.cfg.Transcription; tc.Enabled {
+		if tc.APIKey == "" && a.cfg.LLM.Provider == "openai" {
+			tc.APIKey = a.cfg.LLM.APIKey
+		}
+		if a.transcriber, err = llm.NewTranscriber(tc); err != nil {
+			log.Printf("voice transcription disabled: %v", err)
+		}
+	}
+	var embedder llm.Embedder
+	if ec := a.cfg.Embeddings; ec.Enabled {
+		if ec.APIKey == "" && a.cfg.LLM.Provider == "openai" {
+			ec.APIKey = a.cfg.LLM.APIKey
+		}
+		if embedder, err = llm.NewEmbedder(ec); err != nil {
+			log.Printf("semantic recall disabled: %v", err)
+		}
+	}
+	if mem, ok := a.mem.(interface{ SetEmbedder(llm.Embedder, int) }); ok {
+		mem.SetEmbedder(embedder, a.cfg.Embeddings.BatchSize)
+	}
+	channels := 0
 	if a.cfg.Channels.Telegram != nil && a.cfg.Channels.Telegram.Token != "" {
-		tg := channel.NewTelegramChannel(channel.TelegramConfig{
-			Token:      a.cfg.Channels.Telegram.Token,
-			AllowedIDs: a.cfg.Channels.Telegram.AllowedIDs,
-		})
-		a.chanMgr.Register(tg)
+		a.chanMgr.Register(a.newTelegramChannel(a.cfg.Channels.Telegram))
+		channels++
+	}
+	if dc := a.cfg.Channels.Discord; dc != nil && dc.Token != "" {
+		a.chanMgr.Register(channel.NewDiscordChannel(channel.DiscordConfig{
+			Token:          dc.Token,
+			AllowedUserIDs: dc.AllowedUserIDs,
+			AllowedRoleIDs: dc.AllowedRoleIDs,
+		}))
+		channels++
+	}
+	if sc := a.cfg.Channels.Slack; sc != nil && sc.BotToken != "" && sc.AppToken != "" {
+		a.chanMgr.Register(channel.NewSlackChannel(channel.SlackConfig{
+			BotToken:       sc.BotToken,
+			AppToken:       sc.AppToken,
+			AllowedUserIDs: sc.AllowedUserIDs,
+			AllowedTeamIDs: sc.AllowedTeamIDs,
+		}))
+		channels++
+	}
+	if hc := a.cfg.Channels.HTTP; hc != nil && hc.Token != "" {
+		addr := hc.Addr
+		if addr == "" {
+			addr = "127.0.0.1:8765"
+		}
+		a.chanMgr.Register(channel.NewHTTPChannel(channel.HTTPConfig{
+			Addr:         addr,
+			Token:        hc.Token,
+			ReplyTimeout: time.Duration(hc.ReplyTimeoutSecs) * time.Second,
+		}))
+		channels++
+	}
+	if channels > 0 {
 		if err := a.chanMgr.StartAll(a.ctx); err != nil {
 			log.Printf("failed to start channels: %v", err)
 		}
@@ -247,6 +465,37 @@ func (a *App) initAgent() {
 	debug.FreeOSMemory()
 }
 
+// newTelegramChannel builds the Telegram channel for tc.
+func (a *App) newTelegramChannel(tc *config.TelegramConfig) *channel.TelegramChannel {
+	return channel.NewTelegramChannel(channel.TelegramConfig{
+		Token:         tc.Token,
+		AllowedIDs:    tc.AllowedIDs,
+		Attachments:   attachmentPolicy(tc.Attachments),
+		AttachmentDir: filepath.Join(a.workspace, "attachments"),
+		Transcriber:   a.transcriber,
+		PerUserMemory: tc.PerUserMemory,
+	})
+}
+
+// replaceChannel stops the running channel called name and starts ch in its
+// place, leaving the agent and LLM untouched. A nil ch just removes it.
+func (a *App) replaceChannel(name string, ch channel.Channel) {
+	if _, ok := a.chanMgr.Get(name); ok {
+		if err := a.chanMgr.Remove(a.ctx, name); err != nil {
+			log.Printf("failed to remove %s channel: %v", name, err)
+		}
+	}
+	if ch == nil {
+		return
+	}
+	a.chanMgr.Register(ch)
+	if err := ch.Start(a.ctx); err != nil {
+		log.Printf("failed to start %s channel: %v", name, err)
+		return
+	}
+	log.Printf("%s channel restarted with new settings", name)
+}
+
 // resolveSecrets loads secrets from Keychain into in-memory config.
 // On first run, migrates plaintext secrets from config.json to Keychain.
 func (a *App) resolveSecrets() {
@@ -288,6 +537,107 @@ func (a *App) resolveSecrets() {
 		}
 	}
 
+	// Discord Token
+	if a.cfg.Channels.Discord != nil {
+		switch {
+		case a.cfg.Channels.Discord.Token == keyringPlaceholder:
+			if val, err := a.keyStore.Get(secretNameDiscordToken); err == nil {
+				a.cfg.Channels.Discord.Token = val
+			} else {
+				log.Printf("warning: failed to read Discord token from keyring: %v", err)
+			}
+		case a.cfg.Channels.Discord.Token != "":
+			if err := a.keyStore.Set(secretNameDiscordToken, a.cfg.Channels.Discord.Token); err == nil {
+				migrated = true
+				log.Println("Migrated Discord token to secure storage")
+			}
+		}
+	}
+
+	// Slack tokens
+	if sc := a.cfg.Channels.Slack; sc != nil {
+		for _, s := range []struct {
+			name  string
+			value *string
+		}{{secretNameSlackBot, &sc.BotToken}, {secretNameSlackApp, &sc.AppToken}} {
+			switch {
+			case *s.value == keyringPlaceholder:
+				if val, err := a.keyStore.Get(s.name); err == nil {
+					*s.value = val
+				} else {
+					log.Printf("warning: failed to read %s from keyring: %v", s.name, err)
+				}
+			case *s.value != "":
+				if err := a.keyStore.Set(s.name, *s.value); err == nil {
+					migrated = true
+					log.Printf("Migrated %s to secure storage", s.name)
+				}
+			}
+		}
+	}
+
+	// HTTP channel token
+	if a.cfg.Channels.HTTP != nil {
+		switch {
+		case a.cfg.Channels.HTTP.Token == keyringPlaceholder:
+			if val, err := a.keyStore.Get(secretNameHTTPToken); err == nil {
+				a.cfg.Channels.HTTP.Token = val
+			} else {
+				log.Printf("warning: failed to read HTTP channel token from keyring: %v", err)
+			}
+		case a.cfg.Channels.HTTP.Token != "":
+			if err := a.keyStore.Set(secretNameHTTPToken, a.cfg.Channels.HTTP.Token); err == nil {
+				migrated = true
+				log.Println("Migrated HTTP channel token to secure storage")
+			}
+		}
+	}
+
+	// Brave Search API key
+	switch {
+	case a.cfg.WebSearch.BraveAPIKey == keyringPlaceholder:
+		if val, err := a.keyStore.Get(secretNameBraveKey); err == nil {
+			a.cfg.WebSearch.BraveAPIKey = val
+		} else {
+			log.Printf("warning: failed to read Brave API key from keyring: %v", err)
+		}
+	case a.cfg.WebSearch.BraveAPIKey != "":
+		if err := a.keyStore.Set(secretNameBraveKey, a.cfg.WebSearch.BraveAPIKey); err == nil {
+			migrated = true
+			log.Println("Migrated Brave API key to secure storage")
+		}
+	}
+
+	// Transcription API key
+	switch {
+	case a.cfg.Transcription.APIKey == keyringPlaceholder:
+		if val, err := a.keyStore.Get(secretNameTranscriptionKey); err == nil {
+			a.cfg.Transcription.APIKey = val
+		} else {
+			log.Printf("warning: failed to read transcription API key from keyring: %v", err)
+		}
+	case a.cfg.Transcription.APIKey != "":
+		if err := a.keyStore.Set(secretNameTranscriptionKey, a.cfg.Transcription.APIKey); err == nil {
+			migrated = true
+			log.Println("Migrated transcription API key to secure storage")
+		}
+	}
+
+	// Embeddings API key
+	switch {
+	case a.cfg.Embeddings.APIKey == keyringPlaceholder:
+		if val, err := a.keyStore.Get(secretNameEmbeddingsKey); err == nil {
+			a.cfg.Embeddings.APIKey = val
+		} else {
+			log.Printf("warning: failed to read embeddings API key from keyring: %v", err)
+		}
+	case a.cfg.Embeddings.APIKey != "":
+		if err := a.keyStore.Set(secretNameEmbeddingsKey, a.cfg.Embeddings.APIKey); err == nil {
+			migrated = true
+			log.Println("Migrated embeddings API key to secure storage")
+		}
+	}
+
 	// Rewrite config.json with placeholders instead of real keys
 	if migrated {
 		if err := a.saveConfig(); err != nil {
@@ -296,24 +646,153 @@ func (a *App) resolveSecrets() {
 	}
 }
 
+// DeleteSecret removes a stored secret, by its keychain name, from the
+// keychain and vault and clears it from the config. Deleting a secret that
+// isn't stored is not an error.
+func (a *App) DeleteSecret(name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.clearSecret(name) {
+		return fmt.Errorf("unknown secret %q", name)
+	}
+	if a.keyStore != nil {
+		if err := a.keyStore.Delete(name); err != nil {
+			return fmt.Errorf("delete %s: %w", name, err)
+		}
+	}
+	return a.saveConfig()
+}
+
+// deleteStoredSecret removes a secret from the keychain and vault after its
+// config value was cleared. a.mu must be held.
+func (a *App) deleteStoredSecret(name string) {
+	if a.keyStore == nil {
+		return
+	}
+	if err := a.keyStore.Delete(name); err != nil {
+		log.Printf("warning: failed to delete %s from secure storage: %v", name, err)
+	}
+}
+
+// clearSecret empties the config value stored under name and reports
+// whether name is a known secret. a.mu must be held.
+func (a *App) clearSecret(name string) bool {
+	switch name {
+	case secretNameLLMKey:
+		a.cfg.LLM.APIKey = ""
+	case secretNameTelegramToken:
+		if a.cfg.Channels.Telegram != nil {
+			a.cfg.Channels.Telegram.Token = ""
+		}
+	case secretNameDiscordToken:
+		if a.cfg.Channels.Discord != nil {
+			a.cfg.Channels.Discord.Token = ""
+		}
+	case secretNameSlackBot:
+		if a.cfg.Channels.Slack != nil {
+			a.cfg.Channels.Slack.BotToken = ""
+		}
+	case secretNameSlackApp:
+		if a.cfg.Channels.Slack != nil {
+			a.cfg.Channels.Slack.AppToken = ""
+		}
+	case secretNameHTTPToken:
+		if a.cfg.Channels.HTTP != nil {
+			a.cfg.Channels.HTTP.Token = ""
+		}
+	case secretNameBraveKey:
+		a.cfg.WebSearch.BraveAPIKey = ""
+	case secretNameTranscriptionKey:
+		a.cfg.Transcription.APIKey = ""
+	case secretNameEmbeddingsKey:
+		a.cfg.Embeddings.APIKey = ""
+	case secretNameCookieKey:
+		// Not part of the config; saved cookies become unreadable.
+	default:
+		return false
+	}
+	return true
+}
+
+// cookieKey returns the key that encrypts saved browser cookies, generating
+// and storing it in the keyring on first use.
+func (a *App) cookieKey() ([]byte, error) {
+	if a.keyStore == nil {
+		return nil, fmt.Errorf("no secure key storage")
+	}
+	if val, err := a.keyStore.Get(secretNameCookieKey); err == nil {
+		return base64.StdEncoding.DecodeString(val)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := a.keyStore.Set(secretNameCookieKey, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 // saveConfig writes config to disk with secrets replaced by [keyring] placeholders.
 // In-memory a.cfg always retains real keys; only the file gets placeholders.
 func (a *App) saveConfig() error {
+	a.updateLogSecrets()
 	if a.keyStore == nil {
-		return a.saveConfig()
+		return a.cfgLoader.Save(a.cfg)
 	}
 
 	// Store secrets in Keychain
 	if a.cfg.LLM.APIKey != "" && a.cfg.LLM.APIKey != keyringPlaceholder {
 		if err := a.keyStore.Set(secretNameLLMKey, a.cfg.LLM.APIKey); err != nil {
 			log.Printf("warning: failed to store LLM key in keyring: %v", err)
-			return a.saveConfig() // fallback: save plaintext
+			return a.cfgLoader.Save(a.cfg) // fallback: save plaintext
 		}
 	}
 	if a.cfg.Channels.Telegram != nil && a.cfg.Channels.Telegram.Token != "" && a.cfg.Channels.Telegram.Token != keyringPlaceholder {
 		if err := a.keyStore.Set(secretNameTelegramToken, a.cfg.Channels.Telegram.Token); err != nil {
 			log.Printf("warning: failed to store Telegram token in keyring: %v", err)
-			return a.saveConfig()
+			return a.cfgLoader.Save(a.cfg)
+		}
+	}
+	if a.cfg.Channels.Discord != nil && a.cfg.Channels.Discord.Token != "" && a.cfg.Channels.Discord.Token != keyringPlaceholder {
+		if err := a.keyStore.Set(secretNameDiscordToken, a.cfg.Channels.Discord.Token); err != nil {
+			log.Printf("warning: failed to store Discord token in keyring: %v", err)
+			return a.cfgLoader.Save(a.cfg)
+		}
+	}
+	if sc := a.cfg.Channels.Slack; sc != nil {
+		for name, value := range map[string]string{secretNameSlackBot: sc.BotToken, secretNameSlackApp: sc.AppToken} {
+			if value == "" || value == keyringPlaceholder {
+				continue
+			}
+			if err := a.keyStore.Set(name, value); err != nil {
+				log.Printf("warning: failed to store %s in keyring: %v", name, err)
+				return a.cfgLoader.Save(a.cfg)
+			}
+		}
+	}
+	if a.cfg.Channels.HTTP != nil && a.cfg.Channels.HTTP.Token != "" && a.cfg.Channels.HTTP.Token != keyringPlaceholder {
+		if err := a.keyStore.Set(secretNameHTTPToken, a.cfg.Channels.HTTP.Token); err != nil {
+			log.Printf("warning: failed to store HTTP channel token in keyring: %v", err)
+			return a.cfgLoader.Save(a.cfg)
+		}
+	}
+	if a.cfg.WebSearch.BraveAPIKey != "" && a.cfg.WebSearch.BraveAPIKey != keyringPlaceholder {
+		if err := a.keyStore.Set(secretNameBraveKey, a.cfg.WebSearch.BraveAPIKey); err != nil {
+			log.Printf("warning: failed to store Brave API key in keyring: %v", err)
+			return a.cfgLoader.Save(a.cfg)
+		}
+	}
+	if a.cfg.Transcription.APIKey != "" && a.cfg.Transcription.APIKey != keyringPlaceholder {
+		if err := a.keyStore.Set(secretNameTranscriptionKey, a.cfg.Transcription.APIKey); err != nil {
+			log.Printf("warning: failed to store transcription API key in keyring: %v", err)
+			return a.cfgLoader.Save(a.cfg)
+		}
+	}
+	if a.cfg.Embeddings.APIKey != "" && a.cfg.Embeddings.APIKey != keyringPlaceholder {
+		if err := a.keyStore.Set(secretNameEmbeddingsKey, a.cfg.Embeddings.APIKey); err != nil {
+			log.Printf("warning: failed to store embeddings API key in keyring: %v", err)
+			return a.cfgLoader.Save(a.cfg)
 		}
 	}
 
@@ -327,6 +806,35 @@ func (a *App) saveConfig() error {
 		tgCopy.Token = keyringPlaceholder
 		cfgForDisk.Channels.Telegram = &tgCopy
 	}
+	if cfgForDisk.Channels.Discord != nil && cfgForDisk.Channels.Discord.Token != "" {
+		dcCopy := *cfgForDisk.Channels.Discord
+		dcCopy.Token = keyringPlaceholder
+		cfgForDisk.Channels.Discord = &dcCopy
+	}
+	if cfgForDisk.Channels.Slack != nil {
+		slackCopy := *cfgForDisk.Channels.Slack
+		if slackCopy.BotToken != "" {
+			slackCopy.BotToken = keyringPlaceholder
+		}
+		if slackCopy.AppToken != "" {
+			slackCopy.AppToken = keyringPlaceholder
+		}
+		cfgForDisk.Channels.Slack = &slackCopy
+	}
+	if cfgForDisk.Channels.HTTP != nil && cfgForDisk.Channels.HTTP.Token != "" {
+		httpCopy := *cfgForDisk.Channels.HTTP
+		httpCopy.Token = keyringPlaceholder
+		cfgForDisk.Channels.HTTP = &httpCopy
+	}
+	if cfgForDisk.WebSearch.BraveAPIKey != "" {
+		cfgForDisk.WebSearch.BraveAPIKey = keyringPlaceholder
+	}
+	if cfgForDisk.Transcription.APIKey != "" {
+		cfgForDisk.Transcription.APIKey = keyringPlaceholder
+	}
+	if cfgForDisk.Embeddings.APIKey != "" {
+		cfgForDisk.Embeddings.APIKey = keyringPlaceholder
+	}
 
 	return a.cfgLoader.Save(&cfgForDisk)
 }
@@ -343,6 +851,7 @@ func (a *App) addLog(level string, payload any) {
 		entry.Message = v.Error()
 	}
 	a.logsMu.Lock()
+	entry.Message = a.redactLog(entry.Message)
 	a.logs = append(a.logs, entry)
 	if len(a.logs) > 1000 {
 		a.logs = a.logs[len(a.logs)-500:]
@@ -350,6 +859,52 @@ func (a *App) addLog(level string, payload any) {
 	a.logsMu.Unlock()
 }
 
+// redactLog masks configured secrets, API keys, and PII in a log message so
+// the diagnostics panel can't leak them. a.logsMu must be held.
+func (a *App) redactLog(msg string) string {
+	for _, secret := range a.logSecrets {
+		msg = scrubSecret(msg, secret)
+	}
+	if a.sanitizer != nil {
+		msg = a.sanitizer.Redact(msg)
+	}
+	return msg
+}
+
+// updateLogSecrets records the secrets in a.cfg for redactLog. a.mu must be
+// held, or the app still starting up.
+func (a *App) updateLogSecrets() {
+	secrets := []string{a.cfg.LLM.APIKey, a.cfg.WebSearch.BraveAPIKey, a.cfg.Transcription.APIKey, a.cfg.Embeddings.APIKey}
+	if a.cfg.FallbackLLM != nil {
+		secrets = append(secrets, a.cfg.FallbackLLM.APIKey)
+	}
+	if tg := a.cfg.Channels.Telegram; tg != nil {
+		secrets = append(secrets, tg.Token)
+	}
+	if dc := a.cfg.Channels.Discord; dc != nil {
+		secrets = append(secrets, dc.Token)
+	}
+	if sc := a.cfg.Channels.Slack; sc != nil {
+		secrets = append(secrets, sc.BotToken, sc.AppToken)
+	}
+	if hc := a.cfg.Channels.HTTP; hc != nil {
+		secrets = append(secrets, hc.Token)
+	}
+	secrets = slices.DeleteFunc(secrets, func(s string) bool { return s == "" || s == keyringPlaceholder })
+
+	a.logsMu.Lock()
+	a.logSecrets = secrets
+	a.logsMu.Unlock()
+}
+
+// scrubSecret masks every occurrence of secret in msg.
+func scrubSecret(msg, secret string) string {
+	if secret == "" {
+		return msg
+	}
+	return strings.ReplaceAll(msg, secret, security.MaskKey(secret))
+}
+
 // --- Wails Bindings (exposed to frontend) ---
 
 // IsSetupCompleted returns whether the initial setup has been done.
@@ -372,17 +927,21 @@ func (a *App) GetConfig() map[string]any {
 	}
 
 	return map[string]any{
-		"provider":         a.cfg.LLM.Provider,
-		"model":            a.cfg.LLM.Model,
-		"api_key_masked":   security.MaskKey(a.cfg.LLM.APIKey),
-		"base_url":         a.cfg.LLM.BaseURL,
-		"has_telegram":     a.cfg.Channels.Telegram != nil && a.cfg.Channels.Telegram.Token != "",
-		"pii_filtering":    a.cfg.Security.PIIFiltering.Enabled,
-		"browser_enabled":  a.cfg.Browser.Enabled,
-		"browser_headless": a.cfg.Browser.Headless,
-		"plugins_enabled":  a.cfg.Plugins.Enabled,
-		"skills_count":     skillsCount,
-		"setup_completed":  a.cfg.SetupCompleted,
+		"provider":                a.cfg.LLM.Provider,
+		"model":                   a.cfg.LLM.Model,
+		"api_key_masked":          security.MaskKey(a.cfg.LLM.APIKey),
+		"base_url":                a.cfg.LLM.BaseURL,
+		"has_telegram":            a.cfg.Channels.Telegram != nil && a.cfg.Channels.Telegram.Token != "",
+		"has_discord":             a.cfg.Channels.Discord != nil && a.cfg.Channels.Discord.Token != "",
+		"pii_filtering":           a.cfg.Security.PIIFiltering.Enabled,
+		"browser_enabled":         a.cfg.Browser.Enabled,
+		"browser_headless":        a.cfg.Browser.Headless,
+		"browser_user_agent":      a.cfg.Browser.UserAgent,
+		"browser_viewport_width":  a.cfg.Browser.ViewportWidth,
+		"browser_viewport_height": a.cfg.Browser.ViewportHeight,
+		"plugins_enabled":         a.cfg.Plugins.Enabled,
+		"skills_count":            skillsCount,
+		"setup_completed":         a.cfg.SetupCompleted,
 	}
 }
 
@@ -401,35 +960,228 @@ func (a *App) SaveLLMConfig(provider, apiKey, model, baseURL string) error {
 		a.cfg.LLM.Model = model
 	}
 	a.cfg.LLM.BaseURL = baseURL
-	return a.saveConfig()
+	if apiKey == "" {
+		a.deleteStoredSecret(secretNameLLMKey)
+	}
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	return a.applyProvider()
 }
 
-// SaveTelegramConfig saves Telegram settings.
+// SaveTelegramConfig saves Telegram settings. If the agent is running, the
+// Telegram channel is restarted with them.
 func (a *App) SaveTelegramConfig(token string, allowedIDs []int64) error {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.cfg.Channels.Telegram = &config.TelegramConfig{
-		Token:      token,
-		AllowedIDs: allowedIDs,
+	tg := config.TelegramConfig{}
+	if a.cfg.Channels.Telegram != nil {
+		tg = *a.cfg.Channels.Telegram
 	}
-	return a.saveConfig()
+	tg.Token = token
+	tg.AllowedIDs = allowedIDs
+	a.cfg.Channels.Telegram = &tg
+	if token == "" {
+		a.deleteStoredSecret(secretNameTelegramToken)
+	}
+	err := a.saveConfig()
+	running := a.agent != nil
+	a.mu.Unlock()
+	if err != nil || !running {
+		return err
+	}
+
+	// Starting the bot calls Telegram, so it happens outside the lock.
+	var ch channel.Channel
+	if token != "" {
+		ch = a.newTelegramChannel(&tg)
+	}
+	a.replaceChannel("telegram", ch)
+	return nil
+}
+
+// SaveDiscordConfig saves Discord settings. allowedUserIDs and
+// allowedRoleIDs are comma-separated; empty allows everyone. If the agent
+// is running, the Discord channel is restarted with them.
+func (a *App) SaveDiscordConfig(token, allowedUserIDs, allowedRoleIDs string) error {
+	a.mu.Lock()
+	dc := &config.DiscordConfig{
+		Token:          token,
+		AllowedUserIDs: splitAndTrim(allowedUserIDs),
+		AllowedRoleIDs: splitAndTrim(allowedRoleIDs),
+	}
+	a.cfg.Channels.Discord = dc
+	if token == "" {
+		a.deleteStoredSecret(secretNameDiscordToken)
+	}
+	err := a.saveConfig()
+	running := a.agent != nil
+	a.mu.Unlock()
+	if err != nil || !running {
+		return err
+	}
+
+	var ch channel.Channel
+	if token != "" {
+		ch = channel.NewDiscordChannel(channel.DiscordConfig{
+			Token:          dc.Token,
+			AllowedUserIDs: dc.AllowedUserIDs,
+			AllowedRoleIDs: dc.AllowedRoleIDs,
+		})
+	}
+	a.replaceChannel("discord", ch)
+	return nil
 }
 
 // SaveSecurityConfig saves security settings.
-func (a *App) SaveSecurityConfig(piiEnabled, filterEmails, filterPhones, filterCards, filterIPs, filterSSN bool) error {
+func (a *App) SaveSecurityConfig(piiEnabled, filterEmails, filterPhones, filterCards, filterIPs, filterSSN, filterIBANs, filterPassports, filterAPIKeys bool) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	a.cfg.Security.PIIFiltering = config.PIIFilterConfig{
-		Enabled:      piiEnabled,
-		FilterEmails: filterEmails,
-		FilterPhones: filterPhones,
-		FilterCards:  filterCards,
-		FilterIPs:    filterIPs,
-		FilterSSN:    filterSSN,
-	}
+	// Update in place so settings the form doesn't show, such as custom
+	// patterns, are kept.
+	pii := &a.cfg.Security.PIIFiltering
+	pii.Enabled = piiEnabled
+	pii.FilterEmails = filterEmails
+	pii.FilterPhones = filterPhones
+	pii.FilterCards = filterCards
+	pii.FilterIPs = filterIPs
+	pii.FilterSSN = filterSSN
+	pii.FilterIBANs = filterIBANs
+	pii.FilterPassports = filterPassports
+	pii.FilterAPIKeys = filterAPIKeys
 	return a.saveConfig()
 }
 
+// IsLocked reports whether a master password protects the secret vault and
+// has not been entered yet. The GUI prompts for it and calls Unlock.
+func (a *App) IsLocked() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.keyStore != nil && a.cfg != nil &&
+		a.cfg.Security.MasterPasswordHash != "" && a.keyStore.IsLocked()
+}
+
+// SetMasterPassword sets the master password that encrypts the secret vault,
+// used when the OS keyring is unavailable. It can only be set once.
+func (a *App) SetMasterPassword(password string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keyStore == nil {
+		return fmt.Errorf("secure key storage is unavailable")
+	}
+	if a.cfg.Security.MasterPasswordHash != "" {
+		return fmt.Errorf("a master password is already set")
+	}
+	record, key, err := security.NewMasterPassword(password)
+	if err != nil {
+		return err
+	}
+	a.keyStore.SetMasterKey(key)
+	a.cfg.Security.MasterPasswordHash = record
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.unlockMemory()
+	return nil
+}
+
+// ChangeMasterPassword re-encrypts the secret vault under a key derived
+// from newPassword once current is verified.
+func (a *App) ChangeMasterPassword(current, newPassword string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keyStore == nil || a.cfg.Security.MasterPasswordHash == "" {
+		return fmt.Errorf("no master password is set")
+	}
+	oldKey, err := security.VerifyMasterPassword(a.cfg.Security.MasterPasswordHash, current)
+	if err != nil {
+		return err
+	}
+	record, newKey, err := security.NewMasterPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := a.keyStore.Rotate(oldKey, newKey); err != nil {
+		return fmt.Errorf("re-encrypt vault: %w", err)
+	}
+
+	oldRecord := a.cfg.Security.MasterPasswordHash
+	a.cfg.Security.MasterPasswordHash = record
+	if err := a.saveConfig(); err != nil {
+		// Keep the vault readable with the password config still holds.
+		a.cfg.Security.MasterPasswordHash = oldRecord
+		if rerr := a.keyStore.Rotate(newKey, oldKey); rerr != nil {
+			log.Printf("failed to restore the vault key: %v", rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Unlock checks the master password, loads the vault key, and reads the
+// secrets kept in the vault. If setup is complete, the agent is started.
+func (a *App) Unlock(password string) error {
+	a.mu.Lock()
+	if a.keyStore == nil || a.cfg.Security.MasterPasswordHash == "" {
+		a.mu.Unlock()
+		return fmt.Errorf("no master password is set")
+	}
+	key, err := security.VerifyMasterPassword(a.cfg.Security.MasterPasswordHash, password)
+	if err != nil {
+		a.mu.Unlock()
+		return err
+	}
+	a.keyStore.SetMasterKey(key)
+	a.unlockMemory()
+	a.resolveSecrets()
+	a.updateLogSecrets()
+	start := a.cfg.SetupCompleted && a.agent == nil
+	a.mu.Unlock()
+
+	if start {
+		a.initAgent()
+	}
+	return nil
+}
+
+// memoryKeyName is the vault entry holding the key that encrypts the memory
+// database at rest.
+const memoryKeyName = "memory_key"
+
+// unlockMemory gives the memory database its key from the vault, which the
+// master password has just unlocked. Rows written in plaintext before are
+// encrypted then. a.mu must be held.
+func (a *App) unlockMemory() {
+	mem, ok := a.mem.(*memory.SQLiteMemory)
+	if !ok {
+		return
+	}
+	key, err := a.keyStore.VaultKey(memoryKeyName)
+	if err != nil {
+		log.Printf("memory stays unencrypted: %v", err)
+		return
+	}
+	if err := mem.Unlock(key); err != nil {
+		log.Printf("failed to encrypt memory: %v", err)
+		return
+	}
+	a.loadPIIMappings()
+}
+
+// loadPIIMappings attaches the memory database to the sanitizer so PII
+// placeholders and their counters persist. An encrypted database can't be
+// read until unlockMemory, which calls this again.
+func (a *App) loadPIIMappings() {
+	mem, ok := a.mem.(*memory.SQLiteMemory)
+	if !ok || !a.cfg.Security.PIIFiltering.PersistMappings {
+		return
+	}
+	if err := a.sanitizer.SetStore(mem); errors.Is(err, memory.ErrLocked) {
+		log.Println("Persisted PII mappings will load once memory is unlocked")
+	} else if err != nil {
+		log.Printf("warning: failed to load persisted PII mappings: %v", err)
+	}
+}
+
 // CompleteSetup marks setup as done and initializes the agent.
 func (a *App) CompleteSetup() error {
 	a.mu.Lock()
@@ -471,8 +1223,19 @@ func (a *App) TestLLMConnection(provider, apiKey, model, baseURL string) string
 	)
 
 	if err := tmpAgent.TestConnection(a.ctx); err != nil {
-		return "Connection failed: " + err.Error()
+		// Provider errors can echo the key back.
+		return "Connection failed: " + scrubSecret(err.Error(), apiKey)
+	}
+	return "OK"
+}
+
+// TestDiscordConnection tests a Discord bot token.
+func (a *App) TestDiscordConnection(token string) string {
+	dc := channel.NewDiscordChannel(channel.DiscordConfig{Token: token})
+	if err := dc.Start(a.ctx); err != nil {
+		return "Connection failed: " + scrubSecret(err.Error(), token)
 	}
+	dc.Stop(a.ctx)
 	return "OK"
 }
 
@@ -480,7 +1243,7 @@ func (a *App) TestLLMConnection(provider, apiKey, model, baseURL string) string
 func (a *App) TestTelegramConnection(token string) string {
 	tg := channel.NewTelegramChannel(channel.TelegramConfig{Token: token})
 	if err := tg.Start(a.ctx); err != nil {
-		return "Connection failed: " + err.Error()
+		return "Connection failed: " + scrubSecret(err.Error(), token)
 	}
 	tg.Stop(a.ctx)
 	return "OK"
@@ -495,17 +1258,115 @@ func (a *App) SendMessage(text string) string {
 		return "Agent not initialized. Please complete setup first."
 	}
 	// Sanitize PII
-	sanitized := a.sanitizer.Sanitize(text)
-	response, err := ag.HandleDirectMessage(a.ctx, "gui", sanitized)
+	sanitized := a.sanitizer.Sanitize(guiChatID, text)
+	response, err := ag.HandleDirectMessage(a.ctx, guiChatID, sanitized)
 	if err != nil {
 		return "Error: " + err.Error()
 	}
 	// Restore PII in response
-	return a.sanitizer.Restore(response)
+	return a.sanitizer.Restore(guiChatID, response)
+}
+
+// CancelRequest stops the message the agent is processing for chatID. The
+// pending SendMessage call returns with whatever the agent had written.
+func (a *App) CancelRequest(chatID string) error {
+	a.mu.RLock()
+	ag := a.agent
+	a.mu.RUnlock()
+	if ag == nil {
+		return fmt.Errorf("agent not running")
+	}
+	if !ag.CancelRequest(chatID) {
+		return fmt.Errorf("no request in progress for %s", chatID)
+	}
+	return nil
+}
+
+// GetToolMetrics returns per-tool call counts, error counts, timings, and
+// last errors since the agent started, for the diagnostics panel.
+func (a *App) GetToolMetrics() []tool.ToolStats {
+	a.mu.RLock()
+	ag := a.agent
+	a.mu.RUnlock()
+	if ag == nil {
+		return []tool.ToolStats{}
+	}
+	return ag.ToolMetrics()
+}
+
+// ApproveToolCall answers a tool_approval_request event, letting the tool
+// call run or denying it.
+func (a *App) ApproveToolCall(callID string, approved bool) error {
+	a.mu.RLock()
+	ag := a.agent
+	a.mu.RUnlock()
+	if ag == nil {
+		return fmt.Errorf("agent not running")
+	}
+	return ag.Approve(callID, approved)
+}
+
+// GetSystemPrompt returns the system prompt set for chatID, or "" if the
+// chat uses the default.
+func (a *App) GetSystemPrompt(chatID string) (string, error) {
+	if a.mem == nil {
+		return "", fmt.Errorf("memory not initialized")
+	}
+	s, err := a.mem.GetChatSettings(a.ctx, chatID)
+	return s.SystemPrompt, err
+}
+
+// SetSystemPrompt gives chatID its own system prompt. An empty prompt
+// reverts the chat to the default from the agent settings.
+func (a *App) SetSystemPrompt(chatID, prompt string) error {
+	a.mu.RLock()
+	ag := a.agent
+	a.mu.RUnlock()
+	if ag == nil {
+		return fmt.Errorf("agent not running")
+	}
+	return ag.SetSystemPrompt(a.ctx, chatID, prompt)
+}
+
+// GetChatSettings returns chatID's overrides of the agent defaults.
+func (a *App) GetChatSettings(chatID string) (memory.ChatSettings, error) {
+	if a.mem == nil {
+		return memory.ChatSettings{}, fmt.Errorf("memory not initialized")
+	}
+	return a.mem.GetChatSettings(a.ctx, chatID)
+}
+
+// SetChatSettings replaces chatID's system prompt, model, temperature, and
+// max tokens overrides. Empty fields use the defaults.
+func (a *App) SetChatSettings(chatID string, s memory.ChatSettings) error {
+	a.mu.RLock()
+	ag := a.agent
+	a.mu.RUnlock()
+	if ag == nil {
+		return fmt.Errorf("agent not running")
+	}
+	return ag.SetChatSettings(a.ctx, chatID, s)
+}
+
+// AdminBroadcast sends an announcement to every chat that has messaged the
+// agent, on channelName or on all running channels if it is empty. It is
+// refused unless channels.allow_broadcast is enabled.
+func (a *App) AdminBroadcast(channelName, text string) (int, error) {
+	if !a.cfg.Channels.AllowBroadcast {
+		return 0, fmt.Errorf("broadcast is disabled; enable channels.allow_broadcast in the config")
+	}
+	if a.chanMgr == nil {
+		return 0, fmt.Errorf("channels not initialized")
+	}
+	if strings.TrimSpace(text) == "" {
+		return 0, fmt.Errorf("broadcast text is empty")
+	}
+	log.Printf("Admin broadcast to %q", channelName)
+	return a.chanMgr.BroadcastChannel(a.ctx, channelName, text)
 }
 
 // SaveBrowserConfig saves browser control settings.
-func (a *App) SaveBrowserConfig(enabled, headless bool, timeoutSecs, maxTabs int, allowedDomains, deniedDomains string) error {
+func (a *App) SaveBrowserConfig(enabled, headless bool, timeoutSecs, maxTabs int, allowedDomains, deniedDomains, userAgent string, viewportWidth, viewportHeight int) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.cfg.Browser.Enabled = enabled
@@ -526,6 +1387,11 @@ func (a *App) SaveBrowserConfig(enabled, headless bool, timeoutSecs, maxTabs int
 	} else {
 		a.cfg.Browser.DeniedDomains = nil
 	}
+	a.cfg.Browser.UserAgent = strings.TrimSpace(userAgent)
+	if viewportWidth > 0 && viewportHeight > 0 {
+		a.cfg.Browser.ViewportWidth = viewportWidth
+		a.cfg.Browser.ViewportHeight = viewportHeight
+	}
 	return a.saveConfig()
 }
 
@@ -539,28 +1405,115 @@ func (a *App) SavePluginsConfig(enabled bool, enabledSkills []string, timeoutSec
 		a.cfg.Plugins.TimeoutSecs = timeoutSecs
 	}
 	a.cfg.Plugins.SandboxEnabled = sandboxEnabled
-	return a.saveConfig()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	// Load newly enabled skills and drop disabled ones right away
+	if a.skillLoader != nil && a.registry != nil {
+		if _, err := a.skillLoader.Sync(a.registry, enabledSkills); err != nil {
+			log.Printf("failed to reload skills: %v", err)
+		}
+	}
+	return nil
 }
 
 // GetInstalledSkills returns the list of installed skills.
 func (a *App) GetInstalledSkills() []skill.SkillInfo {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	if a.skillLoader == nil {
-		// Create a temporary loader to list skills even before agent init
-		home, err := os.UserHomeDir()
-		if err != nil {
-			log.Printf("failed to get home directory: %v", err)
-			return nil
+	loader, err := a.currentSkillLoader()
+	if err != nil {
+		log.Printf("failed to get home directory: %v", err)
+		return nil
+	}
+	return loader.ListInstalled(a.cfg.Plugins.EnabledSkills)
+}
+
+// InstallSkill downloads a skill from an https URL of a zip or tar archive,
+// or of a git repository, into the skills directory. Nothing from the
+// source is run. A running agent picks the skill up from the directory.
+func (a *App) InstallSkill(source string) (*skill.SkillInfo, error) {
+	a.mu.RLock()
+	loader, err := a.currentSkillLoader()
+	a.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	info, err := loader.Install(context.Background(), source)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Installed skill %s %s from %s", info.Name, info.Version, source)
+	return info, nil
+}
+
+// SetSkillSecret stores the value of an environment variable that a skill
+// declares in its manifest, such as an API key, in the keychain or vault.
+// The skill sees it on its next run. An empty value removes it.
+func (a *App) SetSkillSecret(skillName, name, value string) error {
+	a.mu.RLock()
+	loader, err := a.currentSkillLoader()
+	ks := a.keyStore
+	a.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if ks == nil {
+		return fmt.Errorf("no secure key storage")
+	}
+	declared := false
+	for _, s := range loader.ListInstalled(nil) {
+		if s.Name != skillName {
+			continue
 		}
-		skillsDir := a.cfg.Plugins.SkillsDir
-		if skillsDir == "" {
-			skillsDir = filepath.Join(home, ".opendan", "skills")
+		for _, v := range s.Env {
+			declared = declared || v.Name == name
 		}
-		loader := skill.NewLoader(skillsDir, a.cfg.Plugins.TimeoutSecs, a.cfg.Plugins.SandboxEnabled)
-		return loader.ListInstalled(a.cfg.Plugins.EnabledSkills)
 	}
-	return a.skillLoader.ListInstalled(a.cfg.Plugins.EnabledSkills)
+	if !declared {
+		return fmt.Errorf("skill %s doesn't declare %s", skillName, name)
+	}
+	key := skill.EnvSecretName(skillName, name)
+	if value == "" {
+		return ks.Delete(key)
+	}
+	return ks.Set(key, value)
+}
+
+// currentSkillLoader returns the agent's skill loader, or a temporary one
+// so skills can be managed before the agent starts. a.mu must be held.
+func (a *App) currentSkillLoader() (*skill.Loader, error) {
+	if a.skillLoader != nil {
+		return a.skillLoader, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	skillsDir := a.cfg.Plugins.SkillsDir
+	if skillsDir == "" {
+		skillsDir = filepath.Join(home, ".opendan", "skills")
+	}
+	return a.newSkillLoader(skillsDir), nil
+}
+
+// newSkillLoader creates a loader for the skills in dir that verifies them
+// as configured. If the trusted keys are unusable it fails closed, loading
+// only skills with a matching checksum.
+func (a *App) newSkillLoader(dir string) *skill.Loader {
+	loader := skill.NewLoader(dir, a.cfg.Plugins.TimeoutSecs, a.cfg.Plugins.SandboxEnabled)
+	v, err := skill.NewVerifier(a.cfg.Plugins.VerifyMode, a.cfg.Plugins.TrustedKeys)
+	if err != nil {
+		log.Printf("skill verification: %v; loading only skills with a matching sha256", err)
+		v, _ = skill.NewVerifier(config.SkillVerifyStrict, nil)
+	}
+	loader.SetVerifier(v)
+	loader.SetURLPolicy(tool.NewURLPolicy(a.cfg.HTTPRequest))
+	loader.SetEventBus(a.bus)
+	if a.keyStore != nil {
+		loader.SetSecrets(a.keyStore)
+	}
+	return loader
 }
 
 // validateBaseURL checks that a base URL is valid and uses http/https scheme.
@@ -580,6 +1533,15 @@ func validateBaseURL(rawURL string) error {
 	return nil
 }
 
+// attachmentPolicy converts attachment limits from config units to bytes.
+func attachmentPolicy(cfg config.AttachmentConfig) channel.AttachmentPolicy {
+	return channel.AttachmentPolicy{
+		MaxSize:        int64(cfg.MaxSizeKB) << 10,
+		AllowedTypes:   cfg.AllowedTypes,
+		MaxChatStorage: int64(cfg.MaxChatStorageMB) << 20,
+	}
+}
+
 func splitAndTrim(s string) []string {
 	var result []string
 	for _, part := range strings.Split(s, ",") {
@@ -591,6 +1553,120 @@ func splitAndTrim(s string) []string {
 	return result
 }
 
+// ListConversations returns all stored chats, most recently active first.
+func (a *App) ListConversations() []memory.ChatSummary {
+	if a.mem == nil {
+		return nil
+	}
+	chats, err := a.mem.ListChats(a.ctx)
+	if err != nil {
+		log.Printf("failed to list conversations: %v", err)
+		return nil
+	}
+	return chats
+}
+
+// GetHistoryPage returns one page of a chat's messages, newest page first
+// (offset 0), for scrolling back through the conversation.
+func (a *App) GetHistoryPage(chatID string, limit, offset int) []llm.Message {
+	if a.mem == nil {
+		return nil
+	}
+	messages, err := a.mem.GetHistoryPage(a.ctx, chatID, limit, offset)
+	if err != nil {
+		log.Printf("failed to load history: %v", err)
+		return nil
+	}
+	for i := range messages {
+		messages[i].Content = a.sanitizer.Restore(chatID, messages[i].Content)
+	}
+	return messages
+}
+
+// SearchMemory runs a full-text search across all stored messages.
+func (a *App) SearchMemory(query string) []memory.SearchResult {
+	if a.mem == nil {
+		return nil
+	}
+	results, err := a.mem.SearchMessages(a.ctx, query, 50)
+	if err != nil {
+		log.Printf("failed to search memory: %v", err)
+		return nil
+	}
+	return results
+}
+
+// ExportConversation returns a chat transcript as "markdown" or "json" for the
+// frontend to download. PII placeholders are restored to their original values.
+func (a *App) ExportConversation(chatID, format string) ([]byte, error) {
+	if a.mem == nil {
+		return nil, fmt.Errorf("memory not initialized")
+	}
+	data, err := a.mem.ExportConversation(a.ctx, chatID, format)
+	if err != nil {
+		return nil, err
+	}
+	if format != "json" {
+		return []byte(a.sanitizer.Restore(chatID, string(data))), nil
+	}
+
+	// Restore field by field so original values are JSON-escaped.
+	var conv memory.Conversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		return nil, err
+	}
+	conv.Summary = a.sanitizer.Restore(chatID, conv.Summary)
+	for i := range conv.Messages {
+		conv.Messages[i].Content = a.sanitizer.Restore(chatID, conv.Messages[i].Content)
+	}
+	return json.MarshalIndent(conv, "", "  ")
+}
+
+// GetUsageStats reports which providers and models served stored replies,
+// including how often a fallback provider was used and how many retries
+// were needed.
+func (a *App) GetUsageStats() []memory.ProviderUsage {
+	if a.mem == nil {
+		return nil
+	}
+	stats, err := a.mem.UsageStats(a.ctx)
+	if err != nil {
+		log.Printf("failed to load usage stats: %v", err)
+		return nil
+	}
+	return stats
+}
+
+// SetGlobalFact stores a fact that is included in every conversation's
+// prompt. An empty value removes it. Values pass through the PII filter
+// like chat messages do.
+func (a *App) SetGlobalFact(key, value string) error {
+	if a.mem == nil {
+		return fmt.Errorf("memory not initialized")
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("fact key is required")
+	}
+	return a.mem.SetFact(a.ctx, memory.GlobalScope, key, a.sanitizer.Sanitize(memory.GlobalScope, value))
+}
+
+// GetGlobalFacts returns the facts shared across all conversations.
+func (a *App) GetGlobalFacts() []memory.Fact {
+	if a.mem == nil {
+		return nil
+	}
+	facts, err := a.mem.GetFacts(a.ctx, memory.GlobalScope)
+	if err != nil {
+		log.Printf("failed to load global facts: %v", err)
+		return nil
+	}
+	for i := range facts {
+		facts[i].Value = a.sanitizer.Restore(memory.GlobalScope, facts[i].Value)
+	}
+	return facts
+}
+
 // GetLogs returns recent log entries.
 func (a *App) GetLogs() []LogEntry {
 	a.logsMu.Lock()
@@ -608,6 +1684,18 @@ func (a *App) GetChannelStatus() map[string]bool {
 	return a.chanMgr.List()
 }
 
+// GetQueueStatus returns how many messages each busy chat has in flight,
+// keyed by "channel:chatID".
+func (a *App) GetQueueStatus() map[string]int {
+	a.mu.RLock()
+	ag := a.agent
+	a.mu.RUnlock()
+	if ag == nil {
+		return nil
+	}
+	return ag.QueueDepths()
+}
+
 // GetMemStats returns current memory usage statistics.
 func (a *App) GetMemStats() map[string]any {
 	var m runtime.MemStats
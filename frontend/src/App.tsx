@@ -4,6 +4,7 @@ import { IsSetupCompleted } from '../wailsjs/go/main/App';
 import SetupWizard from './pages/SetupWizard';
 import Dashboard from './pages/Dashboard';
 import Settings from './pages/Settings';
+import ToolApproval from './components/ToolApproval';
 
 type Page = 'loading' | 'wizard' | 'dashboard' | 'settings';
 
@@ -38,6 +39,7 @@ function App() {
       {page === 'settings' && (
         <Settings onBack={() => setPage('dashboard')} />
       )}
+      <ToolApproval />
     </div>
   );
 }
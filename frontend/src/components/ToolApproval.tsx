@@ -0,0 +1,57 @@
+import { useState, useEffect } from 'react';
+import { ApproveToolCall } from '../../wailsjs/go/main/App';
+import { EventsOn } from '../../wailsjs/runtime/runtime';
+
+interface ApprovalRequest {
+  chat_id: string;
+  call_id: string;
+  tool: string;
+  arguments: any;
+  timeout_secs: number;
+}
+
+// ToolApproval shows each tool call that waits for the user's go-ahead.
+// Requests drop off once answered or when the agent stops waiting.
+function ToolApproval() {
+  const [requests, setRequests] = useState<ApprovalRequest[]>([]);
+
+  const dismiss = (callID: string) => {
+    setRequests((prev) => prev.filter((r) => r.call_id !== callID));
+  };
+
+  useEffect(() => {
+    return EventsOn('tool_approval_request', (req: ApprovalRequest) => {
+      setRequests((prev) => [...prev, req]);
+      setTimeout(() => dismiss(req.call_id), req.timeout_secs * 1000);
+    });
+  }, []);
+
+  const answer = async (callID: string, approved: boolean) => {
+    dismiss(callID);
+    try {
+      await ApproveToolCall(callID, approved);
+    } catch (e) {
+      console.error('Failed to answer approval request:', e);
+    }
+  };
+
+  if (requests.length === 0) return null;
+
+  return (
+    <div className="approval-stack">
+      {requests.map((r) => (
+        <div key={r.call_id} className="approval-card">
+          <h4>Allow {r.tool}?</h4>
+          <p className="help-text">Requested in chat {r.chat_id}</p>
+          <pre>{JSON.stringify(r.arguments, null, 2)}</pre>
+          <div className="button-row">
+            <button className="btn btn-danger" onClick={() => answer(r.call_id, false)}>Deny</button>
+            <button className="btn btn-success" onClick={() => answer(r.call_id, true)}>Allow</button>
+          </div>
+        </div>
+      ))}
+    </div>
+  );
+}
+
+export default ToolApproval;
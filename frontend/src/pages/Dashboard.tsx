@@ -1,5 +1,6 @@
 import { useState, useEffect, useRef } from 'react';
 import {
+  CancelRequest,
   GetConfig,
   GetChannelStatus,
   GetLogs,
@@ -70,6 +71,14 @@ function Dashboard({ onNavigate }: Props) {
     setSending(false);
   };
 
+  const stopMessage = async () => {
+    try {
+      await CancelRequest('gui');
+    } catch (e) {
+      console.error('Failed to cancel:', e);
+    }
+  };
+
   return (
     <div className="dashboard">
       <header className="dashboard-header">
@@ -162,9 +171,15 @@ function Dashboard({ onNavigate }: Props) {
                 placeholder="Type a message..."
                 disabled={sending}
               />
-              <button className="btn btn-primary" onClick={sendMessage} disabled={sending || !chatInput.trim()}>
-                Send
-              </button>
+              {sending ? (
+                <button className="btn btn-secondary" onClick={stopMessage}>
+                  Stop
+                </button>
+              ) : (
+                <button className="btn btn-primary" onClick={sendMessage} disabled={!chatInput.trim()}>
+                  Send
+                </button>
+              )}
             </div>
           </div>
         </div>
@@ -9,6 +9,8 @@ import {
   GetInstalledSkills,
   TestLLMConnection,
   TestTelegramConnection,
+  SaveDiscordConfig,
+  TestDiscordConnection,
 } from '../../wailsjs/go/main/App';
 import ProviderForm from '../components/ProviderForm';
 import ChannelForm from '../components/ChannelForm';
@@ -19,6 +21,8 @@ interface SkillInfo {
   description: string;
   author: string;
   enabled: boolean;
+  available: boolean;
+  missing?: string[];
 }
 
 interface Props {
@@ -31,18 +35,27 @@ function Settings({ onBack }: Props) {
   const [model, setModel] = useState('');
   const [baseURL, setBaseURL] = useState('');
   const [tgToken, setTgToken] = useState('');
+  const [dcToken, setDcToken] = useState('');
+  const [dcUsers, setDcUsers] = useState('');
+  const [dcRoles, setDcRoles] = useState('');
   const [piiEnabled, setPiiEnabled] = useState(true);
   const [filterEmails, setFilterEmails] = useState(true);
   const [filterPhones, setFilterPhones] = useState(true);
   const [filterCards, setFilterCards] = useState(true);
   const [filterIPs, setFilterIPs] = useState(false);
   const [filterSSN, setFilterSSN] = useState(true);
+  const [filterIBANs, setFilterIBANs] = useState(true);
+  const [filterPassports, setFilterPassports] = useState(true);
+  const [filterAPIKeys, setFilterAPIKeys] = useState(true);
   const [browserEnabled, setBrowserEnabled] = useState(false);
   const [browserHeadless, setBrowserHeadless] = useState(true);
   const [browserTimeout, setBrowserTimeout] = useState(30);
   const [browserMaxTabs, setBrowserMaxTabs] = useState(3);
   const [browserAllowed, setBrowserAllowed] = useState('');
   const [browserDenied, setBrowserDenied] = useState('');
+  const [browserUserAgent, setBrowserUserAgent] = useState('');
+  const [browserViewportWidth, setBrowserViewportWidth] = useState(1920);
+  const [browserViewportHeight, setBrowserViewportHeight] = useState(1080);
   const [pluginsEnabled, setPluginsEnabled] = useState(true);
   const [pluginsTimeout, setPluginsTimeout] = useState(60);
   const [pluginsSandbox, setPluginsSandbox] = useState(true);
@@ -60,6 +73,9 @@ function Settings({ onBack }: Props) {
         setPiiEnabled(cfg.pii_filtering ?? true);
         setBrowserEnabled(cfg.browser_enabled ?? false);
         setBrowserHeadless(cfg.browser_headless ?? true);
+        setBrowserUserAgent(cfg.browser_user_agent || '');
+        setBrowserViewportWidth(cfg.browser_viewport_width || 1920);
+        setBrowserViewportHeight(cfg.browser_viewport_height || 1080);
         setPluginsEnabled(cfg.plugins_enabled ?? true);
       }
     });
@@ -95,9 +111,27 @@ function Settings({ onBack }: Props) {
     }
   };
 
+  const saveDiscord = async () => {
+    try {
+      await SaveDiscordConfig(dcToken, dcUsers, dcRoles);
+      showMessage('Discord settings saved', 'success');
+    } catch (e: any) {
+      showMessage(e.toString(), 'error');
+    }
+  };
+
+  const testDC = async () => {
+    try {
+      const result = await TestDiscordConnection(dcToken);
+      showMessage(result === 'OK' ? 'Connection successful' : result, result === 'OK' ? 'success' : 'error');
+    } catch (e: any) {
+      showMessage(e.toString(), 'error');
+    }
+  };
+
   const saveSecurity = async () => {
     try {
-      await SaveSecurityConfig(piiEnabled, filterEmails, filterPhones, filterCards, filterIPs, filterSSN);
+      await SaveSecurityConfig(piiEnabled, filterEmails, filterPhones, filterCards, filterIPs, filterSSN, filterIBANs, filterPassports, filterAPIKeys);
       showMessage('Security settings saved', 'success');
     } catch (e: any) {
       showMessage(e.toString(), 'error');
@@ -124,7 +158,7 @@ function Settings({ onBack }: Props) {
 
   const saveBrowser = async () => {
     try {
-      await SaveBrowserConfig(browserEnabled, browserHeadless, browserTimeout, browserMaxTabs, browserAllowed, browserDenied);
+      await SaveBrowserConfig(browserEnabled, browserHeadless, browserTimeout, browserMaxTabs, browserAllowed, browserDenied, browserUserAgent, browserViewportWidth, browserViewportHeight);
       showMessage('Browser settings saved', 'success');
     } catch (e: any) {
       showMessage(e.toString(), 'error');
@@ -183,6 +217,33 @@ function Settings({ onBack }: Props) {
           </div>
         </section>
 
+        <section className="settings-section">
+          <h2>Discord</h2>
+          <div className="form-group">
+            <label>Discord Bot Token</label>
+            <input
+              type="password"
+              className="input"
+              value={dcToken}
+              onChange={(e) => setDcToken(e.target.value)}
+            />
+            <span className="help-text">Enable the Message Content intent for the bot. In servers it answers only when mentioned.</span>
+          </div>
+          <div className="form-group">
+            <label>Allowed User IDs (comma-separated)</label>
+            <input type="text" className="input" value={dcUsers} onChange={(e) => setDcUsers(e.target.value)} />
+          </div>
+          <div className="form-group">
+            <label>Allowed Role IDs (comma-separated)</label>
+            <input type="text" className="input" value={dcRoles} onChange={(e) => setDcRoles(e.target.value)} />
+            <span className="help-text">Leave both empty to allow everyone</span>
+          </div>
+          <div className="button-row">
+            <button className="btn btn-secondary" onClick={testDC} disabled={!dcToken}>Test</button>
+            <button className="btn btn-primary" onClick={saveDiscord}>Save</button>
+          </div>
+        </section>
+
         <section className="settings-section">
           <h2>Security</h2>
           <div className="form-group">
@@ -213,6 +274,18 @@ function Settings({ onBack }: Props) {
                 <input type="checkbox" checked={filterSSN} onChange={(e) => setFilterSSN(e.target.checked)} />
                 SSN
               </label>
+              <label className="checkbox-label">
+                <input type="checkbox" checked={filterIBANs} onChange={(e) => setFilterIBANs(e.target.checked)} />
+                IBANs
+              </label>
+              <label className="checkbox-label">
+                <input type="checkbox" checked={filterPassports} onChange={(e) => setFilterPassports(e.target.checked)} />
+                Passports
+              </label>
+              <label className="checkbox-label">
+                <input type="checkbox" checked={filterAPIKeys} onChange={(e) => setFilterAPIKeys(e.target.checked)} />
+                API keys
+              </label>
             </div>
           )}
           <div className="button-row">
@@ -280,6 +353,35 @@ function Settings({ onBack }: Props) {
                   placeholder="e.g. malware.com"
                 />
               </div>
+              <div className="form-group">
+                <label>User Agent</label>
+                <input
+                  type="text"
+                  className="input"
+                  value={browserUserAgent}
+                  onChange={(e) => setBrowserUserAgent(e.target.value)}
+                  placeholder="Leave empty for desktop Chrome"
+                />
+              </div>
+              <div className="form-group">
+                <label>Viewport (width × height)</label>
+                <input
+                  type="number"
+                  className="input"
+                  value={browserViewportWidth}
+                  onChange={(e) => setBrowserViewportWidth(Number(e.target.value))}
+                  min={320}
+                  max={3840}
+                />
+                <input
+                  type="number"
+                  className="input"
+                  value={browserViewportHeight}
+                  onChange={(e) => setBrowserViewportHeight(Number(e.target.value))}
+                  min={240}
+                  max={2160}
+                />
+              </div>
             </>
           )}
           <div className="button-row">
@@ -330,6 +432,9 @@ function Settings({ onBack }: Props) {
                         <div className="skill-info">
                           <strong>{s.name}</strong> <span className="skill-version">v{s.version}</span>
                           {s.description && <p className="help-text">{s.description}</p>}
+                          {s.missing && s.missing.length > 0 && (
+                            <p className="help-text">Missing dependency: {s.missing.join(', ')}</p>
+                          )}
                         </div>
                       </label>
                     </div>
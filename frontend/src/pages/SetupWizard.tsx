@@ -36,6 +36,9 @@ function SetupWizard({ onComplete }: Props) {
   const [filterCards, setFilterCards] = useState(true);
   const [filterIPs, setFilterIPs] = useState(false);
   const [filterSSN, setFilterSSN] = useState(true);
+  const [filterIBANs, setFilterIBANs] = useState(true);
+  const [filterPassports, setFilterPassports] = useState(true);
+  const [filterAPIKeys, setFilterAPIKeys] = useState(true);
 
   const testLLM = async () => {
     setLlmStatus('testing');
@@ -79,7 +82,7 @@ function SetupWizard({ onComplete }: Props) {
       if (tgToken) {
         await SaveTelegramConfig(tgToken, []);
       }
-      await SaveSecurityConfig(piiEnabled, filterEmails, filterPhones, filterCards, filterIPs, filterSSN);
+      await SaveSecurityConfig(piiEnabled, filterEmails, filterPhones, filterCards, filterIPs, filterSSN, filterIBANs, filterPassports, filterAPIKeys);
       await CompleteSetup();
       onComplete();
     } catch (e: any) {
@@ -185,6 +188,18 @@ function SetupWizard({ onComplete }: Props) {
             <input type="checkbox" checked={filterSSN} onChange={(e) => setFilterSSN(e.target.checked)} />
             Filter SSN numbers
           </label>
+          <label className="checkbox-label">
+            <input type="checkbox" checked={filterIBANs} onChange={(e) => setFilterIBANs(e.target.checked)} />
+            Filter IBANs
+          </label>
+          <label className="checkbox-label">
+            <input type="checkbox" checked={filterPassports} onChange={(e) => setFilterPassports(e.target.checked)} />
+            Filter passport numbers
+          </label>
+          <label className="checkbox-label">
+            <input type="checkbox" checked={filterAPIKeys} onChange={(e) => setFilterAPIKeys(e.target.checked)} />
+            Filter API keys
+          </label>
         </div>
       )}
       <div className="button-row">
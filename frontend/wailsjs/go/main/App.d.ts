@@ -2,33 +2,86 @@
 // This file is automatically generated. DO NOT EDIT
 import {skill} from '../models';
 import {main} from '../models';
+import {memory} from '../models';
+import {llm} from '../models';
+import {tool} from '../models';
+
+export function AdminBroadcast(arg1:string,arg2:string):Promise<number>;
+
+export function ApproveToolCall(arg1:string,arg2:boolean):Promise<void>;
+
+export function CancelRequest(arg1:string):Promise<void>;
+
+export function ChangeMasterPassword(arg1:string,arg2:string):Promise<void>;
 
 export function CompleteSetup():Promise<void>;
 
+export function DeleteSecret(arg1:string):Promise<void>;
+
+export function ExportConversation(arg1:string,arg2:string):Promise<string>;
+
 export function GetChannelStatus():Promise<Record<string, boolean>>;
 
+export function GetChatSettings(arg1:string):Promise<memory.ChatSettings>;
+
 export function GetConfig():Promise<Record<string, any>>;
 
+export function GetGlobalFacts():Promise<Array<memory.Fact>>;
+
+export function GetHistoryPage(arg1:string,arg2:number,arg3:number):Promise<Array<llm.Message>>;
+
 export function GetInstalledSkills():Promise<Array<skill.SkillInfo>>;
 
 export function GetLogs():Promise<Array<main.LogEntry>>;
 
 export function GetMemStats():Promise<Record<string, any>>;
 
+export function GetQueueStatus():Promise<Record<string, number>>;
+
+export function GetSystemPrompt(arg1:string):Promise<string>;
+
+export function GetToolMetrics():Promise<Array<tool.ToolStats>>;
+
+export function GetUsageStats():Promise<Array<memory.ProviderUsage>>;
+
+export function InstallSkill(arg1:string):Promise<skill.SkillInfo>;
+
+export function IsLocked():Promise<boolean>;
+
 export function IsSetupCompleted():Promise<boolean>;
 
-export function SaveBrowserConfig(arg1:boolean,arg2:boolean,arg3:number,arg4:number,arg5:string,arg6:string):Promise<void>;
+export function ListConversations():Promise<Array<memory.ChatSummary>>;
+
+export function SaveBrowserConfig(arg1:boolean,arg2:boolean,arg3:number,arg4:number,arg5:string,arg6:string,arg7:string,arg8:number,arg9:number):Promise<void>;
+
+export function SaveDiscordConfig(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function SaveLLMConfig(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
 
 export function SavePluginsConfig(arg1:boolean,arg2:Array<string>,arg3:number,arg4:boolean):Promise<void>;
 
-export function SaveSecurityConfig(arg1:boolean,arg2:boolean,arg3:boolean,arg4:boolean,arg5:boolean,arg6:boolean):Promise<void>;
+export function SaveSecurityConfig(arg1:boolean,arg2:boolean,arg3:boolean,arg4:boolean,arg5:boolean,arg6:boolean,arg7:boolean,arg8:boolean,arg9:boolean):Promise<void>;
 
 export function SaveTelegramConfig(arg1:string,arg2:Array<number>):Promise<void>;
 
+export function SearchMemory(arg1:string):Promise<Array<memory.SearchResult>>;
+
 export function SendMessage(arg1:string):Promise<string>;
 
+export function SetChatSettings(arg1:string,arg2:memory.ChatSettings):Promise<void>;
+
+export function SetGlobalFact(arg1:string,arg2:string):Promise<void>;
+
+export function SetMasterPassword(arg1:string):Promise<void>;
+
+export function SetSkillSecret(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function SetSystemPrompt(arg1:string,arg2:string):Promise<void>;
+
+export function TestDiscordConnection(arg1:string):Promise<string>;
+
 export function TestLLMConnection(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
 export function TestTelegramConnection(arg1:string):Promise<string>;
+
+export function Unlock(arg1:string):Promise<void>;
@@ -2,18 +2,54 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AdminBroadcast(arg1, arg2) {
+  return window['go']['main']['App']['AdminBroadcast'](arg1, arg2);
+}
+
+export function ApproveToolCall(arg1, arg2) {
+  return window['go']['main']['App']['ApproveToolCall'](arg1, arg2);
+}
+
+export function CancelRequest(arg1) {
+  return window['go']['main']['App']['CancelRequest'](arg1);
+}
+
+export function ChangeMasterPassword(arg1, arg2) {
+  return window['go']['main']['App']['ChangeMasterPassword'](arg1, arg2);
+}
+
 export function CompleteSetup() {
   return window['go']['main']['App']['CompleteSetup']();
 }
 
+export function DeleteSecret(arg1) {
+  return window['go']['main']['App']['DeleteSecret'](arg1);
+}
+
+export function ExportConversation(arg1, arg2) {
+  return window['go']['main']['App']['ExportConversation'](arg1, arg2);
+}
+
 export function GetChannelStatus() {
   return window['go']['main']['App']['GetChannelStatus']();
 }
 
+export function GetChatSettings(arg1) {
+  return window['go']['main']['App']['GetChatSettings'](arg1);
+}
+
 export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
 
+export function GetGlobalFacts() {
+  return window['go']['main']['App']['GetGlobalFacts']();
+}
+
+export function GetHistoryPage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetHistoryPage'](arg1, arg2, arg3);
+}
+
 export function GetInstalledSkills() {
   return window['go']['main']['App']['GetInstalledSkills']();
 }
@@ -26,12 +62,44 @@ export function GetMemStats() {
   return window['go']['main']['App']['GetMemStats']();
 }
 
+export function GetQueueStatus() {
+  return window['go']['main']['App']['GetQueueStatus']();
+}
+
+export function GetSystemPrompt(arg1) {
+  return window['go']['main']['App']['GetSystemPrompt'](arg1);
+}
+
+export function GetToolMetrics() {
+  return window['go']['main']['App']['GetToolMetrics']();
+}
+
+export function GetUsageStats() {
+  return window['go']['main']['App']['GetUsageStats']();
+}
+
+export function InstallSkill(arg1) {
+  return window['go']['main']['App']['InstallSkill'](arg1);
+}
+
+export function IsLocked() {
+  return window['go']['main']['App']['IsLocked']();
+}
+
 export function IsSetupCompleted() {
   return window['go']['main']['App']['IsSetupCompleted']();
 }
 
-export function SaveBrowserConfig(arg1, arg2, arg3, arg4, arg5, arg6) {
-  return window['go']['main']['App']['SaveBrowserConfig'](arg1, arg2, arg3, arg4, arg5, arg6);
+export function ListConversations() {
+  return window['go']['main']['App']['ListConversations']();
+}
+
+export function SaveBrowserConfig(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9) {
+  return window['go']['main']['App']['SaveBrowserConfig'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9);
+}
+
+export function SaveDiscordConfig(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SaveDiscordConfig'](arg1, arg2, arg3);
 }
 
 export function SaveLLMConfig(arg1, arg2, arg3, arg4) {
@@ -42,18 +110,46 @@ export function SavePluginsConfig(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['SavePluginsConfig'](arg1, arg2, arg3, arg4);
 }
 
-export function SaveSecurityConfig(arg1, arg2, arg3, arg4, arg5, arg6) {
-  return window['go']['main']['App']['SaveSecurityConfig'](arg1, arg2, arg3, arg4, arg5, arg6);
+export function SaveSecurityConfig(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9) {
+  return window['go']['main']['App']['SaveSecurityConfig'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9);
 }
 
 export function SaveTelegramConfig(arg1, arg2) {
   return window['go']['main']['App']['SaveTelegramConfig'](arg1, arg2);
 }
 
+export function SearchMemory(arg1) {
+  return window['go']['main']['App']['SearchMemory'](arg1);
+}
+
 export function SendMessage(arg1) {
   return window['go']['main']['App']['SendMessage'](arg1);
 }
 
+export function SetChatSettings(arg1, arg2) {
+  return window['go']['main']['App']['SetChatSettings'](arg1, arg2);
+}
+
+export function SetGlobalFact(arg1, arg2) {
+  return window['go']['main']['App']['SetGlobalFact'](arg1, arg2);
+}
+
+export function SetMasterPassword(arg1) {
+  return window['go']['main']['App']['SetMasterPassword'](arg1);
+}
+
+export function SetSkillSecret(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetSkillSecret'](arg1, arg2, arg3);
+}
+
+export function SetSystemPrompt(arg1, arg2) {
+  return window['go']['main']['App']['SetSystemPrompt'](arg1, arg2);
+}
+
+export function TestDiscordConnection(arg1) {
+  return window['go']['main']['App']['TestDiscordConnection'](arg1);
+}
+
 export function TestLLMConnection(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['TestLLMConnection'](arg1, arg2, arg3, arg4);
 }
@@ -61,3 +157,7 @@ export function TestLLMConnection(arg1, arg2, arg3, arg4) {
 export function TestTelegramConnection(arg1) {
   return window['go']['main']['App']['TestTelegramConnection'](arg1);
 }
+
+export function Unlock(arg1) {
+  return window['go']['main']['App']['Unlock'](arg1);
+}
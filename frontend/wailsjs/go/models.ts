@@ -1,3 +1,86 @@
+export namespace llm {
+	
+	export class Message {
+	    role: string;
+	    content: string;
+	    tool_calls?: ToolCall[];
+	    tool_call_id?: string;
+	    metadata?: ResponseMetadata;
+	
+	    static createFrom(source: any = {}) {
+	        return new Message(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.role = source["role"];
+	        this.content = source["content"];
+	        this.tool_calls = this.convertValues(source["tool_calls"], ToolCall);
+	        this.tool_call_id = source["tool_call_id"];
+	        this.metadata = this.convertValues(source["metadata"], ResponseMetadata);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class ResponseMetadata {
+	    id?: string;
+	    model?: string;
+	    system_fingerprint?: string;
+	    provider?: string;
+	    fallback?: boolean;
+	    retries?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResponseMetadata(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.model = source["model"];
+	        this.system_fingerprint = source["system_fingerprint"];
+	        this.provider = source["provider"];
+	        this.fallback = source["fallback"];
+	        this.retries = source["retries"];
+	    }
+	}
+	
+	export class ToolCall {
+	    id: string;
+	    name: string;
+	    arguments: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ToolCall(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.arguments = source["arguments"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
 	export class LogEntry {
@@ -19,14 +102,149 @@ export namespace main {
 
 }
 
+export namespace memory {
+	
+	export class ChatSettings {
+	    system_prompt?: string;
+	    model?: string;
+	    temperature?: number;
+	    max_tokens?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.system_prompt = source["system_prompt"];
+	        this.model = source["model"];
+	        this.temperature = source["temperature"];
+	        this.max_tokens = source["max_tokens"];
+	    }
+	}
+	
+	export class ChatSummary {
+	    chat_id: string;
+	    message_count: number;
+	    // Go type: time
+	    last_activity: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.chat_id = source["chat_id"];
+	        this.message_count = source["message_count"];
+	        this.last_activity = this.convertValues(source["last_activity"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Fact {
+	    key: string;
+	    value: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Fact(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.value = source["value"];
+	    }
+	}
+	
+	export class ProviderUsage {
+	    provider: string;
+	    model: string;
+	    messages: number;
+	    fallbacks: number;
+	    retries: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderUsage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.messages = source["messages"];
+	        this.fallbacks = source["fallbacks"];
+	        this.retries = source["retries"];
+	    }
+	}
+	
+	export class SearchResult {
+	    chat_id: string;
+	    role: string;
+	    snippet: string;
+	    rank: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.chat_id = source["chat_id"];
+	        this.role = source["role"];
+	        this.snippet = source["snippet"];
+	        this.rank = source["rank"];
+	    }
+	}
+
+}
+
 export namespace skill {
 	
+	export class EnvVar {
+	    name: string;
+	    description?: string;
+	    required?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new EnvVar(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.required = source["required"];
+	    }
+	}
+	
 	export class SkillInfo {
 	    name: string;
 	    version: string;
 	    description: string;
 	    author: string;
 	    enabled: boolean;
+	    available: boolean;
+	    missing?: string[];
+	    env?: EnvVar[];
+	    error?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new SkillInfo(source);
@@ -39,7 +257,84 @@ export namespace skill {
 	        this.description = source["description"];
 	        this.author = source["author"];
 	        this.enabled = source["enabled"];
+	        this.available = source["available"];
+	        this.missing = source["missing"];
+	        this.env = this.convertValues(source["env"], EnvVar);
+	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace tool {
+	
+	export class ToolStats {
+	    name: string;
+	    calls: number;
+	    errors: number;
+	    total_ms: number;
+	    avg_ms: number;
+	    max_ms: number;
+	    last_ms: number;
+	    // Go type: time
+	    last_call_at: any;
+	    last_error?: string;
+	    // Go type: time
+	    last_error_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ToolStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.calls = source["calls"];
+	        this.errors = source["errors"];
+	        this.total_ms = source["total_ms"];
+	        this.avg_ms = source["avg_ms"];
+	        this.max_ms = source["max_ms"];
+	        this.last_ms = source["last_ms"];
+	        this.last_call_at = this.convertValues(source["last_call_at"], null);
+	        this.last_error = source["last_error"];
+	        this.last_error_at = this.convertValues(source["last_error_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
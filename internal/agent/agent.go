@@ -2,8 +2,12 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"open-dan/internal/channel"
 	"open-dan/internal/config"
@@ -23,6 +27,14 @@ type Agent struct {
 	bus        *eventbus.Bus
 	chanMgr    *channel.Manager
 	ctxManager *contextManager
+	retryDelay time.Duration // initial backoff before retrying a failed message
+	queues     chatQueues
+	cache      *tool.ResultCache // nil when tool result caching is off
+	metrics    *tool.Metrics
+	approvals  approvals
+	inflight   inflight
+	loc        *time.Location   // time zone for the date in the system prompt
+	now        func() time.Time // overridable in tests
 }
 
 // New creates a new Agent.
@@ -34,42 +46,126 @@ func New(
 	bus *eventbus.Bus,
 	chanMgr *channel.Manager,
 ) *Agent {
-	return &Agent{
+	a := &Agent{
 		cfg:        cfg,
 		provider:   provider,
 		tools:      tools,
 		memory:     mem,
 		bus:        bus,
 		chanMgr:    chanMgr,
-		ctxManager: newContextManager(provider, cfg.ContextWindow, cfg.SummarizeAt),
+		ctxManager: newContextManager(provider, cfg),
+		retryDelay: 2 * time.Second,
+		metrics:    tool.NewMetrics(),
+		loc:        loadTimeZone(cfg.TimeZone),
+		now:        time.Now,
 	}
+	if cfg.ToolCacheTTLSecs > 0 {
+		a.cache = tool.NewResultCache(time.Duration(cfg.ToolCacheTTLSecs) * time.Second)
+	}
+	return a
 }
 
-// Start begins listening for inbound messages from all channels.
+// Start begins listening for inbound messages from all channels, including
+// channels registered with the manager afterwards. Calling it again
+// rewires every channel to the new ctx.
 func (a *Agent) Start(ctx context.Context) {
-	// Wire up all channels to route messages to the agent
-	for name, running := range a.chanMgr.List() {
-		if !running {
-			continue
-		}
-		ch, ok := a.chanMgr.Get(name)
-		if !ok {
-			continue
+	a.chanMgr.SetHandler(func(ch channel.Channel, msg channel.InboundMessage) {
+		a.bus.Publish("inbound_message", msg)
+		a.enqueueMessage(ctx, ch, msg)
+	})
+
+	log.Println("[agent] started and listening for messages")
+}
+
+// enqueueMessage queues msg behind any message its chat is already
+// processing. With QueueAck set, a queued sender is told their position.
+func (a *Agent) enqueueMessage(ctx context.Context, ch channel.Channel, msg channel.InboundMessage) {
+	pos := a.queues.enqueue(queueKey(msg.ChannelName, msg.ChatID), func() {
+		a.handleMessage(ctx, msg)
+	})
+	if pos == 0 || !a.cfg.QueueAck {
+		return
+	}
+	ack := channel.OutboundMessage{
+		ChatID:  msg.ChatID,
+		ReplyTo: replyTo(ch, msg),
+		Text:    fmt.Sprintf("Your message is queued (position %d). I'll reply once I've finished the previous one.", pos),
+	}
+	if err := ch.Send(ctx, ack); err != nil {
+		log.Printf("[agent] error sending queue acknowledgment: %v", err)
+	}
+}
+
+// QueueDepths reports, per busy chat, how many messages are being processed
+// or waiting. Keys are "channel:chatID"; idle chats are omitted.
+func (a *Agent) QueueDepths() map[string]int {
+	return a.queues.depths()
+}
+
+// replyTo returns the ID a reply to msg should thread under, or "" if ch
+// doesn't support threads.
+func replyTo(ch channel.Channel, msg channel.InboundMessage) string {
+	if !ch.Supports(channel.FeatureThreads) {
+		return ""
+	}
+	return msg.MessageID
+}
+
+// withAttachments appends a note listing attached files to text, so the
+// model knows where to find them with its file tools.
+func withAttachments(text string, attachments []channel.Attachment) string {
+	if len(attachments) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString(text)
+	if text != "" {
+		b.WriteString("\n\n")
+	}
+	for _, att := range attachments {
+		desc := fmt.Sprintf("%d bytes", att.Size)
+		if att.MIME != "" {
+			desc = att.MIME + ", " + desc
 		}
-		ch.OnMessage(func(msg channel.InboundMessage) {
-			a.bus.Publish("inbound_message", msg)
-			a.handleMessage(ctx, msg)
-		})
+		fmt.Fprintf(&b, "[Attached file: %s (%s)]\n", att.Path, desc)
 	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
 
-	log.Println("[agent] started and listening for messages")
+func queueKey(channelName, chatID string) string {
+	return channelName + ":" + chatID
 }
 
 // handleMessage processes an inbound message and sends the response back.
 func (a *Agent) handleMessage(ctx context.Context, msg channel.InboundMessage) {
 	log.Printf("[agent] processing message from %s (%s): %s", msg.SenderName, msg.ChannelName, truncate(msg.Text, 100))
+	a.chanMgr.TrackChat(msg.ChannelName, msg.ChatID)
+
+	ch, ok := a.chanMgr.Get(msg.ChannelName)
+	if !ok {
+		log.Printf("[agent] channel %s not found", msg.ChannelName)
+		return
+	}
+
+	if reply, ok := a.runCommand(ctx, msg); ok {
+		err := a.chanMgr.Send(ctx, msg.ChannelName, channel.OutboundMessage{ChatID: msg.ChatID, Text: reply, ReplyTo: replyTo(ch, msg)})
+		if err != nil {
+			log.Printf("[agent] error sending command reply: %v", err)
+		}
+		return
+	}
+
+	// Stream into channels that can edit messages in place; buffer elsewhere.
+	var stream *replyStream
+	var onText func(string)
+	if sc, ok := ch.(channel.StreamingChannel); ok && ch.Supports(channel.FeatureStreaming) {
+		stream = &replyStream{ch: sc, chatID: msg.ChatID, replyTo: replyTo(ch, msg)}
+		onText = func(text string) { stream.update(ctx, text) }
+	}
 
-	response, err := a.processMessage(ctx, msg.ChatID, msg.Text)
+	stopTyping := keepTyping(ctx, ch, msg.ChatID)
+	response, err := a.processMessage(ctx, msg.Conversation(), withAttachments(msg.Text, msg.Attachments), onText)
+	stopTyping()
 	if err != nil {
 		log.Printf("[agent] error processing message: %v", err)
 		response = "Sorry, I encountered an error processing your message. Please try again."
@@ -77,26 +173,82 @@ func (a *Agent) handleMessage(ctx context.Context, msg channel.InboundMessage) {
 	}
 
 	// Send response back through the channel
-	ch, ok := a.chanMgr.Get(msg.ChannelName)
-	if !ok {
-		log.Printf("[agent] channel %s not found", msg.ChannelName)
-		return
-	}
-
 	outMsg := channel.OutboundMessage{
-		ChatID: msg.ChatID,
-		Text:   response,
+		ChatID:  msg.ChatID,
+		Text:    response,
+		ReplyTo: replyTo(ch, msg),
 	}
 	a.bus.Publish("outbound_message", outMsg)
 
-	if err := ch.Send(ctx, outMsg); err != nil {
+	if stream != nil {
+		err = stream.finish(ctx, response)
+	} else {
+		err = a.chanMgr.Send(ctx, msg.ChannelName, outMsg)
+	}
+	if err != nil {
 		log.Printf("[agent] error sending response: %v", err)
 	}
 }
 
-// HandleDirectMessage processes a message from the GUI directly.
+// HandleDirectMessage processes a message from the GUI directly. It waits
+// its turn behind other messages for the same chat.
 func (a *Agent) HandleDirectMessage(ctx context.Context, chatID, text string) (string, error) {
-	return a.processMessage(ctx, chatID, text)
+	type result struct {
+		response string
+		err      error
+	}
+	done := make(chan result, 1)
+	a.queues.enqueue(queueKey("gui", chatID), func() {
+		if err := ctx.Err(); err != nil {
+			done <- result{err: err}
+			return
+		}
+		response, err := a.processMessage(ctx, chatID, text, nil)
+		done <- result{response, err}
+	})
+	select {
+	case r := <-done:
+		return r.response, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// SendProactive delivers a message the agent initiates itself, such as a
+// reminder or notification, through the named channel. The message is stored
+// in the chat history so later replies have context for it. A group chat
+// whose members have their own histories (Telegram's PerUserMemory) has no
+// shared one to store it in; use SendProactiveTo there.
+func (a *Agent) SendProactive(ctx context.Context, channelName, chatID, text string) error {
+	return a.SendProactiveTo(ctx, channelName, chatID, chatID, text)
+}
+
+// SendProactiveTo is SendProactive for a conversation stored under a
+// different key from its chat, as returned by InboundMessage.Conversation:
+// the message goes to chatID and is stored in conversation's history.
+func (a *Agent) SendProactiveTo(ctx context.Context, channelName, chatID, conversation, text string) error {
+	ch, ok := a.chanMgr.Get(channelName)
+	if !ok {
+		return fmt.Errorf("channel %s not found", channelName)
+	}
+	if !ch.IsRunning() {
+		return fmt.Errorf("channel %s is not running", channelName)
+	}
+
+	outMsg := channel.OutboundMessage{
+		ChatID: chatID,
+		Text:   text,
+	}
+	// A queued message is delivered once the channel recovers.
+	if err := a.chanMgr.Send(ctx, channelName, outMsg); err != nil && !errors.Is(err, channel.ErrQueued) {
+		return fmt.Errorf("send via %s: %w", channelName, err)
+	}
+	a.bus.Publish("outbound_message", outMsg)
+
+	if err := a.memory.SaveMessage(ctx, conversation, llm.Message{Role: "assistant", Content: text}); err != nil {
+		log.Printf("[agent] failed to save proactive message: %v", err)
+	}
+	return nil
 }
 
 func truncate(s string, maxLen int) string {
@@ -0,0 +1,1082 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"open-dan/internal/channel"
+	"open-dan/internal/config"
+	"open-dan/internal/eventbus"
+	"open-dan/internal/llm"
+	"open-dan/internal/memory"
+	"open-dan/internal/tool"
+)
+
+// mockChannel records sent messages.
+type mockChannel struct {
+	mu      sync.Mutex
+	name    string
+	running bool
+	sent    []channel.OutboundMessage
+}
+
+func (c *mockChannel) Name() string                           { return c.name }
+func (c *mockChannel) Start(_ context.Context) error          { c.running = true; return nil }
+func (c *mockChannel) Stop(_ context.Context) error           { c.running = false; return nil }
+func (c *mockChannel) OnMessage(func(channel.InboundMessage)) {}
+func (c *mockChannel) Supports(string) bool                   { return false }
+func (c *mockChannel) IsRunning() bool                        { return c.running }
+
+func (c *mockChannel) Send(_ context.Context, msg channel.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// mockStreamingChannel records drafts and in-place edits.
+type mockStreamingChannel struct {
+	mockChannel
+	drafts []string
+	edits  []string
+}
+
+func (c *mockStreamingChannel) Supports(feature string) bool {
+	return feature == channel.FeatureStreaming
+}
+
+func (c *mockStreamingChannel) SendDraft(_ context.Context, _, text string) (string, error) {
+	c.drafts = append(c.drafts, text)
+	return "msg1", nil
+}
+
+func (c *mockStreamingChannel) Edit(_ context.Context, _, _, text string) error {
+	c.edits = append(c.edits, text)
+	return nil
+}
+
+// mockProvider replies with fixed text, split into deltas when streaming.
+type mockProvider struct {
+	deltas   []string
+	streamed bool
+}
+
+func (p *mockProvider) Chat(_ context.Context, _ *llm.ChatRequest) (*llm.LLMResponse, error) {
+	return &llm.LLMResponse{Content: strings.Join(p.deltas, "")}, nil
+}
+
+func (p *mockProvider) StreamChat(_ context.Context, _ *llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	p.streamed = true
+	ch := make(chan llm.StreamEvent, len(p.deltas)+1)
+	for _, d := range p.deltas {
+		ch <- llm.StreamEvent{ContentDelta: d}
+	}
+	ch <- llm.StreamEvent{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *mockProvider) Name() string         { return "mock" }
+func (p *mockProvider) DefaultModel() string { return "mock-1" }
+
+// scriptedProvider returns canned Chat results in order.
+type scriptedProvider struct {
+	mockProvider
+	steps    []scriptedStep
+	calls    int
+	requests []*llm.ChatRequest
+}
+
+type scriptedStep struct {
+	resp *llm.LLMResponse
+	err  error
+}
+
+func (p *scriptedProvider) Chat(_ context.Context, req *llm.ChatRequest) (*llm.LLMResponse, error) {
+	step := p.steps[p.calls]
+	p.calls++
+	p.requests = append(p.requests, req)
+	return step.resp, step.err
+}
+
+// echoTool returns its arguments as output, or a fixed output if set.
+type echoTool struct {
+	runs   int
+	output string
+}
+
+func (e *echoTool) Name() string                { return "echo" }
+func (e *echoTool) Description() string         { return "echo" }
+func (e *echoTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (e *echoTool) Execute(_ context.Context, args json.RawMessage) (*tool.Result, error) {
+	e.runs++
+	if e.output != "" {
+		return &tool.Result{Output: e.output}, nil
+	}
+	return &tool.Result{Output: string(args)}, nil
+}
+
+func newTestAgent(t *testing.T, channels ...channel.Channel) *Agent {
+	mem := memory.NewInMemory()
+	mgr := channel.NewManager()
+	for _, ch := range channels {
+		mgr.Register(ch)
+	}
+	cfg := config.AgentConfig{MaxToolCalls: 5, ContextWindow: 100000, SummarizeAt: 80000}
+	return New(cfg, &mockProvider{deltas: []string{"Hello", ", ", "world"}}, tool.NewRegistry(), mem, eventbus.New(), mgr)
+}
+
+func TestSendProactive(t *testing.T) {
+	ch := &mockChannel{name: "mock", running: true}
+	a := newTestAgent(t, ch)
+	ctx := context.Background()
+
+	if err := a.SendProactive(ctx, "mock", "chat1", "Reminder: stand-up in 5 minutes"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ch.sent) != 1 || ch.sent[0].ChatID != "chat1" || ch.sent[0].Text != "Reminder: stand-up in 5 minutes" {
+		t.Fatalf("unexpected sent messages: %+v", ch.sent)
+	}
+
+	history, _ := a.memory.GetHistory(ctx, "chat1", 10)
+	if len(history) != 1 || history[0].Role != "assistant" {
+		t.Fatalf("expected proactive message in history, got %+v", history)
+	}
+}
+
+func TestSendProactiveToMemberConversation(t *testing.T) {
+	ch := &mockChannel{name: "mock", running: true}
+	a := newTestAgent(t, ch)
+	ctx := context.Background()
+
+	// A group with per-user memory stores each member's history under
+	// chatID-senderID, as handleMessage does.
+	in := channel.InboundMessage{ChatID: "-100", SenderID: "42", ConversationID: "-100-42"}
+	if err := a.SendProactiveTo(ctx, "mock", in.ChatID, in.Conversation(), "Reminder: your report is due"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ch.sent) != 1 || ch.sent[0].ChatID != "-100" {
+		t.Fatalf("expected the message to go to the group, got %+v", ch.sent)
+	}
+	if history, _ := a.memory.GetHistory(ctx, "-100-42", 10); len(history) != 1 || history[0].Content != "Reminder: your report is due" {
+		t.Fatalf("expected the message in the member's history, got %+v", history)
+	}
+	if shared, _ := a.memory.GetHistory(ctx, "-100", 10); len(shared) != 0 {
+		t.Fatalf("nothing should be stored under the group's id, got %+v", shared)
+	}
+}
+
+func TestSendProactiveStoppedChannel(t *testing.T) {
+	ch := &mockChannel{name: "mock"}
+	a := newTestAgent(t, ch)
+
+	if err := a.SendProactive(context.Background(), "mock", "chat1", "hi"); err == nil {
+		t.Fatal("expected error for stopped channel")
+	}
+	if err := a.SendProactive(context.Background(), "missing", "chat1", "hi"); err == nil {
+		t.Fatal("expected error for unknown channel")
+	}
+	if len(ch.sent) != 0 {
+		t.Fatalf("nothing should be sent, got %+v", ch.sent)
+	}
+}
+
+func TestHandleMessageBuffersForNonStreamingChannel(t *testing.T) {
+	ch := &mockChannel{name: "mock", running: true}
+	a := newTestAgent(t, ch)
+
+	a.handleMessage(context.Background(), channel.InboundMessage{ChannelName: "mock", ChatID: "chat1", Text: "hi"})
+
+	if a.provider.(*mockProvider).streamed {
+		t.Fatal("non-streaming channel should not use StreamChat")
+	}
+	if len(ch.sent) != 1 || ch.sent[0].Text != "Hello, world" {
+		t.Fatalf("expected one buffered reply, got %+v", ch.sent)
+	}
+}
+
+// threadedChannel replies in threads, like Slack.
+type threadedChannel struct{ mockChannel }
+
+func (c *threadedChannel) Supports(feature string) bool { return feature == channel.FeatureThreads }
+
+func TestHandleMessageRepliesInThread(t *testing.T) {
+	threaded := &threadedChannel{mockChannel{name: "threaded", running: true}}
+	plain := &mockChannel{name: "plain", running: true}
+	a := newTestAgent(t, threaded, plain)
+
+	a.handleMessage(context.Background(), channel.InboundMessage{ChannelName: "threaded", ChatID: "C1", MessageID: "1700.01", Text: "hi"})
+	a.handleMessage(context.Background(), channel.InboundMessage{ChannelName: "plain", ChatID: "C1", MessageID: "55", Text: "hi"})
+
+	if len(threaded.sent) != 1 || threaded.sent[0].ReplyTo != "1700.01" {
+		t.Fatalf("expected the reply in thread 1700.01, got %+v", threaded.sent)
+	}
+	if len(plain.sent) != 1 || plain.sent[0].ReplyTo != "" {
+		t.Fatalf("channels without threads should not get ReplyTo, got %+v", plain.sent)
+	}
+}
+
+func TestHandleMessageKeepsHistoryPerConversation(t *testing.T) {
+	ch := &mockChannel{name: "mock", running: true}
+	a := newTestAgent(t, ch)
+	ctx := context.Background()
+
+	a.handleMessage(ctx, channel.InboundMessage{ChannelName: "mock", ChatID: "group", ConversationID: "group-1", Text: "hi from 1"})
+	a.handleMessage(ctx, channel.InboundMessage{ChannelName: "mock", ChatID: "group", ConversationID: "group-2", Text: "hi from 2"})
+
+	for _, conv := range []string{"group-1", "group-2"} {
+		history, _ := a.memory.GetHistory(ctx, conv, 10)
+		if len(history) != 2 || !strings.HasSuffix(history[0].Content, conv[len(conv)-1:]) {
+			t.Fatalf("%s: unexpected history %+v", conv, history)
+		}
+	}
+	if history, _ := a.memory.GetHistory(ctx, "group", 10); len(history) != 0 {
+		t.Fatalf("nothing should be stored under the shared chat, got %+v", history)
+	}
+	if len(ch.sent) != 2 || ch.sent[0].ChatID != "group" || ch.sent[1].ChatID != "group" {
+		t.Fatalf("replies should go to the group chat, got %+v", ch.sent)
+	}
+}
+
+func TestWithAttachmentsListsFiles(t *testing.T) {
+	got := withAttachments("what is this?", []channel.Attachment{
+		{Path: "/ws/attachments/1/a.jpg", MIME: "image/jpeg", Size: 2048},
+		{Path: "/ws/attachments/1/notes", Size: 10},
+	})
+	want := "what is this?\n\n[Attached file: /ws/attachments/1/a.jpg (image/jpeg, 2048 bytes)]\n[Attached file: /ws/attachments/1/notes (10 bytes)]"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := withAttachments("plain", nil); got != "plain" {
+		t.Fatalf("text without attachments changed: %q", got)
+	}
+}
+
+// typingChannel records typing indicators alongside sent messages.
+type typingChannel struct {
+	mockChannel
+	events []string
+}
+
+func (c *typingChannel) SendTyping(_ context.Context, chatID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, "typing:"+chatID)
+	return nil
+}
+
+func (c *typingChannel) Send(_ context.Context, msg channel.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, "send:"+msg.ChatID)
+	return nil
+}
+
+func TestHandleMessageShowsTyping(t *testing.T) {
+	ch := &typingChannel{mockChannel: mockChannel{name: "typing", running: true}}
+	a := newTestAgent(t, ch)
+
+	a.handleMessage(context.Background(), channel.InboundMessage{ChannelName: "typing", ChatID: "c1", Text: "hi"})
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.events) < 2 || ch.events[0] != "typing:c1" || ch.events[len(ch.events)-1] != "send:c1" {
+		t.Fatalf("expected typing before the reply and none after, got %v", ch.events)
+	}
+}
+
+func TestHandleMessageEditsForStreamingChannel(t *testing.T) {
+	ch := &mockStreamingChannel{mockChannel: mockChannel{name: "stream", running: true}}
+	a := newTestAgent(t, ch)
+
+	a.handleMessage(context.Background(), channel.InboundMessage{ChannelName: "stream", ChatID: "chat1", Text: "hi"})
+
+	if !a.provider.(*mockProvider).streamed {
+		t.Fatal("streaming channel should use StreamChat")
+	}
+	if len(ch.drafts) != 1 || ch.drafts[0] != "Hello" {
+		t.Fatalf("expected a draft with the first delta, got %+v", ch.drafts)
+	}
+	if len(ch.edits) == 0 || ch.edits[len(ch.edits)-1] != "Hello, world" {
+		t.Fatalf("expected the draft to be edited to the full reply, got %+v", ch.edits)
+	}
+	if len(ch.sent) != 0 {
+		t.Fatalf("streamed reply should not also be sent, got %+v", ch.sent)
+	}
+}
+
+func TestProcessMessageRetriesTransientFailure(t *testing.T) {
+	toolCall := &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "1", Name: "echo", Arguments: []byte(`{}`)}}}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: toolCall},
+		{err: &llm.LLMError{Type: llm.ErrorNetwork, Message: "connection reset"}},
+		{resp: toolCall},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxMessageRetries = 2
+	a.retryDelay = time.Millisecond
+	a.SetProvider(provider)
+	echo := &echoTool{}
+	a.tools.Register(echo)
+
+	response, err := a.HandleDirectMessage(context.Background(), "chat1", "go")
+	if err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if response != "done" || provider.calls != 4 || echo.runs != 2 {
+		t.Fatalf("unexpected result %q after %d calls, %d tool runs", response, provider.calls, echo.runs)
+	}
+
+	// The user message is saved once, not per attempt
+	history, _ := a.memory.GetHistory(context.Background(), "chat1", 10)
+	if len(history) != 2 || history[0].Content != "go" || history[1].Content != "done" {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+}
+
+func TestProcessMessageDoesNotRetryPermanentFailure(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{err: &llm.LLMError{Type: llm.ErrorAuth, Message: "bad key"}},
+		{resp: &llm.LLMResponse{Content: "unreachable"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxMessageRetries = 2
+	a.retryDelay = time.Millisecond
+	a.SetProvider(provider)
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err == nil {
+		t.Fatal("expected auth error")
+	}
+	if provider.calls != 1 {
+		t.Fatalf("permanent error should not be retried, got %d calls", provider.calls)
+	}
+}
+
+func TestGlobalFactsInEveryChatPrompt(t *testing.T) {
+	ctx := context.Background()
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "hi"}},
+		{resp: &llm.LLMResponse{Content: "hi"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.SystemPrompt = "You are a test."
+	a.SetProvider(provider)
+
+	_ = a.memory.SetFact(ctx, memory.GlobalScope, "name", "Alex")
+	_ = a.memory.SetFact(ctx, "chat1", "project", "open-dan")
+
+	for _, chatID := range []string{"chat1", "chat2"} {
+		if _, err := a.HandleDirectMessage(ctx, chatID, "hello"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, req := range provider.requests {
+		if !strings.HasPrefix(req.SystemPrompt, "You are a test.") || !strings.Contains(req.SystemPrompt, "- name: Alex") {
+			t.Errorf("request %d: global fact missing from prompt:\n%s", i, req.SystemPrompt)
+		}
+	}
+	if !strings.Contains(provider.requests[0].SystemPrompt, "- project: open-dan") {
+		t.Errorf("chat fact missing from its own chat's prompt")
+	}
+	if strings.Contains(provider.requests[1].SystemPrompt, "project") {
+		t.Errorf("chat fact leaked into another chat's prompt")
+	}
+}
+
+func TestProcessMessageContinuesTruncatedReply(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "The quick brown", StopReason: "max_tokens"}},
+		{resp: &llm.LLMResponse{Content: " fox jumps", StopReason: "length"}},
+		{resp: &llm.LLMResponse{Content: " over the dog.", StopReason: "end_turn"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxContinuations = 2
+	a.SetProvider(provider)
+
+	response, err := a.HandleDirectMessage(context.Background(), "chat1", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "The quick brown fox jumps over the dog." {
+		t.Fatalf("expected concatenated reply, got %q", response)
+	}
+
+	last := provider.requests[2].Messages
+	if n := len(last); n != 5 || last[n-2].Content != " fox jumps" || last[n-1].Content != continuePrompt {
+		t.Fatalf("unexpected continuation request: %+v", last)
+	}
+
+	history, _ := a.memory.GetHistory(context.Background(), "chat1", 10)
+	if len(history) != 2 || history[1].Content != response {
+		t.Fatalf("expected one combined assistant message, got %+v", history)
+	}
+}
+
+func TestProcessMessageMarksTruncatedReply(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "part one", StopReason: "max_tokens"}},
+		{resp: &llm.LLMResponse{Content: " part two", StopReason: "max_tokens"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxContinuations = 1
+	a.SetProvider(provider)
+
+	response, err := a.HandleDirectMessage(context.Background(), "chat1", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "part one part two"+truncatedNote || provider.calls != 2 {
+		t.Fatalf("unexpected reply %q after %d calls", response, provider.calls)
+	}
+}
+
+func TestMatchUserLanguageDirective(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "Hallo"}},
+		{resp: &llm.LLMResponse{Content: "hi"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MatchUserLanguage = true
+	a.SetProvider(provider)
+
+	ctx := context.Background()
+	if _, err := a.HandleDirectMessage(ctx, "chat1", "Ich habe eine Frage, bitte hilf mir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.HandleDirectMessage(ctx, "chat1", "ok"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(provider.requests[0].SystemPrompt, "Reply in German") {
+		t.Errorf("missing language directive:\n%s", provider.requests[0].SystemPrompt)
+	}
+	if strings.Contains(provider.requests[1].SystemPrompt, "Reply in") {
+		t.Errorf("directive added for undetected language:\n%s", provider.requests[1].SystemPrompt)
+	}
+}
+
+func TestSystemPromptStartsWithDateTime(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{{resp: &llm.LLMResponse{Content: "hi"}}}}
+	a := newTestAgent(t)
+	a.cfg.InjectDateTime = true
+	a.cfg.SystemPrompt = "Be brief."
+	a.loc = loadTimeZone("America/New_York")
+	a.now = func() time.Time { return time.Date(2026, 3, 10, 1, 30, 0, 0, time.UTC) }
+	a.SetProvider(provider)
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "what day is it?"); err != nil {
+		t.Fatal(err)
+	}
+	want := "Current date and time: Monday, 9 March 2026, 21:30 (America/New_York, UTC-04:00).\n\nBe brief."
+	if got := provider.requests[0].SystemPrompt; got != want {
+		t.Fatalf("system prompt:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPerChatSystemPrompt(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "1"}},
+		{resp: &llm.LLMResponse{Content: "2"}},
+		{resp: &llm.LLMResponse{Content: "3"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.SystemPrompt = "You are helpful."
+	a.SetProvider(provider)
+
+	ctx := context.Background()
+	if err := a.SetSystemPrompt(ctx, "coding", "You are a senior Go reviewer."); err != nil {
+		t.Fatal(err)
+	}
+	a.HandleDirectMessage(ctx, "coding", "hi")
+	a.HandleDirectMessage(ctx, "writing", "hi")
+	if err := a.SetSystemPrompt(ctx, "coding", ""); err != nil {
+		t.Fatal(err)
+	}
+	a.HandleDirectMessage(ctx, "coding", "hi")
+
+	for i, want := range []string{"You are a senior Go reviewer.", "You are helpful.", "You are helpful."} {
+		if got := provider.requests[i].SystemPrompt; got != want {
+			t.Errorf("request %d: system prompt %q, want %q", i, got, want)
+		}
+	}
+}
+
+// failingTool always returns a tool error.
+type failingTool struct{ echoTool }
+
+func (f *failingTool) Name() string { return "broken" }
+func (f *failingTool) Execute(context.Context, json.RawMessage) (*tool.Result, error) {
+	return &tool.Result{Error: "file not found", IsError: true}, nil
+}
+
+func TestToolErrorInjectsReflectionHint(t *testing.T) {
+	call := func(name string) *llm.LLMResponse {
+		return &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: name, Name: name, Arguments: []byte(`{}`)}}}
+	}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: call("broken")},
+		{resp: call("echo")},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxReflections = 1
+	a.SetProvider(provider)
+	a.tools.Register(&failingTool{})
+	a.tools.Register(&echoTool{})
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := provider.requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != "tool" || !strings.HasPrefix(last.Content, "Error: file not found") || !strings.Contains(last.Content, "[Reflection]") {
+		t.Fatalf("expected reflection hint on failed tool result, got %+v", last)
+	}
+	msgs = provider.requests[2].Messages
+	if last := msgs[len(msgs)-1]; strings.Contains(last.Content, "[Reflection]") {
+		t.Fatalf("successful tool result should not get a hint: %+v", last)
+	}
+}
+
+func TestReflectionDisabledByDefault(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "1", Name: "broken", Arguments: []byte(`{}`)}}}},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.SetProvider(provider)
+	a.tools.Register(&failingTool{})
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := provider.requests[1].Messages
+	if strings.Contains(msgs[len(msgs)-1].Content, "[Reflection]") {
+		t.Fatal("reflection hint added while disabled")
+	}
+}
+
+// gateTool blocks each run until want runs are in flight at once, so it
+// fails unless its calls execute concurrently.
+type gateTool struct {
+	echoTool
+	mu      sync.Mutex
+	running int
+	want    int
+	ready   chan struct{}
+}
+
+func (g *gateTool) Name() string { return "gate" }
+func (g *gateTool) Execute(_ context.Context, args json.RawMessage) (*tool.Result, error) {
+	g.mu.Lock()
+	if g.running++; g.running == g.want {
+		close(g.ready)
+	}
+	g.mu.Unlock()
+	select {
+	case <-g.ready:
+		return &tool.Result{Output: string(args)}, nil
+	case <-time.After(2 * time.Second):
+		return &tool.Result{IsError: true, Error: "ran alone"}, nil
+	}
+}
+
+func TestToolCallsRunConcurrentlyInOrder(t *testing.T) {
+	calls := []llm.ToolCall{
+		{ID: "a", Name: "gate", Arguments: []byte(`"first"`)},
+		{ID: "b", Name: "gate", Arguments: []byte(`"second"`)},
+		{ID: "c", Name: "gate", Arguments: []byte(`"third"`)},
+	}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{ToolCalls: calls}},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxParallelTools = len(calls)
+	a.SetProvider(provider)
+	a.tools.Register(&gateTool{want: len(calls), ready: make(chan struct{})})
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := provider.requests[1].Messages
+	results := msgs[len(msgs)-len(calls):]
+	for i, tc := range calls {
+		if results[i].Role != "tool" || results[i].ToolCallID != tc.ID || results[i].Content != string(tc.Arguments) {
+			t.Fatalf("result %d: got %+v, want output of call %s", i, results[i], tc.ID)
+		}
+	}
+}
+
+// blockingTool runs until its context is cancelled.
+type blockingTool struct{ echoTool }
+
+func (b *blockingTool) Name() string { return "block" }
+func (b *blockingTool) Execute(ctx context.Context, _ json.RawMessage) (*tool.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestProcessTimeoutCancelsAndReportsProgress(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "Looking it up.", ToolCalls: []llm.ToolCall{{ID: "1", Name: "block", Arguments: []byte(`{}`)}}}},
+		{resp: &llm.LLMResponse{Content: "too late"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.ProcessTimeoutSecs = 1
+	a.SetProvider(provider)
+	a.tools.Register(&blockingTool{})
+
+	response, err := a.HandleDirectMessage(context.Background(), "chat1", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != timeoutReply+"Looking it up." || provider.calls != 1 {
+		t.Fatalf("unexpected reply %q after %d LLM calls", response, provider.calls)
+	}
+	history, _ := a.memory.GetHistory(context.Background(), "chat1", 10)
+	if last := history[len(history)-1]; last.Content != response {
+		t.Fatalf("timeout reply not saved: %+v", last)
+	}
+}
+
+func TestCancelRequestStopsMidLoop(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "Checking the logs.", ToolCalls: []llm.ToolCall{{ID: "1", Name: "block", Arguments: []byte(`{}`)}}}},
+		{resp: &llm.LLMResponse{Content: "never sent"}},
+	}}
+	a := newTestAgent(t)
+	a.SetProvider(provider)
+	a.tools.Register(&blockingTool{})
+	if a.CancelRequest("chat1") {
+		t.Fatal("nothing should be running yet")
+	}
+
+	// Cancel once the tool is running.
+	a.bus.Subscribe("tool_call", func(eventbus.Event) {
+		if !a.CancelRequest("chat1") {
+			t.Error("CancelRequest found no request in flight")
+		}
+	})
+
+	response, err := a.HandleDirectMessage(context.Background(), "chat1", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "Checking the logs."+cancelledNote || provider.calls != 1 {
+		t.Fatalf("unexpected reply %q after %d LLM calls", response, provider.calls)
+	}
+	history, _ := a.memory.GetHistory(context.Background(), "chat1", 10)
+	if last := history[len(history)-1]; last.Content != response {
+		t.Fatalf("cancelled reply not saved: %+v", last)
+	}
+	if a.CancelRequest("chat1") {
+		t.Fatal("finished request still tracked")
+	}
+}
+
+func TestSetProviderAppliesToNextMessage(t *testing.T) {
+	before := &scriptedProvider{steps: []scriptedStep{{resp: &llm.LLMResponse{Content: "from before"}}}}
+	after := &scriptedProvider{steps: []scriptedStep{{resp: &llm.LLMResponse{Content: "from after"}}}}
+	a := newTestAgent(t)
+	a.SetProvider(before)
+
+	if response, _ := a.HandleDirectMessage(context.Background(), "chat1", "one"); response != "from before" {
+		t.Fatalf("unexpected reply %q", response)
+	}
+	a.SetProvider(after)
+	if response, _ := a.HandleDirectMessage(context.Background(), "chat1", "two"); response != "from after" {
+		t.Fatalf("unexpected reply %q", response)
+	}
+	if before.calls != 1 || after.calls != 1 {
+		t.Fatalf("calls: before %d, after %d", before.calls, after.calls)
+	}
+	if a.contextManager().provider != after {
+		t.Fatal("context manager still summarizes with the old provider")
+	}
+}
+
+// cachedEcho is an echoTool that declares its results cacheable.
+type cachedEcho struct{ echoTool }
+
+func (c *cachedEcho) Cacheable() bool { return true }
+
+func TestCacheableToolResultsAreReused(t *testing.T) {
+	call := &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "1", Name: "echo", Arguments: []byte(`{"q":1}`)}}}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: call}, {resp: call}, {resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cache = tool.NewResultCache(time.Minute)
+	a.SetProvider(provider)
+	echo := &cachedEcho{}
+	a.tools.Register(echo)
+
+	var events []string
+	for _, topic := range []eventbus.Topic{"tool_cache_hit", "tool_cache_miss"} {
+		a.bus.Subscribe(topic, func(e eventbus.Event) { events = append(events, string(e.Topic)) })
+	}
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+	if echo.runs != 1 {
+		t.Fatalf("tool ran %d times, want 1", echo.runs)
+	}
+	if want := []string{"tool_cache_miss", "tool_cache_hit"}; !slices.Equal(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	msgs := provider.requests[2].Messages
+	if last := msgs[len(msgs)-1]; last.Content != `{"q":1}` {
+		t.Fatalf("cached result not returned: %+v", last)
+	}
+}
+
+func TestToolCallsAreTimedAndCounted(t *testing.T) {
+	call := func(name string) *llm.LLMResponse {
+		return &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: name, Name: name, Arguments: []byte(`{}`)}}}
+	}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: call("broken")},
+		{resp: call("echo")},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.SetProvider(provider)
+	a.tools.Register(&failingTool{})
+	a.tools.Register(&echoTool{})
+
+	var results []ToolResult
+	a.bus.Subscribe("tool_result", func(e eventbus.Event) { results = append(results, e.Payload.(ToolResult)) })
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Tool != "broken" || !results[0].IsError || results[1].IsError || results[1].ChatID != "chat1" {
+		t.Fatalf("tool_result events = %+v", results)
+	}
+
+	stats := a.ToolMetrics()
+	if len(stats) != 2 {
+		t.Fatalf("ToolMetrics = %+v", stats)
+	}
+	if broken := stats[0]; broken.Name != "broken" || broken.Calls != 1 || broken.Errors != 1 || !strings.Contains(broken.LastError, "file not found") {
+		t.Errorf("broken stats = %+v", broken)
+	}
+	if echo := stats[1]; echo.Name != "echo" || echo.Calls != 1 || echo.Errors != 0 {
+		t.Errorf("echo stats = %+v", echo)
+	}
+}
+
+func TestFlaggedToolCallsWaitForApproval(t *testing.T) {
+	call := func(id, action string) *llm.LLMResponse {
+		return &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: id, Name: "echo", Arguments: []byte(`{"action":"` + action + `"}`)}}}
+	}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: call("1", "write")},
+		{resp: call("2", "write")},
+		{resp: call("3", "read")},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.SetApproval(config.ApprovalConfig{Tools: []string{"echo:write"}, TimeoutSecs: 5})
+	a.SetProvider(provider)
+	echo := &echoTool{}
+	a.tools.Register(echo)
+
+	var asked []string
+	a.bus.Subscribe("tool_approval_request", func(e eventbus.Event) {
+		req := e.Payload.(ApprovalRequest)
+		asked = append(asked, req.CallID)
+		if err := a.Approve(req.CallID, req.CallID == "1"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(asked, []string{"1", "2"}) {
+		t.Fatalf("approval asked for %v, want the two writes", asked)
+	}
+	if echo.runs != 2 {
+		t.Fatalf("tool ran %d times, want the approved write and the read", echo.runs)
+	}
+	msgs := provider.requests[2].Messages
+	if last := msgs[len(msgs)-1]; !strings.HasPrefix(last.Content, "Denied by user") {
+		t.Fatalf("denied call should report the denial: %+v", last)
+	}
+	if err := a.Approve("1", true); err == nil {
+		t.Fatal("expected an error answering a request twice")
+	}
+}
+
+func TestUnansweredApprovalIsDenied(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "1", Name: "echo", Arguments: []byte(`{}`)}}}},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.SetApproval(config.ApprovalConfig{Tools: []string{"echo"}})
+	a.approvals.timeout = 10 * time.Millisecond
+	a.SetProvider(provider)
+	echo := &echoTool{}
+	a.tools.Register(echo)
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "go"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := provider.requests[1].Messages
+	if last := msgs[len(msgs)-1]; echo.runs != 0 || !strings.HasPrefix(last.Content, "Denied by user") {
+		t.Fatalf("unanswered call ran or wasn't reported denied: runs=%d, %+v", echo.runs, last)
+	}
+}
+
+func TestChatQueueRunsInOrderAndReportsPositions(t *testing.T) {
+	var q chatQueues
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+
+	record := func(i int) func() {
+		wg.Add(1)
+		return func() {
+			defer wg.Done()
+			if i == 0 {
+				<-release // hold the chat busy while the others queue
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}
+	}
+
+	var positions []int
+	for i := range 4 {
+		positions = append(positions, q.enqueue("chat1", record(i)))
+	}
+	if other := q.enqueue("chat2", func() {}); other != 0 {
+		t.Errorf("another chat should not queue, got position %d", other)
+	}
+	if want := []int{0, 1, 2, 3}; !slices.Equal(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+	if d := q.depths()["chat1"]; d != 4 {
+		t.Errorf("depth = %d, want 4", d)
+	}
+
+	close(release)
+	wg.Wait()
+	if want := []int{0, 1, 2, 3}; !slices.Equal(order, want) {
+		t.Errorf("processed in order %v, want %v", order, want)
+	}
+}
+
+// blockingProvider holds each request until released.
+type blockingProvider struct {
+	mockProvider
+	started chan string
+	release chan struct{}
+}
+
+func (p *blockingProvider) Chat(_ context.Context, req *llm.ChatRequest) (*llm.LLMResponse, error) {
+	text := req.Messages[len(req.Messages)-1].Content
+	p.started <- text
+	<-p.release
+	return &llm.LLMResponse{Content: "re: " + text}, nil
+}
+
+func TestQueuedMessageIsAcknowledged(t *testing.T) {
+	ch := &mockChannel{name: "test", running: true}
+	a := newTestAgent(t, ch)
+	a.cfg.QueueAck = true
+	provider := &blockingProvider{started: make(chan string, 2), release: make(chan struct{})}
+	a.SetProvider(provider)
+
+	ctx := context.Background()
+	a.enqueueMessage(ctx, ch, channel.InboundMessage{ChannelName: "test", ChatID: "c1", Text: "first"})
+	if got := <-provider.started; got != "first" {
+		t.Fatalf("started %q first", got)
+	}
+	a.enqueueMessage(ctx, ch, channel.InboundMessage{ChannelName: "test", ChatID: "c1", Text: "second"})
+
+	if d := a.QueueDepths()["test:c1"]; d != 2 {
+		t.Errorf("queue depth = %d, want 2", d)
+	}
+	ch.mu.Lock()
+	if len(ch.sent) != 1 || !strings.Contains(ch.sent[0].Text, "position 1") {
+		t.Errorf("expected queue acknowledgment, got %+v", ch.sent)
+	}
+	ch.mu.Unlock()
+
+	provider.release <- struct{}{}
+	if got := <-provider.started; got != "second" {
+		t.Fatalf("started %q second", got)
+	}
+	provider.release <- struct{}{}
+
+	deadline := time.After(2 * time.Second)
+	for len(a.QueueDepths()) > 0 {
+		select {
+		case <-deadline:
+			t.Fatal("queue did not drain")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.sent) != 3 || ch.sent[1].Text != "re: first" || ch.sent[2].Text != "re: second" {
+		t.Fatalf("unexpected sends: %+v", ch.sent)
+	}
+}
+
+func TestFallbackServedReplyIsRecorded(t *testing.T) {
+	primary := &scriptedProvider{steps: []scriptedStep{
+		{err: &llm.LLMError{Type: llm.ErrorRateLimit, Message: "slow down"}},
+		{err: &llm.LLMError{Type: llm.ErrorRateLimit, Message: "slow down"}},
+	}}
+	backup := &scriptedProvider{steps: []scriptedStep{
+		{err: &llm.LLMError{Type: llm.ErrorNetwork, Message: "connection reset"}},
+		{resp: &llm.LLMResponse{Content: "from backup", Metadata: llm.ResponseMetadata{Model: "backup-1"}}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxMessageRetries = 1
+	a.retryDelay = time.Millisecond
+	a.SetProvider(llm.NewFallbackProvider(primary, &namedProvider{backup, "backup"}))
+
+	ctx := context.Background()
+	if _, err := a.HandleDirectMessage(ctx, "chat1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, _ := a.memory.GetHistory(ctx, "chat1", 10)
+	reply := history[len(history)-1]
+	want := llm.ResponseMetadata{Model: "backup-1", Provider: "backup", Fallback: true, Retries: 2}
+	if reply.Metadata == nil || *reply.Metadata != want {
+		t.Fatalf("reply metadata = %+v, want %+v", reply.Metadata, want)
+	}
+
+	stats, _ := a.memory.UsageStats(ctx)
+	if len(stats) != 1 || stats[0].Provider != "backup" || stats[0].Fallbacks != 1 || stats[0].Retries != 2 {
+		t.Fatalf("unexpected usage stats: %+v", stats)
+	}
+}
+
+// namedProvider overrides a provider's name.
+type namedProvider struct {
+	llm.Provider
+	name string
+}
+
+func (p *namedProvider) Name() string { return p.name }
+
+func TestShellResultReachesModelWithStreamsAndExitCode(t *testing.T) {
+	shell, err := tool.NewShellTool(tool.ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "shell", Arguments: []byte(`{"command":"echo out; echo err >&2; exit 2"}`)}}}},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.StreamToolOutput = true
+	a.SetProvider(provider)
+	a.tools.Register(shell)
+
+	var progress []string
+	a.bus.Subscribe("tool_progress", func(e eventbus.Event) {
+		progress = append(progress, e.Payload.(map[string]string)["line"])
+	})
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "run it"); err != nil {
+		t.Fatal(err)
+	}
+	var observed string
+	for _, m := range provider.requests[1].Messages {
+		if m.Role == "tool" {
+			observed = m.Content
+		}
+	}
+	for _, want := range []string{"exit code 2", "[stdout]\nout", "[stderr]\nerr"} {
+		if !strings.Contains(observed, want) {
+			t.Errorf("model's observation lacks %q:\n%s", want, observed)
+		}
+	}
+	if len(progress) != 2 {
+		t.Errorf("expected both lines as progress, got %q", progress)
+	}
+}
+
+func TestToolOutputStreamsOnlyWhenEnabled(t *testing.T) {
+	shell, err := tool.NewShellTool(tool.ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stream := range []bool{false, true} {
+		provider := &scriptedProvider{steps: []scriptedStep{
+			{resp: &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "shell", Arguments: []byte(`{"command":"echo hi; exit 1"}`)}}}},
+			{resp: &llm.LLMResponse{Content: "done"}},
+		}}
+		a := newTestAgent(t)
+		a.cfg.StreamToolOutput = stream
+		a.SetProvider(provider)
+		a.tools.Register(shell)
+		progress := 0
+		a.bus.Subscribe("tool_progress", func(eventbus.Event) { progress++ })
+
+		if _, err := a.HandleDirectMessage(context.Background(), "chat1", "run it"); err != nil {
+			t.Fatal(err)
+		}
+		if stream != (progress > 0) {
+			t.Errorf("stream_tool_output=%v: %d progress events", stream, progress)
+		}
+		var observed string
+		for _, m := range provider.requests[1].Messages {
+			if m.Role == "tool" {
+				observed = m.Content
+			}
+		}
+		if observed != "Error: exit code 1\nhi\n" {
+			t.Errorf("stream_tool_output=%v: model saw %q", stream, observed)
+		}
+	}
+}
+
+func TestStreamedShellFailureCountsAsError(t *testing.T) {
+	shell, err := tool.NewShellTool(tool.ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(id, command string) *llm.LLMResponse {
+		return &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: id, Name: "shell", Arguments: []byte(`{"command":"` + command + `"}`)}}}
+	}
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: call("c1", "echo partial; exit 3")},
+		{resp: call("c2", "true")}, // empty output isn't an error
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.StreamToolOutput = true
+	a.SetProvider(provider)
+	a.tools.Register(shell)
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "run it"); err != nil {
+		t.Fatal(err)
+	}
+	stats := a.ToolMetrics()
+	if len(stats) != 1 || stats[0].Calls != 2 || stats[0].Errors != 1 || !strings.Contains(stats[0].LastError, "exit code 3") {
+		t.Fatalf("ToolMetrics = %+v", stats)
+	}
+}
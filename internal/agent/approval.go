@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"open-dan/internal/config"
+	"open-dan/internal/llm"
+)
+
+// defaultApprovalTimeout applies when the approval config leaves it unset.
+const defaultApprovalTimeout = 2 * time.Minute
+
+// ApprovalRequest is published on TopicToolApprovalRequest when a tool call
+// must be confirmed before it runs. Answer it with Agent.Approve.
+type ApprovalRequest struct {
+	ChatID      string          `json:"chat_id"`
+	CallID      string          `json:"call_id"`
+	Tool        string          `json:"tool"`
+	Arguments   json.RawMessage `json:"arguments"`
+	TimeoutSecs int             `json:"timeout_secs"`
+}
+
+// approvals holds the decisions awaited by tool calls, keyed by call ID.
+type approvals struct {
+	mu      sync.Mutex
+	rules   []string // "tool" or "tool:action"
+	timeout time.Duration
+	pending map[string]chan bool
+}
+
+// SetApproval sets which tool calls need the user's approval and how long
+// to wait for it. A rule is a tool name, such as "shell", or a tool name
+// and the value of its action argument, such as "filesystem:write".
+func (a *Agent) SetApproval(cfg config.ApprovalConfig) {
+	a.approvals.mu.Lock()
+	defer a.approvals.mu.Unlock()
+	a.approvals.rules = cfg.Tools
+	a.approvals.timeout = time.Duration(cfg.TimeoutSecs) * time.Second
+	if a.approvals.timeout <= 0 {
+		a.approvals.timeout = defaultApprovalTimeout
+	}
+}
+
+// Approve answers the pending approval request for callID.
+func (a *Agent) Approve(callID string, approved bool) error {
+	a.approvals.mu.Lock()
+	decision, ok := a.approvals.pending[callID]
+	delete(a.approvals.pending, callID)
+	a.approvals.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no tool call %q is awaiting approval", callID)
+	}
+	decision <- approved
+	return nil
+}
+
+// needsApproval reports whether tc matches one of the approval rules.
+func (a *Agent) needsApproval(tc llm.ToolCall) (bool, time.Duration) {
+	a.approvals.mu.Lock()
+	rules, timeout := a.approvals.rules, a.approvals.timeout
+	a.approvals.mu.Unlock()
+
+	var args struct {
+		Action string `json:"action"`
+	}
+	_ = json.Unmarshal(tc.Arguments, &args)
+	for _, rule := range rules {
+		name, action, scoped := strings.Cut(rule, ":")
+		if name == tc.Name && (!scoped || action == args.Action) {
+			return true, timeout
+		}
+	}
+	return false, 0
+}
+
+// awaitApproval asks for approval of tc and waits for the answer. It
+// returns "" if the call may run, or else the text to use as its result.
+func (a *Agent) awaitApproval(ctx context.Context, chatID string, tc llm.ToolCall, timeout time.Duration) string {
+	decision := make(chan bool, 1)
+	a.approvals.mu.Lock()
+	if a.approvals.pending == nil {
+		a.approvals.pending = make(map[string]chan bool)
+	}
+	a.approvals.pending[tc.ID] = decision
+	a.approvals.mu.Unlock()
+	defer func() {
+		a.approvals.mu.Lock()
+		if a.approvals.pending[tc.ID] == decision {
+			delete(a.approvals.pending, tc.ID)
+		}
+		a.approvals.mu.Unlock()
+	}()
+
+	a.bus.Publish("tool_approval_request", ApprovalRequest{
+		ChatID:      chatID,
+		CallID:      tc.ID,
+		Tool:        tc.Name,
+		Arguments:   tc.Arguments,
+		TimeoutSecs: int(timeout / time.Second),
+	})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ok := <-decision:
+		if ok {
+			return ""
+		}
+		return "Denied by user: the " + tc.Name + " call was not run."
+	case <-timer.C:
+		return fmt.Sprintf("Denied by user: no approval for the %s call within %s, so it was not run.", tc.Name, timeout)
+	case <-ctx.Done():
+		return "Error executing tool: " + ctx.Err().Error()
+	}
+}
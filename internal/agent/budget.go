@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"open-dan/internal/llm"
+)
+
+const (
+	// charsPerToken converts a token budget back to characters, matching
+	// llm.EstimateTokens.
+	charsPerToken = 4
+	// minToolOutputChars keeps results useful even when the context is nearly
+	// full; summarization reclaims space on the next turn.
+	minToolOutputChars = 500
+)
+
+// toolOutputBudget returns how many characters each of n tool results from
+// the current turn may keep. Half of the context left after the response
+// reservation is shared between them, leaving room for later turns. The
+// configured MaxToolOutputChars caps the result when the context is roomy.
+// 0 means no limit.
+func (a *Agent) toolOutputBudget(model string, messages []llm.Message, n int) int {
+	budget := a.cfg.MaxToolOutputChars
+	if a.cfg.ContextWindow > 0 {
+		remaining := a.cfg.ContextWindow - a.contextManager().countTokens(model, messages) - a.cfg.MaxTokens
+		contextBudget := max(remaining*charsPerToken/2/max(n, 1), minToolOutputChars)
+		if budget <= 0 || contextBudget < budget {
+			budget = contextBudget
+		}
+	}
+	return budget
+}
+
+// trimToolOutput shortens s to about limit characters, keeping the start and
+// the end (where errors and exit codes usually are). A limit of 0 means no limit.
+func trimToolOutput(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	head := limit * 2 / 3
+	tail := limit - head
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	start := len(s) - tail
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return fmt.Sprintf("%s\n... (%d characters omitted to fit the context window) ...\n%s", s[:head], start-head, s[start:])
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"open-dan/internal/config"
+	"open-dan/internal/llm"
+)
+
+func TestToolOutputBudget(t *testing.T) {
+	a := newTestAgent(t)
+	a.cfg.MaxToolOutputChars = 20000
+	a.cfg.MaxTokens = 1000
+
+	// Plenty of room: the static cap applies
+	a.cfg.ContextWindow = 100000
+	if got := a.toolOutputBudget("", nil, 1); got != 20000 {
+		t.Fatalf("expected static cap 20000, got %d", got)
+	}
+
+	// Tight context: half the remaining tokens, split across results
+	a.cfg.ContextWindow = 3000
+	history := []llm.Message{{Role: "user", Content: strings.Repeat("x", 4000)}} // ~1000 tokens
+	if got := a.toolOutputBudget("", history, 2); got != 1000 {
+		t.Fatalf("expected (3000-1000-1000)*4/2/2 = 1000, got %d", got)
+	}
+
+	// Context exhausted: never below the floor
+	a.cfg.ContextWindow = 1000
+	if got := a.toolOutputBudget("", history, 1); got != minToolOutputChars {
+		t.Fatalf("expected floor %d, got %d", minToolOutputChars, got)
+	}
+}
+
+func TestToolResultsTrimmedToRemainingContext(t *testing.T) {
+	output := "BEGIN" + strings.Repeat("a", 9990) + "END.." // under the static cap
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{ToolCalls: []llm.ToolCall{{ID: "1", Name: "echo", Arguments: []byte(`{}`)}}}},
+		{resp: &llm.LLMResponse{Content: "done"}},
+	}}
+	a := newTestAgent(t)
+	a.cfg.MaxToolOutputChars = 20000
+	a.cfg.MaxTokens = 500
+	a.cfg.ContextWindow = 2000
+	a.cfg.SummarizeAt = 1 << 30
+	a.SetProvider(provider)
+	a.tools.Register(&echoTool{output: output})
+
+	if _, err := a.HandleDirectMessage(context.Background(), "chat1", "run it"); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := provider.requests[1].Messages
+	result := msgs[len(msgs)-1].Content
+	if len(result) >= len(output) || len(result) > 4000 {
+		t.Fatalf("expected result trimmed well below %d chars, got %d", len(output), len(result))
+	}
+	if !strings.HasPrefix(result, "BEGIN") || !strings.HasSuffix(result, "END..") {
+		t.Fatalf("trimmed result should keep head and tail: %q...%q", result[:10], result[len(result)-10:])
+	}
+}
+
+// byteCounter is a provider whose tokenizer counts one token per byte.
+type byteCounter struct{ mockProvider }
+
+func (byteCounter) CountTokens(_, text string) int { return len(text) }
+
+func TestSummarizeUsesProviderTokenizer(t *testing.T) {
+	history := []llm.Message{{Role: "user", Content: strings.Repeat("x", 400)}} // estimated at 100 tokens
+
+	if cm := newContextManager(&mockProvider{}, config.AgentConfig{ContextWindow: 100000, SummarizeAt: 150}); cm.shouldSummarize("", history) {
+		t.Fatal("estimate of 100 tokens should stay under the threshold")
+	}
+	if cm := newContextManager(&byteCounter{}, config.AgentConfig{ContextWindow: 100000, SummarizeAt: 150}); !cm.shouldSummarize("", history) {
+		t.Fatal("tokenizer count of 400 should trigger summarization")
+	}
+	if cm := newContextManager(&byteCounter{}, config.AgentConfig{ContextWindow: 100000, SummarizeAt: 1000}); cm.countTokens("", history) != 100 {
+		t.Fatal("histories far below the threshold should only be estimated")
+	}
+}
+
+func TestSummarizeKeepsConfiguredRecent(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{{resp: &llm.LLMResponse{Content: "- asked about the weather"}}}}
+	cm := newContextManager(provider, config.AgentConfig{SummaryKeepRecent: 3, SummaryPrompt: "Bullet points please:"})
+	messages := []llm.Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "1", Name: "echo"}, {ID: "2", Name: "echo"}}},
+		{Role: "tool", ToolCallID: "1", Content: "sunny"},
+		{Role: "tool", ToolCallID: "2", Content: "warm"},
+		{Role: "user", Content: "and tomorrow?"},
+	}
+
+	summary, recent, err := cm.summarize(context.Background(), messages)
+	if err != nil || summary != "- asked about the weather" {
+		t.Fatalf("summary %q, err %v", summary, err)
+	}
+	// The last 3 would start on a tool result, so its call is kept too.
+	if len(recent) != 4 || len(recent[0].ToolCalls) != 2 {
+		t.Fatalf("recent window should start at the tool call: %+v", recent)
+	}
+	if prompt := provider.requests[0].Messages[0].Content; !strings.HasPrefix(prompt, "Bullet points please:") {
+		t.Fatalf("custom prompt not used: %q", prompt)
+	}
+}
+
+func TestSlidingWindowDropsOldMessages(t *testing.T) {
+	provider := &scriptedProvider{steps: []scriptedStep{{resp: &llm.LLMResponse{Content: "ok"}}}}
+	a := newTestAgent(t)
+	a.cfg.SummarizeAt = 10
+	a.cfg.SummaryStrategy = config.SummaryStrategySlidingWindow
+	a.cfg.SummaryKeepRecent = 2
+	a.SetProvider(provider)
+	ctx := context.Background()
+	for _, m := range []llm.Message{
+		{Role: "user", Content: "first question, long enough to count"},
+		{Role: "assistant", Content: "first answer, long enough to count"},
+		{Role: "user", Content: "second question"},
+		{Role: "assistant", Content: "second answer"},
+	} {
+		a.memory.SaveMessage(ctx, "chat1", m)
+	}
+
+	if _, err := a.HandleDirectMessage(ctx, "chat1", "third question"); err != nil {
+		t.Fatal(err)
+	}
+	// No summary call: the only request is the reply, with the last 2 messages.
+	if provider.calls != 1 {
+		t.Fatalf("expected 1 LLM call, got %d", provider.calls)
+	}
+	got := provider.requests[0].Messages
+	if len(got) != 2 || got[0].Content != "second answer" || got[1].Content != "third question" {
+		t.Fatalf("unexpected window: %+v", got)
+	}
+	if summary, _ := a.memory.GetSummary(ctx, "chat1"); summary != "" {
+		t.Fatalf("sliding window should not save a summary, got %q", summary)
+	}
+}
+
+func TestSplitNeverOrphansToolResults(t *testing.T) {
+	calls := func(ids ...string) []llm.ToolCall {
+		var tcs []llm.ToolCall
+		for _, id := range ids {
+			tcs = append(tcs, llm.ToolCall{ID: id, Name: "echo"})
+		}
+		return tcs
+	}
+	messages := []llm.Message{
+		{Role: "user", Content: "check both"},
+		{Role: "assistant", ToolCalls: calls("a", "b")},
+		{Role: "tool", ToolCallID: "a", Content: "1"},
+		{Role: "tool", ToolCallID: "b", Content: "2"},
+		{Role: "assistant", Content: "both fine"},
+		{Role: "user", Content: "again"},
+		{Role: "assistant", Content: "checking", ToolCalls: calls("c")},
+		{Role: "tool", ToolCallID: "c", Content: "3"},
+		{Role: "assistant", ToolCalls: calls("d", "e", "f")},
+		{Role: "tool", ToolCallID: "d", Content: "4"},
+		{Role: "tool", ToolCallID: "e", Content: "5"},
+		{Role: "tool", ToolCallID: "f", Content: "6"},
+	}
+
+	// Every window size lands the cut on a different message, including
+	// each position inside the two tool-call groups.
+	for keep := 1; keep <= len(messages); keep++ {
+		cm := newContextManager(&mockProvider{}, config.AgentConfig{SummaryKeepRecent: keep})
+		older, recent := cm.split(messages)
+		if len(older)+len(recent) != len(messages) || len(recent) < keep {
+			t.Fatalf("keep %d: split into %d + %d messages", keep, len(older), len(recent))
+		}
+		if err := checkToolPairs(recent); err != nil {
+			t.Errorf("keep %d: %v", keep, err)
+		}
+	}
+}
+
+// checkToolPairs reports a message list that a provider would reject: a
+// tool result without its call before it, or a call without its result.
+func checkToolPairs(messages []llm.Message) error {
+	pending := map[string]bool{}
+	for i, m := range messages {
+		if m.Role == "tool" {
+			if !pending[m.ToolCallID] {
+				return fmt.Errorf("message %d: result %s has no call", i, m.ToolCallID)
+			}
+			delete(pending, m.ToolCallID)
+			continue
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("message %d: calls %v have no results", i, pending)
+		}
+		for _, tc := range m.ToolCalls {
+			pending[tc.ID] = true
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("calls %v have no results", pending)
+	}
+	return nil
+}
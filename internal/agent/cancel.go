@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"open-dan/internal/llm"
+)
+
+const (
+	// cancelledReply is sent when a request is cancelled before the model
+	// wrote anything.
+	cancelledReply = "Request cancelled."
+	// cancelledNote ends the partial reply of a cancelled request.
+	cancelledNote = "\n\n[Request cancelled]"
+)
+
+// errCancelled is the cancellation cause of a request stopped by
+// CancelRequest, telling it apart from a closing app or an expired deadline.
+var errCancelled = errors.New("request cancelled")
+
+// inflight holds the cancel functions of the messages being processed,
+// by chat. A chat may have more than one when GUI calls overlap.
+type inflight struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[string]map[uint64]context.CancelCauseFunc
+}
+
+// track returns a context that CancelRequest can cancel for chatID, and a
+// function that releases it once the message is done.
+func (f *inflight) track(ctx context.Context, chatID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	f.mu.Lock()
+	if f.active == nil {
+		f.active = make(map[string]map[uint64]context.CancelCauseFunc)
+	}
+	if f.active[chatID] == nil {
+		f.active[chatID] = make(map[uint64]context.CancelCauseFunc)
+	}
+	f.nextID++
+	id := f.nextID
+	f.active[chatID][id] = cancel
+	f.mu.Unlock()
+
+	return ctx, func() {
+		f.mu.Lock()
+		delete(f.active[chatID], id)
+		if len(f.active[chatID]) == 0 {
+			delete(f.active, chatID)
+		}
+		f.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// CancelRequest stops the message being processed for chatID. In-flight LLM
+// and tool calls are cancelled, and the reply keeps whatever text the model
+// had written. It reports whether anything was running.
+func (a *Agent) CancelRequest(chatID string) bool {
+	a.inflight.mu.Lock()
+	defer a.inflight.mu.Unlock()
+	for _, cancel := range a.inflight.active[chatID] {
+		cancel(errCancelled)
+	}
+	return len(a.inflight.active[chatID]) > 0
+}
+
+// interrupted returns the reply for a message whose ctx was cancelled by
+// CancelRequest or ran past ProcessTimeoutSecs, and false if neither
+// happened. progress is the text the model has written so far.
+func (a *Agent) interrupted(ctx context.Context, chatID, progress string) (string, bool) {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errCancelled):
+		return a.cancelled(ctx, chatID, progress), true
+	case errors.Is(cause, context.DeadlineExceeded):
+		return a.timedOut(ctx, chatID, progress), true
+	}
+	return "", false
+}
+
+// cancelled records and returns the reply for a message stopped by
+// CancelRequest. ctx is done, so the reply is saved without it.
+func (a *Agent) cancelled(ctx context.Context, chatID, progress string) string {
+	log.Printf("[agent] message in %s cancelled", chatID)
+	msg := cancelledReply
+	if progress != "" {
+		msg = progress + cancelledNote
+	}
+	_ = a.memory.SaveMessage(context.WithoutCancel(ctx), chatID, llm.Message{Role: "assistant", Content: msg})
+	return msg
+}
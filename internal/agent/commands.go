@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"open-dan/internal/channel"
+	"open-dan/internal/memory"
+)
+
+// command is a built-in chat command, handled without calling the LLM.
+type command struct {
+	args string // shown in help, e.g. "[name]"
+	help string
+	run  func(a *Agent, ctx context.Context, msg channel.InboundMessage, args string) string
+}
+
+var commands = map[string]command{
+	"reset": {
+		help: "clear this conversation's history",
+		run:  (*Agent).cmdReset,
+	},
+	"model": {
+		args: "[name|default]",
+		help: "show or switch the model used in this chat",
+		run:  (*Agent).cmdModel,
+	},
+	"temp": {
+		args: "[0-2|default]",
+		help: "show or set the temperature used in this chat",
+		run:  (*Agent).cmdTemp,
+	},
+	"maxtokens": {
+		args: "[n|default]",
+		help: "show or set the reply length limit in this chat",
+		run:  (*Agent).cmdMaxTokens,
+	},
+	"status": {
+		help: "show the current configuration",
+		run:  (*Agent).cmdStatus,
+	},
+}
+
+// commandName matches what may follow the prefix, so a message such as
+// "/etc/hosts is missing" is not taken for a command.
+var commandName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// runCommand handles msg if it is a command and returns the reply. It
+// returns false for ordinary messages, which go to the LLM.
+func (a *Agent) runCommand(ctx context.Context, msg channel.InboundMessage) (string, bool) {
+	prefix := a.cfg.CommandPrefix
+	text := strings.TrimSpace(msg.Text)
+	if prefix == "" || !strings.HasPrefix(text, prefix) {
+		return "", false
+	}
+	name, args, _ := strings.Cut(strings.TrimPrefix(text, prefix), " ")
+	// In groups Telegram addresses commands to a bot as /reset@botname.
+	name, _, _ = strings.Cut(name, "@")
+	if !commandName.MatchString(name) {
+		return "", false
+	}
+
+	name = strings.ToLower(name)
+	if name == "help" {
+		return a.commandHelp(), true
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Sprintf("Unknown command %s%s.\n\n%s", prefix, name, a.commandHelp()), true
+	}
+	return cmd.run(a, ctx, msg, strings.TrimSpace(args)), true
+}
+
+func (a *Agent) commandHelp() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, name := range names {
+		cmd := commands[name]
+		usage := a.cfg.CommandPrefix + name
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(&b, "\n%s — %s", usage, cmd.help)
+	}
+	fmt.Fprintf(&b, "\n%shelp — list commands", a.cfg.CommandPrefix)
+	return b.String()
+}
+
+func (a *Agent) cmdReset(ctx context.Context, msg channel.InboundMessage, _ string) string {
+	if err := a.memory.ClearConversation(ctx, msg.Conversation()); err != nil {
+		log.Printf("[agent] failed to clear conversation %s: %v", msg.Conversation(), err)
+		return "Sorry, I couldn't clear the conversation."
+	}
+	a.bus.Publish("conversation_cleared", msg.Conversation())
+	return "Conversation cleared."
+}
+
+func (a *Agent) cmdModel(ctx context.Context, msg channel.InboundMessage, args string) string {
+	conv := msg.Conversation()
+	switch args {
+	case "":
+		if model := a.chatSettings(ctx, conv).Model; model != "" {
+			return "This chat uses " + model + "."
+		}
+		return "This chat uses the default model, " + a.currentProvider().DefaultModel() + "."
+	case "default", "reset":
+		if err := a.updateChatSettings(ctx, conv, func(s *memory.ChatSettings) { s.Model = "" }); err != nil {
+			return settingsError(conv, err)
+		}
+		return "Switched back to the default model, " + a.currentProvider().DefaultModel() + "."
+	default:
+		if err := a.updateChatSettings(ctx, conv, func(s *memory.ChatSettings) { s.Model = args }); err != nil {
+			return settingsError(conv, err)
+		}
+		return "Switched this chat to " + args + "."
+	}
+}
+
+func (a *Agent) cmdTemp(ctx context.Context, msg channel.InboundMessage, args string) string {
+	conv := msg.Conversation()
+	switch args {
+	case "":
+		if t := a.chatSettings(ctx, conv).Temperature; t != nil {
+			return fmt.Sprintf("This chat uses temperature %g.", *t)
+		}
+		return fmt.Sprintf("This chat uses the default temperature, %g.", a.cfg.Temperature)
+	case "default", "reset":
+		if err := a.updateChatSettings(ctx, conv, func(s *memory.ChatSettings) { s.Temperature = nil }); err != nil {
+			return settingsError(conv, err)
+		}
+		return fmt.Sprintf("Switched back to the default temperature, %g.", a.cfg.Temperature)
+	default:
+		t, err := strconv.ParseFloat(args, 64)
+		if err != nil {
+			return "Temperature must be a number between 0 and 2."
+		}
+		if err := a.updateChatSettings(ctx, conv, func(s *memory.ChatSettings) { s.Temperature = &t }); err != nil {
+			return settingsError(conv, err)
+		}
+		return fmt.Sprintf("Set this chat's temperature to %g.", t)
+	}
+}
+
+func (a *Agent) cmdMaxTokens(ctx context.Context, msg channel.InboundMessage, args string) string {
+	conv := msg.Conversation()
+	switch args {
+	case "":
+		if n := a.chatSettings(ctx, conv).MaxTokens; n > 0 {
+			return fmt.Sprintf("Replies in this chat are limited to %d tokens.", n)
+		}
+		return fmt.Sprintf("Replies in this chat use the default limit, %d tokens.", a.cfg.MaxTokens)
+	case "default", "reset":
+		if err := a.updateChatSettings(ctx, conv, func(s *memory.ChatSettings) { s.MaxTokens = 0 }); err != nil {
+			return settingsError(conv, err)
+		}
+		return fmt.Sprintf("Switched back to the default limit, %d tokens.", a.cfg.MaxTokens)
+	default:
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 {
+			return "Max tokens must be a positive whole number."
+		}
+		if err := a.updateChatSettings(ctx, conv, func(s *memory.ChatSettings) { s.MaxTokens = n }); err != nil {
+			return settingsError(conv, err)
+		}
+		return fmt.Sprintf("Limited replies in this chat to %d tokens.", n)
+	}
+}
+
+// settingsError logs a failed settings change and returns the reply for it.
+func settingsError(conv string, err error) string {
+	log.Printf("[agent] failed to update settings for %s: %v", conv, err)
+	return "Sorry, I couldn't change that: " + err.Error()
+}
+
+func (a *Agent) cmdStatus(ctx context.Context, msg channel.InboundMessage, _ string) string {
+	model, temperature, maxTokens := a.requestParams(ctx, msg.Conversation())
+	if model == "" {
+		model = a.currentProvider().DefaultModel() + " (default)"
+	}
+	history, _ := a.memory.GetHistory(ctx, msg.Conversation(), -1)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Provider: %s\n", a.currentProvider().Name())
+	fmt.Fprintf(&b, "Model: %s\n", model)
+	fmt.Fprintf(&b, "Temperature: %g\n", temperature)
+	fmt.Fprintf(&b, "Max tokens: %d\n", maxTokens)
+	fmt.Fprintf(&b, "Tools: %d\n", len(a.tools.Definitions()))
+	fmt.Fprintf(&b, "Messages in this conversation: %d", len(history))
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"open-dan/internal/channel"
+	"open-dan/internal/eventbus"
+	"open-dan/internal/llm"
+)
+
+func TestCommandsAreHandledWithoutLLM(t *testing.T) {
+	ch := &mockChannel{name: "mock", running: true}
+	a := newTestAgent(t, ch)
+	a.cfg.CommandPrefix = "/"
+	provider := &scriptedProvider{steps: []scriptedStep{
+		{resp: &llm.LLMResponse{Content: "first"}},
+		{resp: &llm.LLMResponse{Content: "second"}},
+	}}
+	a.provider = provider
+	ctx := context.Background()
+	send := func(text string) string {
+		t.Helper()
+		a.handleMessage(ctx, channel.InboundMessage{ChannelName: "mock", ChatID: "c1", Text: text})
+		return ch.sent[len(ch.sent)-1].Text
+	}
+
+	if got := send("hello"); got != "first" {
+		t.Fatalf("ordinary message should reach the LLM, got %q", got)
+	}
+	if got := send("/status"); !strings.Contains(got, "Model: mock-1 (default)") || !strings.Contains(got, "Messages in this conversation: 2") {
+		t.Fatalf("unexpected status: %q", got)
+	}
+	if got := send("/model@dan_bot gpt-4o"); got != "Switched this chat to gpt-4o." {
+		t.Fatalf("unexpected /model reply: %q", got)
+	}
+	if got := send("/temp 3"); !strings.Contains(got, "between 0 and 2") {
+		t.Fatalf("out-of-range temperature should be rejected, got %q", got)
+	}
+	if got := send("/temp 0"); got != "Set this chat's temperature to 0." {
+		t.Fatalf("unexpected /temp reply: %q", got)
+	}
+	if got := send("/maxtokens 256"); got != "Limited replies in this chat to 256 tokens." {
+		t.Fatalf("unexpected /maxtokens reply: %q", got)
+	}
+	if got := send("/reset"); got != "Conversation cleared." {
+		t.Fatalf("unexpected /reset reply: %q", got)
+	}
+	if h, _ := a.memory.GetHistory(ctx, "c1", -1); len(h) != 0 {
+		t.Fatalf("history not cleared: %+v", h)
+	}
+	if got := send("/frobnicate"); !strings.HasPrefix(got, "Unknown command /frobnicate.") || !strings.Contains(got, "/reset — ") {
+		t.Fatalf("unknown command should list commands, got %q", got)
+	}
+
+	// Paths are not commands.
+	if got := send("/etc/hosts looks wrong"); got != "second" {
+		t.Fatalf("path should reach the LLM, got %q", got)
+	}
+	if last := provider.requests[len(provider.requests)-1]; provider.calls != 2 || last.Model != "gpt-4o" || last.Temperature != 0 || last.MaxTokens != 256 {
+		t.Fatalf("expected 2 LLM calls, the last with the chat's overrides; got %d %+v", provider.calls, provider.requests)
+	}
+	if h, _ := a.memory.GetHistory(ctx, "c1", -1); len(h) != 2 {
+		t.Fatalf("commands should not be stored in history, got %+v", h)
+	}
+}
+
+func TestCommandsDisabledWithoutPrefix(t *testing.T) {
+	a := newTestAgent(t)
+	if _, ok := a.runCommand(context.Background(), channel.InboundMessage{Text: "/reset"}); ok {
+		t.Fatal("commands should be disabled with an empty prefix")
+	}
+}
+
+func TestResetAnnouncesClearedConversation(t *testing.T) {
+	ch := &mockChannel{name: "mock", running: true}
+	a := newTestAgent(t, ch)
+	a.cfg.CommandPrefix = "/"
+	var cleared []string
+	a.bus.Subscribe("conversation_cleared", func(e eventbus.Event) {
+		cleared = append(cleared, e.Payload.(string))
+	})
+
+	// The sanitizer's mappings are keyed like the history, per member here
+	a.handleMessage(context.Background(), channel.InboundMessage{ChannelName: "mock", ChatID: "c1", SenderID: "42", ConversationID: "c1-42", Text: "/reset"})
+	if len(cleared) != 1 || cleared[0] != "c1-42" {
+		t.Fatalf("expected c1-42 to be announced as cleared, got %v", cleared)
+	}
+}
@@ -3,53 +3,122 @@ package agent
 import (
 	"context"
 
+	"open-dan/internal/config"
 	"open-dan/internal/llm"
 )
 
+const (
+	// defaultKeepRecent applies when SummaryKeepRecent is unset.
+	defaultKeepRecent = 4
+	// defaultSummaryPrompt introduces the messages to be summarized unless
+	// SummaryPrompt replaces it.
+	defaultSummaryPrompt = "Summarize this conversation concisely, preserving key facts, decisions, and context:"
+)
+
 // contextManager handles conversation context, including summarization
 // when the context window approaches its limit.
 type contextManager struct {
 	provider      llm.Provider
 	contextWindow int
 	summarizeAt   int
+	strategy      string
+	keepRecent    int
+	summaryPrompt string
 }
 
-func newContextManager(provider llm.Provider, contextWindow, summarizeAt int) *contextManager {
-	return &contextManager{
+func newContextManager(provider llm.Provider, cfg config.AgentConfig) *contextManager {
+	cm := &contextManager{
 		provider:      provider,
-		contextWindow: contextWindow,
-		summarizeAt:   summarizeAt,
+		contextWindow: cfg.ContextWindow,
+		summarizeAt:   cfg.SummarizeAt,
+		strategy:      cfg.SummaryStrategy,
+		keepRecent:    cfg.SummaryKeepRecent,
+		summaryPrompt: cfg.SummaryPrompt,
+	}
+	if cm.keepRecent <= 0 {
+		cm.keepRecent = defaultKeepRecent
 	}
+	if cm.summaryPrompt == "" {
+		cm.summaryPrompt = defaultSummaryPrompt
+	}
+	return cm
 }
 
-// estimateTokens provides a rough token estimate (4 chars ≈ 1 token).
+// countTokens returns how many tokens messages take up for model, using the
+// provider's tokenizer when it has one. Histories whose byte estimate is
+// under half the summarization threshold (or context window, if smaller)
+// are only estimated: the exact count can't matter yet, and short chats
+// never load a tokenizer.
+func (cm *contextManager) countTokens(model string, messages []llm.Message) int {
+	threshold := cm.summarizeAt
+	if cm.contextWindow > 0 && cm.contextWindow < threshold {
+		threshold = cm.contextWindow
+	}
+	count := llm.EstimateTokens
+	if estimateTokens(messages)*2 >= threshold {
+		count = func(text string) int { return llm.CountTokens(cm.provider, model, text) }
+	}
+	total := 0
+	for _, m := range messages {
+		total += count(m.Content)
+		for _, tc := range m.ToolCalls {
+			total += count(tc.Name) + count(string(tc.Arguments))
+		}
+	}
+	return total
+}
+
+// estimateTokens is the byte-based estimate of messages' size in tokens.
 func estimateTokens(messages []llm.Message) int {
 	total := 0
 	for _, m := range messages {
-		total += len(m.Content) / 4
+		total += llm.EstimateTokens(m.Content)
 		for _, tc := range m.ToolCalls {
-			total += len(tc.Arguments) / 4
+			total += llm.EstimateTokens(string(tc.Arguments))
 		}
 	}
 	return total
 }
 
 // shouldSummarize returns true if the message history approaches the context limit.
-func (cm *contextManager) shouldSummarize(messages []llm.Message) bool {
-	return estimateTokens(messages) > cm.summarizeAt
+func (cm *contextManager) shouldSummarize(model string, messages []llm.Message) bool {
+	return cm.countTokens(model, messages) > cm.summarizeAt
+}
+
+// slidingWindow reports whether older messages are dropped rather than
+// summarized.
+func (cm *contextManager) slidingWindow() bool {
+	return cm.strategy == config.SummaryStrategySlidingWindow
+}
+
+// split divides messages into the older ones to summarize or drop and the
+// keepRecent latest ones, widened as needed to keep tool-call groups whole.
+func (cm *contextManager) split(messages []llm.Message) (older, recent []llm.Message) {
+	cutoff := safeCutoff(messages, len(messages)-cm.keepRecent)
+	if cutoff <= 0 {
+		return nil, messages
+	}
+	return messages[:cutoff], messages[cutoff:]
+}
+
+// safeCutoff moves cutoff back until messages[cutoff:] doesn't start inside
+// a tool-call group, an assistant message with tool calls followed by their
+// results. Providers reject tool results whose call is missing, so the
+// group moves into the recent part as a whole.
+func safeCutoff(messages []llm.Message, cutoff int) int {
+	for cutoff > 0 && cutoff < len(messages) && messages[cutoff].Role == "tool" {
+		cutoff--
+	}
+	return max(cutoff, 0)
 }
 
 // summarize compresses the conversation into a summary + recent messages.
 func (cm *contextManager) summarize(ctx context.Context, messages []llm.Message) (string, []llm.Message, error) {
-	if len(messages) <= 4 {
+	toSummarize, recent := cm.split(messages)
+	if len(toSummarize) == 0 {
 		return "", messages, nil
 	}
 
-	// Keep last 4 messages as recent context
-	cutoff := len(messages) - 4
-	toSummarize := messages[:cutoff]
-	recent := messages[cutoff:]
-
 	// Build summarization prompt
 	var text string
 	for _, m := range toSummarize {
@@ -58,7 +127,7 @@ func (cm *contextManager) summarize(ctx context.Context, messages []llm.Message)
 
 	summaryReq := &llm.ChatRequest{
 		Messages: []llm.Message{
-			{Role: "user", Content: "Summarize this conversation concisely, preserving key facts, decisions, and context:\n\n" + text},
+			{Role: "user", Content: cm.summaryPrompt + "\n\n" + text},
 		},
 		MaxTokens:    1024,
 		Temperature:  0.3,
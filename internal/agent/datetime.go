@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"log"
+	"time"
+)
+
+// loadTimeZone returns the named time zone, or the local one if name is
+// empty or unknown.
+func loadTimeZone(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("[agent] unknown time zone %q, using local time: %v", name, err)
+		return time.Local
+	}
+	return loc
+}
+
+// dateTimeLine states the current date and time in the user's time zone.
+func (a *Agent) dateTimeLine() string {
+	now := a.now().In(a.loc)
+	zone := now.Format("UTC-07:00")
+	if a.loc != time.Local {
+		zone = a.loc.String() + ", " + zone
+	}
+	return "Current date and time: " + now.Format("Monday, 2 January 2006, 15:04") + " (" + zone + ")."
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"open-dan/internal/memory"
+)
+
+// systemPrompt returns the chat's system prompt, or the configured default,
+// followed by any stored facts. Global facts and facts about this chat go in separate sections so
+// the model can tell which apply everywhere. With InjectDateTime set, the
+// current date and time come first. A reply-language directive is appended
+// when the user's language was detected.
+func (a *Agent) systemPrompt(ctx context.Context, chatID string) string {
+	var b strings.Builder
+	if a.cfg.InjectDateTime {
+		b.WriteString(a.dateTimeLine() + "\n\n")
+	}
+	b.WriteString(a.basePrompt(ctx, chatID))
+
+	sections := []struct {
+		title, scope string
+	}{
+		{"Facts about the user (apply to every conversation)", memory.GlobalScope},
+		{"Facts about this conversation", chatID},
+	}
+	for _, s := range sections {
+		facts, err := a.memory.GetFacts(ctx, s.scope)
+		if err != nil {
+			log.Printf("[agent] failed to load facts for %q: %v", s.scope, err)
+			continue
+		}
+		if len(facts) == 0 {
+			continue
+		}
+		b.WriteString("\n\n## " + s.title + "\n")
+		for _, f := range facts {
+			b.WriteString("- " + f.Key + ": " + f.Value + "\n")
+		}
+	}
+	if d := languageDirective(ctx); d != "" {
+		b.WriteString("\n\n" + d)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
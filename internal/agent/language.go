@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"context"
+
+	"open-dan/internal/security"
+)
+
+type languageKey struct{}
+
+// withUserLanguage records the detected language of the message being
+// processed so systemPrompt can add a reply-language directive.
+func withUserLanguage(ctx context.Context, text string) context.Context {
+	lang := security.DetectLanguage(text)
+	if lang == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// languageDirective returns the instruction to reply in the user's language,
+// or "" if none was detected.
+func languageDirective(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	name := security.LanguageName(lang)
+	if name == "" {
+		return ""
+	}
+	return "The user is writing in " + name + ". Reply in " + name + " unless asked otherwise."
+}
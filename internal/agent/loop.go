@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"open-dan/internal/llm"
+	"open-dan/internal/tool"
 )
 
 // processMessage runs the agent loop for a single user message.
 // Loop: think → act → observe, repeating until the LLM produces a final text response.
-func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (string, error) {
+// If onText is non-nil, each LLM turn is streamed and onText receives the partial text.
+func (a *Agent) processMessage(ctx context.Context, chatID, userText string, onText func(string)) (string, error) {
 	// Load history from memory
 	history, err := a.memory.GetHistory(ctx, chatID, 50)
 	if err != nil {
@@ -42,13 +48,82 @@ func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (st
 	// Save user message
 	_ = a.memory.SaveMessage(ctx, chatID, llm.Message{Role: "user", Content: userText})
 
-	// Agent loop
+	if a.cfg.MatchUserLanguage {
+		ctx = withUserLanguage(ctx, userText)
+	}
+
+	// Let CancelRequest stop the message between or during steps.
+	ctx, release := a.inflight.track(ctx, chatID)
+	defer release()
+
+	// Bound the whole message, however many turns and tool calls it takes.
+	// In-flight LLM and tool calls are cancelled when the deadline passes.
+	if a.cfg.ProcessTimeoutSecs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(a.cfg.ProcessTimeoutSecs)*time.Second)
+		defer cancel()
+	}
+
+	// Retry the whole loop on transient provider failures. Each attempt starts
+	// from the saved user message; partial tool-call state is discarded.
+	delay := a.retryDelay
+	for attempt := 0; ; attempt++ {
+		response, err := a.runLoop(withAttempt(ctx, attempt), chatID, slices.Clone(messages), onText)
+		if err == nil || attempt >= a.cfg.MaxMessageRetries || !llm.IsTransient(err) {
+			return response, err
+		}
+		log.Printf("[agent] transient failure (attempt %d/%d), retrying in %s: %v", attempt+1, a.cfg.MaxMessageRetries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return "", err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+type attemptKey struct{}
+
+// withAttempt records how many times the current message has already been
+// retried, so the reply's metadata can report it.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+func attemptFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+const (
+	// continuePrompt asks the model to resume a reply cut off by max_tokens.
+	continuePrompt = "Your previous reply was cut off. Continue exactly where it stopped, without repeating anything."
+	// truncatedNote marks a reply that was still incomplete after the
+	// allowed continuations.
+	truncatedNote = "\n\n[Response truncated: reached the output token limit]"
+	// timeoutReply starts the reply sent when ProcessTimeoutSecs runs out.
+	timeoutReply = "I ran out of time for this request. Here's what I have so far: "
+)
+
+// runLoop runs think → act → observe until the LLM produces a final response.
+func (a *Agent) runLoop(ctx context.Context, chatID string, messages []llm.Message, onText func(string)) (string, error) {
 	toolCallCount := 0
+	continuations := 0
+	reflections := 0
+	var partial string  // text of earlier turns cut off by max_tokens
+	var progress string // text the model wrote alongside its latest tool calls
+	systemPrompt := a.systemPrompt(ctx, chatID)
+	model, temperature, maxTokens := a.requestParams(ctx, chatID)
 	for {
-		// Check context window, summarize if needed
-		if a.ctxManager.shouldSummarize(messages) {
-			newSummary, recent, err := a.ctxManager.summarize(ctx, messages)
-			if err == nil && newSummary != "" {
+		if reply, ok := a.interrupted(ctx, chatID, partial+progress); ok {
+			return reply, nil
+		}
+
+		// Check context window; summarize or drop older messages if needed
+		if cm := a.contextManager(); cm.shouldSummarize(model, messages) {
+			if cm.slidingWindow() {
+				_, messages = cm.split(messages)
+			} else if newSummary, recent, err := cm.summarize(ctx, messages); err == nil && newSummary != "" {
 				_ = a.memory.SaveSummary(ctx, chatID, newSummary)
 				messages = append([]llm.Message{
 					{Role: "user", Content: "[Conversation summary]: " + newSummary},
@@ -59,17 +134,29 @@ func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (st
 
 		// Think: send to LLM
 		req := &llm.ChatRequest{
+			Model:        model,
 			Messages:     messages,
 			Tools:        a.tools.Definitions(),
-			MaxTokens:    a.cfg.MaxTokens,
-			Temperature:  a.cfg.Temperature,
-			SystemPrompt: a.cfg.SystemPrompt,
+			MaxTokens:    maxTokens,
+			Temperature:  temperature,
+			SystemPrompt: systemPrompt,
 		}
 
 		a.bus.Publish("llm_request", req)
 
-		resp, err := a.provider.Chat(ctx, req)
+		streamText := onText
+		if onText != nil {
+			prefix := partial
+			streamText = func(s string) {
+				progress = s // keep streamed text if the turn is interrupted
+				onText(prefix + s)
+			}
+		}
+		resp, err := a.complete(ctx, req, streamText)
 		if err != nil {
+			if reply, ok := a.interrupted(ctx, chatID, partial+progress); ok {
+				return reply, nil
+			}
 			return "", fmt.Errorf("LLM error: %w", err)
 		}
 
@@ -77,8 +164,29 @@ func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (st
 
 		// If no tool calls, we have the final response
 		if len(resp.ToolCalls) == 0 {
-			_ = a.memory.SaveMessage(ctx, chatID, llm.Message{Role: "assistant", Content: resp.Content})
-			return resp.Content, nil
+			content := partial + resp.Content
+			if resp.Truncated() {
+				// Cut off by max_tokens: ask the model to carry on, up to a bound
+				if continuations < a.cfg.MaxContinuations {
+					continuations++
+					partial, progress = content, ""
+					messages = append(messages,
+						llm.Message{Role: "assistant", Content: resp.Content},
+						llm.Message{Role: "user", Content: continuePrompt},
+					)
+					continue
+				}
+				content += truncatedNote
+			}
+			reply := llm.Message{Role: "assistant", Content: content}
+			md := resp.Metadata
+			if md.Provider == "" {
+				md.Provider = a.currentProvider().Name()
+			}
+			md.Retries += attemptFromContext(ctx)
+			reply.Metadata = &md
+			_ = a.memory.SaveMessage(ctx, chatID, reply)
+			return content, nil
 		}
 
 		// Guard against infinite tool call loops
@@ -89,6 +197,8 @@ func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (st
 			return msg, nil
 		}
 
+		progress = resp.Content
+
 		// Record assistant message with tool calls
 		assistantMsg := llm.Message{
 			Role:      "assistant",
@@ -97,26 +207,27 @@ func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (st
 		}
 		messages = append(messages, assistantMsg)
 
-		// Act: execute each tool call
-		for _, tc := range resp.ToolCalls {
-			a.bus.Publish("tool_call", tc)
-
-			t, err := a.tools.Get(tc.Name)
-			var result string
-			if err != nil {
-				result = fmt.Sprintf("Error: tool '%s' not found", tc.Name)
-			} else {
-				res, err := t.Execute(ctx, tc.Arguments)
-				if err != nil {
-					result = "Error executing tool: " + err.Error()
-				} else if res.IsError {
-					result = "Error: " + res.Error
-				} else {
-					result = res.Output
-				}
+		// Act: execute each tool call, trimming results to the context budget
+		budget := a.toolOutputBudget(model, messages, len(resp.ToolCalls))
+		outcomes := a.runTools(ctx, chatID, resp.ToolCalls)
+		for i, tc := range resp.ToolCalls {
+			result, failed := outcomes[i].result, outcomes[i].failed
+			result = trimToolOutput(result, budget)
+			// Nudge the model to rethink rather than give up, while it still
+			// has tool calls left to act on the hint.
+			if failed && reflections < a.cfg.MaxReflections && toolCallCount < a.cfg.MaxToolCalls {
+				reflections++
+				result += reflectionHint(tc.Name)
 			}
 
-			a.bus.Publish("tool_result", map[string]string{"id": tc.ID, "result": result})
+			a.bus.Publish("tool_result", ToolResult{
+				ChatID:     chatID,
+				ID:         tc.ID,
+				Tool:       tc.Name,
+				Result:     result,
+				DurationMs: outcomes[i].duration.Milliseconds(),
+				IsError:    outcomes[i].isError,
+			})
 
 			// Observe: add tool result to messages
 			toolMsg := llm.Message{
@@ -129,22 +240,176 @@ func (a *Agent) processMessage(ctx context.Context, chatID, userText string) (st
 	}
 }
 
+// timedOut records and returns the reply for a message that ran past
+// ProcessTimeoutSecs. ctx has expired, so the reply is saved without it.
+func (a *Agent) timedOut(ctx context.Context, chatID, progress string) string {
+	log.Printf("[agent] message in %s timed out after %ds", chatID, a.cfg.ProcessTimeoutSecs)
+	msg := timeoutReply + progress
+	_ = a.memory.SaveMessage(context.WithoutCancel(ctx), chatID, llm.Message{Role: "assistant", Content: msg})
+	return msg
+}
+
+// ToolResult is published on TopicToolResult for each finished tool call,
+// with the result as sent to the model.
+type ToolResult struct {
+	ChatID     string `json:"chat_id"`
+	ID         string `json:"id"`
+	Tool       string `json:"tool"`
+	Result     string `json:"result"`
+	DurationMs int64  `json:"duration_ms"`
+	IsError    bool   `json:"is_error"`
+}
+
+// toolOutcome is the result of one tool call. failed covers errors and
+// empty output, which both prompt a reflection hint; isError is set only
+// for errors, which are what the metrics count.
+type toolOutcome struct {
+	result   string
+	failed   bool
+	isError  bool
+	duration time.Duration
+}
+
+// toolError is the outcome of a call that failed with the text result.
+func toolError(result string) toolOutcome {
+	return toolOutcome{result: result, failed: true, isError: true}
+}
+
+// toolOutput is the outcome of a call that succeeded with output, which
+// counts as failed if it is empty.
+func toolOutput(output string) toolOutcome {
+	return toolOutcome{result: output, failed: strings.TrimSpace(output) == ""}
+}
+
+// runTools executes a turn's tool calls, up to MaxParallelTools at a time,
+// and returns their outcomes in call order so results are observed in the
+// sequence the provider expects.
+func (a *Agent) runTools(ctx context.Context, chatID string, calls []llm.ToolCall) []toolOutcome {
+	for _, tc := range calls {
+		a.bus.Publish("tool_call", tc)
+	}
+
+	outcomes := make([]toolOutcome, len(calls))
+	sem := make(chan struct{}, max(a.cfg.MaxParallelTools, 1))
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			outcomes[i] = a.executeTool(ctx, chatID, tc)
+			outcomes[i].duration = time.Since(start)
+			var errText string
+			if outcomes[i].isError {
+				errText = strings.TrimSpace(outcomes[i].result)
+			}
+			a.metrics.Record(tc.Name, outcomes[i].duration, errText)
+		}()
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// ToolMetrics returns call counts, errors, and timings for every tool the
+// agent has run, sorted by tool name.
+func (a *Agent) ToolMetrics() []tool.ToolStats {
+	return a.metrics.Snapshot()
+}
+
+// executeTool runs a single tool call and returns the text to observe, and
+// whether the call failed or produced nothing.
+// With StreamToolOutput, streaming tools have their output forwarded as
+// progress events while running.
+// Results of cacheable tools are reused for repeated calls in the same chat,
+// and calls matching an approval rule wait for the user's decision first.
+func (a *Agent) executeTool(ctx context.Context, chatID string, tc llm.ToolCall) toolOutcome {
+	t, err := a.tools.Get(tc.Name)
+	if err != nil {
+		return toolError(fmt.Sprintf("Error: tool '%s' not found", tc.Name))
+	}
+	ctx = tool.WithChatID(ctx, chatID)
+
+	if ok, timeout := a.needsApproval(tc); ok {
+		if denied := a.awaitApproval(ctx, chatID, tc, timeout); denied != "" {
+			if strings.HasPrefix(denied, "Error") {
+				return toolError(denied)
+			}
+			return toolOutcome{result: denied}
+		}
+	}
+
+	cacheable := a.cache != nil && tool.IsCacheable(t)
+	event := map[string]string{"chat_id": chatID, "id": tc.ID, "tool": tc.Name}
+	if cacheable {
+		if res, ok := a.cache.Get(chatID, tc.Name, tc.Arguments); ok {
+			a.bus.Publish("tool_cache_hit", event)
+			return toolOutput(res.Output)
+		}
+		a.bus.Publish("tool_cache_miss", event)
+	}
+
+	var res *tool.Result
+	if st, ok := t.(tool.StreamingTool); ok && a.cfg.StreamToolOutput {
+		res, err = st.ExecuteStream(ctx, tc.Arguments, func(line string) {
+			a.bus.Publish("tool_progress", map[string]string{
+				"chat_id": chatID,
+				"id":      tc.ID,
+				"tool":    tc.Name,
+				"line":    line,
+			})
+		})
+	} else {
+		res, err = t.Execute(ctx, tc.Arguments)
+	}
+	if err != nil {
+		return toolError("Error executing tool: " + err.Error())
+	}
+	if cacheable {
+		a.cache.Put(chatID, tc.Name, tc.Arguments, res)
+	}
+	if res.IsError {
+		if res.Output != "" {
+			return toolError("Error: " + res.Error + "\n" + res.Output)
+		}
+		return toolError("Error: " + res.Error)
+	}
+	return toolOutput(res.Output)
+}
+
 // TestConnection sends a simple message to verify the LLM provider works.
 func (a *Agent) TestConnection(ctx context.Context) error {
 	req := &llm.ChatRequest{
 		Messages:  []llm.Message{{Role: "user", Content: "Say 'OK' if you can hear me."}},
 		MaxTokens: 32,
 	}
-	_, err := a.provider.Chat(ctx, req)
+	_, err := a.currentProvider().Chat(ctx, req)
 	return err
 }
 
 // SetProvider replaces the LLM provider (e.g., after config change).
+// Messages already being processed may finish with either provider; the
+// next message uses p.
 func (a *Agent) SetProvider(p llm.Provider) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.provider = p
-	a.ctxManager = newContextManager(p, a.cfg.ContextWindow, a.cfg.SummarizeAt)
+	a.ctxManager = newContextManager(p, a.cfg)
+}
+
+// currentProvider returns the provider set by SetProvider.
+func (a *Agent) currentProvider() llm.Provider {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.provider
+}
+
+// contextManager returns the context manager for the current provider.
+func (a *Agent) contextManager() *contextManager {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.ctxManager
 }
 
 // ProcessingResult is returned to the caller with the response.
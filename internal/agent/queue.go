@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"sync"
+)
+
+// chatQueues runs jobs one at a time per chat, in arrival order, so
+// replies to one conversation never interleave. Different chats run
+// concurrently.
+type chatQueues struct {
+	mu     sync.Mutex
+	queues map[string]*chatQueue
+}
+
+type chatQueue struct {
+	pending []func()
+}
+
+// enqueue schedules job for chat and returns its queue position: 0 if it
+// starts right away, otherwise how many jobs must start before it.
+func (q *chatQueues) enqueue(chat string, job func()) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queues == nil {
+		q.queues = make(map[string]*chatQueue)
+	}
+	if cq, ok := q.queues[chat]; ok {
+		cq.pending = append(cq.pending, job)
+		return len(cq.pending)
+	}
+	q.queues[chat] = &chatQueue{}
+	go q.run(chat, job)
+	return 0
+}
+
+// run executes job and then each queued job for chat until none remain.
+func (q *chatQueues) run(chat string, job func()) {
+	for job != nil {
+		job()
+
+		q.mu.Lock()
+		cq := q.queues[chat]
+		if len(cq.pending) == 0 {
+			delete(q.queues, chat)
+			job = nil
+		} else {
+			job = cq.pending[0]
+			cq.pending = cq.pending[1:]
+		}
+		q.mu.Unlock()
+	}
+}
+
+// depths returns how many messages each busy chat has in flight, counting
+// the one being processed.
+func (q *chatQueues) depths() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make(map[string]int, len(q.queues))
+	for chat, cq := range q.queues {
+		out[chat] = len(cq.pending) + 1
+	}
+	return out
+}
@@ -0,0 +1,14 @@
+package agent
+
+import "fmt"
+
+// reflectionHint is appended to a failed or empty tool result so the model
+// reconsiders its approach instead of giving up or repeating the same call.
+func reflectionHint(toolName string) string {
+	return fmt.Sprintf(`
+
+[Reflection] The %s call did not succeed. Before continuing:
+1. Check the error and whether the arguments were right (names, paths, formats).
+2. Consider different arguments or a different tool that could get the same result.
+3. If the task can't be done this way, explain why instead of repeating the same call.`, toolName)
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"open-dan/internal/memory"
+)
+
+// chatSettings returns the conversation's overrides of the agent defaults.
+// A failed lookup is logged and treated as no overrides.
+func (a *Agent) chatSettings(ctx context.Context, chatID string) memory.ChatSettings {
+	s, err := a.memory.GetChatSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("[agent] failed to load settings for %s: %v", chatID, err)
+	}
+	return s
+}
+
+// updateChatSettings applies change to the conversation's overrides and
+// saves them if they are valid.
+func (a *Agent) updateChatSettings(ctx context.Context, chatID string, change func(*memory.ChatSettings)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, err := a.memory.GetChatSettings(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	change(&s)
+	return a.memory.SetChatSettings(ctx, chatID, s)
+}
+
+// SetSystemPrompt gives a conversation its own system prompt in place of
+// the configured one. An empty prompt reverts to the default.
+func (a *Agent) SetSystemPrompt(ctx context.Context, chatID, prompt string) error {
+	return a.updateChatSettings(ctx, chatID, func(s *memory.ChatSettings) {
+		s.SystemPrompt = strings.TrimSpace(prompt)
+	})
+}
+
+// SetChatSettings replaces all of a conversation's overrides: system
+// prompt, model, temperature, and max tokens. Zero fields use the defaults.
+func (a *Agent) SetChatSettings(ctx context.Context, chatID string, s memory.ChatSettings) error {
+	s.SystemPrompt = strings.TrimSpace(s.SystemPrompt)
+	s.Model = strings.TrimSpace(s.Model)
+	return a.updateChatSettings(ctx, chatID, func(cur *memory.ChatSettings) { *cur = s })
+}
+
+// basePrompt returns the conversation's own system prompt, or the
+// configured default if it has none.
+func (a *Agent) basePrompt(ctx context.Context, chatID string) string {
+	if p := a.chatSettings(ctx, chatID).SystemPrompt; p != "" {
+		return p
+	}
+	return a.cfg.SystemPrompt
+}
+
+// requestParams returns the model, temperature, and max tokens to use for
+// the conversation: its overrides where set, otherwise the defaults. An
+// empty model means the provider's default.
+func (a *Agent) requestParams(ctx context.Context, chatID string) (model string, temperature float64, maxTokens int) {
+	s := a.chatSettings(ctx, chatID)
+	temperature, maxTokens = a.cfg.Temperature, a.cfg.MaxTokens
+	if s.Temperature != nil {
+		temperature = *s.Temperature
+	}
+	if s.MaxTokens > 0 {
+		maxTokens = s.MaxTokens
+	}
+	return s.Model, temperature, maxTokens
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"open-dan/internal/channel"
+	"open-dan/internal/llm"
+)
+
+const (
+	// editInterval throttles in-place edits to stay under channel rate limits.
+	editInterval = time.Second
+	// typingInterval repeats the typing indicator before it fades; Telegram
+	// shows it for five seconds.
+	typingInterval = 4 * time.Second
+)
+
+// complete runs one LLM turn. With a nil onText it makes a plain Chat call;
+// otherwise it streams, calling onText with the text generated so far.
+func (a *Agent) complete(ctx context.Context, req *llm.ChatRequest, onText func(string)) (*llm.LLMResponse, error) {
+	provider := a.currentProvider()
+	if onText == nil {
+		return provider.Chat(ctx, req)
+	}
+
+	events, err := provider.StreamChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &llm.LLMResponse{}
+	var text strings.Builder
+	for evt := range events {
+		if evt.Error != nil {
+			return nil, evt.Error
+		}
+		if evt.ContentDelta != "" {
+			text.WriteString(evt.ContentDelta)
+			onText(text.String())
+		}
+		if len(evt.ToolCalls) > 0 {
+			resp.ToolCalls = append(resp.ToolCalls, evt.ToolCalls...)
+		}
+		if evt.Usage != nil {
+			resp.Usage = *evt.Usage
+		}
+		if evt.StopReason != "" {
+			resp.StopReason = evt.StopReason
+		}
+		if evt.Metadata != nil {
+			resp.Metadata = *evt.Metadata
+		}
+	}
+	resp.Content = text.String()
+	return resp, nil
+}
+
+// replyStream shows a reply in a streaming channel as it is generated: the
+// first text is sent as a draft, and later text edits that message.
+type replyStream struct {
+	ch       channel.StreamingChannel
+	chatID   string
+	replyTo  string
+	msgID    string
+	lastEdit time.Time
+}
+
+func (s *replyStream) update(ctx context.Context, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if s.msgID == "" {
+		id, err := s.ch.SendDraft(ctx, s.chatID, text)
+		if err != nil {
+			return
+		}
+		s.msgID, s.lastEdit = id, time.Now()
+		return
+	}
+	if time.Since(s.lastEdit) < editInterval {
+		return
+	}
+	if err := s.ch.Edit(ctx, s.chatID, s.msgID, text); err == nil {
+		s.lastEdit = time.Now()
+	}
+}
+
+// finish writes the final reply, editing the draft if one was sent.
+func (s *replyStream) finish(ctx context.Context, text string) error {
+	if s.msgID == "" {
+		return s.ch.Send(ctx, channel.OutboundMessage{ChatID: s.chatID, Text: text, ReplyTo: s.replyTo})
+	}
+	if f, ok := s.ch.(channel.DraftFinisher); ok {
+		return f.FinishDraft(ctx, s.chatID, s.msgID, text)
+	}
+	return s.ch.Edit(ctx, s.chatID, s.msgID, text)
+}
+
+// keepTyping shows the typing indicator in chatID, if ch has one, until the
+// returned stop function is called. stop waits for an indicator in flight so
+// none arrives after the reply.
+func keepTyping(ctx context.Context, ch channel.Channel, chatID string) (stop func()) {
+	tn, ok := ch.(channel.TypingNotifier)
+	if !ok {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+		for {
+			if err := tn.SendTyping(ctx, chatID); err != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,168 @@
+package channel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMaxAttachmentSize matches the Telegram Bot API download limit.
+const defaultMaxAttachmentSize = 20 << 20
+
+// AttachmentPolicy limits the files a channel downloads into the workspace.
+type AttachmentPolicy struct {
+	MaxSize        int64    // bytes per file; 0 uses defaultMaxAttachmentSize
+	AllowedTypes   []string // MIME types, with "image/*" wildcards; empty allows any
+	MaxChatStorage int64    // total bytes kept per chat; 0 is unlimited
+}
+
+// AttachmentError is returned when an attachment is refused. Its message is
+// meant to be shown to the sender.
+type AttachmentError struct {
+	Reason string
+}
+
+func (e *AttachmentError) Error() string { return e.Reason }
+
+func (p AttachmentPolicy) maxSize() int64 {
+	if p.MaxSize <= 0 {
+		return defaultMaxAttachmentSize
+	}
+	return p.MaxSize
+}
+
+// Check rejects an attachment from its declared type and size, before any
+// of it is downloaded. A size of 0 or less means unknown.
+func (p AttachmentPolicy) Check(mimeType string, size int64) error {
+	if size > p.maxSize() {
+		return &AttachmentError{Reason: fmt.Sprintf("File is too large (%s; the limit is %s).", formatBytes(size), formatBytes(p.maxSize()))}
+	}
+	if mimeType != "" && !p.allowsType(mimeType) {
+		return &AttachmentError{Reason: fmt.Sprintf("Files of type %s are not accepted.", mimeType)}
+	}
+	return nil
+}
+
+func (p AttachmentPolicy) allowsType(mimeType string) bool {
+	if len(p.AllowedTypes) == 0 {
+		return true
+	}
+	// Drop parameters such as "; charset=utf-8".
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	for _, allowed := range p.AllowedTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == mimeType || allowed == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Save stores an attachment in dir/<chatID>/ and returns its path. The
+// declared type is checked, or the content is sniffed when none is given;
+// the size is enforced while copying, so a sender can't bypass the limit by
+// misreporting it. Nothing is left on disk when the attachment is refused.
+func (p AttachmentPolicy) Save(dir, chatID, name, mimeType string, r io.Reader) (string, error) {
+	br := bufio.NewReaderSize(r, 512)
+	if mimeType == "" {
+		head, _ := br.Peek(512)
+		mimeType = http.DetectContentType(head)
+	}
+	if err := p.Check(mimeType, 0); err != nil {
+		return "", err
+	}
+
+	chatDir := filepath.Join(dir, safeFileName(chatID))
+	if err := os.MkdirAll(chatDir, 0700); err != nil {
+		return "", fmt.Errorf("create attachment directory: %w", err)
+	}
+	limit := p.maxSize()
+	var used int64
+	if p.MaxChatStorage > 0 {
+		var err error
+		if used, err = dirSize(chatDir); err != nil {
+			return "", fmt.Errorf("measure attachment storage: %w", err)
+		}
+		if used >= p.MaxChatStorage {
+			return "", &AttachmentError{Reason: "Attachment storage for this chat is full."}
+		}
+		limit = min(limit, p.MaxChatStorage-used)
+	}
+
+	tmp, err := os.CreateTemp(chatDir, ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("create attachment file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	n, err := io.Copy(tmp, io.LimitReader(br, limit+1))
+	tmp.Close()
+	if err != nil {
+		return "", fmt.Errorf("save attachment: %w", err)
+	}
+	if n > limit {
+		if limit < p.maxSize() {
+			return "", &AttachmentError{Reason: fmt.Sprintf("Not enough attachment storage left for this chat (%s free).", formatBytes(p.MaxChatStorage-used))}
+		}
+		return "", &AttachmentError{Reason: fmt.Sprintf("File is too large (the limit is %s).", formatBytes(p.maxSize()))}
+	}
+
+	path := filepath.Join(chatDir, safeFileName(name))
+	ext := filepath.Ext(path)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filepath.Join(chatDir, safeFileName(name)), ext), i, ext)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("save attachment: %w", err)
+	}
+	return path, nil
+}
+
+// safeFileName reduces name to a single path element.
+func safeFileName(name string) string {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == "/" || name == "." {
+		return "attachment"
+	}
+	return name
+}
+
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
@@ -0,0 +1,87 @@
+package channel
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentRejectsOversized(t *testing.T) {
+	dir := t.TempDir()
+	p := AttachmentPolicy{MaxSize: 100}
+
+	var ae *AttachmentError
+	if err := p.Check("text/plain", 101); !errors.As(err, &ae) {
+		t.Fatalf("Check: expected AttachmentError, got %v", err)
+	}
+
+	// A file that declares no size is still stopped while copying.
+	_, err := p.Save(dir, "chat1", "big.txt", "text/plain", strings.NewReader(strings.Repeat("x", 101)))
+	if !errors.As(err, &ae) || !strings.Contains(ae.Reason, "too large") {
+		t.Fatalf("Save: expected size rejection, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "chat1")); len(entries) != 0 {
+		t.Fatalf("rejected attachment left files behind: %v", entries)
+	}
+
+	path, err := p.Save(dir, "chat1", "ok.txt", "text/plain", strings.NewReader(strings.Repeat("x", 100)))
+	if err != nil {
+		t.Fatalf("Save within limit: %v", err)
+	}
+	if info, _ := os.Stat(path); info == nil || info.Size() != 100 {
+		t.Fatalf("saved file missing or wrong size: %v", info)
+	}
+}
+
+func TestAttachmentRejectsDisallowedType(t *testing.T) {
+	dir := t.TempDir()
+	p := AttachmentPolicy{AllowedTypes: []string{"image/*", "application/pdf"}}
+
+	var ae *AttachmentError
+	if _, err := p.Save(dir, "chat1", "run.sh", "application/x-sh", strings.NewReader("#!/bin/sh")); !errors.As(err, &ae) {
+		t.Fatalf("expected type rejection, got %v", err)
+	}
+	// Without a declared type the content is sniffed.
+	if _, err := p.Save(dir, "chat1", "notes", "", strings.NewReader("plain text")); !errors.As(err, &ae) {
+		t.Fatalf("expected sniffed text to be rejected, got %v", err)
+	}
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 16)...)
+	if _, err := p.Save(dir, "chat1", "pic.png", "", bytes.NewReader(png)); err != nil {
+		t.Fatalf("image/* should be allowed: %v", err)
+	}
+	if err := p.Check("application/pdf; name=x.pdf", 10); err != nil {
+		t.Fatalf("exact type should be allowed: %v", err)
+	}
+}
+
+func TestAttachmentChatStorageLimit(t *testing.T) {
+	dir := t.TempDir()
+	p := AttachmentPolicy{MaxChatStorage: 150}
+
+	if _, err := p.Save(dir, "chat1", "a.txt", "text/plain", strings.NewReader(strings.Repeat("a", 100))); err != nil {
+		t.Fatal(err)
+	}
+	var ae *AttachmentError
+	if _, err := p.Save(dir, "chat1", "b.txt", "text/plain", strings.NewReader(strings.Repeat("b", 100))); !errors.As(err, &ae) {
+		t.Fatalf("expected storage rejection, got %v", err)
+	}
+	// Other chats have their own quota.
+	if _, err := p.Save(dir, "chat2", "b.txt", "text/plain", strings.NewReader(strings.Repeat("b", 100))); err != nil {
+		t.Fatalf("other chat rejected: %v", err)
+	}
+}
+
+func TestAttachmentNameConfined(t *testing.T) {
+	dir := t.TempDir()
+	path, err := AttachmentPolicy{}.Save(dir, "../chat1", "../../evil.txt", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(filepath.Dir(path)) != dir {
+		t.Fatalf("attachment escaped its directory: %s", path)
+	}
+}
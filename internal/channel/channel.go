@@ -11,10 +11,35 @@ type InboundMessage struct {
 	SenderID    string
 	SenderName  string
 	ChatID      string
-	Text        string
+	// ConversationID keys the conversation history when it differs from
+	// ChatID, e.g. one history per member of a group chat. Replies still go
+	// to ChatID.
+	ConversationID string
+	// MessageID identifies the message, or the thread it belongs to, on
+	// channels that support FeatureThreads. Replies pass it as ReplyTo.
+	MessageID string
+	Text      string
+	// Attachments are files sent with the message, already saved locally.
+	Attachments []Attachment
 	Timestamp   time.Time
 }
 
+// Conversation returns the key the message's conversation history is
+// stored under.
+func (m InboundMessage) Conversation() string {
+	if m.ConversationID != "" {
+		return m.ConversationID
+	}
+	return m.ChatID
+}
+
+// Attachment is a file received with an inbound message.
+type Attachment struct {
+	Path string // where the file was saved
+	MIME string
+	Size int64
+}
+
 // OutboundMessage is a message to send through a channel.
 type OutboundMessage struct {
 	ChatID  string
@@ -30,4 +55,39 @@ type Channel interface {
 	Send(ctx context.Context, msg OutboundMessage) error
 	OnMessage(handler func(InboundMessage))
 	IsRunning() bool
+	// Supports reports whether the channel offers an optional feature.
+	Supports(feature string) bool
+}
+
+// Optional channel features, queried with Channel.Supports.
+const (
+	// FeatureStreaming means replies can be shown while they are generated
+	// by editing a sent message in place. Such channels implement StreamingChannel.
+	FeatureStreaming = "streaming"
+	// FeatureThreads means a reply is posted in the thread of the message
+	// it answers, named by OutboundMessage.ReplyTo.
+	FeatureThreads = "threads"
+)
+
+// StreamingChannel is implemented by channels that support FeatureStreaming.
+type StreamingChannel interface {
+	Channel
+	// SendDraft sends a message that will be updated later and returns its ID.
+	SendDraft(ctx context.Context, chatID, text string) (messageID string, err error)
+	// Edit replaces the text of a message sent with SendDraft.
+	Edit(ctx context.Context, chatID, messageID, text string) error
+}
+
+// DraftFinisher is implemented by streaming channels that need to know when
+// a draft holds the complete reply. The agent calls FinishDraft instead of
+// a last Edit.
+type DraftFinisher interface {
+	FinishDraft(ctx context.Context, chatID, messageID, text string) error
+}
+
+// TypingNotifier is implemented by channels that can show the sender that a
+// reply is being prepared. The indicator fades on its own after a few
+// seconds, so the agent repeats it while it works.
+type TypingNotifier interface {
+	SendTyping(ctx context.Context, chatID string) error
 }
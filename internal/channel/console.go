@@ -61,6 +61,8 @@ func (c *ConsoleChannel) OnMessage(handler func(InboundMessage)) {
 	c.handler = handler
 }
 
+func (c *ConsoleChannel) Supports(string) bool { return false }
+
 func (c *ConsoleChannel) IsRunning() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -0,0 +1,377 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	discordAPIBase    = "https://discord.com/api/v10"
+	discordGatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+	discordMaxMessage = 2000
+	// Guild messages, direct messages, and message content.
+	discordIntents   = 1<<9 | 1<<12 | 1<<15
+	discordReconnect = 5 * time.Second
+)
+
+// DiscordChannel integrates with Discord as a bot. Direct messages always
+// reach the agent; in server channels only messages that mention the bot do.
+type DiscordChannel struct {
+	mu           sync.Mutex
+	token        string
+	allowedUsers map[string]bool
+	allowedRoles map[string]bool
+	handler      func(InboundMessage)
+	running      bool
+	botID        string
+	cancel       context.CancelFunc
+
+	client     *http.Client
+	apiBase    string // overridable in tests
+	gatewayURL string
+}
+
+// DiscordConfig holds Discord-specific configuration.
+type DiscordConfig struct {
+	Token          string
+	AllowedUserIDs []string
+	AllowedRoleIDs []string
+}
+
+// NewDiscordChannel creates a new Discord channel.
+func NewDiscordChannel(cfg DiscordConfig) *DiscordChannel {
+	return &DiscordChannel{
+		token:        cfg.Token,
+		allowedUsers: stringSet(cfg.AllowedUserIDs),
+		allowedRoles: stringSet(cfg.AllowedRoleIDs),
+		client:       &http.Client{Timeout: 30 * time.Second},
+		apiBase:      discordAPIBase,
+		gatewayURL:   discordGatewayURL,
+	}
+}
+
+func stringSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, s := range items {
+		set[s] = true
+	}
+	return set
+}
+
+func (d *DiscordChannel) Name() string { return "discord" }
+
+// Start checks the token, then connects to the gateway in the background,
+// reconnecting if the connection drops.
+func (d *DiscordChannel) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.running {
+		return nil
+	}
+
+	var me struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := d.api(ctx, "GET", "/users/@me", nil, &me); err != nil {
+		return fmt.Errorf("discord bot init: %w", err)
+	}
+	d.botID = me.ID
+
+	ctx, cancel := context.WithCancel(ctx)
+	d.cancel = cancel
+	d.running = true
+
+	go func() {
+		for {
+			if err := d.runGateway(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[discord] gateway error: %v; reconnecting", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(discordReconnect):
+			}
+		}
+	}()
+
+	log.Printf("[discord] connected as %s", me.Username)
+	return nil
+}
+
+func (d *DiscordChannel) Stop(_ context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.running = false
+	return nil
+}
+
+type discordPayload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d,omitempty"`
+	Sequence *int            `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+// runGateway holds one gateway session until it fails or ctx ends.
+func (d *DiscordChannel) runGateway(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, d.gatewayURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var writeMu sync.Mutex
+	send := func(op int, data any) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(map[string]any{"op": op, "d": data})
+	}
+
+	var seqMu sync.Mutex
+	var seq *int
+	for {
+		var p discordPayload
+		if err := conn.ReadJSON(&p); err != nil {
+			return err
+		}
+		if p.Sequence != nil {
+			seqMu.Lock()
+			seq = p.Sequence
+			seqMu.Unlock()
+		}
+
+		switch p.Op {
+		case 10: // Hello: start heartbeating and identify
+			var hello struct {
+				Interval int `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(p.Data, &hello)
+			go func() {
+				ticker := time.NewTicker(time.Duration(hello.Interval) * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						seqMu.Lock()
+						s := seq
+						seqMu.Unlock()
+						if err := send(1, s); err != nil {
+							return
+						}
+					}
+				}
+			}()
+			if err := send(2, map[string]any{
+				"token":   d.token,
+				"intents": discordIntents,
+				"properties": map[string]string{
+					"os": "linux", "browser": "opendan", "device": "opendan",
+				},
+			}); err != nil {
+				return err
+			}
+		case 0: // Dispatch
+			if p.Type == "MESSAGE_CREATE" {
+				var m discordMessage
+				if err := json.Unmarshal(p.Data, &m); err == nil {
+					d.dispatch(m)
+				}
+			}
+		case 7, 9: // Reconnect, Invalid Session
+			return fmt.Errorf("gateway asked to reconnect (op %d)", p.Op)
+		}
+	}
+}
+
+type discordMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID         string `json:"id"`
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Bot        bool   `json:"bot"`
+	} `json:"author"`
+	Member *struct {
+		Roles []string `json:"roles"`
+	} `json:"member"`
+	Mentions []struct {
+		ID string `json:"id"`
+	} `json:"mentions"`
+}
+
+// dispatch passes m to the handler if it is addressed to the bot and the
+// author is allowed.
+func (d *DiscordChannel) dispatch(m discordMessage) {
+	text, ok := d.accept(m)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	handler := d.handler
+	d.mu.Unlock()
+
+	name := m.Author.GlobalName
+	if name == "" {
+		name = m.Author.Username
+	}
+	if handler != nil {
+		handler(InboundMessage{
+			ChannelName: "discord",
+			SenderID:    m.Author.ID,
+			SenderName:  name,
+			ChatID:      m.ChannelID,
+			Text:        text,
+			Timestamp:   time.Now(),
+		})
+	}
+}
+
+// accept returns the text of m with the bot's mention removed, or false if
+// the message should be ignored.
+func (d *DiscordChannel) accept(m discordMessage) (string, bool) {
+	if m.Author.Bot || m.Author.ID == d.botID {
+		return "", false
+	}
+
+	// In servers, only messages that mention the bot are for us.
+	if m.GuildID != "" {
+		mentioned := false
+		for _, u := range m.Mentions {
+			if u.ID == d.botID {
+				mentioned = true
+				break
+			}
+		}
+		if !mentioned {
+			return "", false
+		}
+	}
+
+	// Authorization check
+	if len(d.allowedUsers) > 0 || len(d.allowedRoles) > 0 {
+		allowed := d.allowedUsers[m.Author.ID]
+		if !allowed && m.Member != nil {
+			for _, r := range m.Member.Roles {
+				if d.allowedRoles[r] {
+					allowed = true
+					break
+				}
+			}
+		}
+		if !allowed {
+			log.Printf("[discord] unauthorized user: %s (%s)", m.Author.ID, m.Author.Username)
+			return "", false
+		}
+	}
+
+	text := strings.NewReplacer("<@"+d.botID+">", "", "<@!"+d.botID+">", "").Replace(m.Content)
+	text = strings.TrimSpace(text)
+	return text, text != ""
+}
+
+func (d *DiscordChannel) Send(ctx context.Context, msg OutboundMessage) error {
+	if !d.IsRunning() {
+		return fmt.Errorf("discord bot not started")
+	}
+
+	// Split long messages (Discord limit is 2000 characters)
+	for _, chunk := range splitRunes(msg.Text, discordMaxMessage) {
+		if err := d.api(ctx, "POST", "/channels/"+msg.ChatID+"/messages", map[string]any{"content": chunk}, nil); err != nil {
+			return fmt.Errorf("discord send: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitRunes splits text into chunks of at most limit characters.
+func splitRunes(text string, limit int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		chunks = append(chunks, string(runes[:limit]))
+		runes = runes[limit:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+func (d *DiscordChannel) OnMessage(handler func(InboundMessage)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handler = handler
+}
+
+func (d *DiscordChannel) IsRunning() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.running
+}
+
+func (d *DiscordChannel) Supports(string) bool { return false }
+
+// api calls the Discord REST API, decoding the JSON response into out if
+// it is non-nil.
+func (d *DiscordChannel) api(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.apiBase+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+d.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return Permanent(err) // e.g. unknown channel or missing permissions
+		}
+		return err
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeDiscord serves the REST endpoints the channel uses and a gateway that
+// says hello and then sends events.
+type fakeDiscord struct {
+	*httptest.Server
+	events chan string
+
+	mu       sync.Mutex
+	sent     []string
+	identify map[string]any
+}
+
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+	f := &fakeDiscord{events: make(chan string, 8)}
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/@me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot good-token" {
+			http.Error(w, `{"message": "401: Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id": "42", "username": "dan"}`))
+	})
+	mux.HandleFunc("POST /channels/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		f.sent = append(f.sent, r.PathValue("id")+":"+body.Content)
+		f.mu.Unlock()
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/gateway", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteJSON(map[string]any{"op": 10, "d": map[string]any{"heartbeat_interval": 45000}})
+		var ident struct {
+			Op   int            `json:"op"`
+			Data map[string]any `json:"d"`
+		}
+		if err := conn.ReadJSON(&ident); err != nil || ident.Op != 2 {
+			return
+		}
+		f.mu.Lock()
+		f.identify = ident.Data
+		f.mu.Unlock()
+		for evt := range f.events {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"op":0,"s":1,"t":"MESSAGE_CREATE","d":`+evt+`}`))
+		}
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeDiscord) channel(cfg DiscordConfig) *DiscordChannel {
+	d := NewDiscordChannel(cfg)
+	d.apiBase = f.URL
+	d.gatewayURL = "ws" + strings.TrimPrefix(f.URL, "http") + "/gateway"
+	return d
+}
+
+func TestDiscordRoutesMentionsAndDMs(t *testing.T) {
+	f := newFakeDiscord(t)
+	d := f.channel(DiscordConfig{Token: "good-token", AllowedUserIDs: []string{"7"}, AllowedRoleIDs: []string{"mods"}})
+
+	got := make(chan InboundMessage, 8)
+	d.OnMessage(func(m InboundMessage) { got <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop(ctx)
+
+	for _, evt := range []string{
+		// Server message without a mention: ignored.
+		`{"channel_id":"c1","guild_id":"g","content":"hello all","author":{"id":"7"}}`,
+		// Mention from an allowed user.
+		`{"channel_id":"c1","guild_id":"g","content":"<@42> what's up?","author":{"id":"7","username":"ann"},"mentions":[{"id":"42"}]}`,
+		// Mention from a user outside the allowlist but with an allowed role.
+		`{"channel_id":"c1","guild_id":"g","content":"<@!42> hi","author":{"id":"8"},"member":{"roles":["mods"]},"mentions":[{"id":"42"}]}`,
+		// Mention from someone not allowed at all.
+		`{"channel_id":"c1","guild_id":"g","content":"<@42> hi","author":{"id":"9"},"mentions":[{"id":"42"}]}`,
+		// Another bot: ignored.
+		`{"channel_id":"dm","content":"beep","author":{"id":"7","bot":true}}`,
+		// Direct message from an allowed user.
+		`{"channel_id":"dm","content":"private question","author":{"id":"7","global_name":"Ann"}}`,
+	} {
+		f.events <- evt
+	}
+	close(f.events)
+
+	var msgs []InboundMessage
+	for len(msgs) < 3 {
+		select {
+		case m := <-got:
+			msgs = append(msgs, m)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %d messages: %+v", len(msgs), msgs)
+		}
+	}
+	want := []string{"c1|what's up?", "c1|hi", "dm|private question"}
+	for i, m := range msgs {
+		if m.ChatID+"|"+m.Text != want[i] || m.ChannelName != "discord" {
+			t.Errorf("message %d: got %+v, want %s", i, m, want[i])
+		}
+	}
+	select {
+	case m := <-got:
+		t.Fatalf("unexpected extra message: %+v", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.identify["token"] != "good-token" {
+		t.Fatalf("identify did not carry the token: %v", f.identify)
+	}
+}
+
+func TestDiscordSendSplitsLongMessages(t *testing.T) {
+	f := newFakeDiscord(t)
+	close(f.events)
+
+	if err := f.channel(DiscordConfig{Token: "bad"}).Start(context.Background()); err == nil {
+		t.Fatal("expected an invalid token to fail")
+	}
+
+	d := f.channel(DiscordConfig{Token: "good-token"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop(ctx)
+
+	text := strings.Repeat("é", discordMaxMessage+10)
+	if err := d.Send(ctx, OutboundMessage{ChatID: "c1", Text: text}); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(f.sent))
+	}
+	if n := utf8.RuneCountInString(strings.TrimPrefix(f.sent[0], "c1:")); n != discordMaxMessage {
+		t.Fatalf("first chunk has %d characters", n)
+	}
+}
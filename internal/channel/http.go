@@ -0,0 +1,305 @@
+package channel
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHTTPReplyTimeout = 5 * time.Minute
+	maxHTTPRequestBody      = 1 << 20
+)
+
+// HTTPChannel lets other applications talk to the agent over HTTP. Each
+// POST /message waits for the agent's reply and returns it as JSON, or
+// streams it as server-sent events when the client asks for them.
+type HTTPChannel struct {
+	mu           sync.Mutex
+	addr         string
+	token        string
+	replyTimeout time.Duration
+	server       *http.Server
+	handler      func(InboundMessage)
+	running      bool
+	// waiters holds the request waiting for a reply, per chat. Only one
+	// request per chat is accepted at a time so replies can't cross.
+	waiters map[string]*httpWaiter
+	drafts  int
+}
+
+// HTTPConfig holds HTTP channel configuration.
+type HTTPConfig struct {
+	Addr         string // listen address, e.g. "127.0.0.1:8765"
+	Token        string // bearer token required on every request
+	ReplyTimeout time.Duration
+}
+
+// httpWaiter receives a reply's text as it is produced on drafts, and the
+// final text once on reply.
+type httpWaiter struct {
+	drafts chan string
+	reply  chan string
+}
+
+// NewHTTPChannel creates a new HTTP channel.
+func NewHTTPChannel(cfg HTTPConfig) *HTTPChannel {
+	if cfg.ReplyTimeout <= 0 {
+		cfg.ReplyTimeout = defaultHTTPReplyTimeout
+	}
+	return &HTTPChannel{
+		addr:         cfg.Addr,
+		token:        cfg.Token,
+		replyTimeout: cfg.ReplyTimeout,
+		waiters:      make(map[string]*httpWaiter),
+	}
+}
+
+func (h *HTTPChannel) Name() string { return "http" }
+
+func (h *HTTPChannel) Start(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.running {
+		return nil
+	}
+	if h.token == "" {
+		return fmt.Errorf("http channel requires a token")
+	}
+
+	ln, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return fmt.Errorf("http listen: %w", err)
+	}
+	srv := &http.Server{Handler: h.routes(), ReadHeaderTimeout: 10 * time.Second}
+	h.server = srv
+	h.running = true
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[http] server error: %v", err)
+		}
+	}()
+
+	// Stop server when context is cancelled
+	go func() {
+		<-ctx.Done()
+		h.Stop(context.Background())
+	}()
+
+	log.Printf("[http] listening on %s", ln.Addr())
+	return nil
+}
+
+func (h *HTTPChannel) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	srv := h.server
+	h.server = nil
+	h.running = false
+	h.mu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// Send delivers the final reply to the request waiting on msg.ChatID.
+func (h *HTTPChannel) Send(_ context.Context, msg OutboundMessage) error {
+	return h.deliver(msg.ChatID, msg.Text, true)
+}
+
+func (h *HTTPChannel) OnMessage(handler func(InboundMessage)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handler = handler
+}
+
+func (h *HTTPChannel) IsRunning() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.running
+}
+
+func (h *HTTPChannel) Supports(feature string) bool {
+	return feature == FeatureStreaming
+}
+
+func (h *HTTPChannel) SendDraft(_ context.Context, chatID, text string) (string, error) {
+	if err := h.deliver(chatID, text, false); err != nil {
+		return "", err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drafts++
+	return strconv.Itoa(h.drafts), nil
+}
+
+func (h *HTTPChannel) Edit(_ context.Context, chatID, _, text string) error {
+	return h.deliver(chatID, text, false)
+}
+
+func (h *HTTPChannel) FinishDraft(_ context.Context, chatID, _, text string) error {
+	return h.deliver(chatID, text, true)
+}
+
+// deliver passes text to the request waiting on chatID. A final reply
+// completes the request.
+func (h *HTTPChannel) deliver(chatID, text string, final bool) error {
+	h.mu.Lock()
+	w, ok := h.waiters[chatID]
+	if ok && final {
+		delete(h.waiters, chatID)
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		return Permanent(fmt.Errorf("no request is waiting for chat %s", chatID))
+	}
+	if final {
+		w.reply <- text // buffered, and only the first final reply gets here
+		return nil
+	}
+	// Drafts are best effort: a slow client skips intermediate text.
+	select {
+	case w.drafts <- text:
+	default:
+	}
+	return nil
+}
+
+func (h *HTTPChannel) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /message", h.handleMessage)
+	return mux
+}
+
+type httpMessageRequest struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type httpMessageReply struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func (h *HTTPChannel) authorized(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && h.token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) == 1
+}
+
+func (h *HTTPChannel) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		httpError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+
+	var req httpMessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHTTPRequestBody)).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		httpError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	if req.ChatID == "" {
+		req.ChatID = "default"
+	}
+
+	waiter := &httpWaiter{drafts: make(chan string, 16), reply: make(chan string, 1)}
+	h.mu.Lock()
+	handler := h.handler
+	_, busy := h.waiters[req.ChatID]
+	if !busy && handler != nil {
+		h.waiters[req.ChatID] = waiter
+	}
+	h.mu.Unlock()
+
+	switch {
+	case handler == nil:
+		httpError(w, http.StatusServiceUnavailable, "agent is not ready")
+		return
+	case busy:
+		httpError(w, http.StatusConflict, "a message for this chat is still being processed")
+		return
+	}
+	defer func() {
+		h.mu.Lock()
+		if h.waiters[req.ChatID] == waiter {
+			delete(h.waiters, req.ChatID)
+		}
+		h.mu.Unlock()
+	}()
+
+	handler(InboundMessage{
+		ChannelName: "http",
+		SenderID:    "http",
+		SenderName:  "HTTP client",
+		ChatID:      req.ChatID,
+		Text:        req.Text,
+		Timestamp:   time.Now(),
+	})
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.replyTimeout)
+	defer cancel()
+
+	flusher, canFlush := w.(http.Flusher)
+	stream := canFlush && (r.URL.Query().Get("stream") == "true" || strings.Contains(r.Header.Get("Accept"), "text/event-stream"))
+	if stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if stream {
+				writeEvent(w, "error", map[string]string{"error": "timed out waiting for a reply"})
+				flusher.Flush()
+			} else {
+				httpError(w, http.StatusGatewayTimeout, "timed out waiting for a reply")
+			}
+			return
+		case text := <-waiter.drafts:
+			if stream {
+				writeEvent(w, "draft", httpMessageReply{ChatID: req.ChatID, Text: text})
+				flusher.Flush()
+			}
+		case text := <-waiter.reply:
+			reply := httpMessageReply{ChatID: req.ChatID, Text: text}
+			if stream {
+				writeEvent(w, "reply", reply)
+				flusher.Flush()
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(reply)
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data any) {
+	b, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+}
+
+func httpError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
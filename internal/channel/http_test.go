@@ -0,0 +1,130 @@
+package channel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postMessage(t *testing.T, srv *httptest.Server, token, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest("POST", srv.URL+"/message", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestHTTPChannelReplies(t *testing.T) {
+	h := NewHTTPChannel(HTTPConfig{Token: "secret", ReplyTimeout: time.Second})
+	srv := httptest.NewServer(h.routes())
+	defer srv.Close()
+
+	var got InboundMessage
+	h.OnMessage(func(msg InboundMessage) {
+		got = msg
+		go h.Send(context.Background(), OutboundMessage{ChatID: msg.ChatID, Text: "echo: " + msg.Text})
+	})
+
+	for _, token := range []string{"", "wrong"} {
+		resp := postMessage(t, srv, token, `{"chat_id":"c1","text":"hi"}`, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("token %q: expected 401, got %d", token, resp.StatusCode)
+		}
+	}
+
+	resp := postMessage(t, srv, "secret", `{"chat_id":"c1","text":"hi"}`, nil)
+	defer resp.Body.Close()
+	var reply httpMessageReply
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || reply.ChatID != "c1" || reply.Text != "echo: hi" {
+		t.Fatalf("unexpected reply %d %+v", resp.StatusCode, reply)
+	}
+	if got.ChannelName != "http" || got.ChatID != "c1" {
+		t.Fatalf("unexpected inbound message: %+v", got)
+	}
+
+	resp = postMessage(t, srv, "secret", `{"chat_id":"c1"}`, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without text, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPChannelRejectsConcurrentRequestsPerChat(t *testing.T) {
+	h := NewHTTPChannel(HTTPConfig{Token: "secret", ReplyTimeout: 200 * time.Millisecond})
+	srv := httptest.NewServer(h.routes())
+	defer srv.Close()
+
+	started := make(chan struct{}, 1)
+	h.OnMessage(func(InboundMessage) { started <- struct{}{} }) // never replies
+
+	done := make(chan int)
+	go func() {
+		resp := postMessage(t, srv, "secret", `{"chat_id":"c1","text":"slow"}`, nil)
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	<-started
+
+	resp := postMessage(t, srv, "secret", `{"chat_id":"c1","text":"again"}`, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 while the chat is busy, got %d", resp.StatusCode)
+	}
+	if code := <-done; code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504 after the reply timeout, got %d", code)
+	}
+}
+
+func TestHTTPChannelStreamsDrafts(t *testing.T) {
+	h := NewHTTPChannel(HTTPConfig{Token: "secret", ReplyTimeout: time.Second})
+	srv := httptest.NewServer(h.routes())
+	defer srv.Close()
+
+	h.OnMessage(func(msg InboundMessage) {
+		go func() {
+			ctx := context.Background()
+			id, _ := h.SendDraft(ctx, msg.ChatID, "Hel")
+			time.Sleep(10 * time.Millisecond)
+			h.Edit(ctx, msg.ChatID, id, "Hello")
+			time.Sleep(10 * time.Millisecond)
+			h.FinishDraft(ctx, msg.ChatID, id, "Hello there")
+		}()
+	})
+
+	resp := postMessage(t, srv, "secret", `{"chat_id":"s","text":"hi"}`, map[string]string{"Accept": "text/event-stream"})
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	var events []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if line, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			events = append(events, line)
+		}
+		if strings.Contains(sc.Text(), `"Hello there"`) {
+			events = append(events, "final text")
+		}
+	}
+	if strings.Join(events, ",") != "draft,draft,reply,final text" {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
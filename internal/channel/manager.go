@@ -2,29 +2,95 @@ package channel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 )
 
+// defaultBroadcastInterval spaces out broadcast messages on one channel,
+// keeping well under Telegram's limit of about 30 messages per second.
+const defaultBroadcastInterval = 50 * time.Millisecond
+
 // Manager manages the lifecycle of all channels.
 type Manager struct {
 	mu       sync.RWMutex
 	channels map[string]Channel
+	chats    map[string]map[string]bool // channel name → chat IDs seen
+	handler  func(Channel, InboundMessage)
+
+	broadcastInterval time.Duration
+
+	// Send retries and the outbox of messages awaiting redelivery.
+	sendRetries    int
+	retryDelay     time.Duration
+	outboxInterval time.Duration
+	outbox         map[string][]pendingMessage
+	flushing       bool
 }
 
 // NewManager creates a new channel manager.
 func NewManager() *Manager {
 	return &Manager{
-		channels: make(map[string]Channel),
+		channels:          make(map[string]Channel),
+		chats:             make(map[string]map[string]bool),
+		broadcastInterval: defaultBroadcastInterval,
+		sendRetries:       defaultSendRetries,
+		retryDelay:        defaultRetryDelay,
+		outboxInterval:    defaultOutboxInterval,
+		outbox:            make(map[string][]pendingMessage),
 	}
 }
 
-// Register adds a channel to the manager.
+// Register adds a channel to the manager, replacing any channel with the
+// same name. If a handler is set, the channel's messages are routed to it.
 func (m *Manager) Register(ch Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.channels[ch.Name()] = ch
+	if m.handler != nil {
+		m.wire(ch)
+	}
+}
+
+// Remove stops the named channel if it is running and unregisters it.
+// Messages held for it stay in the outbox and go to a channel registered
+// later under the same name.
+func (m *Manager) Remove(ctx context.Context, name string) error {
+	m.mu.Lock()
+	ch, ok := m.channels[name]
+	delete(m.channels, name)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("channel %s not found", name)
+	}
+
+	if ch.IsRunning() {
+		if err := ch.Stop(ctx); err != nil {
+			return fmt.Errorf("stop %s: %w", name, err)
+		}
+		log.Printf("[channel] stopped %s", name)
+	}
+	return nil
+}
+
+// SetHandler routes inbound messages from every channel to handler,
+// including channels registered later. Calling it again rewires them all.
+func (m *Manager) SetHandler(handler func(Channel, InboundMessage)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = handler
+	for _, ch := range m.channels {
+		m.wire(ch)
+	}
+}
+
+// wire points ch's messages at the current handler. m.mu must be held.
+func (m *Manager) wire(ch Channel) {
+	handler := m.handler
+	ch.OnMessage(func(msg InboundMessage) { handler(ch, msg) })
 }
 
 // StartAll starts all registered channels.
@@ -76,3 +142,89 @@ func (m *Manager) List() map[string]bool {
 	}
 	return result
 }
+
+// TrackChat records that chatID on channelName has sent a message, making
+// it a target for Broadcast.
+func (m *Manager) TrackChat(channelName, chatID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chats[channelName] == nil {
+		m.chats[channelName] = make(map[string]bool)
+	}
+	m.chats[channelName][chatID] = true
+}
+
+// Broadcast sends text to every known chat on every running channel and
+// returns the number of messages sent.
+func (m *Manager) Broadcast(ctx context.Context, text string) (int, error) {
+	return m.BroadcastChannel(ctx, "", text)
+}
+
+// BroadcastChannel sends text to every known chat on channelName, or on all
+// running channels if channelName is empty. Sends on a channel are spaced
+// out to respect platform rate limits; failures don't stop the broadcast.
+func (m *Manager) BroadcastChannel(ctx context.Context, channelName, text string) (int, error) {
+	type target struct {
+		ch    Channel
+		chats []string
+	}
+	m.mu.RLock()
+	var targets []target
+	for name, ch := range m.channels {
+		if (channelName != "" && name != channelName) || !ch.IsRunning() {
+			continue
+		}
+		var chats []string
+		for id := range m.chats[name] {
+			chats = append(chats, id)
+		}
+		sort.Strings(chats)
+		targets = append(targets, target{ch, chats})
+	}
+	interval := m.broadcastInterval
+	m.mu.RUnlock()
+
+	if channelName != "" && len(targets) == 0 {
+		return 0, fmt.Errorf("channel %s not found or not running", channelName)
+	}
+
+	// Channels have independent limits, so each is throttled on its own.
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sent int
+		errs []error
+	)
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, chatID := range t.chats {
+				if i > 0 {
+					select {
+					case <-ctx.Done():
+						mu.Lock()
+						errs = append(errs, ctx.Err())
+						mu.Unlock()
+						return
+					case <-time.After(interval):
+					}
+				}
+				err := t.ch.Send(ctx, OutboundMessage{ChatID: chatID, Text: text})
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s/%s: %w", t.ch.Name(), chatID, err))
+				} else {
+					sent++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		log.Printf("[channel] broadcast sent %d messages, %d failed", sent, len(errs))
+	}
+	return sent, errors.Join(errs...)
+}
@@ -0,0 +1,220 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingChannel records sent messages with their send times.
+type recordingChannel struct {
+	name    string
+	running bool
+	failFor string
+	handler func(InboundMessage)
+
+	mu   sync.Mutex
+	sent []OutboundMessage
+	at   []time.Time
+}
+
+func (c *recordingChannel) Name() string                     { return c.name }
+func (c *recordingChannel) Start(context.Context) error      { c.running = true; return nil }
+func (c *recordingChannel) Stop(context.Context) error       { c.running = false; return nil }
+func (c *recordingChannel) OnMessage(h func(InboundMessage)) { c.handler = h }
+func (c *recordingChannel) IsRunning() bool                  { return c.running }
+func (c *recordingChannel) Supports(string) bool             { return false }
+
+func (c *recordingChannel) Send(_ context.Context, msg OutboundMessage) error {
+	if msg.ChatID == c.failFor {
+		return errors.New("blocked by user")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	c.at = append(c.at, time.Now())
+	return nil
+}
+
+func TestBroadcastFansOutWithThrottling(t *testing.T) {
+	tg := &recordingChannel{name: "telegram", running: true}
+	other := &recordingChannel{name: "other", running: true}
+	stopped := &recordingChannel{name: "stopped"}
+
+	m := NewManager()
+	m.broadcastInterval = 20 * time.Millisecond
+	for _, ch := range []*recordingChannel{tg, other, stopped} {
+		m.Register(ch)
+	}
+	for _, id := range []string{"1", "2", "3", "2"} {
+		m.TrackChat("telegram", id)
+	}
+	m.TrackChat("other", "a")
+	m.TrackChat("stopped", "x")
+
+	sent, err := m.Broadcast(context.Background(), "maintenance tonight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 4 || len(tg.sent) != 3 || len(other.sent) != 1 || len(stopped.sent) != 0 {
+		t.Fatalf("unexpected fan-out: sent=%d telegram=%v other=%v stopped=%v", sent, tg.sent, other.sent, stopped.sent)
+	}
+	for i, msg := range tg.sent {
+		if msg.Text != "maintenance tonight" {
+			t.Fatalf("unexpected text %q", msg.Text)
+		}
+		if i > 0 && tg.at[i].Sub(tg.at[i-1]) < m.broadcastInterval {
+			t.Fatalf("messages %d and %d sent %v apart, want at least %v", i-1, i, tg.at[i].Sub(tg.at[i-1]), m.broadcastInterval)
+		}
+	}
+}
+
+func TestBroadcastChannelContinuesAfterFailure(t *testing.T) {
+	tg := &recordingChannel{name: "telegram", running: true, failFor: "1"}
+	other := &recordingChannel{name: "other", running: true}
+	m := NewManager()
+	m.broadcastInterval = time.Millisecond
+	m.Register(tg)
+	m.Register(other)
+	m.TrackChat("telegram", "1")
+	m.TrackChat("telegram", "2")
+	m.TrackChat("other", "a")
+
+	sent, err := m.BroadcastChannel(context.Background(), "telegram", "hi")
+	if err == nil || sent != 1 || len(tg.sent) != 1 || tg.sent[0].ChatID != "2" {
+		t.Fatalf("expected one failure and one delivery, got sent=%d err=%v", sent, err)
+	}
+	if len(other.sent) != 0 {
+		t.Fatal("broadcast to one channel reached another")
+	}
+
+	if _, err := m.BroadcastChannel(context.Background(), "missing", "hi"); err == nil {
+		t.Fatal("expected error for unknown channel")
+	}
+}
+
+// flakyChannel fails sends with err until failures runs out.
+type flakyChannel struct {
+	recordingChannel
+	failures int
+	err      error
+	attempts int
+}
+
+func (c *flakyChannel) Send(ctx context.Context, msg OutboundMessage) error {
+	c.mu.Lock()
+	c.attempts++
+	if c.failures > 0 {
+		c.failures--
+		c.mu.Unlock()
+		return c.err
+	}
+	c.mu.Unlock()
+	return c.recordingChannel.Send(ctx, msg)
+}
+
+func newRetryManager(ch Channel) *Manager {
+	m := NewManager()
+	m.retryDelay = time.Millisecond
+	m.outboxInterval = 5 * time.Millisecond
+	m.Register(ch)
+	return m
+}
+
+func TestSendRetriesTransientFailures(t *testing.T) {
+	ch := &flakyChannel{recordingChannel: recordingChannel{name: "flaky", running: true}, failures: 2, err: errors.New("502 Bad Gateway")}
+	m := newRetryManager(ch)
+
+	if err := m.Send(context.Background(), "flaky", OutboundMessage{ChatID: "1", Text: "hi"}); err != nil {
+		t.Fatalf("expected delivery after retries, got %v", err)
+	}
+	if ch.attempts != 3 || len(ch.sent) != 1 {
+		t.Fatalf("expected 3 attempts and 1 delivery, got %d and %d", ch.attempts, len(ch.sent))
+	}
+}
+
+func TestSendFailsFastOnPermanentError(t *testing.T) {
+	ch := &flakyChannel{recordingChannel: recordingChannel{name: "flaky", running: true}, failures: 5, err: Permanent(errors.New("bot was blocked by the user"))}
+	m := newRetryManager(ch)
+
+	err := m.Send(context.Background(), "flaky", OutboundMessage{ChatID: "1", Text: "hi"})
+	if !IsPermanent(err) || errors.Is(err, ErrQueued) {
+		t.Fatalf("expected the permanent error, got %v", err)
+	}
+	if ch.attempts != 1 {
+		t.Fatalf("permanent errors should not be retried, got %d attempts", ch.attempts)
+	}
+}
+
+func TestSendQueuesDuringOutage(t *testing.T) {
+	ch := &flakyChannel{recordingChannel: recordingChannel{name: "flaky", running: true}, failures: 1000, err: errors.New("connection reset")}
+	m := newRetryManager(ch)
+	m.SetSendRetries(1)
+
+	for _, text := range []string{"first", "second"} {
+		if err := m.Send(context.Background(), "flaky", OutboundMessage{ChatID: "1", Text: text}); !errors.Is(err, ErrQueued) {
+			t.Fatalf("expected ErrQueued, got %v", err)
+		}
+	}
+
+	// The channel recovers; held messages go out in order.
+	ch.mu.Lock()
+	ch.failures = 0
+	ch.mu.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ch.mu.Lock()
+		n := len(ch.sent)
+		ch.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("held messages were not delivered, sent %d", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.sent[0].Text != "first" || ch.sent[1].Text != "second" {
+		t.Fatalf("messages delivered out of order: %+v", ch.sent)
+	}
+}
+
+func TestHandlerReachesChannelsRegisteredLater(t *testing.T) {
+	m := NewManager()
+	early := &recordingChannel{name: "early"}
+	m.Register(early)
+
+	var got []string
+	m.SetHandler(func(ch Channel, msg InboundMessage) { got = append(got, ch.Name()+":"+msg.Text) })
+	late := &recordingChannel{name: "late"}
+	m.Register(late)
+
+	early.handler(InboundMessage{Text: "a"})
+	late.handler(InboundMessage{Text: "b"})
+	if len(got) != 2 || got[0] != "early:a" || got[1] != "late:b" {
+		t.Fatalf("unexpected routing: %v", got)
+	}
+}
+
+func TestRemoveStopsChannel(t *testing.T) {
+	m := NewManager()
+	ch := &recordingChannel{name: "telegram", running: true}
+	m.Register(ch)
+
+	if err := m.Remove(context.Background(), "telegram"); err != nil {
+		t.Fatal(err)
+	}
+	if ch.running {
+		t.Fatal("removed channel is still running")
+	}
+	if _, ok := m.Get("telegram"); ok {
+		t.Fatal("removed channel is still registered")
+	}
+	if err := m.Remove(context.Background(), "telegram"); err == nil {
+		t.Fatal("expected an error removing an unknown channel")
+	}
+}
@@ -0,0 +1,185 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultSendRetries = 3
+	defaultRetryDelay  = time.Second
+	// outboxSize caps the replies held per channel during an outage; the
+	// oldest is dropped when it is full.
+	outboxSize = 50
+	// outboxMaxAge drops held replies that are too stale to be useful.
+	outboxMaxAge = 15 * time.Minute
+	// defaultOutboxInterval is how often held replies are retried.
+	defaultOutboxInterval = 30 * time.Second
+)
+
+// ErrQueued is returned by Manager.Send when a message could not be
+// delivered yet and is held for another attempt.
+var ErrQueued = errors.New("delivery failed; message queued for retry")
+
+// PermanentError marks a send failure that retrying won't fix, such as an
+// unknown chat or a bot the user has blocked.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent wraps err so Manager.Send fails fast instead of retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+type pendingMessage struct {
+	msg      OutboundMessage
+	queuedAt time.Time
+}
+
+// SetSendRetries sets how many times Send retries a transient failure
+// before holding the message in the outbox.
+func (m *Manager) SetSendRetries(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendRetries = max(n, 0)
+}
+
+// Send delivers msg through the named channel, retrying transient failures
+// with exponential backoff. Permanent failures are returned at once. If the
+// channel is still failing after the retries, the message is held in a
+// small outbox and retried in the background, and ErrQueued is returned.
+func (m *Manager) Send(ctx context.Context, channelName string, msg OutboundMessage) error {
+	m.mu.RLock()
+	ch, ok := m.channels[channelName]
+	retries, delay := m.sendRetries, m.retryDelay
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("channel %s not found", channelName)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = ch.Send(ctx, msg); err == nil || IsPermanent(err) {
+			return err
+		}
+		if attempt >= retries {
+			break
+		}
+		log.Printf("[channel] send via %s failed (attempt %d/%d), retrying in %s: %v", channelName, attempt+1, retries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	m.hold(channelName, msg)
+	return fmt.Errorf("%w: %v", ErrQueued, err)
+}
+
+// hold adds msg to the channel's outbox and starts the flusher if needed.
+func (m *Manager) hold(channelName string, msg OutboundMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	box := append(m.outbox[channelName], pendingMessage{msg: msg, queuedAt: time.Now()})
+	if len(box) > outboxSize {
+		log.Printf("[channel] outbox for %s is full; dropping the oldest message to chat %s", channelName, box[0].msg.ChatID)
+		box = box[1:]
+	}
+	m.outbox[channelName] = box
+
+	if !m.flushing {
+		m.flushing = true
+		go m.flushLoop()
+	}
+}
+
+// flushLoop retries held messages until every outbox is empty.
+func (m *Manager) flushLoop() {
+	for {
+		m.mu.RLock()
+		interval := m.outboxInterval
+		m.mu.RUnlock()
+		time.Sleep(interval)
+
+		if m.flushOutbox(context.Background()) == 0 {
+			m.mu.Lock()
+			// Recheck under the lock: hold may have added a message.
+			if m.pendingCount() == 0 {
+				m.flushing = false
+				m.mu.Unlock()
+				return
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// flushOutbox makes one delivery attempt per held message, in order, and
+// returns how many remain. A channel's remaining messages wait for the next
+// round after its first transient failure, so replies stay in order.
+func (m *Manager) flushOutbox(ctx context.Context) int {
+	m.mu.Lock()
+	boxes := m.outbox
+	m.outbox = make(map[string][]pendingMessage)
+	m.mu.Unlock()
+
+	for name, box := range boxes {
+		ch, ok := m.Get(name)
+		if !ok {
+			continue
+		}
+		for len(box) > 0 {
+			p := box[0]
+			if time.Since(p.queuedAt) > outboxMaxAge {
+				log.Printf("[channel] dropping stale message to %s/%s", name, p.msg.ChatID)
+				box = box[1:]
+				continue
+			}
+			err := ch.Send(ctx, p.msg)
+			if err != nil && !IsPermanent(err) {
+				break
+			}
+			if err != nil {
+				log.Printf("[channel] dropping undeliverable message to %s/%s: %v", name, p.msg.ChatID, err)
+			}
+			box = box[1:]
+		}
+		if len(box) > 0 {
+			m.mu.Lock()
+			m.outbox[name] = append(box, m.outbox[name]...)
+			m.mu.Unlock()
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pendingCount()
+}
+
+// pendingCount returns the number of held messages. m.mu must be held.
+func (m *Manager) pendingCount() int {
+	n := 0
+	for _, box := range m.outbox {
+		n += len(box)
+	}
+	return n
+}
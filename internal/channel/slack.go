@@ -0,0 +1,313 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	slackAPIBase    = "https://slack.com/api"
+	slackMaxMessage = 4000
+	slackReconnect  = 5 * time.Second
+)
+
+// slackTransient lists Web API errors worth retrying; the rest mean the
+// request itself can't succeed.
+var slackTransient = map[string]bool{
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// SlackChannel integrates with Slack over Socket Mode, so no public URL is
+// needed. Direct messages and mentions of the app reach the agent, and
+// replies go into the thread of the message that triggered them.
+type SlackChannel struct {
+	mu           sync.Mutex
+	botToken     string // xoxb-, for the Web API
+	appToken     string // xapp-, for opening Socket Mode connections
+	allowedUsers map[string]bool
+	allowedTeams map[string]bool
+	handler      func(InboundMessage)
+	running      bool
+	botID        string
+	cancel       context.CancelFunc
+
+	client  *http.Client
+	apiBase string // overridable in tests
+}
+
+// SlackConfig holds Slack-specific configuration.
+type SlackConfig struct {
+	BotToken       string
+	AppToken       string
+	AllowedUserIDs []string
+	AllowedTeamIDs []string
+}
+
+// NewSlackChannel creates a new Slack channel.
+func NewSlackChannel(cfg SlackConfig) *SlackChannel {
+	return &SlackChannel{
+		botToken:     cfg.BotToken,
+		appToken:     cfg.AppToken,
+		allowedUsers: stringSet(cfg.AllowedUserIDs),
+		allowedTeams: stringSet(cfg.AllowedTeamIDs),
+		client:       &http.Client{Timeout: 30 * time.Second},
+		apiBase:      slackAPIBase,
+	}
+}
+
+func (s *SlackChannel) Name() string { return "slack" }
+
+// Start checks the bot token, then holds a Socket Mode connection in the
+// background, reconnecting when Slack closes it.
+func (s *SlackChannel) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	var auth struct {
+		UserID string `json:"user_id"`
+		Team   string `json:"team"`
+	}
+	if err := s.api(ctx, s.botToken, "auth.test", nil, &auth); err != nil {
+		return fmt.Errorf("slack auth: %w", err)
+	}
+	s.botID = auth.UserID
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.running = true
+
+	go func() {
+		for {
+			if err := s.runSocket(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[slack] socket error: %v; reconnecting", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(slackReconnect):
+			}
+		}
+	}()
+
+	log.Printf("[slack] connected to %s", auth.Team)
+	return nil
+}
+
+func (s *SlackChannel) Stop(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.running = false
+	return nil
+}
+
+type slackEnvelope struct {
+	EnvelopeID string `json:"envelope_id"`
+	Type       string `json:"type"`
+	Payload    struct {
+		TeamID string     `json:"team_id"`
+		Event  slackEvent `json:"event"`
+	} `json:"payload"`
+}
+
+type slackEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	ChannelType string `json:"channel_type"`
+	Channel     string `json:"channel"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// runSocket holds one Socket Mode connection until it fails or ctx ends.
+func (s *SlackChannel) runSocket(ctx context.Context) error {
+	var open struct {
+		URL string `json:"url"`
+	}
+	if err := s.api(ctx, s.appToken, "apps.connections.open", nil, &open); err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, open.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var env slackEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return err
+		}
+		// Every envelope must be acknowledged or Slack retries it.
+		if env.EnvelopeID != "" {
+			if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return err
+			}
+		}
+		switch env.Type {
+		case "events_api":
+			s.dispatch(env.Payload.TeamID, env.Payload.Event)
+		case "disconnect":
+			return fmt.Errorf("slack asked to reconnect")
+		}
+	}
+}
+
+// dispatch passes an event to the handler if it is a direct message or a
+// mention from an allowed user.
+func (s *SlackChannel) dispatch(teamID string, e slackEvent) {
+	text, ok := s.accept(teamID, e)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	handler := s.handler
+	s.mu.Unlock()
+
+	// Reply in the existing thread, or start one under the message.
+	thread := e.ThreadTS
+	if thread == "" {
+		thread = e.TS
+	}
+	if handler != nil {
+		handler(InboundMessage{
+			ChannelName: "slack",
+			SenderID:    e.User,
+			SenderName:  e.User,
+			ChatID:      e.Channel,
+			MessageID:   thread,
+			Text:        text,
+			Timestamp:   time.Now(),
+		})
+	}
+}
+
+// accept returns the event's text with the app's mention removed, or false
+// if the event should be ignored.
+func (s *SlackChannel) accept(teamID string, e slackEvent) (string, bool) {
+	switch {
+	case e.BotID != "" || e.Subtype != "" || e.User == "" || e.User == s.botID:
+		return "", false // bots, edits, joins, and our own messages
+	case e.Type == "app_mention":
+	case e.Type == "message" && e.ChannelType == "im":
+	default:
+		return "", false
+	}
+
+	// Authorization check
+	if len(s.allowedTeams) > 0 && !s.allowedTeams[teamID] {
+		log.Printf("[slack] message from unauthorized workspace: %s", teamID)
+		return "", false
+	}
+	if len(s.allowedUsers) > 0 && !s.allowedUsers[e.User] {
+		log.Printf("[slack] unauthorized user: %s", e.User)
+		return "", false
+	}
+
+	text := strings.TrimSpace(strings.ReplaceAll(e.Text, "<@"+s.botID+">", ""))
+	return text, text != ""
+}
+
+// Send posts msg to its channel, into the thread named by msg.ReplyTo if set.
+func (s *SlackChannel) Send(ctx context.Context, msg OutboundMessage) error {
+	if !s.IsRunning() {
+		return fmt.Errorf("slack not started")
+	}
+
+	for _, chunk := range splitRunes(msg.Text, slackMaxMessage) {
+		body := map[string]string{"channel": msg.ChatID, "text": chunk}
+		if msg.ReplyTo != "" {
+			body["thread_ts"] = msg.ReplyTo
+		}
+		if err := s.api(ctx, s.botToken, "chat.postMessage", body, nil); err != nil {
+			return fmt.Errorf("slack send: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *SlackChannel) OnMessage(handler func(InboundMessage)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+func (s *SlackChannel) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *SlackChannel) Supports(feature string) bool {
+	return feature == FeatureThreads
+}
+
+// api calls a Slack Web API method with token, decoding the response into
+// out if it is non-nil. Slack reports failures in the body, not the status.
+func (s *SlackChannel) api(ctx context.Context, token, method string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBase+"/"+method, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	json.Unmarshal(raw, &status)
+	if !status.OK {
+		err := fmt.Errorf("%s: %s", method, status.Error)
+		if !slackTransient[status.Error] {
+			return Permanent(err) // e.g. channel_not_found, not_in_channel
+		}
+		return err
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeSlack serves the Web API methods the channel uses and a Socket Mode
+// endpoint that delivers queued envelopes.
+type fakeSlack struct {
+	*httptest.Server
+	envelopes chan string
+
+	mu     sync.Mutex
+	posted []map[string]string
+	acks   []string
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	f := &fakeSlack{envelopes: make(chan string, 8)}
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth.test", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-good" {
+			w.Write([]byte(`{"ok": false, "error": "invalid_auth"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "user_id": "UBOT", "team": "Acme"}`))
+	})
+	mux.HandleFunc("POST /apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "url": "ws" + strings.TrimPrefix(f.URL, "http") + "/socket"})
+	})
+	mux.HandleFunc("POST /chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		f.posted = append(f.posted, body)
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok": true}`))
+	})
+	mux.HandleFunc("/socket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			for {
+				var ack struct {
+					EnvelopeID string `json:"envelope_id"`
+				}
+				if err := conn.ReadJSON(&ack); err != nil {
+					return
+				}
+				f.mu.Lock()
+				f.acks = append(f.acks, ack.EnvelopeID)
+				f.mu.Unlock()
+			}
+		}()
+		for env := range f.envelopes {
+			conn.WriteMessage(websocket.TextMessage, []byte(env))
+		}
+		<-readDone // stay connected until the client hangs up
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func slackEventEnvelope(id, team, event string) string {
+	return `{"envelope_id":"` + id + `","type":"events_api","payload":{"team_id":"` + team + `","event":` + event + `}}`
+}
+
+func TestSlackRoutesMentionsAndDMsIntoThreads(t *testing.T) {
+	f := newFakeSlack(t)
+	s := NewSlackChannel(SlackConfig{BotToken: "xoxb-good", AppToken: "xapp-1", AllowedTeamIDs: []string{"T1"}})
+	s.apiBase = f.URL
+
+	got := make(chan InboundMessage, 8)
+	s.OnMessage(func(m InboundMessage) { got <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop(ctx)
+
+	f.envelopes <- slackEventEnvelope("e1", "T1", `{"type":"app_mention","channel":"C1","user":"U1","text":"<@UBOT> summarize this","ts":"100.1"}`)
+	f.envelopes <- slackEventEnvelope("e2", "T1", `{"type":"message","channel_type":"channel","channel":"C1","user":"U1","text":"chatter","ts":"100.2"}`)
+	f.envelopes <- slackEventEnvelope("e3", "T1", `{"type":"message","channel_type":"im","channel":"D1","user":"U1","text":"follow-up","ts":"100.4","thread_ts":"100.3"}`)
+	f.envelopes <- slackEventEnvelope("e4", "T1", `{"type":"message","channel_type":"im","channel":"D1","bot_id":"B1","text":"beep","ts":"100.5"}`)
+	f.envelopes <- slackEventEnvelope("e5", "T2", `{"type":"app_mention","channel":"C9","user":"U2","text":"<@UBOT> hi","ts":"100.6"}`)
+	close(f.envelopes)
+
+	var msgs []InboundMessage
+	for len(msgs) < 2 {
+		select {
+		case m := <-got:
+			msgs = append(msgs, m)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %d messages", len(msgs))
+		}
+	}
+	if m := msgs[0]; m.ChatID != "C1" || m.Text != "summarize this" || m.MessageID != "100.1" {
+		t.Fatalf("mention should start a thread under its own message: %+v", m)
+	}
+	if m := msgs[1]; m.ChatID != "D1" || m.Text != "follow-up" || m.MessageID != "100.3" {
+		t.Fatalf("threaded DM should stay in its thread: %+v", m)
+	}
+	select {
+	case m := <-got:
+		t.Fatalf("unexpected message: %+v", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := s.Send(ctx, OutboundMessage{ChatID: "C1", Text: "Here you go", ReplyTo: "100.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.posted) != 1 || f.posted[0]["thread_ts"] != "100.1" || f.posted[0]["channel"] != "C1" {
+		t.Fatalf("reply not posted in the thread: %+v", f.posted)
+	}
+	if len(f.acks) != 5 {
+		t.Fatalf("every envelope must be acknowledged, got %v", f.acks)
+	}
+}
+
+func TestSlackRejectsBadToken(t *testing.T) {
+	f := newFakeSlack(t)
+	close(f.envelopes)
+	s := NewSlackChannel(SlackConfig{BotToken: "xoxb-bad", AppToken: "xapp-1"})
+	s.apiBase = f.URL
+	if err := s.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Fatalf("expected invalid_auth, got %v", err)
+	}
+}
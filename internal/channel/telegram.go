@@ -2,15 +2,25 @@ package channel
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
 
 	tele "gopkg.in/telebot.v3"
+
+	"open-dan/internal/llm"
 )
 
+const transcribeTimeout = 2 * time.Minute
+
 // TelegramChannel integrates with the Telegram Bot API.
 type TelegramChannel struct {
 	mu         sync.Mutex
@@ -19,12 +29,27 @@ type TelegramChannel struct {
 	bot        *tele.Bot
 	handler    func(InboundMessage)
 	running    bool
+	// attachments is enforced on every file downloaded from a chat.
+	attachments   AttachmentPolicy
+	attachmentDir string
+	transcriber   llm.Transcriber
+	perUserMemory bool
 }
 
 // TelegramConfig holds Telegram-specific configuration.
 type TelegramConfig struct {
-	Token      string
-	AllowedIDs []int64
+	Token       string
+	AllowedIDs  []int64
+	Attachments AttachmentPolicy
+	// AttachmentDir is where received photos and documents are saved, one
+	// subdirectory per chat. Files are refused when it is empty.
+	AttachmentDir string
+	// Transcriber turns voice messages into text. Voice messages are
+	// refused when it is nil.
+	Transcriber llm.Transcriber
+	// PerUserMemory gives each member of a group chat their own
+	// conversation history instead of one shared by the whole group.
+	PerUserMemory bool
 }
 
 // NewTelegramChannel creates a new Telegram channel.
@@ -34,8 +59,12 @@ func NewTelegramChannel(cfg TelegramConfig) *TelegramChannel {
 		allowed[id] = true
 	}
 	return &TelegramChannel{
-		token:      cfg.Token,
-		allowedIDs: allowed,
+		token:         cfg.Token,
+		allowedIDs:    allowed,
+		attachments:   cfg.Attachments,
+		attachmentDir: cfg.AttachmentDir,
+		transcriber:   cfg.Transcriber,
+		perUserMemory: cfg.PerUserMemory,
 	}
 }
 
@@ -59,31 +88,7 @@ func (t *TelegramChannel) Start(ctx context.Context) error {
 		return fmt.Errorf("telegram bot init: %w", err)
 	}
 
-	bot.Handle(tele.OnText, func(c tele.Context) error {
-		sender := c.Sender()
-
-		// Authorization check
-		if len(t.allowedIDs) > 0 && !t.allowedIDs[sender.ID] {
-			log.Printf("[telegram] unauthorized user: %d (%s)", sender.ID, sender.Username)
-			return nil // silently ignore
-		}
-
-		t.mu.Lock()
-		handler := t.handler
-		t.mu.Unlock()
-
-		if handler != nil {
-			handler(InboundMessage{
-				ChannelName: "telegram",
-				SenderID:    strconv.FormatInt(sender.ID, 10),
-				SenderName:  sender.FirstName + " " + sender.LastName,
-				ChatID:      strconv.FormatInt(c.Chat().ID, 10),
-				Text:        c.Text(),
-				Timestamp:   time.Now(),
-			})
-		}
-		return nil
-	})
+	t.register(bot)
 
 	t.bot = bot
 	t.running = true
@@ -101,6 +106,142 @@ func (t *TelegramChannel) Start(ctx context.Context) error {
 	return nil
 }
 
+// register installs the bot's update handlers.
+func (t *TelegramChannel) register(bot *tele.Bot) {
+	bot.Handle(tele.OnText, func(c tele.Context) error {
+		if !t.authorized(c.Sender()) {
+			return nil // silently ignore
+		}
+		t.dispatch(c, c.Text(), nil)
+		return nil
+	})
+	bot.Handle(tele.OnPhoto, func(c tele.Context) error {
+		photo := c.Message().Photo
+		// Telegram delivers photos re-encoded as JPEG.
+		return t.receiveFile(c, &photo.File, photo.UniqueID+".jpg", "image/jpeg")
+	})
+	bot.Handle(tele.OnDocument, func(c tele.Context) error {
+		doc := c.Message().Document
+		name := doc.FileName
+		if name == "" {
+			name = doc.UniqueID
+		}
+		return t.receiveFile(c, &doc.File, name, doc.MIME)
+	})
+	bot.Handle(tele.OnVoice, t.receiveVoice)
+}
+
+func (t *TelegramChannel) authorized(sender *tele.User) bool {
+	if len(t.allowedIDs) > 0 && !t.allowedIDs[sender.ID] {
+		log.Printf("[telegram] unauthorized user: %d (%s)", sender.ID, sender.Username)
+		return false
+	}
+	return true
+}
+
+// receiveFile downloads an attachment into the chat's attachment directory
+// and passes it to the handler with the message caption. The sender and the
+// declared size and type are checked before anything is downloaded.
+func (t *TelegramChannel) receiveFile(c tele.Context, file *tele.File, name, mimeType string) error {
+	if !t.authorized(c.Sender()) {
+		return nil
+	}
+	if t.attachmentDir == "" {
+		return c.Send("Files are not accepted here.")
+	}
+	if err := t.attachments.Check(mimeType, file.FileSize); err != nil {
+		return c.Send(err.Error())
+	}
+
+	r, err := c.Bot().File(file)
+	if err != nil {
+		log.Printf("[telegram] download %s: %v", name, err)
+		return c.Send("Sorry, I couldn't download that file.")
+	}
+	defer r.Close()
+
+	chatID := strconv.FormatInt(c.Chat().ID, 10)
+	path, err := t.attachments.Save(t.attachmentDir, chatID, name, mimeType, r)
+	var ae *AttachmentError
+	if errors.As(err, &ae) {
+		return c.Send(ae.Reason)
+	}
+	if err != nil {
+		log.Printf("[telegram] save %s: %v", name, err)
+		return c.Send("Sorry, I couldn't save that file.")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(path))
+	}
+
+	t.dispatch(c, c.Message().Caption, []Attachment{{Path: path, MIME: mimeType, Size: info.Size()}})
+	return nil
+}
+
+// receiveVoice transcribes a voice message and passes the transcript to the
+// handler as the message text. The recording itself is not kept.
+func (t *TelegramChannel) receiveVoice(c tele.Context) error {
+	if !t.authorized(c.Sender()) {
+		return nil
+	}
+	if t.transcriber == nil {
+		return c.Send("Voice messages are not enabled. Please send text instead.")
+	}
+	voice := c.Message().Voice
+	if err := t.attachments.Check("", voice.FileSize); err != nil {
+		return c.Send(err.Error())
+	}
+
+	r, err := c.Bot().File(&voice.File)
+	if err != nil {
+		log.Printf("[telegram] download voice: %v", err)
+		return c.Send("Sorry, I couldn't download that voice message.")
+	}
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcribeTimeout)
+	defer cancel()
+	text, err := t.transcriber.Transcribe(ctx, io.LimitReader(r, t.attachments.maxSize()), "voice.ogg")
+	if err != nil {
+		log.Printf("[telegram] transcribe voice: %v", err)
+		return c.Send("Sorry, I couldn't transcribe that voice message. Please try again or send text.")
+	}
+	if text == "" {
+		return c.Send("I couldn't make out any words in that voice message.")
+	}
+
+	t.dispatch(c, text, nil)
+	return nil
+}
+
+func (t *TelegramChannel) dispatch(c tele.Context, text string, attachments []Attachment) {
+	t.mu.Lock()
+	handler := t.handler
+	t.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	sender := c.Sender()
+	msg := InboundMessage{
+		ChannelName: "telegram",
+		SenderID:    strconv.FormatInt(sender.ID, 10),
+		SenderName:  sender.FirstName + " " + sender.LastName,
+		ChatID:      strconv.FormatInt(c.Chat().ID, 10),
+		Text:        text,
+		Attachments: attachments,
+		Timestamp:   time.Now(),
+	}
+	if t.perUserMemory && c.Chat().Type != tele.ChatPrivate {
+		msg.ConversationID = msg.ChatID + "-" + msg.SenderID
+	}
+	handler(msg)
+}
+
 func (t *TelegramChannel) Stop(_ context.Context) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -123,7 +264,7 @@ func (t *TelegramChannel) Send(_ context.Context, msg OutboundMessage) error {
 
 	chatID, err := strconv.ParseInt(msg.ChatID, 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid chat ID: %w", err)
+		return Permanent(fmt.Errorf("invalid chat ID: %w", err))
 	}
 
 	recipient := &tele.Chat{ID: chatID}
@@ -139,13 +280,88 @@ func (t *TelegramChannel) Send(_ context.Context, msg OutboundMessage) error {
 			text = ""
 		}
 		if _, err := bot.Send(recipient, chunk); err != nil {
-			return fmt.Errorf("telegram send: %w", err)
+			return telegramSendError(err)
 		}
 	}
 
 	return nil
 }
 
+// telegramSendError wraps a failed send, marking errors Telegram won't
+// recover from, such as a blocked bot or an unknown chat, as permanent.
+func telegramSendError(err error) error {
+	err = fmt.Errorf("telegram send: %w", err)
+	var te *tele.Error
+	if errors.As(err, &te) && te.Code >= 400 && te.Code < 500 && te.Code != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+func (t *TelegramChannel) Supports(feature string) bool {
+	return feature == FeatureStreaming
+}
+
+func (t *TelegramChannel) SendDraft(_ context.Context, chatID, text string) (string, error) {
+	bot, chat, err := t.recipient(chatID)
+	if err != nil {
+		return "", err
+	}
+	sent, err := bot.Send(chat, text)
+	if err != nil {
+		return "", fmt.Errorf("telegram send: %w", err)
+	}
+	return strconv.Itoa(sent.ID), nil
+}
+
+func (t *TelegramChannel) Edit(ctx context.Context, chatID, messageID, text string) error {
+	bot, chat, err := t.recipient(chatID)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(messageID)
+	if err != nil {
+		return fmt.Errorf("invalid message ID: %w", err)
+	}
+
+	// A message can't grow past the limit; overflow goes out as new messages.
+	rest := ""
+	if len(text) > 4000 {
+		text, rest = text[:4000], text[4000:]
+	}
+	_, err = bot.Edit(&tele.Message{ID: id, Chat: chat}, text)
+	if err != nil && !errors.Is(err, tele.ErrMessageNotModified) && !errors.Is(err, tele.ErrSameMessageContent) {
+		return fmt.Errorf("telegram edit: %w", err)
+	}
+	if rest != "" {
+		return t.Send(ctx, OutboundMessage{ChatID: chatID, Text: rest})
+	}
+	return nil
+}
+
+func (t *TelegramChannel) SendTyping(_ context.Context, chatID string) error {
+	bot, chat, err := t.recipient(chatID)
+	if err != nil {
+		return err
+	}
+	return bot.Notify(chat, tele.Typing)
+}
+
+func (t *TelegramChannel) recipient(chatID string) (*tele.Bot, *tele.Chat, error) {
+	t.mu.Lock()
+	bot := t.bot
+	t.mu.Unlock()
+
+	if bot == nil {
+		return nil, nil, fmt.Errorf("telegram bot not started")
+	}
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid chat ID: %w", err)
+	}
+	return bot, &tele.Chat{ID: id}, nil
+}
+
 func (t *TelegramChannel) OnMessage(handler func(InboundMessage)) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
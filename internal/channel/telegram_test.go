@@ -0,0 +1,200 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// fakeTelegram serves the Bot API methods used when receiving files.
+type fakeTelegram struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	downloaded []string
+	sent       []string
+}
+
+func newFakeTelegram(t *testing.T, files map[string]string) *fakeTelegram {
+	f := &fakeTelegram{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bottok/getFile", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		id := body["file_id"]
+		w.Write([]byte(`{"ok":true,"result":{"file_id":"` + id + `","file_path":"files/` + id + `"}}`))
+	})
+	mux.HandleFunc("/file/bottok/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.downloaded = append(f.downloaded, r.PathValue("id"))
+		f.mu.Unlock()
+		w.Write([]byte(files[r.PathValue("id")]))
+	})
+	mux.HandleFunc("/bottok/sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		f.sent = append(f.sent, body["text"].(string))
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestTelegramReceivesPhotosAndDocuments(t *testing.T) {
+	f := newFakeTelegram(t, map[string]string{"p1": "\xff\xd8\xff jpeg data", "d1": "%PDF-1.4 small"})
+	dir := t.TempDir()
+	tg := NewTelegramChannel(TelegramConfig{
+		Token:         "tok",
+		AllowedIDs:    []int64{7},
+		Attachments:   AttachmentPolicy{MaxSize: 100, AllowedTypes: []string{"image/*", "application/pdf"}},
+		AttachmentDir: dir,
+	})
+	var got []InboundMessage
+	tg.OnMessage(func(m InboundMessage) { got = append(got, m) })
+
+	bot, err := tele.NewBot(tele.Settings{URL: f.URL, Token: "tok", Offline: true, Synchronous: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg.register(bot)
+
+	allowed := &tele.User{ID: 7, FirstName: "Ann"}
+	chat := &tele.Chat{ID: 100}
+	for i, m := range []*tele.Message{
+		{Sender: allowed, Chat: chat, Caption: "what is this?", Photo: &tele.Photo{File: tele.File{FileID: "p1", UniqueID: "u1", FileSize: 13}}},
+		{Sender: allowed, Chat: chat, Document: &tele.Document{File: tele.File{FileID: "d1", FileSize: 14}, MIME: "application/pdf", FileName: "report.pdf"}},
+		// Refused before download: too large, wrong type, or not allowed.
+		{Sender: allowed, Chat: chat, Document: &tele.Document{File: tele.File{FileID: "big", FileSize: 5000}, MIME: "application/pdf", FileName: "big.pdf"}},
+		{Sender: allowed, Chat: chat, Document: &tele.Document{File: tele.File{FileID: "sh", FileSize: 9}, MIME: "application/x-sh", FileName: "run.sh"}},
+		{Sender: &tele.User{ID: 9}, Chat: chat, Document: &tele.Document{File: tele.File{FileID: "d1", FileSize: 14}, MIME: "application/pdf", FileName: "spy.pdf"}},
+	} {
+		bot.ProcessUpdate(tele.Update{ID: i, Message: m})
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 messages, got %+v", got)
+	}
+	photo := got[0]
+	if photo.Text != "what is this?" || photo.ChatID != "100" || len(photo.Attachments) != 1 {
+		t.Fatalf("unexpected photo message: %+v", photo)
+	}
+	if att := photo.Attachments[0]; att.MIME != "image/jpeg" || att.Size != 13 || !strings.HasPrefix(att.Path, dir) {
+		t.Fatalf("unexpected attachment: %+v", att)
+	}
+	doc := got[1].Attachments[0]
+	if data, _ := os.ReadFile(doc.Path); string(data) != "%PDF-1.4 small" || !strings.HasSuffix(doc.Path, "report.pdf") {
+		t.Fatalf("document saved wrong: %s %q", doc.Path, data)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.Join(f.downloaded, ",") != "p1,d1" {
+		t.Fatalf("only accepted files should be downloaded, got %v", f.downloaded)
+	}
+	if len(f.sent) != 2 || !strings.Contains(f.sent[0], "too large") || !strings.Contains(f.sent[1], "not accepted") {
+		t.Fatalf("expected refusals to be explained, got %q", f.sent)
+	}
+}
+
+// stubTranscriber returns a fixed transcript, or fails if err is set.
+type stubTranscriber struct {
+	text string
+	err  error
+	got  string
+}
+
+func (s *stubTranscriber) Transcribe(_ context.Context, audio io.Reader, _ string) (string, error) {
+	data, _ := io.ReadAll(audio)
+	s.got = string(data)
+	return s.text, s.err
+}
+
+func TestTelegramTranscribesVoiceMessages(t *testing.T) {
+	f := newFakeTelegram(t, map[string]string{"v1": "OggS voice"})
+	stt := &stubTranscriber{text: "remind me to call mum"}
+	tg := NewTelegramChannel(TelegramConfig{Token: "tok", Transcriber: stt})
+	var got []InboundMessage
+	tg.OnMessage(func(m InboundMessage) { got = append(got, m) })
+
+	bot, err := tele.NewBot(tele.Settings{URL: f.URL, Token: "tok", Offline: true, Synchronous: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg.register(bot)
+
+	voice := func() *tele.Message {
+		return &tele.Message{Sender: &tele.User{ID: 7}, Chat: &tele.Chat{ID: 100}, Voice: &tele.Voice{File: tele.File{FileID: "v1", FileSize: 10}}}
+	}
+	bot.ProcessUpdate(tele.Update{ID: 1, Message: voice()})
+	if len(got) != 1 || got[0].Text != "remind me to call mum" || stt.got != "OggS voice" {
+		t.Fatalf("expected the transcript as message text, got %+v (audio %q)", got, stt.got)
+	}
+
+	stt.err = errors.New("service unavailable")
+	bot.ProcessUpdate(tele.Update{ID: 2, Message: voice()})
+	if len(got) != 1 {
+		t.Fatalf("a failed transcription should not reach the agent: %+v", got)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) != 1 || !strings.Contains(f.sent[0], "couldn't transcribe") {
+		t.Fatalf("expected a friendly error reply, got %q", f.sent)
+	}
+}
+
+func TestTelegramPerUserMemoryInGroups(t *testing.T) {
+	bot, err := tele.NewBot(tele.Settings{Token: "tok", Offline: true, Synchronous: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := &tele.Chat{ID: -100, Type: tele.ChatGroup}
+	dm := &tele.Chat{ID: 7, Type: tele.ChatPrivate}
+
+	for _, perUser := range []bool{false, true} {
+		tg := NewTelegramChannel(TelegramConfig{Token: "tok", PerUserMemory: perUser})
+		var got []string
+		tg.OnMessage(func(m InboundMessage) { got = append(got, m.ChatID+"|"+m.Conversation()) })
+		tg.register(bot)
+
+		bot.ProcessUpdate(tele.Update{Message: &tele.Message{Sender: &tele.User{ID: 7}, Chat: group, Text: "hi"}})
+		bot.ProcessUpdate(tele.Update{Message: &tele.Message{Sender: &tele.User{ID: 8}, Chat: group, Text: "hi"}})
+		bot.ProcessUpdate(tele.Update{Message: &tele.Message{Sender: &tele.User{ID: 7}, Chat: dm, Text: "hi"}})
+
+		want := "-100|-100,-100|-100,7|7"
+		if perUser {
+			want = "-100|-100-7,-100|-100-8,7|7"
+		}
+		if strings.Join(got, ",") != want {
+			t.Errorf("PerUserMemory=%v: got %v, want %s", perUser, got, want)
+		}
+	}
+}
+
+func TestTelegramSendErrorClassification(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		permanent bool
+	}{
+		{tele.ErrBlockedByUser, true},
+		{tele.ErrChatNotFound, true},
+		{tele.NewError(502, "Bad Gateway"), false},
+		{tele.FloodError{RetryAfter: 5}, false},
+		{errors.New("connection reset by peer"), false},
+	} {
+		if got := IsPermanent(telegramSendError(tc.err)); got != tc.permanent {
+			t.Errorf("%v: permanent = %v, want %v", tc.err, got, tc.permanent)
+		}
+	}
+}
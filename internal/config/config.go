@@ -2,16 +2,29 @@ package config
 
 // Config is the top-level application configuration.
 type Config struct {
-	Agent          AgentConfig    `json:"agent"`
-	LLM            LLMConfig      `json:"llm"`
-	FallbackLLM    *LLMConfig     `json:"fallback_llm,omitempty"`
-	Channels       ChannelsConfig `json:"channels"`
-	Security       SecurityConfig `json:"security"`
-	Browser        BrowserConfig  `json:"browser"`
-	Plugins        PluginsConfig  `json:"plugins"`
-	SetupCompleted bool           `json:"setup_completed"`
+	Agent          AgentConfig         `json:"agent"`
+	LLM            LLMConfig           `json:"llm"`
+	FallbackLLM    *LLMConfig          `json:"fallback_llm,omitempty"`
+	Channels       ChannelsConfig      `json:"channels"`
+	Security       SecurityConfig      `json:"security"`
+	Browser        BrowserConfig       `json:"browser"`
+	Fetch          FetchConfig         `json:"fetch"`
+	HTTPRequest    HTTPRequestConfig   `json:"http_request"`
+	WebSearch      WebSearchConfig     `json:"web_search"`
+	Notes          NotesConfig         `json:"notes"`
+	Plugins        PluginsConfig       `json:"plugins"`
+	Memory         MemoryConfig        `json:"memory"`
+	Transcription  TranscriptionConfig `json:"transcription"`
+	Embeddings     EmbeddingsConfig    `json:"embeddings"`
+	SetupCompleted bool                `json:"setup_completed"`
 }
 
+// Summary strategies for AgentConfig.SummaryStrategy.
+const (
+	SummaryStrategySummarize     = "summarize"
+	SummaryStrategySlidingWindow = "sliding_window"
+)
+
 type AgentConfig struct {
 	SystemPrompt  string  `json:"system_prompt"`
 	MaxTokens     int     `json:"max_tokens"`
@@ -19,39 +32,188 @@ type AgentConfig struct {
 	MaxToolCalls  int     `json:"max_tool_calls"`
 	ContextWindow int     `json:"context_window"`
 	SummarizeAt   int     `json:"summarize_at"`
+	// SummaryStrategy decides what happens to older messages once the
+	// history passes SummarizeAt: "summarize" replaces them with a summary
+	// written by the LLM, "sliding_window" drops them.
+	SummaryStrategy string `json:"summary_strategy"`
+	// SummaryKeepRecent is how many of the latest messages are kept as they
+	// are when older ones are summarized or dropped. 0 keeps 4.
+	SummaryKeepRecent int `json:"summary_keep_recent"`
+	// SummaryPrompt replaces the instruction sent along with the messages to
+	// summarize. Empty uses the built-in prompt.
+	SummaryPrompt string `json:"summary_prompt,omitempty"`
+	// MaxMessageRetries re-runs a whole message after a transient provider
+	// failure (network, 5xx, timeout), with exponential backoff. 0 disables.
+	MaxMessageRetries int `json:"max_message_retries"`
+	// MaxToolOutputChars caps each tool result kept in context. Results are
+	// trimmed further when the remaining context window is smaller. 0 = no cap.
+	MaxToolOutputChars int `json:"max_tool_output_chars"`
+	// MaxContinuations is how many times a reply cut off by max_tokens is
+	// continued automatically. 0 disables; the reply is then marked truncated.
+	MaxContinuations int `json:"max_continuations"`
+	// MaxReflections is how many failed or empty tool results per message
+	// get a hint asking the model to reconsider its approach. 0 disables.
+	MaxReflections int `json:"max_reflections,omitempty"`
+	// ProcessTimeoutSecs bounds the wall-clock time spent on one message,
+	// across all LLM turns and tool calls. When it runs out, in-flight calls
+	// are cancelled and the user gets what was produced so far. 0 disables.
+	ProcessTimeoutSecs int `json:"process_timeout_secs"`
+	// MaxParallelTools caps how many tool calls from one model turn run at
+	// the same time. Results are still returned in call order. 1 runs them
+	// one after another.
+	MaxParallelTools int `json:"max_parallel_tools"`
+	// ToolCacheTTLSecs is how long results of cacheable tools, such as
+	// web_search and fetch, are reused for identical calls in the same chat.
+	// Tools with side effects are never cached. 0 disables.
+	ToolCacheTTLSecs int `json:"tool_cache_ttl_secs"`
+	// QueueAck tells a sender when their message is queued behind another
+	// one in the same chat, and at what position.
+	QueueAck bool `json:"queue_ack,omitempty"`
+	// MatchUserLanguage detects the language of each user message and tells
+	// the model to reply in it.
+	MatchUserLanguage bool `json:"match_user_language,omitempty"`
+	// InjectDateTime starts the system prompt with the current date, time,
+	// and time zone, so the model can reason about "today" or "next Tuesday".
+	InjectDateTime bool `json:"inject_datetime"`
+	// TimeZone is the IANA name of the user's time zone, such as
+	// "Europe/Berlin". Empty uses the system's local time zone.
+	TimeZone string `json:"time_zone,omitempty"`
+	// CommandPrefix starts built-in chat commands such as /reset, which are
+	// handled without calling the LLM. Empty disables commands.
+	CommandPrefix string `json:"command_prefix"`
+	// StreamToolOutput publishes the output of tools that support it, such
+	// as shell, line by line as tool_progress events while they run. The
+	// model sees the same final result either way.
+	StreamToolOutput bool `json:"stream_tool_output,omitempty"`
 }
 
 type LLMConfig struct {
-	Provider    string `json:"provider"`
-	Model       string `json:"model"`
-	APIKey      string `json:"api_key,omitempty"`
-	BaseURL     string `json:"base_url,omitempty"`
-	MaxRetries  int    `json:"max_retries"`
-	TimeoutSecs int    `json:"timeout_secs"`
+	Provider           string `json:"provider"`
+	Model              string `json:"model"`
+	APIKey             string `json:"api_key,omitempty"`
+	BaseURL            string `json:"base_url,omitempty"`
+	MaxRetries         int    `json:"max_retries"`
+	TimeoutSecs        int    `json:"timeout_secs"`         // whole request, including generation
+	ConnectTimeoutSecs int    `json:"connect_timeout_secs"` // dial + TLS handshake
+	// MaxConcurrent caps simultaneous requests to each model (0 = unlimited).
+	// ModelConcurrency overrides it for specific models.
+	MaxConcurrent    int            `json:"max_concurrent,omitempty"`
+	ModelConcurrency map[string]int `json:"model_concurrency,omitempty"`
 }
 
 type ChannelsConfig struct {
-	Telegram *TelegramConfig `json:"telegram,omitempty"`
+	Telegram *TelegramConfig    `json:"telegram,omitempty"`
+	HTTP     *HTTPChannelConfig `json:"http,omitempty"`
+	Discord  *DiscordConfig     `json:"discord,omitempty"`
+	Slack    *SlackConfig       `json:"slack,omitempty"`
+	// AllowBroadcast enables the admin binding that messages every known chat.
+	AllowBroadcast bool `json:"allow_broadcast,omitempty"`
+	// SendRetries is how many times a reply that fails to send for a
+	// transient reason is retried before it is queued for later delivery.
+	SendRetries int `json:"send_retries"`
 }
 
 type TelegramConfig struct {
-	Token      string   `json:"token"`
-	AllowedIDs []int64  `json:"allowed_ids,omitempty"`
+	Token       string           `json:"token"`
+	AllowedIDs  []int64          `json:"allowed_ids,omitempty"`
+	Attachments AttachmentConfig `json:"attachments"`
+	// PerUserMemory keeps a separate history for each member of a group.
+	PerUserMemory bool `json:"per_user_memory,omitempty"`
+}
+
+type DiscordConfig struct {
+	Token          string   `json:"token"`
+	AllowedUserIDs []string `json:"allowed_user_ids,omitempty"`
+	AllowedRoleIDs []string `json:"allowed_role_ids,omitempty"`
+}
+
+// SlackConfig connects a Slack app over Socket Mode.
+type SlackConfig struct {
+	BotToken       string   `json:"bot_token"` // xoxb-...
+	AppToken       string   `json:"app_token"` // xapp-..., with connections:write
+	AllowedUserIDs []string `json:"allowed_user_ids,omitempty"`
+	AllowedTeamIDs []string `json:"allowed_team_ids,omitempty"`
+}
+
+// TranscriptionConfig turns voice messages into text for the agent.
+type TranscriptionConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider"` // "openai"
+	Model    string `json:"model"`    // e.g. "whisper-1"
+	// APIKey defaults to the LLM API key when the LLM provider is OpenAI.
+	APIKey  string `json:"api_key,omitempty"`
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+// EmbeddingsConfig enables semantic recall: saved messages are embedded in
+// the background, and the recall tool finds past messages by meaning.
+type EmbeddingsConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider"` // "openai"
+	Model    string `json:"model"`    // e.g. "text-embedding-3-small"
+	// APIKey defaults to the LLM API key when the LLM provider is OpenAI.
+	APIKey  string `json:"api_key,omitempty"`
+	BaseURL string `json:"base_url,omitempty"`
+	// BatchSize is how many saved messages are embedded per request.
+	BatchSize int `json:"batch_size"`
+}
+
+// HTTPChannelConfig enables the HTTP API channel for integrating other apps.
+type HTTPChannelConfig struct {
+	Addr             string `json:"addr,omitempty"` // default "127.0.0.1:8765"
+	Token            string `json:"token"`          // bearer token clients must send
+	ReplyTimeoutSecs int    `json:"reply_timeout_secs,omitempty"`
+}
+
+// AttachmentConfig limits files a channel downloads into the workspace.
+type AttachmentConfig struct {
+	MaxSizeKB        int      `json:"max_size_kb,omitempty"`         // per file; 0 = 20 MB
+	AllowedTypes     []string `json:"allowed_types,omitempty"`       // MIME types, "image/*" wildcards; empty = any
+	MaxChatStorageMB int      `json:"max_chat_storage_mb,omitempty"` // per chat; 0 = unlimited
 }
 
 type SecurityConfig struct {
 	MasterPasswordHash string          `json:"master_password_hash,omitempty"`
 	PIIFiltering       PIIFilterConfig `json:"pii_filtering"`
 	Sandbox            SandboxConfig   `json:"sandbox"`
+	Approval           ApprovalConfig  `json:"approval"`
+}
+
+// ApprovalConfig lists tool calls that wait for the user's approval before
+// they run. Each entry is a tool name ("shell") or a tool name and action
+// ("filesystem:write"). Calls left unanswered for TimeoutSecs are denied.
+type ApprovalConfig struct {
+	Tools       []string `json:"tools"`
+	TimeoutSecs int      `json:"timeout_secs"`
 }
 
 type PIIFilterConfig struct {
-	Enabled      bool `json:"enabled"`
-	FilterEmails bool `json:"filter_emails"`
-	FilterPhones bool `json:"filter_phones"`
-	FilterCards  bool `json:"filter_cards"`
-	FilterIPs    bool `json:"filter_ips"`
-	FilterSSN    bool `json:"filter_ssn"`
+	Enabled         bool `json:"enabled"`
+	FilterEmails    bool `json:"filter_emails"`
+	FilterPhones    bool `json:"filter_phones"`
+	FilterCards     bool `json:"filter_cards"`
+	FilterIPs       bool `json:"filter_ips"`
+	FilterSSN       bool `json:"filter_ssn"`
+	FilterIBANs     bool `json:"filter_ibans"`
+	FilterPassports bool `json:"filter_passports"` // numbers introduced by "passport"
+	FilterAPIKeys   bool `json:"filter_api_keys"`  // OpenAI, Anthropic, AWS, GitHub, Slack, Google
+	// PersistMappings stores placeholder mappings in the memory database so
+	// responses can be restored after a restart.
+	PersistMappings bool `json:"persist_mappings"`
+	// DetectLanguage guesses each message's language and adds that locale's
+	// phone and national ID formats to the enabled filters.
+	DetectLanguage bool `json:"detect_language,omitempty"`
+	// CustomPatterns are extra site-specific patterns, such as employee IDs
+	// or internal hostnames, applied alongside the built-in filters.
+	CustomPatterns []CustomPIIPattern `json:"custom_patterns,omitempty"`
+}
+
+// CustomPIIPattern is a user-defined PII filter. Matches are replaced with
+// placeholders like [PREFIX_1].
+type CustomPIIPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"` // Go regexp syntax
+	Prefix  string `json:"prefix"`  // upper-case letters and digits
 }
 
 type SandboxConfig struct {
@@ -59,6 +221,16 @@ type SandboxConfig struct {
 	WorkspaceDir   string `json:"workspace_dir,omitempty"`
 	TimeoutSecs    int    `json:"timeout_secs"`
 	MaxOutputChars int    `json:"max_output_chars"`
+	// AllowedCommands, when non-empty, restricts the shell tool to these
+	// program names and replaces the built-in denylist.
+	AllowedCommands []string `json:"allowed_commands,omitempty"`
+	// ExtraDenyPatterns are regexes blocked on top of the built-in denylist.
+	ExtraDenyPatterns []string `json:"extra_deny_patterns,omitempty"`
+	// AllowPatterns exempt matching simple commands from the denylist
+	// (allow overrides deny).
+	AllowPatterns []string `json:"allow_patterns,omitempty"`
+	// MaxFileSizeKB caps files written by the filesystem tool (0 = 10 MiB).
+	MaxFileSizeKB int `json:"max_file_size_kb,omitempty"`
 }
 
 type BrowserConfig struct {
@@ -69,6 +241,57 @@ type BrowserConfig struct {
 	AllowedDomains []string `json:"allowed_domains,omitempty"`
 	DeniedDomains  []string `json:"denied_domains,omitempty"`
 	MaxPageSizeKB  int      `json:"max_page_size_kb"`
+	// UserAgent and the viewport size are applied to every new tab.
+	UserAgent      string `json:"user_agent,omitempty"`
+	ViewportWidth  int    `json:"viewport_width,omitempty"`
+	ViewportHeight int    `json:"viewport_height,omitempty"`
+	// PersistCookies saves browser cookies to an encrypted file on shutdown
+	// and restores them on launch, so logins survive restarts.
+	PersistCookies bool `json:"persist_cookies,omitempty"`
+	// AllowPrivateNetwork turns off SSRF protection so pages on localhost
+	// and the private network can be opened. Only for trusted setups.
+	AllowPrivateNetwork bool `json:"allow_private_network,omitempty"`
+	// TabIdleSecs closes tabs that no action has used for this long.
+	// 0 keeps tabs open until closed.
+	TabIdleSecs int `json:"tab_idle_secs,omitempty"`
+	// EvictLRU closes the least recently used tab when a new one would
+	// exceed MaxTabs, instead of failing the navigation.
+	EvictLRU bool `json:"evict_lru,omitempty"`
+}
+
+type FetchConfig struct {
+	TimeoutSecs int `json:"timeout_secs"`
+	MaxBodyKB   int `json:"max_body_kb"`
+	// AllowPrivateNetwork lets fetch reach localhost and private addresses.
+	AllowPrivateNetwork bool `json:"allow_private_network,omitempty"`
+}
+
+// HTTPRequestConfig controls the http_request tool, which calls APIs with any
+// method, headers, and body. The tool is off until hosts are allowed.
+type HTTPRequestConfig struct {
+	// AllowedHosts lists the hosts the tool may call. "*.example.com"
+	// matches subdomains of example.com, and "*" matches any public host.
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+	TimeoutSecs  int      `json:"timeout_secs"`
+	MaxBodyKB    int      `json:"max_body_kb"`
+	// AllowPrivateNetwork lets http_request reach localhost and private
+	// addresses on the allowed hosts.
+	AllowPrivateNetwork bool `json:"allow_private_network,omitempty"`
+}
+
+// NotesConfig controls the notes tool, a per-chat scratchpad the agent keeps
+// in the memory database.
+type NotesConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxKB caps the total size of one chat's notes.
+	MaxKB int `json:"max_kb"`
+}
+
+type WebSearchConfig struct {
+	Backend     string `json:"backend"` // "duckduckgo" (default), "searxng", or "brave"
+	SearxngURL  string `json:"searxng_url,omitempty"`
+	BraveAPIKey string `json:"brave_api_key,omitempty"`
+	MaxResults  int    `json:"max_results"`
 }
 
 type PluginsConfig struct {
@@ -77,4 +300,35 @@ type PluginsConfig struct {
 	EnabledSkills  []string `json:"enabled_skills,omitempty"`
 	TimeoutSecs    int      `json:"timeout_secs"`
 	SandboxEnabled bool     `json:"sandbox_enabled"`
+	// VerifyMode is "" to check a skill's checksum and signature only when
+	// it has them, or "strict" to refuse skills that can't be verified.
+	VerifyMode string `json:"verify_mode,omitempty"`
+	// TrustedKeys are base64 Ed25519 public keys whose signatures on a
+	// skill's manifest.json are accepted.
+	TrustedKeys []string `json:"trusted_keys,omitempty"`
+	// Isolation runs skills on Linux inside bubblewrap or nsjail, with only
+	// the skill and workspace directories writable and the home directory
+	// hidden: "bwrap", "nsjail", "auto" for whichever is installed, or ""
+	// to run them directly.
+	Isolation string `json:"isolation,omitempty"`
+	// UnisolatedSkills run directly even when Isolation is set.
+	UnisolatedSkills []string `json:"unisolated_skills,omitempty"`
+}
+
+// SkillVerifyStrict is the PluginsConfig.VerifyMode that refuses to load
+// skills without a matching sha256 (and, with trusted keys, a signature).
+const SkillVerifyStrict = "strict"
+
+// Values for PluginsConfig.Isolation.
+const (
+	SkillIsolationAuto   = "auto"
+	SkillIsolationBwrap  = "bwrap"
+	SkillIsolationNsjail = "nsjail"
+)
+
+type MemoryConfig struct {
+	MaxMessagesPerChat int `json:"max_messages_per_chat"` // 0 = unlimited
+	MaxAgeDays         int `json:"max_age_days"`          // 0 = keep forever
+	// Ephemeral keeps conversations in RAM only (privacy mode); nothing is written to disk.
+	Ephemeral bool `json:"ephemeral"`
 }
@@ -1,5 +1,9 @@
 package config
 
+// DefaultBrowserUserAgent is a current desktop Chrome user agent. Sites that
+// sniff for headless browsers get the same layout a desktop user would.
+const DefaultBrowserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
 // Defaults returns a Config with sensible default values.
 func Defaults() *Config {
 	return &Config{
@@ -10,35 +14,83 @@ func Defaults() *Config {
 			MaxToolCalls:    20,
 			ContextWindow:   100000,
 			SummarizeAt:     80000,
+			SummaryStrategy: SummaryStrategySummarize,
+			SummaryKeepRecent: 4,
+			MaxMessageRetries: 2,
+			MaxToolOutputChars: 20000,
+			MaxContinuations: 2,
+			MaxParallelTools: 4,
+			ProcessTimeoutSecs: 300,
+			ToolCacheTTLSecs: 300,
+			InjectDateTime:  true,
+			CommandPrefix:   "/",
 		},
 		LLM: LLMConfig{
-			Provider:    "openai",
-			Model:       "gpt-4o-mini",
-			MaxRetries:  3,
-			TimeoutSecs: 120,
+			Provider:           "openai",
+			Model:              "gpt-4o-mini",
+			MaxRetries:         3,
+			TimeoutSecs:        120,
+			ConnectTimeoutSecs: 10,
 		},
 		Security: SecurityConfig{
 			PIIFiltering: PIIFilterConfig{
-				Enabled:      true,
-				FilterEmails: true,
-				FilterPhones: true,
-				FilterCards:  true,
-				FilterIPs:    false,
-				FilterSSN:    true,
+				Enabled:         true,
+				FilterEmails:    true,
+				FilterPhones:    true,
+				FilterCards:     true,
+				FilterIPs:       false,
+				FilterSSN:       true,
+				FilterIBANs:     true,
+				FilterPassports: true,
+				FilterAPIKeys:   true,
 			},
 			Sandbox: SandboxConfig{
 				Enabled:        true,
 				TimeoutSecs:    60,
 				MaxOutputChars: 10000,
 			},
+			Approval: ApprovalConfig{
+				Tools:       []string{"shell", "filesystem:write", "filesystem:delete"},
+				TimeoutSecs: 120,
+			},
+		},
+		Channels: ChannelsConfig{
+			SendRetries: 3,
 		},
-		Channels: ChannelsConfig{},
 		Browser: BrowserConfig{
-			Enabled:       false,
-			Headless:      true,
-			TimeoutSecs:   30,
-			MaxTabs:       3,
-			MaxPageSizeKB: 2048,
+			Enabled:        false,
+			Headless:       true,
+			TimeoutSecs:    30,
+			MaxTabs:        3,
+			MaxPageSizeKB:  2048,
+			UserAgent:      DefaultBrowserUserAgent,
+			ViewportWidth:  1920,
+			ViewportHeight: 1080,
+		},
+		Fetch: FetchConfig{
+			TimeoutSecs: 20,
+			MaxBodyKB:   1024,
+		},
+		HTTPRequest: HTTPRequestConfig{
+			TimeoutSecs: 30,
+			MaxBodyKB:   256,
+		},
+		Transcription: TranscriptionConfig{
+			Provider: "openai",
+			Model:    "whisper-1",
+		},
+		Embeddings: EmbeddingsConfig{
+			Provider:  "openai",
+			Model:     "text-embedding-3-small",
+			BatchSize: 16,
+		},
+		WebSearch: WebSearchConfig{
+			Backend:    "duckduckgo",
+			MaxResults: 8,
+		},
+		Notes: NotesConfig{
+			Enabled: true,
+			MaxKB:   64,
 		},
 		Plugins: PluginsConfig{
 			Enabled:        true,
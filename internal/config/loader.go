@@ -35,6 +35,8 @@ func NewLoader() (*Loader, error) {
 }
 
 // Load reads the config from disk. If the file doesn't exist, returns defaults.
+// Invalid settings are disabled and reported in the error, which then comes
+// with the rest of the config instead of a nil one.
 func (l *Loader) Load() (*Config, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -53,9 +55,10 @@ func (l *Loader) Load() (*Config, error) {
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
+	err = cfg.disableInvalid()
 
 	l.config = cfg
-	return cfg, nil
+	return cfg, err
 }
 
 // Save writes the current config to disk.
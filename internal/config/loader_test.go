@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -58,3 +59,133 @@ func TestSaveAndLoad(t *testing.T) {
 		t.Fatal("expected setup_completed to be true")
 	}
 }
+
+func TestLoadRejectsInvalidCustomPIIPatterns(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, prefix, want string
+	}{
+		{`EMP-(\d+`, "EMP", "invalid pattern"},
+		{`\d*`, "EMP", "matches empty text"},
+		{`EMP-\d+`, "emp id", "prefix"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		cfg := Defaults()
+		cfg.Security.PIIFiltering.CustomPatterns = []CustomPIIPattern{{Name: "employee", Pattern: tc.pattern, Prefix: tc.prefix}}
+		if err := (&Loader{filePath: path}).Save(cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := (&Loader{filePath: path}).Load()
+		if err == nil || !strings.Contains(err.Error(), "custom_patterns[0] (employee)") || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("pattern %q prefix %q: expected an error mentioning %q, got %v", tc.pattern, tc.prefix, tc.want, err)
+		}
+	}
+}
+
+func TestLoadKeepsHistoryWithoutMemoryConfig(t *testing.T) {
+	// A config from before retention existed must not start deleting history
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"llm": {"provider": "openai"}, "setup_completed": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := (&Loader{filePath: path}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Memory.MaxAgeDays != 0 || cfg.Memory.MaxMessagesPerChat != 0 {
+		t.Fatalf("expected retention off, got %+v", cfg.Memory)
+	}
+}
+
+func TestLoadRejectsUnknownTimeZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := Defaults()
+	cfg.Agent.TimeZone = "Mars/Olympus_Mons"
+	if err := (&Loader{filePath: path}).Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Loader{filePath: path}).Load(); err == nil || !strings.Contains(err.Error(), "agent.time_zone") {
+		t.Fatalf("expected a time zone error, got %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownSummaryStrategy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := Defaults()
+	cfg.Agent.SummaryStrategy = "forget_everything"
+	if err := (&Loader{filePath: path}).Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Loader{filePath: path}).Load(); err == nil || !strings.Contains(err.Error(), "agent.summary_strategy") {
+		t.Fatalf("expected a summary strategy error, got %v", err)
+	}
+}
+
+func TestLoadRejectsInvalidPluginsConfig(t *testing.T) {
+	for _, tc := range []struct {
+		mode      string
+		keys      []string
+		isolation string
+		want      string
+	}{
+		{mode: "paranoid", want: "plugins.verify_mode"},
+		{mode: SkillVerifyStrict, keys: []string{"not-base64!"}, want: "plugins.trusted_keys[0]"},
+		{keys: []string{"c2hvcnQ="}, want: "plugins.trusted_keys[0]"},
+		{isolation: "docker", want: "plugins.isolation"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		cfg := Defaults()
+		cfg.Plugins.VerifyMode = tc.mode
+		cfg.Plugins.TrustedKeys = tc.keys
+		cfg.Plugins.Isolation = tc.isolation
+		if err := (&Loader{filePath: path}).Save(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (&Loader{filePath: path}).Load(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("mode %q keys %v: expected an error mentioning %q, got %v", tc.mode, tc.keys, tc.want, err)
+		}
+	}
+}
+
+func TestLoadDisablesOnlyInvalidSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := Defaults()
+	cfg.LLM.Provider = "anthropic"
+	cfg.SetupCompleted = true
+	cfg.Agent.TimeZone = "Mars/Olympus_Mons"
+	cfg.Security.PIIFiltering.CustomPatterns = []CustomPIIPattern{
+		{Name: "broken", Pattern: `EMP-(\d+`, Prefix: "EMP"},
+		{Name: "project", Pattern: `PRJ-\d+`, Prefix: "PROJECT"},
+	}
+	cfg.Plugins.VerifyMode = "paranoid"
+	cfg.Plugins.Isolation = "docker"
+	cfg.Plugins.TrustedKeys = []string{"not-base64!"}
+	if err := (&Loader{filePath: path}).Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := (&Loader{filePath: path}).Load()
+	if err == nil {
+		t.Fatal("expected the invalid settings to be reported")
+	}
+	for _, want := range []string{"agent.time_zone", "custom_patterns[0] (broken)", "plugins.verify_mode", "plugins.isolation", "plugins.trusted_keys[0]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %q, got %v", want, err)
+		}
+	}
+	if loaded == nil {
+		t.Fatal("expected the rest of the config to be kept")
+	}
+	if !loaded.SetupCompleted || loaded.LLM.Provider != "anthropic" {
+		t.Fatalf("valid settings were lost: %+v", loaded)
+	}
+	if loaded.Agent.TimeZone != "" {
+		t.Errorf("expected the time zone to be cleared, got %q", loaded.Agent.TimeZone)
+	}
+	if patterns := loaded.Security.PIIFiltering.CustomPatterns; len(patterns) != 1 || patterns[0].Name != "project" {
+		t.Errorf("expected only the valid custom pattern, got %+v", patterns)
+	}
+	if p := loaded.Plugins; p.VerifyMode != SkillVerifyStrict || p.Isolation != SkillIsolationAuto || len(p.TrustedKeys) != 0 {
+		t.Errorf("expected plugin settings to fail closed, got %+v", p)
+	}
+}
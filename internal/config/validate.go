@@ -0,0 +1,114 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var piiPrefix = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+
+// disableInvalid turns off each invalid setting in c and returns an error
+// listing them, so one mistake doesn't discard the rest of the config.
+func (c *Config) disableInvalid() error {
+	return errors.Join(
+		c.Agent.disableInvalid(),
+		c.Security.PIIFiltering.disableInvalid(),
+		c.Plugins.disableInvalid(),
+	)
+}
+
+// disableInvalid drops each custom pattern that doesn't compile, can match
+// empty text, or lacks a usable placeholder prefix.
+func (c *PIIFilterConfig) disableInvalid() error {
+	var errs []error
+	valid := c.CustomPatterns[:0:0]
+	for i, p := range c.CustomPatterns {
+		if err := p.check(); err != nil {
+			where := fmt.Sprintf("security.pii_filtering.custom_patterns[%d]", i)
+			if p.Name != "" {
+				where += " (" + p.Name + ")"
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			continue
+		}
+		valid = append(valid, p)
+	}
+	if errs != nil {
+		c.CustomPatterns = valid
+	}
+	return errors.Join(errs...)
+}
+
+func (p CustomPIIPattern) check() error {
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if re.MatchString("") {
+		return fmt.Errorf("pattern %q matches empty text", p.Pattern)
+	}
+	if !piiPrefix.MatchString(p.Prefix) {
+		return fmt.Errorf("prefix %q must be upper-case letters and digits, starting with a letter", p.Prefix)
+	}
+	return nil
+}
+
+// disableInvalid resets an unknown SummaryStrategy, a negative
+// SummaryKeepRecent, or an unknown TimeZone to its default.
+func (c *AgentConfig) disableInvalid() error {
+	var errs []error
+	switch c.SummaryStrategy {
+	case "", SummaryStrategySummarize, SummaryStrategySlidingWindow:
+	default:
+		errs = append(errs, fmt.Errorf("agent.summary_strategy: unknown strategy %q (want %q or %q)", c.SummaryStrategy, SummaryStrategySummarize, SummaryStrategySlidingWindow))
+		c.SummaryStrategy = ""
+	}
+	if c.SummaryKeepRecent < 0 {
+		errs = append(errs, fmt.Errorf("agent.summary_keep_recent: must not be negative"))
+		c.SummaryKeepRecent = 0
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			errs = append(errs, fmt.Errorf("agent.time_zone: unknown time zone %q", c.TimeZone))
+			c.TimeZone = ""
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// disableInvalid fails closed: an unknown VerifyMode becomes strict, an
+// unknown Isolation becomes auto, and trusted keys that aren't base64
+// Ed25519 public keys are dropped.
+func (c *PluginsConfig) disableInvalid() error {
+	var errs []error
+	switch c.VerifyMode {
+	case "", SkillVerifyStrict:
+	default:
+		errs = append(errs, fmt.Errorf("plugins.verify_mode: unknown mode %q (want %q or empty)", c.VerifyMode, SkillVerifyStrict))
+		c.VerifyMode = SkillVerifyStrict
+	}
+	switch c.Isolation {
+	case "", SkillIsolationAuto, SkillIsolationBwrap, SkillIsolationNsjail:
+	default:
+		errs = append(errs, fmt.Errorf("plugins.isolation: unknown sandbox %q (want %q, %q, %q, or empty)",
+			c.Isolation, SkillIsolationAuto, SkillIsolationBwrap, SkillIsolationNsjail))
+		c.Isolation = SkillIsolationAuto
+	}
+	keys := c.TrustedKeys[:0:0]
+	for i, k := range c.TrustedKeys {
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			errs = append(errs, fmt.Errorf("plugins.trusted_keys[%d]: not a base64 Ed25519 public key", i))
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) != len(c.TrustedKeys) {
+		c.TrustedKeys = keys
+	}
+	return errors.Join(errs...)
+}
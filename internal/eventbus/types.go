@@ -13,10 +13,25 @@ const (
 	TopicAgentObserve    Topic = "agent_observe"
 	TopicToolCall        Topic = "tool_call"
 	TopicToolResult      Topic = "tool_result"
+	TopicToolProgress    Topic = "tool_progress"
+	TopicToolCacheHit    Topic = "tool_cache_hit"
+	TopicToolCacheMiss   Topic = "tool_cache_miss"
 	TopicLLMRequest      Topic = "llm_request"
 	TopicLLMResponse     Topic = "llm_response"
 	TopicError           Topic = "error"
 	TopicStatusChange    Topic = "status_change"
+
+	// TopicToolApprovalRequest carries an agent.ApprovalRequest for a tool
+	// call that waits for the user's decision.
+	TopicToolApprovalRequest Topic = "tool_approval_request"
+
+	// TopicSkillLog carries a skill.LogLine for each line a running skill
+	// writes to stderr.
+	TopicSkillLog Topic = "skill_log"
+
+	// TopicConversationCleared carries the key of a conversation whose
+	// history /reset deleted, so state kept for it elsewhere can go too.
+	TopicConversationCleared Topic = "conversation_cleared"
 )
 
 // Event is a message passed through the event bus.
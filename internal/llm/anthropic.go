@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -17,8 +18,11 @@ type AnthropicProvider struct {
 
 // AnthropicConfig holds configuration for the Anthropic provider.
 type AnthropicConfig struct {
-	APIKey string
-	Model  string
+	APIKey         string
+	Model          string
+	MaxRetries     int
+	ConnectTimeout time.Duration // dial + TLS handshake
+	RequestTimeout time.Duration // whole request, including generation
 }
 
 // NewAnthropicProvider creates a new Anthropic provider.
@@ -27,8 +31,16 @@ func NewAnthropicProvider(cfg AnthropicConfig) *AnthropicProvider {
 	if model == "" {
 		model = "claude-sonnet-4-5-20250514"
 	}
+	opts := []option.RequestOption{
+		option.WithAPIKey(cfg.APIKey),
+		option.WithHTTPClient(newHTTPClient(cfg.ConnectTimeout)),
+		option.WithMaxRetries(max(cfg.MaxRetries, 0)),
+	}
+	if cfg.RequestTimeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.RequestTimeout))
+	}
 	return &AnthropicProvider{
-		client:       anthropic.NewClient(option.WithAPIKey(cfg.APIKey)),
+		client:       anthropic.NewClient(opts...),
 		defaultModel: model,
 	}
 }
@@ -101,25 +113,24 @@ func (p *AnthropicProvider) StreamChat(ctx context.Context, req *ChatRequest) (<
 
 	go func() {
 		defer close(ch)
+		var acc anthropic.Message
 		for stream.Next() {
 			event := stream.Current()
-			evt := StreamEvent{}
-			switch e := event.AsAny().(type) {
-			case anthropic.ContentBlockDeltaEvent:
-				if e.Delta.Type == "text_delta" {
-					evt.ContentDelta = e.Delta.Text
-				}
-			case anthropic.MessageDeltaEvent:
-				evt.Done = true
-				if e.Usage.OutputTokens > 0 {
-					evt.Usage = &Usage{OutputTokens: int(e.Usage.OutputTokens)}
-				}
+			if err := acc.Accumulate(event); err != nil {
+				ch <- StreamEvent{Error: classifyAnthropicError(err), Done: true}
+				return
+			}
+			if e, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok && e.Delta.Type == "text_delta" {
+				ch <- StreamEvent{ContentDelta: e.Delta.Text}
 			}
-			ch <- evt
 		}
 		if err := stream.Err(); err != nil {
 			ch <- StreamEvent{Error: classifyAnthropicError(err), Done: true}
+			return
 		}
+		// Tool inputs arrive as partial JSON; report them once fully assembled.
+		resp := p.convertResponse(&acc)
+		ch <- StreamEvent{ToolCalls: resp.ToolCalls, Usage: &resp.Usage, StopReason: resp.StopReason, Metadata: &resp.Metadata, Done: true}
 	}()
 
 	return ch, nil
@@ -184,6 +195,10 @@ func (p *AnthropicProvider) convertTools(tools []ToolDefinition) []anthropic.Too
 func (p *AnthropicProvider) convertResponse(resp *anthropic.Message) *LLMResponse {
 	result := &LLMResponse{
 		StopReason: string(resp.StopReason),
+		Metadata: ResponseMetadata{
+			ID:    resp.ID,
+			Model: string(resp.Model),
+		},
 		Usage: Usage{
 			InputTokens:  int(resp.Usage.InputTokens),
 			OutputTokens: int(resp.Usage.OutputTokens),
@@ -213,6 +228,12 @@ func classifyAnthropicError(err error) *LLMError {
 	llmErr := &LLMError{Err: err, Message: msg}
 
 	switch {
+	case isConnectError(err):
+		llmErr.Type = ErrorNetwork
+	case isConnectionLost(err):
+		// Checked before the status codes: its message quotes addresses
+		// whose port numbers can look like one.
+		llmErr.Type = ErrorConnectionLost
 	case strings.Contains(lower, "401") || strings.Contains(lower, "authentication"):
 		llmErr.Type = ErrorAuth
 	case strings.Contains(lower, "429") || strings.Contains(lower, "rate_limit"):
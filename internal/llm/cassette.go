@@ -0,0 +1,219 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Cassette is a recorded sequence of provider interactions.
+type Cassette struct {
+	Provider     string        `json:"provider"`
+	Model        string        `json:"model"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single request and the response (or error) it produced.
+type Interaction struct {
+	Request  *ChatRequest   `json:"request"`
+	Response *LLMResponse   `json:"response,omitempty"`
+	Error    *CassetteError `json:"error,omitempty"`
+}
+
+// CassetteError is the serialized form of a provider error.
+type CassetteError struct {
+	Type    ErrorType `json:"type"`
+	Message string    `json:"message"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path. It is written compactly because
+// indenting would also reformat raw tool arguments and change what replays.
+func (c *Cassette) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// RecordingProvider wraps a real provider and appends every interaction to a
+// cassette file, which ReplayProvider can serve back later.
+type RecordingProvider struct {
+	inner    Provider
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingProvider records interactions with inner into the file at path.
+func NewRecordingProvider(inner Provider, path string) *RecordingProvider {
+	return &RecordingProvider{
+		inner:    inner,
+		path:     path,
+		cassette: Cassette{Provider: inner.Name(), Model: inner.DefaultModel()},
+	}
+}
+
+func (r *RecordingProvider) Name() string         { return r.inner.Name() }
+func (r *RecordingProvider) DefaultModel() string { return r.inner.DefaultModel() }
+
+func (r *RecordingProvider) Chat(ctx context.Context, req *ChatRequest) (*LLMResponse, error) {
+	resp, err := r.inner.Chat(ctx, req)
+	if saveErr := r.record(req, resp, err); saveErr != nil {
+		return nil, saveErr
+	}
+	return resp, err
+}
+
+// StreamChat passes events through and records the assembled response once
+// the stream ends.
+func (r *RecordingProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	events, err := r.inner.StreamChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent, 64)
+	go func() {
+		defer close(out)
+		resp := &LLMResponse{}
+		var text strings.Builder
+		var streamErr error
+		for evt := range events {
+			text.WriteString(evt.ContentDelta)
+			resp.ToolCalls = append(resp.ToolCalls, evt.ToolCalls...)
+			if evt.Usage != nil {
+				resp.Usage = *evt.Usage
+			}
+			if evt.StopReason != "" {
+				resp.StopReason = evt.StopReason
+			}
+			if evt.Metadata != nil {
+				resp.Metadata = *evt.Metadata
+			}
+			if evt.Error != nil {
+				streamErr = evt.Error
+			}
+			out <- evt
+		}
+		resp.Content = text.String()
+		if streamErr != nil {
+			resp = nil
+		}
+		if err := r.record(req, resp, streamErr); err != nil {
+			out <- StreamEvent{Error: err, Done: true}
+		}
+	}()
+	return out, nil
+}
+
+func (r *RecordingProvider) record(req *ChatRequest, resp *LLMResponse, err error) error {
+	interaction := Interaction{Request: req, Response: resp}
+	if err != nil {
+		ce := &CassetteError{Message: err.Error()}
+		var llmErr *LLMError
+		if errors.As(err, &llmErr) {
+			ce.Type, ce.Message = llmErr.Type, llmErr.Message
+		}
+		interaction.Error, interaction.Response = ce, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		return fmt.Errorf("save cassette: %w", err)
+	}
+	return nil
+}
+
+// ReplayProvider serves recorded responses without network access. Each
+// request is matched against the first unused interaction with an identical
+// request, so repeated identical requests replay in recorded order.
+type ReplayProvider struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayProvider loads the cassette at path.
+func NewReplayProvider(path string) (*ReplayProvider, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayProvider{
+		cassette: c,
+		used:     make([]bool, len(c.Interactions)),
+	}, nil
+}
+
+func (p *ReplayProvider) Name() string         { return p.cassette.Provider }
+func (p *ReplayProvider) DefaultModel() string { return p.cassette.Model }
+
+func (p *ReplayProvider) Chat(_ context.Context, req *ChatRequest) (*LLMResponse, error) {
+	it, err := p.match(req)
+	if err != nil {
+		return nil, err
+	}
+	if it.Error != nil {
+		return nil, &LLMError{Type: it.Error.Type, Message: it.Error.Message}
+	}
+	return it.Response, nil
+}
+
+// StreamChat replays the recorded response as a single content delta
+// followed by the final event.
+func (p *ReplayProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	resp, err := p.Chat(ctx, req)
+	ch := make(chan StreamEvent, 2)
+	if err != nil {
+		ch <- StreamEvent{Error: err, Done: true}
+	} else {
+		if resp.Content != "" {
+			ch <- StreamEvent{ContentDelta: resp.Content}
+		}
+		usage := resp.Usage
+		ch <- StreamEvent{ToolCalls: resp.ToolCalls, Usage: &usage, StopReason: resp.StopReason, Metadata: &resp.Metadata, Done: true}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (p *ReplayProvider) match(req *ChatRequest) (*Interaction, error) {
+	key, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.cassette.Interactions {
+		if p.used[i] {
+			continue
+		}
+		recorded, _ := json.Marshal(p.cassette.Interactions[i].Request)
+		if string(recorded) == string(key) {
+			p.used[i] = true
+			return &p.cassette.Interactions[i], nil
+		}
+	}
+	return nil, &LLMError{Type: ErrorInvalidInput, Message: "replay: no recorded interaction matches request"}
+}
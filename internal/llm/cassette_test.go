@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// scriptedProvider answers requests from a fixed list, in order.
+type scriptedProvider struct {
+	responses []*LLMResponse
+	errs      []error
+	calls     int
+}
+
+func (p *scriptedProvider) Chat(_ context.Context, _ *ChatRequest) (*LLMResponse, error) {
+	i := p.calls
+	p.calls++
+	return p.responses[i], p.errs[i]
+}
+
+func (p *scriptedProvider) StreamChat(context.Context, *ChatRequest) (<-chan StreamEvent, error) {
+	panic("not used")
+}
+
+func (p *scriptedProvider) Name() string         { return "scripted" }
+func (p *scriptedProvider) DefaultModel() string { return "scripted-1" }
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+
+	first := &ChatRequest{
+		Messages: []Message{{Role: "user", Content: "What's in the workspace?"}},
+		Tools:    []ToolDefinition{{Name: "shell", Parameters: json.RawMessage(`{"type": "object"}`)}},
+	}
+	second := &ChatRequest{
+		Messages: append(first.Messages,
+			Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Name: "shell", Arguments: json.RawMessage(`{"command":"ls"}`)}}},
+			Message{Role: "tool", Content: "notes.txt", ToolCallID: "1"},
+		),
+	}
+	third := &ChatRequest{Messages: []Message{{Role: "user", Content: "fail please"}}}
+
+	inner := &scriptedProvider{
+		responses: []*LLMResponse{
+			{ToolCalls: []ToolCall{{ID: "1", Name: "shell", Arguments: json.RawMessage(`{"command":"ls"}`)}}, StopReason: "tool_use"},
+			{Content: "There is one file: notes.txt", Usage: Usage{InputTokens: 42, OutputTokens: 7}},
+			nil,
+		},
+		errs: []error{nil, nil, &LLMError{Type: ErrorServerError, Message: "overloaded"}},
+	}
+	rec := NewRecordingProvider(inner, path)
+
+	var recorded []*LLMResponse
+	for _, req := range []*ChatRequest{first, second} {
+		resp, err := rec.Chat(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorded = append(recorded, resp)
+	}
+	if _, err := rec.Chat(ctx, third); err == nil {
+		t.Fatal("expected recorded error")
+	}
+
+	replay, err := NewReplayProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Name() != "scripted" || replay.DefaultModel() != "scripted-1" {
+		t.Fatalf("unexpected replay identity: %s/%s", replay.Name(), replay.DefaultModel())
+	}
+
+	// Requests are matched by content, not order
+	resp2, err := replay.Chat(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp1, err := replay.Chat(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp1, recorded[0]) || !reflect.DeepEqual(resp2, recorded[1]) {
+		t.Fatalf("replayed responses differ:\n%+v\n%+v", resp1, resp2)
+	}
+
+	_, err = replay.Chat(ctx, third)
+	if llmErr, ok := err.(*LLMError); !ok || llmErr.Type != ErrorServerError {
+		t.Fatalf("expected replayed server error, got %v", err)
+	}
+
+	// Each interaction replays once
+	if _, err := replay.Chat(ctx, first); err == nil {
+		t.Fatal("expected no match for an already replayed request")
+	}
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Embedder turns text into vectors whose cosine similarity reflects how
+// close the texts are in meaning.
+type Embedder interface {
+	// Embed returns one vector per text, in the same order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the embedding model. Vectors from different models can't
+	// be compared.
+	Model() string
+}
+
+// OpenAIEmbedder implements Embedder using the OpenAI embeddings API.
+type OpenAIEmbedder struct {
+	client openai.Client
+	model  string
+}
+
+// OpenAIEmbedderConfig holds configuration for the OpenAI embedder.
+type OpenAIEmbedderConfig struct {
+	APIKey         string
+	BaseURL        string
+	Model          string
+	RequestTimeout time.Duration
+}
+
+// NewOpenAIEmbedder creates a new OpenAI embedder.
+func NewOpenAIEmbedder(cfg OpenAIEmbedderConfig) *OpenAIEmbedder {
+	opts := []option.RequestOption{
+		option.WithAPIKey(cfg.APIKey),
+		option.WithHTTPClient(newHTTPClient(0)),
+	}
+	if cfg.RequestTimeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.RequestTimeout))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = openai.EmbeddingModelTextEmbedding3Small
+	}
+
+	return &OpenAIEmbedder{
+		client: openai.NewClient(opts...),
+		model:  model,
+	}
+}
+
+func (e *OpenAIEmbedder) Model() string { return e.model }
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	res, err := e.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model: e.model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	if len(res.Data) != len(texts) {
+		return nil, fmt.Errorf("embed: got %d vectors for %d texts", len(res.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range res.Data {
+		if d.Index < 0 || int(d.Index) >= len(texts) {
+			return nil, fmt.Errorf("embed: vector index %d out of range", d.Index)
+		}
+		v := make([]float32, len(d.Embedding))
+		for i, x := range d.Embedding {
+			v[i] = float32(x)
+		}
+		vectors[d.Index] = v
+	}
+	return vectors, nil
+}
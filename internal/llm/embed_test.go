@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIEmbed(t *testing.T) {
+	var req struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		// Out of order, to check vectors are matched up by index.
+		w.Write([]byte(`{"object": "list", "model": "text-embedding-3-small", "data": [
+			{"object": "embedding", "index": 1, "embedding": [0, 1]},
+			{"object": "embedding", "index": 0, "embedding": [0.5, 0]}
+		]}`))
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbedder(OpenAIEmbedderConfig{APIKey: "sk-test", BaseURL: srv.URL + "/v1"})
+	vectors, err := e.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Model != "text-embedding-3-small" || len(req.Input) != 2 || req.Input[0] != "first" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 0.5 || vectors[1][1] != 1 {
+		t.Fatalf("unexpected vectors: %v", vectors)
+	}
+}
@@ -2,25 +2,74 @@ package llm
 
 import (
 	"fmt"
+	"time"
 
 	"open-dan/internal/config"
 )
 
-// NewProvider creates an LLM provider from config.
+// NewProvider creates an LLM provider from config. If concurrency limits are
+// configured, the provider is wrapped in a LimitedProvider.
 func NewProvider(cfg config.LLMConfig) (Provider, error) {
+	p, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrent > 0 || len(cfg.ModelConcurrency) > 0 {
+		p = NewLimitedProvider(p, cfg.MaxConcurrent, cfg.ModelConcurrency)
+	}
+	return p, nil
+}
+
+func newProvider(cfg config.LLMConfig) (Provider, error) {
 	switch cfg.Provider {
 	case "openai", "openrouter", "local":
 		return NewOpenAIProvider(OpenAIConfig{
-			APIKey:  cfg.APIKey,
-			BaseURL: cfg.BaseURL,
-			Model:   cfg.Model,
+			APIKey:         cfg.APIKey,
+			BaseURL:        cfg.BaseURL,
+			Model:          cfg.Model,
+			MaxRetries:     cfg.MaxRetries,
+			ConnectTimeout: time.Duration(cfg.ConnectTimeoutSecs) * time.Second,
+			RequestTimeout: time.Duration(cfg.TimeoutSecs) * time.Second,
 		}), nil
 	case "anthropic":
 		return NewAnthropicProvider(AnthropicConfig{
-			APIKey: cfg.APIKey,
-			Model:  cfg.Model,
+			APIKey:         cfg.APIKey,
+			Model:          cfg.Model,
+			MaxRetries:     cfg.MaxRetries,
+			ConnectTimeout: time.Duration(cfg.ConnectTimeoutSecs) * time.Second,
+			RequestTimeout: time.Duration(cfg.TimeoutSecs) * time.Second,
 		}), nil
 	default:
 		return nil, fmt.Errorf("unknown LLM provider: %s", cfg.Provider)
 	}
 }
+
+// NewEmbedder creates an embedder for semantic recall from config.
+func NewEmbedder(cfg config.EmbeddingsConfig) (Embedder, error) {
+	switch cfg.Provider {
+	case "", "openai":
+		return NewOpenAIEmbedder(OpenAIEmbedderConfig{
+			APIKey:         cfg.APIKey,
+			BaseURL:        cfg.BaseURL,
+			Model:          cfg.Model,
+			RequestTimeout: time.Minute,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown embeddings provider: %s", cfg.Provider)
+	}
+}
+
+// NewTranscriber creates a speech-to-text transcriber from config.
+func NewTranscriber(cfg config.TranscriptionConfig) (Transcriber, error) {
+	switch cfg.Provider {
+	case "", "openai":
+		return NewWhisperTranscriber(WhisperConfig{
+			APIKey:         cfg.APIKey,
+			BaseURL:        cfg.BaseURL,
+			Model:          cfg.Model,
+			RequestTimeout: 2 * time.Minute,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown transcription provider: %s", cfg.Provider)
+	}
+}
@@ -32,9 +32,10 @@ func (f *FallbackProvider) DefaultModel() string {
 
 func (f *FallbackProvider) Chat(ctx context.Context, req *ChatRequest) (*LLMResponse, error) {
 	var lastErr error
-	for _, p := range f.providers {
-		resp, err := p.Chat(ctx, req)
+	for i, p := range f.providers {
+		resp, err := p.Chat(ctx, forProvider(req, i))
 		if err == nil {
+			servedBy(&resp.Metadata, p, i)
 			return resp, nil
 		}
 		lastErr = err
@@ -48,10 +49,10 @@ func (f *FallbackProvider) Chat(ctx context.Context, req *ChatRequest) (*LLMResp
 
 func (f *FallbackProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
 	var lastErr error
-	for _, p := range f.providers {
-		ch, err := p.StreamChat(ctx, req)
+	for i, p := range f.providers {
+		ch, err := p.StreamChat(ctx, forProvider(req, i))
 		if err == nil {
-			return ch, nil
+			return tagStream(ch, p, i), nil
 		}
 		lastErr = err
 		if !isRetryable(err) {
@@ -62,6 +63,45 @@ func (f *FallbackProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-
 	return nil, lastErr
 }
 
+// forProvider adapts req for the provider at index i. A model named in the
+// request is meant for the primary provider; fallbacks use their own default.
+func forProvider(req *ChatRequest, i int) *ChatRequest {
+	if i == 0 || req.Model == "" {
+		return req
+	}
+	r := *req
+	r.Model = ""
+	return &r
+}
+
+// servedBy records that provider p, at index i in the chain, served a reply.
+func servedBy(md *ResponseMetadata, p Provider, i int) {
+	md.Provider = p.Name()
+	md.Fallback = i > 0
+	md.Retries += i
+}
+
+// tagStream forwards events from in, adding which provider served the
+// stream to the final event.
+func tagStream(in <-chan StreamEvent, p Provider, i int) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		for evt := range in {
+			if evt.Done {
+				var md ResponseMetadata
+				if evt.Metadata != nil {
+					md = *evt.Metadata
+				}
+				servedBy(&md, p, i)
+				evt.Metadata = &md
+			}
+			out <- evt
+		}
+	}()
+	return out
+}
+
 // isRetryable returns true for errors that warrant trying a different provider.
 func isRetryable(err error) bool {
 	var llmErr *LLMError
@@ -71,7 +111,7 @@ func isRetryable(err error) bool {
 	switch llmErr.Type {
 	case ErrorAuth, ErrorInvalidInput:
 		return false // these won't succeed on retry
-	case ErrorRateLimit, ErrorServerError, ErrorTimeout, ErrorNetwork:
+	case ErrorRateLimit, ErrorServerError, ErrorTimeout, ErrorNetwork, ErrorConnectionLost:
 		return true
 	default:
 		return true
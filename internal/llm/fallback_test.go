@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+// stubProvider fails with err, or replies as the requested model, or its
+// own default.
+type stubProvider struct {
+	name  string
+	model string
+	err   error
+}
+
+func (p *stubProvider) Chat(_ context.Context, req *ChatRequest) (*LLMResponse, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	model := p.model
+	if req.Model != "" {
+		model = req.Model
+	}
+	return &LLMResponse{Content: "ok", Metadata: ResponseMetadata{Model: model}}, nil
+}
+
+func (p *stubProvider) StreamChat(context.Context, *ChatRequest) (<-chan StreamEvent, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	ch := make(chan StreamEvent, 2)
+	ch <- StreamEvent{ContentDelta: "ok"}
+	ch <- StreamEvent{Done: true, Metadata: &ResponseMetadata{Model: p.model}}
+	close(ch)
+	return ch, nil
+}
+
+func (p *stubProvider) Name() string         { return p.name }
+func (p *stubProvider) DefaultModel() string { return p.model }
+
+func TestFallbackRecordsServingProvider(t *testing.T) {
+	down := &LLMError{Type: ErrorServerError, Message: "overloaded"}
+	f := NewFallbackProvider(
+		&stubProvider{name: "openai", err: down},
+		&stubProvider{name: "anthropic", err: down},
+		&stubProvider{name: "local", model: "llama3"},
+	)
+	want := ResponseMetadata{Model: "llama3", Provider: "local", Fallback: true, Retries: 2}
+
+	resp, err := f.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Metadata != want {
+		t.Errorf("Chat metadata = %+v, want %+v", resp.Metadata, want)
+	}
+
+	events, err := f.StreamChat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var final *ResponseMetadata
+	for evt := range events {
+		if evt.Done {
+			final = evt.Metadata
+		}
+	}
+	if final == nil || *final != want {
+		t.Errorf("stream metadata = %+v, want %+v", final, want)
+	}
+}
+
+func TestFallbackPrimaryNotMarked(t *testing.T) {
+	f := NewFallbackProvider(&stubProvider{name: "openai", model: "gpt-4o"}, &stubProvider{name: "local"})
+	resp, err := f.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ResponseMetadata{Model: "gpt-4o", Provider: "openai"}
+	if resp.Metadata != want {
+		t.Errorf("metadata = %+v, want %+v", resp.Metadata, want)
+	}
+}
+
+func TestFallbackUsesOwnDefaultModel(t *testing.T) {
+	down := &LLMError{Type: ErrorServerError, Message: "overloaded"}
+	primary := &stubProvider{name: "openai", model: "gpt-4o-mini"}
+	req := &ChatRequest{Model: "gpt-4o"}
+
+	resp, _ := NewFallbackProvider(primary, &stubProvider{name: "local", model: "llama3"}).Chat(context.Background(), req)
+	if resp.Metadata.Model != "gpt-4o" {
+		t.Fatalf("primary should get the requested model, got %q", resp.Metadata.Model)
+	}
+
+	primary.err = down
+	resp, _ = NewFallbackProvider(primary, &stubProvider{name: "local", model: "llama3"}).Chat(context.Background(), req)
+	if resp.Metadata.Model != "llama3" || req.Model != "gpt-4o" {
+		t.Fatalf("fallback should use its default without changing the request, got %q (request %q)", resp.Metadata.Model, req.Model)
+	}
+}
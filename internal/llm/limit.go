@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// LimitedProvider bounds the number of in-flight requests per model, queuing
+// the rest, so parallel chats and tools don't trigger rate limits.
+type LimitedProvider struct {
+	inner        Provider
+	defaultLimit int            // applies to models without an entry in limits; 0 = unlimited
+	limits       map[string]int // per-model overrides
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// NewLimitedProvider wraps inner. Each model may have at most limits[model]
+// concurrent requests, or defaultLimit if it has no entry. Zero means unlimited.
+func NewLimitedProvider(inner Provider, defaultLimit int, limits map[string]int) *LimitedProvider {
+	return &LimitedProvider{
+		inner:        inner,
+		defaultLimit: defaultLimit,
+		limits:       limits,
+		sems:         make(map[string]chan struct{}),
+	}
+}
+
+func (p *LimitedProvider) Name() string         { return p.inner.Name() }
+func (p *LimitedProvider) DefaultModel() string { return p.inner.DefaultModel() }
+
+func (p *LimitedProvider) Chat(ctx context.Context, req *ChatRequest) (*LLMResponse, error) {
+	release, err := p.acquire(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.inner.Chat(ctx, req)
+}
+
+// StreamChat holds the slot until the underlying stream ends.
+func (p *LimitedProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	release, err := p.acquire(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	events, err := p.inner.StreamChat(ctx, req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	out := make(chan StreamEvent, cap(events))
+	go func() {
+		defer close(out)
+		defer release()
+		for evt := range events {
+			out <- evt
+		}
+	}()
+	return out, nil
+}
+
+// acquire waits for a free slot for the request's model and returns a
+// function that frees it.
+func (p *LimitedProvider) acquire(ctx context.Context, req *ChatRequest) (func(), error) {
+	sem := p.semaphore(req.Model)
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, &LLMError{Type: ErrorTimeout, Message: "waiting for a free request slot", Err: ctx.Err()}
+	}
+}
+
+func (p *LimitedProvider) semaphore(model string) chan struct{} {
+	if model == "" {
+		model = p.inner.DefaultModel()
+	}
+	limit, ok := p.limits[model]
+	if !ok {
+		limit = p.defaultLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sem, ok := p.sems[model]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		p.sems[model] = sem
+	}
+	return sem
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowProvider holds each request open and tracks peak concurrency per model.
+type slowProvider struct {
+	delay time.Duration
+
+	mu       sync.Mutex
+	inFlight map[string]int
+	peak     map[string]int
+}
+
+func (p *slowProvider) Chat(ctx context.Context, req *ChatRequest) (*LLMResponse, error) {
+	p.mu.Lock()
+	p.inFlight[req.Model]++
+	p.peak[req.Model] = max(p.peak[req.Model], p.inFlight[req.Model])
+	p.mu.Unlock()
+
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	p.inFlight[req.Model]--
+	p.mu.Unlock()
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func (p *slowProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent, 2)
+	go func() {
+		defer close(ch)
+		resp, _ := p.Chat(ctx, req)
+		ch <- StreamEvent{ContentDelta: resp.Content}
+		ch <- StreamEvent{Done: true}
+	}()
+	return ch, nil
+}
+
+func (p *slowProvider) Name() string         { return "slow" }
+func (p *slowProvider) DefaultModel() string { return "default" }
+
+func TestLimitedProviderBoundsPerModel(t *testing.T) {
+	inner := &slowProvider{delay: 30 * time.Millisecond, inFlight: map[string]int{}, peak: map[string]int{}}
+	p := NewLimitedProvider(inner, 2, map[string]int{"small": 1, "free": 0})
+
+	var wg sync.WaitGroup
+	for _, model := range []string{"big", "big", "big", "big", "big", "small", "small", "small", "free", "free", "free"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Chat(context.Background(), &ChatRequest{Model: model}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if inner.peak["big"] != 2 || inner.peak["small"] != 1 || inner.peak["free"] != 3 {
+		t.Fatalf("unexpected peak concurrency: %v", inner.peak)
+	}
+}
+
+func TestLimitedProviderModelsIndependent(t *testing.T) {
+	inner := &slowProvider{delay: 200 * time.Millisecond, inFlight: map[string]int{}, peak: map[string]int{}}
+	p := NewLimitedProvider(inner, 1, nil)
+
+	// Occupy model a's only slot
+	go p.Chat(context.Background(), &ChatRequest{Model: "a"})
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	p.Chat(context.Background(), &ChatRequest{Model: "b"})
+	if elapsed := time.Since(start); elapsed > 350*time.Millisecond {
+		t.Fatalf("model b waited %v for model a's slot", elapsed)
+	}
+
+	// A queued request for a gives up when its context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	go p.Chat(context.Background(), &ChatRequest{Model: "a"})
+	time.Sleep(5 * time.Millisecond)
+	if _, err := p.Chat(ctx, &ChatRequest{Model: "a"}); err == nil {
+		t.Fatal("expected queued request to fail when its context is done")
+	}
+}
+
+func TestLimitedProviderStreamHoldsSlot(t *testing.T) {
+	inner := &slowProvider{delay: 50 * time.Millisecond, inFlight: map[string]int{}, peak: map[string]int{}}
+	p := NewLimitedProvider(inner, 1, nil)
+
+	events, err := p.StreamChat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Chat(context.Background(), &ChatRequest{})
+	}()
+	for range events {
+	}
+	wg.Wait()
+
+	if inner.peak[""] != 1 {
+		t.Fatalf("stream and request overlapped: peak %d", inner.peak[""])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -18,15 +19,23 @@ type OpenAIProvider struct {
 
 // OpenAIConfig holds configuration for the OpenAI provider.
 type OpenAIConfig struct {
-	APIKey  string
-	BaseURL string
-	Model   string
+	APIKey         string
+	BaseURL        string
+	Model          string
+	MaxRetries     int
+	ConnectTimeout time.Duration // dial + TLS handshake
+	RequestTimeout time.Duration // whole request, including generation
 }
 
 // NewOpenAIProvider creates a new OpenAI provider.
 func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
 	opts := []option.RequestOption{
 		option.WithAPIKey(cfg.APIKey),
+		option.WithHTTPClient(newHTTPClient(cfg.ConnectTimeout)),
+		option.WithMaxRetries(max(cfg.MaxRetries, 0)),
+	}
+	if cfg.RequestTimeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.RequestTimeout))
 	}
 	if cfg.BaseURL != "" {
 		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
@@ -105,27 +114,21 @@ func (p *OpenAIProvider) StreamChat(ctx context.Context, req *ChatRequest) (<-ch
 
 	go func() {
 		defer close(ch)
+		var acc openai.ChatCompletionAccumulator
 		for stream.Next() {
 			chunk := stream.Current()
-			evt := StreamEvent{}
-			if len(chunk.Choices) > 0 {
-				delta := chunk.Choices[0].Delta
-				evt.ContentDelta = delta.Content
-				if chunk.Choices[0].FinishReason != "" {
-					evt.Done = true
-				}
-			}
-			if chunk.Usage.TotalTokens > 0 {
-				evt.Usage = &Usage{
-					InputTokens:  int(chunk.Usage.PromptTokens),
-					OutputTokens: int(chunk.Usage.CompletionTokens),
-				}
+			acc.AddChunk(chunk)
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+				ch <- StreamEvent{ContentDelta: chunk.Choices[0].Delta.Content}
 			}
-			ch <- evt
 		}
 		if err := stream.Err(); err != nil {
 			ch <- StreamEvent{Error: classifyOpenAIError(err), Done: true}
+			return
 		}
+		// Tool calls arrive in fragments; report them once fully assembled.
+		resp := p.convertResponse(&acc.ChatCompletion)
+		ch <- StreamEvent{ToolCalls: resp.ToolCalls, Usage: &resp.Usage, StopReason: resp.StopReason, Metadata: &resp.Metadata, Done: true}
 	}()
 
 	return ch, nil
@@ -200,6 +203,11 @@ func (p *OpenAIProvider) convertResponse(resp *openai.ChatCompletion) *LLMRespon
 			InputTokens:  int(resp.Usage.PromptTokens),
 			OutputTokens: int(resp.Usage.CompletionTokens),
 		},
+		Metadata: ResponseMetadata{
+			ID:                resp.ID,
+			Model:             resp.Model,
+			SystemFingerprint: resp.SystemFingerprint,
+		},
 	}
 
 	if len(resp.Choices) > 0 {
@@ -225,6 +233,12 @@ func classifyOpenAIError(err error) *LLMError {
 	llmErr := &LLMError{Err: err, Message: msg}
 
 	switch {
+	case isConnectError(err):
+		llmErr.Type = ErrorNetwork
+	case isConnectionLost(err):
+		// Checked before the status codes: its message quotes addresses
+		// whose port numbers can look like one.
+		llmErr.Type = ErrorConnectionLost
 	case strings.Contains(lower, "401") || strings.Contains(lower, "403") || strings.Contains(lower, "unauthorized"):
 		llmErr.Type = ErrorAuth
 	case strings.Contains(lower, "429") || strings.Contains(lower, "rate limit"):
@@ -1,6 +1,9 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // Provider is the interface all LLM backends must implement.
 type Provider interface {
@@ -34,3 +37,19 @@ func (e *LLMError) Error() string {
 func (e *LLMError) Unwrap() error {
 	return e.Err
 }
+
+// IsTransient reports whether err is a temporary failure (network, lost
+// connection, server, or timeout) that may succeed if the same request is
+// repeated.
+func IsTransient(err error) bool {
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	switch llmErr.Type {
+	case ErrorNetwork, ErrorConnectionLost, ErrorServerError, ErrorTimeout:
+		return true
+	default:
+		return false
+	}
+}
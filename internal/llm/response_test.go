@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+func TestOpenAIResponseMetadata(t *testing.T) {
+	var completion openai.ChatCompletion
+	err := json.Unmarshal([]byte(`{
+		"id": "chatcmpl-abc123",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "gpt-4o-mini-2024-07-18",
+		"system_fingerprint": "fp_44709d6fcb",
+		"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+		"usage": {"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6}
+	}`), &completion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := (&OpenAIProvider{}).convertResponse(&completion)
+	want := ResponseMetadata{ID: "chatcmpl-abc123", Model: "gpt-4o-mini-2024-07-18", SystemFingerprint: "fp_44709d6fcb"}
+	if resp.Metadata != want {
+		t.Fatalf("got %+v, want %+v", resp.Metadata, want)
+	}
+	if resp.Content != "Hi" || resp.StopReason != "stop" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestAnthropicResponseMetadata(t *testing.T) {
+	var msg anthropic.Message
+	err := json.Unmarshal([]byte(`{
+		"id": "msg_01XFDUDYJgAACzvnptvVoYEL",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet-4-5",
+		"content": [{"type": "text", "text": "Hi"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 5, "output_tokens": 1}
+	}`), &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := (&AnthropicProvider{}).convertResponse(&msg)
+	want := ResponseMetadata{ID: "msg_01XFDUDYJgAACzvnptvVoYEL", Model: "claude-sonnet-4-5"}
+	if resp.Metadata != want {
+		t.Fatalf("got %+v, want %+v", resp.Metadata, want)
+	}
+}
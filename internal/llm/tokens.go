@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// TokenCounter is implemented by providers that can count tokens the way
+// their models do. Use CountTokens, which falls back to an estimate for
+// providers and models without a known tokenizer.
+type TokenCounter interface {
+	// CountTokens returns the number of tokens text takes up for model, or
+	// the provider's default model if model is empty.
+	CountTokens(model, text string) int
+}
+
+// CountTokens counts text's tokens for model with p's tokenizer, or
+// estimates them if p has none.
+func CountTokens(p Provider, model, text string) int {
+	if tc, ok := p.(TokenCounter); ok {
+		return tc.CountTokens(model, text)
+	}
+	return EstimateTokens(text)
+}
+
+// EstimateTokens is the rough count used when no tokenizer is known:
+// about 4 bytes of text per token.
+func EstimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// BPE encodings, as named by tiktoken.
+const (
+	encodingO200K  = "o200k_base"
+	encodingCL100K = "cl100k_base"
+)
+
+// openAIEncoding returns the encoding OpenAI's model uses, or "" if model
+// isn't a known OpenAI model. Routed names such as "openai/gpt-4o" are
+// matched on the part after the slash.
+func openAIEncoding(model string) string {
+	model = strings.ToLower(model[strings.LastIndex(model, "/")+1:])
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "chatgpt-", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return encodingO200K
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5"} {
+		if strings.HasPrefix(model, prefix) {
+			return encodingCL100K
+		}
+	}
+	return ""
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*tiktoken.Tiktoken{}
+	loaderOnce  sync.Once
+)
+
+// countBPE counts text's tokens with the named encoding. The vocabulary is
+// embedded in the binary and loaded on first use; if that fails the count
+// is estimated instead.
+func countBPE(encoding, text string) int {
+	loaderOnce.Do(func() { tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader()) })
+
+	encodingsMu.Lock()
+	enc, ok := encodings[encoding]
+	if !ok {
+		var err error
+		if enc, err = tiktoken.GetEncoding(encoding); err != nil {
+			log.Printf("[llm] tokenizer %s unavailable, estimating tokens: %v", encoding, err)
+		}
+		encodings[encoding] = enc // nil records the failure
+	}
+	encodingsMu.Unlock()
+
+	if enc == nil {
+		return EstimateTokens(text)
+	}
+	return len(enc.EncodeOrdinary(text))
+}
+
+// CountTokens uses OpenAI's tokenizer for OpenAI models. Other models served
+// through OpenAI-compatible APIs, such as local ones, are estimated.
+func (p *OpenAIProvider) CountTokens(model, text string) int {
+	if model == "" {
+		model = p.defaultModel
+	}
+	if enc := openAIEncoding(model); enc != "" {
+		return countBPE(enc, text)
+	}
+	if strings.Contains(strings.ToLower(model), "claude") {
+		return countBPE(encodingCL100K, text)
+	}
+	return EstimateTokens(text)
+}
+
+// CountTokens approximates Claude's tokenizer, which isn't public, with
+// cl100k_base. That is far closer than the byte estimate for code and
+// non-English text.
+func (p *AnthropicProvider) CountTokens(_, text string) int {
+	return countBPE(encodingCL100K, text)
+}
+
+// CountTokens counts with the primary provider, whose context window is
+// the one that matters in the normal case.
+func (f *FallbackProvider) CountTokens(model, text string) int {
+	if len(f.providers) == 0 {
+		return EstimateTokens(text)
+	}
+	return CountTokens(f.providers[0], model, text)
+}
+
+func (p *LimitedProvider) CountTokens(model, text string) int {
+	return CountTokens(p.inner, model, text)
+}
+
+func (r *RecordingProvider) CountTokens(model, text string) int {
+	return CountTokens(r.inner, model, text)
+}
@@ -0,0 +1,28 @@
+package llm
+
+import "testing"
+
+func TestCountTokensPicksTokenizerByModel(t *testing.T) {
+	const chinese = "今天天气很好，我们去公园散步吧。"
+	const code = `func main() { fmt.Println("hi") }`
+	openai := &OpenAIProvider{defaultModel: "gpt-4o"}
+
+	for _, tc := range []struct {
+		p           Provider
+		model, text string
+		want        int
+	}{
+		{openai, "", chinese, 12},                   // default model, o200k_base
+		{openai, "gpt-4", chinese, 20},              // cl100k_base
+		{openai, "openai/gpt-4-turbo", chinese, 20}, // routed name
+		{openai, "o3-mini", code, 10},
+		{openai, "llama3.1:8b", code, len(code) / 4}, // unknown: estimated
+		{&AnthropicProvider{}, "claude-sonnet-4", code, 10},
+		{NewLimitedProvider(openai, 1, nil), "gpt-4", chinese, 20},
+		{&stubProvider{}, "gpt-4o", chinese, len(chinese) / 4}, // no tokenizer
+	} {
+		if got := CountTokens(tc.p, tc.model, tc.text); got != tc.want {
+			t.Errorf("%T %q: got %d tokens, want %d", tc.p, tc.model, got, tc.want)
+		}
+	}
+}
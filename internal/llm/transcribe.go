@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Transcriber turns recorded speech into text.
+type Transcriber interface {
+	// Transcribe returns the text spoken in audio. filename tells the
+	// service the audio format, e.g. "voice.ogg".
+	Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error)
+}
+
+// WhisperTranscriber implements Transcriber using the OpenAI audio API.
+type WhisperTranscriber struct {
+	client openai.Client
+	model  string
+}
+
+// WhisperConfig holds configuration for the Whisper transcriber.
+type WhisperConfig struct {
+	APIKey         string
+	BaseURL        string
+	Model          string
+	RequestTimeout time.Duration
+}
+
+// NewWhisperTranscriber creates a new Whisper transcriber.
+func NewWhisperTranscriber(cfg WhisperConfig) *WhisperTranscriber {
+	opts := []option.RequestOption{
+		option.WithAPIKey(cfg.APIKey),
+		option.WithHTTPClient(newHTTPClient(0)),
+	}
+	if cfg.RequestTimeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.RequestTimeout))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = openai.AudioModelWhisper1
+	}
+
+	return &WhisperTranscriber{
+		client: openai.NewClient(opts...),
+		model:  model,
+	}
+}
+
+func (w *WhisperTranscriber) Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error) {
+	res, err := w.client.Audio.Transcriptions.New(ctx, openai.AudioTranscriptionNewParams{
+		File:  openai.File(audio, filename, ""),
+		Model: w.model,
+	})
+	if err != nil {
+		return "", fmt.Errorf("transcribe: %w", err)
+	}
+	return strings.TrimSpace(res.Text), nil
+}
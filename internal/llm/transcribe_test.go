@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWhisperTranscribe(t *testing.T) {
+	var model, audio, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		model = r.FormValue("model")
+		if f, _, err := r.FormFile("file"); err == nil {
+			b, _ := io.ReadAll(f)
+			audio = string(b)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text": "  hello there \n"}`))
+	}))
+	defer srv.Close()
+
+	w := NewWhisperTranscriber(WhisperConfig{APIKey: "sk-test", BaseURL: srv.URL + "/v1"})
+	text, err := w.Transcribe(context.Background(), strings.NewReader("OggS data"), "voice.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "hello there" {
+		t.Fatalf("expected trimmed transcript, got %q", text)
+	}
+	if model != "whisper-1" || audio != "OggS data" || auth != "Bearer sk-test" {
+		t.Fatalf("unexpected request: model=%q audio=%q auth=%q", model, audio, auth)
+	}
+}
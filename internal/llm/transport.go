@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const defaultConnectTimeout = 10 * time.Second
+
+// dial opens connections for newHTTPClient; tests replace it.
+var dial = (*net.Dialer).DialContext
+
+// newHTTPClient returns a client whose dial and TLS handshake are bounded by
+// connectTimeout, independently of the (much longer) generation timeout.
+func newHTTPClient(connectTimeout time.Duration) *http.Client {
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(dialer, ctx, network, addr)
+	}
+	transport.TLSHandshakeTimeout = connectTimeout
+	return &http.Client{Transport: transport}
+}
+
+// isConnectError reports whether err happened while establishing the
+// connection (DNS, dial, or refused), before any response was received.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// isConnectionLost reports whether err is a failed read or write on an
+// established connection, such as a reset mid-response.
+func isConnectionLost(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "read" || opErr.Op == "write")
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// stubDialHang makes every dial hang until the dialer's timeout, like a host
+// that never answers the TCP handshake, without touching the network.
+func stubDialHang(t *testing.T) {
+	t.Helper()
+	orig := dial
+	dial = func(d *net.Dialer, ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+		<-ctx.Done()
+		return nil, &net.OpError{Op: "dial", Net: network, Err: ctx.Err()}
+	}
+	t.Cleanup(func() { dial = orig })
+}
+
+func TestConnectTimeoutOpenAI(t *testing.T) {
+	stubDialHang(t)
+	p := NewOpenAIProvider(OpenAIConfig{
+		APIKey:         "test",
+		BaseURL:        "http://llm.test/v1",
+		ConnectTimeout: 200 * time.Millisecond,
+		RequestTimeout: 30 * time.Second,
+	})
+	assertFastNetworkError(t, func(ctx context.Context) error {
+		_, err := p.Chat(ctx, &ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}, MaxTokens: 8})
+		return err
+	})
+}
+
+func TestClassifyDialErrorAsNetwork(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}
+	wrapped := fmt.Errorf("Post \"https://api.example.com\": %w", dialErr)
+
+	if got := classifyAnthropicError(wrapped).Type; got != ErrorNetwork {
+		t.Fatalf("anthropic: expected ErrorNetwork, got %v", got)
+	}
+	if got := classifyOpenAIError(wrapped).Type; got != ErrorNetwork {
+		t.Fatalf("openai: expected ErrorNetwork, got %v", got)
+	}
+
+	// A reset mid-response is a lost connection, not a rate limit, even when
+	// a port contains "429"
+	resetErr := &net.OpError{
+		Op:     "read",
+		Net:    "tcp",
+		Source: &net.TCPAddr{IP: net.IPv4(192, 168, 1, 5), Port: 54290},
+		Addr:   &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 80},
+		Err:    errors.New("connection reset by peer"),
+	}
+	if got := classifyAnthropicError(resetErr).Type; got != ErrorConnectionLost {
+		t.Fatalf("anthropic: expected ErrorConnectionLost for %v, got %v", resetErr, got)
+	}
+	if got := classifyOpenAIError(resetErr).Type; got != ErrorConnectionLost {
+		t.Fatalf("openai: expected ErrorConnectionLost for %v, got %v", resetErr, got)
+	}
+	if !IsTransient(classifyOpenAIError(resetErr)) {
+		t.Fatal("expected a lost connection to be transient")
+	}
+
+	// A timeout after the connection is up is still a generation timeout
+	if got := classifyOpenAIError(context.DeadlineExceeded).Type; got != ErrorTimeout {
+		t.Fatalf("expected ErrorTimeout, got %v", got)
+	}
+}
+
+func assertFastNetworkError(t *testing.T, call func(ctx context.Context) error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := call(ctx)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+	if elapsed > 3*time.Second {
+		t.Fatalf("dial timeout did not fire quickly: %v", elapsed)
+	}
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) || llmErr.Type != ErrorNetwork {
+		t.Fatalf("expected ErrorNetwork, got %v", err)
+	}
+}
@@ -8,6 +8,8 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Metadata identifies the provider response an assistant message came from.
+	Metadata *ResponseMetadata `json:"metadata,omitempty"`
 }
 
 // ToolDefinition describes a tool available to the LLM.
@@ -26,10 +28,31 @@ type ToolCall struct {
 
 // LLMResponse is the response from an LLM provider.
 type LLMResponse struct {
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	Usage      Usage      `json:"usage"`
-	StopReason string     `json:"stop_reason"`
+	Content    string           `json:"content"`
+	ToolCalls  []ToolCall       `json:"tool_calls,omitempty"`
+	Usage      Usage            `json:"usage"`
+	StopReason string           `json:"stop_reason"`
+	Metadata   ResponseMetadata `json:"metadata"`
+}
+
+// ResponseMetadata holds provider-side identifiers for a response, used to
+// correlate replies with provider logs and backend changes.
+type ResponseMetadata struct {
+	ID                string `json:"id,omitempty"`                 // OpenAI completion ID or Anthropic message ID
+	Model             string `json:"model,omitempty"`              // model that actually served the request
+	SystemFingerprint string `json:"system_fingerprint,omitempty"` // OpenAI backend configuration
+	// Provider names the provider that served the reply. Fallback is set when
+	// it wasn't the primary one, and Retries counts the failed attempts
+	// (earlier providers and whole-message retries) before it succeeded.
+	Provider string `json:"provider,omitempty"`
+	Fallback bool   `json:"fallback,omitempty"`
+	Retries  int    `json:"retries,omitempty"`
+}
+
+// Truncated reports whether the response was cut off by the max_tokens
+// limit ("max_tokens" from Anthropic, "length" from OpenAI).
+func (r *LLMResponse) Truncated() bool {
+	return r.StopReason == "max_tokens" || r.StopReason == "length"
 }
 
 // Usage tracks token consumption.
@@ -50,22 +73,25 @@ type ChatRequest struct {
 
 // StreamEvent represents a chunk in a streaming response.
 type StreamEvent struct {
-	ContentDelta string     `json:"content_delta,omitempty"`
-	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
-	Usage        *Usage     `json:"usage,omitempty"`
-	Done         bool       `json:"done"`
-	Error        error      `json:"-"`
+	ContentDelta string            `json:"content_delta,omitempty"`
+	ToolCalls    []ToolCall        `json:"tool_calls,omitempty"`
+	Usage        *Usage            `json:"usage,omitempty"`
+	StopReason   string            `json:"stop_reason,omitempty"` // set on the final event
+	Metadata     *ResponseMetadata `json:"metadata,omitempty"`    // set on the final event
+	Done         bool              `json:"done"`
+	Error        error             `json:"-"`
 }
 
 // ErrorType classifies LLM errors for fallback decisions.
 type ErrorType int
 
 const (
-	ErrorUnknown       ErrorType = iota
-	ErrorRateLimit               // 429
-	ErrorAuth                    // 401/403
-	ErrorInvalidInput            // 400
-	ErrorServerError             // 500+
-	ErrorTimeout                 // context deadline exceeded
-	ErrorNetwork                 // connection refused, DNS, etc.
+	ErrorUnknown        ErrorType = iota
+	ErrorRateLimit                // 429
+	ErrorAuth                     // 401/403
+	ErrorInvalidInput             // 400
+	ErrorServerError              // 500+
+	ErrorTimeout                  // context deadline exceeded
+	ErrorNetwork                  // connection refused, DNS, etc.
+	ErrorConnectionLost           // connection reset or closed mid-request
 )
@@ -0,0 +1,387 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"open-dan/internal/llm"
+)
+
+// ErrNoEmbedder is returned by SearchSimilar when no embedder is set.
+var ErrNoEmbedder = errors.New("semantic search is not configured")
+
+const (
+	defaultEmbedBatch = 16
+	// embedDelay is how long a partial batch waits for more messages.
+	embedDelay   = 2 * time.Second
+	embedTimeout = time.Minute
+	// maxEmbedRunes keeps a message within the embedding model's input limit.
+	maxEmbedRunes = 8000
+	// similarPreviewLen caps the snippet of a SearchSimilar result.
+	similarPreviewLen = 2 * snippetRadius
+)
+
+// embedJob is a saved message waiting to be embedded.
+type embedJob struct {
+	id     int64
+	chatID string
+	text   string
+}
+
+// embedQueue batches saved messages for the embedder and passes the vectors
+// to save. Embedding runs in the background, so SaveMessage never waits on
+// the network; a failed batch is logged and skipped.
+type embedQueue struct {
+	save func(ctx context.Context, model string, jobs []embedJob, vectors [][]float32) error
+
+	mu        sync.Mutex
+	embedder  llm.Embedder
+	batchSize int
+	pending   []embedJob
+	timer     *time.Timer
+	running   sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newEmbedQueue(save func(ctx context.Context, model string, jobs []embedJob, vectors [][]float32) error) *embedQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &embedQueue{save: save, ctx: ctx, cancel: cancel}
+}
+
+// set replaces the embedder. A nil embedder turns embedding off and drops
+// messages still waiting.
+func (q *embedQueue) set(e llm.Embedder, batchSize int) {
+	if batchSize <= 0 {
+		batchSize = defaultEmbedBatch
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.embedder, q.batchSize = e, batchSize
+	if e == nil {
+		q.stopTimer()
+		q.pending = nil
+	}
+}
+
+func (q *embedQueue) current() llm.Embedder {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.embedder
+}
+
+// add queues msg for embedding if it is a user or assistant message with
+// text. Tool output is left out; it is mostly noise to search by meaning.
+func (q *embedQueue) add(id int64, chatID string, msg llm.Message) {
+	if msg.Role != "user" && msg.Role != "assistant" {
+		return
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return
+	}
+	if r := []rune(text); len(r) > maxEmbedRunes {
+		text = string(r[:maxEmbedRunes])
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.embedder == nil {
+		return
+	}
+	q.pending = append(q.pending, embedJob{id: id, chatID: chatID, text: text})
+	if len(q.pending) >= q.batchSize {
+		q.send()
+	} else if q.timer == nil {
+		q.timer = time.AfterFunc(embedDelay, func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.timer = nil
+			q.send()
+		})
+	}
+}
+
+// send starts embedding the pending batch. q.mu must be held.
+func (q *embedQueue) send() {
+	q.stopTimer()
+	if len(q.pending) == 0 || q.embedder == nil {
+		return
+	}
+	batch, e := q.pending, q.embedder
+	q.pending = nil
+
+	q.running.Add(1)
+	go func() {
+		defer q.running.Done()
+		ctx, cancel := context.WithTimeout(q.ctx, embedTimeout)
+		defer cancel()
+
+		texts := make([]string, len(batch))
+		for i, j := range batch {
+			texts[i] = j.text
+		}
+		vectors, err := e.Embed(ctx, texts)
+		if err == nil && len(vectors) != len(batch) {
+			err = fmt.Errorf("got %d vectors for %d messages", len(vectors), len(batch))
+		}
+		if err == nil {
+			err = q.save(ctx, e.Model(), batch, vectors)
+		}
+		if err != nil {
+			log.Printf("[memory] failed to embed %d messages: %v", len(batch), err)
+		}
+	}()
+}
+
+// stopTimer cancels a pending delayed send. q.mu must be held.
+func (q *embedQueue) stopTimer() {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+// flush embeds waiting messages now and waits for every batch to be saved.
+func (q *embedQueue) flush() {
+	q.mu.Lock()
+	q.send()
+	q.mu.Unlock()
+	q.running.Wait()
+}
+
+// close drops waiting messages, cancels batches in flight, and waits for
+// them to finish.
+func (q *embedQueue) close() {
+	q.mu.Lock()
+	q.stopTimer()
+	q.pending = nil
+	q.embedder = nil
+	q.mu.Unlock()
+	q.cancel()
+	q.running.Wait()
+}
+
+// SetEmbedder turns on semantic search: messages saved from now on are
+// embedded in batches of batchSize (0 uses 16) in the background. A nil
+// embedder turns it off.
+func (m *SQLiteMemory) SetEmbedder(e llm.Embedder, batchSize int) {
+	m.embeds.set(e, batchSize)
+}
+
+// saveEmbeddings stores the vectors of a batch, skipping messages deleted
+// while it was being embedded.
+func (m *SQLiteMemory) saveEmbeddings(ctx context.Context, model string, jobs []embedJob, vectors [][]float32) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, j := range jobs {
+		vector, err := m.seal(encodeVector(vectors[i]))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR REPLACE INTO embeddings (message_id, chat_id, model, vector)
+			 SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM messages WHERE id = ?)`,
+			j.id, j.chatID, model, vector, j.id,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SearchSimilar returns chatID's messages closest in meaning to query, most
+// similar first, with the cosine similarity as Rank. Only messages embedded
+// by the current embedder's model are considered. It returns ErrNoEmbedder
+// if no embedder is set.
+func (m *SQLiteMemory) SearchSimilar(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	e := m.embeds.current()
+	if e == nil {
+		return nil, ErrNoEmbedder
+	}
+	query = strings.TrimSpace(query)
+	if chatID == "" || query == "" {
+		return nil, nil
+	}
+	target, err := embedQuery(ctx, e, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT e.vector, m.role, m.content FROM embeddings e JOIN messages m ON m.id = e.message_id
+		WHERE e.chat_id = ? AND e.model = ?`,
+		chatID, e.Model(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var vector, content string
+		r := SearchResult{ChatID: chatID}
+		if err := rows.Scan(&vector, &r.Role, &content); err != nil {
+			return nil, err
+		}
+		if vector, err = m.open(vector); err != nil {
+			return nil, err
+		}
+		v, err := decodeVector(vector)
+		if err != nil {
+			return nil, err
+		}
+		if content, err = m.open(content); err != nil {
+			return nil, err
+		}
+		r.Snippet = similarSnippet(content)
+		r.Rank = cosine(target, v)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return topSimilar(results, limit), nil
+}
+
+// SetEmbedder turns on semantic search; see SQLiteMemory.SetEmbedder.
+func (m *InMemory) SetEmbedder(e llm.Embedder, batchSize int) {
+	m.embeds.set(e, batchSize)
+}
+
+func (m *InMemory) saveEmbeddings(_ context.Context, model string, jobs []embedJob, vectors [][]float32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, j := range jobs {
+		if !m.hasMessage(j.chatID, j.id) {
+			continue
+		}
+		m.vectors[j.id] = storedVector{chatID: j.chatID, model: model, vector: vectors[i]}
+	}
+	return nil
+}
+
+// hasMessage reports whether message id is still stored. m.mu must be held.
+func (m *InMemory) hasMessage(chatID string, id int64) bool {
+	stored := m.messages[chatID]
+	i := sort.Search(len(stored), func(i int) bool { return stored[i].id >= id })
+	return i < len(stored) && stored[i].id == id
+}
+
+// SearchSimilar returns chatID's messages closest in meaning to query.
+func (m *InMemory) SearchSimilar(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	e := m.embeds.current()
+	if e == nil {
+		return nil, ErrNoEmbedder
+	}
+	query = strings.TrimSpace(query)
+	if chatID == "" || query == "" {
+		return nil, nil
+	}
+	target, err := embedQuery(ctx, e, query)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var results []SearchResult
+	for _, s := range m.messages[chatID] {
+		v, ok := m.vectors[s.id]
+		if !ok || v.model != e.Model() {
+			continue
+		}
+		results = append(results, SearchResult{
+			ChatID:  chatID,
+			Role:    s.msg.Role,
+			Snippet: similarSnippet(s.msg.Content),
+			Rank:    cosine(target, v.vector),
+		})
+	}
+	return topSimilar(results, limit), nil
+}
+
+func embedQuery(ctx context.Context, e llm.Embedder, query string) ([]float32, error) {
+	vectors, err := e.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embed query: got %d vectors", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// topSimilar sorts results by similarity and keeps the best limit (0 keeps 20).
+func topSimilar(results []SearchResult, limit int) []SearchResult {
+	if limit <= 0 {
+		limit = 20
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// similarSnippet shortens a matched message. A semantic match has no matched
+// words to centre on, so it shows the start.
+func similarSnippet(content string) string {
+	if r := []rune(content); len(r) > similarPreviewLen {
+		return string(r[:similarPreviewLen]) + "..."
+	}
+	return content
+}
+
+// encodeVector packs v as base64 little-endian float32s.
+func encodeVector(v []float32) string {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func decodeVector(s string) ([]float32, error) {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(buf)%4 != 0 {
+		return nil, fmt.Errorf("corrupt embedding vector")
+	}
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
+
+// cosine returns the cosine similarity of a and b, or 0 if they differ in
+// length or either is all zeros.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
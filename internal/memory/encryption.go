@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"open-dan/internal/security"
+)
+
+// encPrefix marks a column value as AES-GCM ciphertext. Values without it are
+// plaintext rows written before encryption was enabled.
+const encPrefix = "enc:v1:"
+
+// plainPrefix escapes plaintext that could be mistaken for ciphertext: seal
+// writes any value starting with "enc:" behind it when there is no key, so
+// a message that happens to begin with encPrefix still reads back as typed.
+const plainPrefix = "enc:plain:"
+
+// ErrLocked is returned when encrypted rows are read without a key.
+var ErrLocked = errors.New("memory is encrypted and locked")
+
+// Unlock sets the key used to encrypt message content and summaries.
+// Plaintext rows left over from before encryption are encrypted in place,
+// and the full-text index is dropped since it would hold plaintext copies;
+// setupFTS leaves it dropped on later opens.
+func (m *SQLiteMemory) Unlock(key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("memory key must be 32 bytes, got %d", len(key))
+	}
+
+	m.mu.Lock()
+	m.key = key
+	m.mu.Unlock()
+
+	if m.fts {
+		if _, err := m.db.Exec(`DROP TABLE messages_fts`); err != nil {
+			return fmt.Errorf("drop search index: %w", err)
+		}
+		m.fts = false
+	}
+
+	n, err := m.encryptPlaintext(context.Background())
+	if err != nil {
+		return fmt.Errorf("encrypt existing rows: %w", err)
+	}
+	if n > 0 {
+		log.Printf("[memory] encrypted %d existing rows", n)
+	}
+	return nil
+}
+
+func (m *SQLiteMemory) encrypted() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.key != nil
+}
+
+// seal encrypts a column value. Without a key it is returned unchanged.
+func (m *SQLiteMemory) seal(s string) (string, error) {
+	m.mu.RLock()
+	key := m.key
+	m.mu.RUnlock()
+	if key == nil {
+		if strings.HasPrefix(s, "enc:") {
+			return plainPrefix + s, nil
+		}
+		return s, nil
+	}
+	enc, err := security.Encrypt([]byte(s), key)
+	if err != nil {
+		return "", err
+	}
+	return encPrefix + enc, nil
+}
+
+// open decrypts a column value written by seal. Plaintext values pass through.
+func (m *SQLiteMemory) open(s string) (string, error) {
+	if strings.HasPrefix(s, plainPrefix) {
+		return strings.TrimPrefix(s, plainPrefix), nil
+	}
+	if !strings.HasPrefix(s, encPrefix) {
+		return s, nil
+	}
+	m.mu.RLock()
+	key := m.key
+	m.mu.RUnlock()
+	if key == nil {
+		return "", ErrLocked
+	}
+	plain, err := security.Decrypt(strings.TrimPrefix(s, encPrefix), key)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// encryptPlaintext rewrites every plaintext content, summary, fact, scratch
+// value, note, embedding vector, persisted PII value, and per-chat system
+// prompt.
+func (m *SQLiteMemory) encryptPlaintext(ctx context.Context) (int, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	type row struct {
+		key   any
+		value string
+	}
+	tables := []struct {
+		query, update string
+	}{
+		{`SELECT id, content FROM messages WHERE content NOT LIKE 'enc:v1:%'`, `UPDATE messages SET content = ? WHERE id = ?`},
+		{`SELECT chat_id, summary FROM summaries WHERE summary NOT LIKE 'enc:v1:%'`, `UPDATE summaries SET summary = ? WHERE chat_id = ?`},
+		{`SELECT placeholder, original FROM pii_mappings WHERE original NOT LIKE 'enc:v1:%'`, `UPDATE pii_mappings SET original = ? WHERE placeholder = ?`},
+		{`SELECT rowid, value FROM facts WHERE value NOT LIKE 'enc:v1:%'`, `UPDATE facts SET value = ? WHERE rowid = ?`},
+		{`SELECT rowid, value FROM kv WHERE value NOT LIKE 'enc:v1:%'`, `UPDATE kv SET value = ? WHERE rowid = ?`},
+		{`SELECT rowid, value FROM notes WHERE value NOT LIKE 'enc:v1:%'`, `UPDATE notes SET value = ? WHERE rowid = ?`},
+		{`SELECT message_id, vector FROM embeddings WHERE vector NOT LIKE 'enc:v1:%'`, `UPDATE embeddings SET vector = ? WHERE message_id = ?`},
+		{`SELECT chat_id, system_prompt FROM chat_settings WHERE system_prompt <> '' AND system_prompt NOT LIKE 'enc:v1:%'`, `UPDATE chat_settings SET system_prompt = ? WHERE chat_id = ?`},
+	}
+
+	total := 0
+	for _, t := range tables {
+		rows, err := tx.QueryContext(ctx, t.query)
+		if err != nil {
+			return 0, err
+		}
+		var pending []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.key, &r.value); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			pending = append(pending, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for _, r := range pending {
+			sealed, err := m.seal(strings.TrimPrefix(r.value, plainPrefix))
+			if err != nil {
+				return 0, err
+			}
+			if _, err := tx.ExecContext(ctx, t.update, sealed, r.key); err != nil {
+				return 0, err
+			}
+		}
+		total += len(pending)
+	}
+
+	return total, tx.Commit()
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"open-dan/internal/llm"
+)
+
+// Conversation is the JSON export format for a single chat.
+type Conversation struct {
+	ChatID   string        `json:"chat_id"`
+	Summary  string        `json:"summary,omitempty"`
+	Messages []llm.Message `json:"messages"`
+}
+
+// ExportConversation renders the full stored history of a chat as
+// "markdown" or "json".
+func (m *SQLiteMemory) ExportConversation(ctx context.Context, chatID, format string) ([]byte, error) {
+	messages, err := m.GetHistory(ctx, chatID, -1) // LIMIT -1: no limit
+	if err != nil {
+		return nil, err
+	}
+	summary, err := m.GetSummary(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	return exportConversation(Conversation{ChatID: chatID, Summary: summary, Messages: messages}, format)
+}
+
+func exportConversation(conv Conversation, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		if conv.Messages == nil {
+			conv.Messages = []llm.Message{}
+		}
+		return json.MarshalIndent(conv, "", "  ")
+	case "markdown", "md":
+		return exportMarkdown(conv), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// exportMarkdown writes each message under a role heading, with tool calls
+// and tool results as fenced code blocks.
+func exportMarkdown(conv Conversation) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Conversation %s\n\n", conv.ChatID)
+	if conv.Summary != "" {
+		fmt.Fprintf(&b, "> **Summary of earlier messages:** %s\n\n", conv.Summary)
+	}
+
+	for _, msg := range conv.Messages {
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&b, "## Tool result (%s)\n\n", msg.ToolCallID)
+			writeFenced(&b, "", msg.Content)
+		default:
+			fmt.Fprintf(&b, "## %s\n\n", roleTitle(msg.Role))
+			if msg.Content != "" {
+				b.WriteString(msg.Content)
+				b.WriteString("\n\n")
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&b, "**Tool call:** `%s` (%s)\n\n", tc.Name, tc.ID)
+			args := string(tc.Arguments)
+			var pretty bytes.Buffer
+			if json.Indent(&pretty, tc.Arguments, "", "  ") == nil {
+				args = pretty.String()
+			}
+			writeFenced(&b, "json", args)
+		}
+	}
+	return b.Bytes()
+}
+
+// writeFenced writes a code block whose fence is longer than any run of
+// backticks in the content, so embedded fences can't close it early.
+func writeFenced(b *bytes.Buffer, lang, content string) {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
+
+func roleTitle(role string) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
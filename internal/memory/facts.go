@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+)
+
+// GlobalScope is the chat ID under which facts that apply to every
+// conversation are stored.
+const GlobalScope = "*"
+
+// Fact is a short piece of durable knowledge, either about one chat or,
+// under GlobalScope, about the user across all chats.
+type Fact struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// SetFact stores a fact for chatID, replacing any previous value for key.
+// An empty value deletes the fact.
+func (m *SQLiteMemory) SetFact(ctx context.Context, chatID, key, value string) error {
+	if value == "" {
+		_, err := m.db.ExecContext(ctx, `DELETE FROM facts WHERE chat_id = ? AND key = ?`, chatID, key)
+		return err
+	}
+	value, err := m.seal(value)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx,
+		`INSERT INTO facts (chat_id, key, value, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(chat_id, key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+		chatID, key, value,
+	)
+	return err
+}
+
+// GetFacts returns the facts stored for chatID, ordered by key.
+func (m *SQLiteMemory) GetFacts(ctx context.Context, chatID string) ([]Fact, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT key, value FROM facts WHERE chat_id = ? ORDER BY key`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var facts []Fact
+	for rows.Next() {
+		var f Fact
+		if err := rows.Scan(&f.Key, &f.Value); err != nil {
+			return nil, err
+		}
+		if f.Value, err = m.open(f.Value); err != nil {
+			return nil, err
+		}
+		facts = append(facts, f)
+	}
+	return facts, rows.Err()
+}
+
+// SetFact stores a fact for chatID. An empty value deletes the fact.
+func (m *InMemory) SetFact(_ context.Context, chatID, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if value == "" {
+		delete(m.facts[chatID], key)
+		return nil
+	}
+	if m.facts[chatID] == nil {
+		m.facts[chatID] = make(map[string]string)
+	}
+	m.facts[chatID][key] = value
+	return nil
+}
+
+// GetFacts returns the facts stored for chatID, ordered by key.
+func (m *InMemory) GetFacts(_ context.Context, chatID string) ([]Fact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var facts []Fact
+	for k, v := range m.facts[chatID] {
+		facts = append(facts, Fact{Key: k, Value: v})
+	}
+	sort.Slice(facts, func(i, j int) bool { return facts[i].Key < facts[j].Key })
+	return facts, nil
+}
@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"open-dan/internal/config"
+	"open-dan/internal/llm"
+)
+
+// InMemory implements Memory with maps. Nothing is written to disk, so it
+// suits tests and privacy mode; everything is lost on Close.
+type InMemory struct {
+	mu        sync.RWMutex
+	messages  map[string][]storedMessage
+	summaries map[string]storedSummary
+	facts     map[string]map[string]string
+	scratch   map[Scratch]map[string]map[string]ScratchEntry
+	settings  map[string]ChatSettings
+	retention config.MemoryConfig
+	nextID    int64
+	vectors   map[int64]storedVector // by message id
+	embeds    *embedQueue
+}
+
+type storedMessage struct {
+	id        int64
+	msg       llm.Message
+	createdAt time.Time
+}
+
+type storedVector struct {
+	chatID string
+	model  string
+	vector []float32
+}
+
+type storedSummary struct {
+	summary   string
+	updatedAt time.Time
+}
+
+// NewInMemory creates an empty in-memory store.
+func NewInMemory() *InMemory {
+	m := &InMemory{
+		messages:  make(map[string][]storedMessage),
+		summaries: make(map[string]storedSummary),
+		facts:     make(map[string]map[string]string),
+		scratch:   make(map[Scratch]map[string]map[string]ScratchEntry),
+		settings:  make(map[string]ChatSettings),
+		vectors:   make(map[int64]storedVector),
+	}
+	m.embeds = newEmbedQueue(m.saveEmbeddings)
+	return m
+}
+
+func (m *InMemory) SaveMessage(_ context.Context, chatID string, msg llm.Message) error {
+	m.mu.Lock()
+	msg.ToolCalls = append([]llm.ToolCall(nil), msg.ToolCalls...)
+	m.nextID++
+	id := m.nextID
+	m.messages[chatID] = append(m.messages[chatID], storedMessage{id: id, msg: msg, createdAt: time.Now()})
+	m.mu.Unlock()
+
+	m.embeds.add(id, chatID, msg)
+	return nil
+}
+
+func (m *InMemory) GetHistory(ctx context.Context, chatID string, limit int) ([]llm.Message, error) {
+	return m.GetHistoryPage(ctx, chatID, limit, 0)
+}
+
+// GetHistoryPage pages backward through a chat: offset counts messages back
+// from the newest, and the page is returned in chronological order.
+// A negative limit means no limit, as with SQLite.
+func (m *InMemory) GetHistoryPage(_ context.Context, chatID string, limit, offset int) ([]llm.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.messages[chatID]
+	end := len(stored) - max(offset, 0)
+	if end <= 0 || limit == 0 {
+		return nil, nil
+	}
+	start := 0
+	if limit > 0 {
+		start = max(0, end-limit)
+	}
+
+	messages := make([]llm.Message, 0, end-start)
+	for _, s := range stored[start:end] {
+		messages = append(messages, s.msg)
+	}
+	return messages, nil
+}
+
+func (m *InMemory) SaveSummary(_ context.Context, chatID string, summary string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.summaries[chatID] = storedSummary{summary: summary, updatedAt: time.Now()}
+	return nil
+}
+
+func (m *InMemory) ClearConversation(_ context.Context, chatID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.messages, chatID)
+	delete(m.summaries, chatID)
+	for id, v := range m.vectors {
+		if v.chatID == chatID {
+			delete(m.vectors, id)
+		}
+	}
+	return nil
+}
+
+func (m *InMemory) GetSummary(_ context.Context, chatID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.summaries[chatID].summary, nil
+}
+
+func (m *InMemory) ListChats(_ context.Context) ([]ChatSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byID := make(map[string]*ChatSummary)
+	get := func(chatID string) *ChatSummary {
+		c, ok := byID[chatID]
+		if !ok {
+			c = &ChatSummary{ChatID: chatID}
+			byID[chatID] = c
+		}
+		return c
+	}
+	for chatID, stored := range m.messages {
+		if len(stored) == 0 {
+			continue
+		}
+		c := get(chatID)
+		c.MessageCount = len(stored)
+		c.LastActivity = stored[len(stored)-1].createdAt
+	}
+	for chatID, s := range m.summaries {
+		c := get(chatID)
+		if s.updatedAt.After(c.LastActivity) {
+			c.LastActivity = s.updatedAt
+		}
+	}
+
+	chats := make([]ChatSummary, 0, len(byID))
+	for _, c := range byID {
+		chats = append(chats, *c)
+	}
+	sort.Slice(chats, func(i, j int) bool {
+		if !chats[i].LastActivity.Equal(chats[j].LastActivity) {
+			return chats[i].LastActivity.After(chats[j].LastActivity)
+		}
+		return chats[i].ChatID < chats[j].ChatID
+	})
+	return chats, nil
+}
+
+// SearchMessages does a case-insensitive substring scan, newest first.
+func (m *InMemory) SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return m.search(ctx, "", query, limit)
+}
+
+// SearchChat is SearchMessages limited to chatID's messages.
+func (m *InMemory) SearchChat(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	if chatID == "" {
+		return nil, nil
+	}
+	return m.search(ctx, chatID, query, limit)
+}
+
+// search scans chatID's messages, or every chat's if chatID is empty.
+func (m *InMemory) search(_ context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type hit struct {
+		chatID string
+		stored storedMessage
+	}
+	var hits []hit
+	needle := strings.ToLower(query)
+	for id, stored := range m.messages {
+		if chatID != "" && id != chatID {
+			continue
+		}
+		for _, s := range stored {
+			if strings.Contains(strings.ToLower(s.msg.Content), needle) {
+				hits = append(hits, hit{id, s})
+			}
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].stored.createdAt.After(hits[j].stored.createdAt)
+	})
+
+	var results []SearchResult
+	for _, h := range hits[:min(limit, len(hits))] {
+		results = append(results, SearchResult{
+			ChatID:  h.chatID,
+			Role:    h.stored.msg.Role,
+			Snippet: likeSnippet(h.stored.msg.Content, query),
+		})
+	}
+	return results, nil
+}
+
+// SetRetention configures the limits applied by Prune.
+func (m *InMemory) SetRetention(cfg config.MemoryConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = cfg
+}
+
+// Prune deletes messages beyond the configured per-chat count or older than
+// the configured age. Summaries are never deleted.
+func (m *InMemory) Prune(_ context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cutoff time.Time
+	if m.retention.MaxAgeDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -m.retention.MaxAgeDays)
+	}
+
+	deleted := 0
+	for chatID, stored := range m.messages {
+		keepFrom := 0
+		if n := m.retention.MaxMessagesPerChat; n > 0 && len(stored) > n {
+			keepFrom = len(stored) - n
+		}
+		var kept []storedMessage
+		for i, s := range stored {
+			if i < keepFrom || (!cutoff.IsZero() && s.createdAt.Before(cutoff)) {
+				delete(m.vectors, s.id)
+				deleted++
+				continue
+			}
+			kept = append(kept, s)
+		}
+		if len(kept) == 0 {
+			delete(m.messages, chatID)
+		} else {
+			m.messages[chatID] = kept
+		}
+	}
+	return deleted, nil
+}
+
+func (m *InMemory) ExportConversation(ctx context.Context, chatID, format string) ([]byte, error) {
+	messages, err := m.GetHistory(ctx, chatID, -1)
+	if err != nil {
+		return nil, err
+	}
+	summary, _ := m.GetSummary(ctx, chatID)
+	return exportConversation(Conversation{ChatID: chatID, Summary: summary, Messages: messages}, format)
+}
+
+// Close discards all stored data.
+func (m *InMemory) Close() error {
+	m.embeds.close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = make(map[string][]storedMessage)
+	m.summaries = make(map[string]storedSummary)
+	m.facts = make(map[string]map[string]string)
+	m.vectors = make(map[int64]storedVector)
+	return nil
+}
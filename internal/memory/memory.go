@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"time"
 
 	"open-dan/internal/llm"
 )
@@ -10,7 +11,49 @@ import (
 type Memory interface {
 	SaveMessage(ctx context.Context, chatID string, msg llm.Message) error
 	GetHistory(ctx context.Context, chatID string, limit int) ([]llm.Message, error)
+	GetHistoryPage(ctx context.Context, chatID string, limit, offset int) ([]llm.Message, error)
 	SaveSummary(ctx context.Context, chatID string, summary string) error
 	GetSummary(ctx context.Context, chatID string) (string, error)
+	// ClearConversation deletes a chat's messages and summary. Facts and
+	// scratch entries are kept.
+	ClearConversation(ctx context.Context, chatID string) error
+	SetFact(ctx context.Context, chatID, key, value string) error
+	GetFacts(ctx context.Context, chatID string) ([]Fact, error)
+	ScratchSet(ctx context.Context, table Scratch, chatID, key, value string) error
+	ScratchGet(ctx context.Context, table Scratch, chatID, key string) (value string, ok bool, err error)
+	ScratchDelete(ctx context.Context, table Scratch, chatID, key string) error
+	ScratchList(ctx context.Context, table Scratch, chatID string) ([]ScratchEntry, error)
+	// GetChatSettings returns a chat's overrides of the agent defaults;
+	// SetChatSettings with zero settings clears them. ClearConversation
+	// keeps them.
+	GetChatSettings(ctx context.Context, chatID string) (ChatSettings, error)
+	SetChatSettings(ctx context.Context, chatID string, s ChatSettings) error
+	ListChats(ctx context.Context) ([]ChatSummary, error)
+	SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	// SearchChat is SearchMessages limited to one chat; an empty chatID
+	// matches nothing.
+	SearchChat(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error)
+	// SearchSimilar finds chatID's messages closest in meaning to query
+	// using embeddings; see SetEmbedder on the implementations. It returns
+	// ErrNoEmbedder when semantic search is off.
+	SearchSimilar(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error)
+	UsageStats(ctx context.Context) ([]ProviderUsage, error)
+	Prune(ctx context.Context) (deleted int, err error)
+	ExportConversation(ctx context.Context, chatID, format string) ([]byte, error)
 	Close() error
 }
+
+// ChatSummary describes a stored conversation (exposed to UI).
+type ChatSummary struct {
+	ChatID       string    `json:"chat_id"`
+	MessageCount int       `json:"message_count"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// SearchResult is a single message matching a search query.
+type SearchResult struct {
+	ChatID  string  `json:"chat_id"`
+	Role    string  `json:"role"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"` // higher is more relevant
+}
@@ -0,0 +1,405 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"open-dan/internal/config"
+	"open-dan/internal/llm"
+)
+
+// implementations returns a fresh instance of every Memory implementation,
+// so behavior can be checked for parity.
+func implementations(t *testing.T) map[string]Memory {
+	sqlite, err := NewSQLiteMemory(filepath.Join(t.TempDir(), "parity.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return map[string]Memory{
+		"sqlite":   sqlite,
+		"inmemory": NewInMemory(),
+	}
+}
+
+func TestParityHistoryAndIsolation(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 0; i < 7; i++ {
+				mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: fmt.Sprintf("a%d", i)})
+			}
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "assistant", Content: "b0", ToolCalls: []llm.ToolCall{
+				{ID: "1", Name: "shell", Arguments: []byte(`{}`)},
+			}})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "tool", Content: "ok", ToolCallID: "1"})
+
+			last3, _ := mem.GetHistory(ctx, "a", 3)
+			if len(last3) != 3 || last3[0].Content != "a4" || last3[2].Content != "a6" {
+				t.Fatalf("unexpected history: %+v", last3)
+			}
+			page, _ := mem.GetHistoryPage(ctx, "a", 3, 6)
+			if len(page) != 1 || page[0].Content != "a0" {
+				t.Fatalf("unexpected last page: %+v", page)
+			}
+			all, _ := mem.GetHistory(ctx, "a", -1)
+			if len(all) != 7 {
+				t.Fatalf("expected unlimited history, got %d", len(all))
+			}
+
+			b, _ := mem.GetHistory(ctx, "b", 10)
+			if len(b) != 2 || b[0].ToolCalls[0].Name != "shell" || b[1].ToolCallID != "1" {
+				t.Fatalf("tool call data not preserved: %+v", b)
+			}
+			meta := &llm.ResponseMetadata{ID: "chatcmpl-1", Model: "gpt-4o", SystemFingerprint: "fp_1"}
+			mem.SaveMessage(ctx, "d", llm.Message{Role: "assistant", Content: "hi", Metadata: meta})
+			if d, _ := mem.GetHistory(ctx, "d", 1); len(d) != 1 || d[0].Metadata == nil || *d[0].Metadata != *meta {
+				t.Fatalf("response metadata not preserved: %+v", d)
+			}
+			if missing, _ := mem.GetHistory(ctx, "c", 10); len(missing) != 0 {
+				t.Fatalf("expected empty history for unknown chat, got %+v", missing)
+			}
+		})
+	}
+}
+
+func TestParitySummariesAndChats(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if s, err := mem.GetSummary(ctx, "a"); err != nil || s != "" {
+				t.Fatalf("expected empty summary, got %q (%v)", s, err)
+			}
+			mem.SaveSummary(ctx, "a", "first")
+			mem.SaveSummary(ctx, "a", "second")
+			if s, _ := mem.GetSummary(ctx, "a"); s != "second" {
+				t.Fatalf("expected overwritten summary, got %q", s)
+			}
+
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "user", Content: "hi"})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "user", Content: "there"})
+			chats, _ := mem.ListChats(ctx)
+			counts := map[string]int{}
+			for _, c := range chats {
+				counts[c.ChatID] = c.MessageCount
+			}
+			if len(chats) != 2 || counts["a"] != 0 || counts["b"] != 2 {
+				t.Fatalf("unexpected chats: %+v", chats)
+			}
+		})
+	}
+}
+
+func TestParityClearConversation(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, chat := range []string{"a", "b"} {
+				mem.SaveMessage(ctx, chat, llm.Message{Role: "user", Content: "remember the zebra"})
+				mem.SaveSummary(ctx, chat, "talked about zebras")
+				mem.SetFact(ctx, chat, "animal", "zebra")
+			}
+
+			if err := mem.ClearConversation(ctx, "a"); err != nil {
+				t.Fatal(err)
+			}
+			if h, _ := mem.GetHistory(ctx, "a", -1); len(h) != 0 {
+				t.Fatalf("history not cleared: %+v", h)
+			}
+			if s, _ := mem.GetSummary(ctx, "a"); s != "" {
+				t.Fatalf("summary not cleared: %q", s)
+			}
+			if f, _ := mem.GetFacts(ctx, "a"); len(f) != 1 {
+				t.Fatalf("facts should be kept, got %+v", f)
+			}
+			if h, _ := mem.GetHistory(ctx, "b", -1); len(h) != 1 {
+				t.Fatalf("other chat affected: %+v", h)
+			}
+			if res, _ := mem.SearchMessages(ctx, "zebra", 10); len(res) != 1 || res[0].ChatID != "b" {
+				t.Fatalf("cleared messages still searchable: %+v", res)
+			}
+		})
+	}
+}
+
+func TestParityFacts(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			mem.SetFact(ctx, GlobalScope, "name", "Alex")
+			mem.SetFact(ctx, GlobalScope, "city", "Berlin")
+			mem.SetFact(ctx, GlobalScope, "city", "Lisbon")
+			mem.SetFact(ctx, "a", "topic", "taxes")
+
+			global, _ := mem.GetFacts(ctx, GlobalScope)
+			want := []Fact{{Key: "city", Value: "Lisbon"}, {Key: "name", Value: "Alex"}}
+			if len(global) != 2 || global[0] != want[0] || global[1] != want[1] {
+				t.Fatalf("unexpected global facts: %+v", global)
+			}
+			if chat, _ := mem.GetFacts(ctx, "a"); len(chat) != 1 || chat[0].Value != "taxes" {
+				t.Fatalf("unexpected chat facts: %+v", chat)
+			}
+
+			mem.SetFact(ctx, GlobalScope, "name", "")
+			if global, _ := mem.GetFacts(ctx, GlobalScope); len(global) != 1 {
+				t.Fatalf("expected fact to be deleted, got %+v", global)
+			}
+		})
+	}
+}
+
+func TestParityScratch(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			mem.ScratchSet(ctx, ScratchNotes, "a", "plan", "step 1")
+			mem.ScratchSet(ctx, ScratchNotes, "a", "plan", "step 2")
+			mem.ScratchSet(ctx, ScratchNotes, "a", "found", "a bug")
+			mem.ScratchSet(ctx, ScratchNotes, "b", "plan", "other")
+			mem.ScratchSet(ctx, ScratchKV, "a", "plan", "kv value")
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: "hi"})
+			mem.ClearConversation(ctx, "a")
+
+			if v, ok, _ := mem.ScratchGet(ctx, ScratchNotes, "a", "plan"); !ok || v != "step 2" {
+				t.Fatalf("ScratchGet = %q, %v", v, ok)
+			}
+			entries, err := mem.ScratchList(ctx, ScratchNotes, "a")
+			if err != nil || len(entries) != 2 || entries[0].Key != "found" || entries[1].Value != "step 2" || entries[0].UpdatedAt.IsZero() {
+				t.Fatalf("ScratchList = %+v, %v", entries, err)
+			}
+			if v, _, _ := mem.ScratchGet(ctx, ScratchKV, "a", "plan"); v != "kv value" {
+				t.Fatalf("tables share entries: kv has %q", v)
+			}
+
+			mem.ScratchDelete(ctx, ScratchNotes, "a", "plan")
+			if _, ok, _ := mem.ScratchGet(ctx, ScratchNotes, "a", "plan"); ok {
+				t.Fatal("expected entry to be deleted")
+			}
+			if v, _, _ := mem.ScratchGet(ctx, ScratchNotes, "b", "plan"); v != "other" {
+				t.Fatalf("other chat's entry changed: %q", v)
+			}
+			if err := mem.ScratchSet(ctx, "messages", "a", "k", "v"); err == nil {
+				t.Fatal("expected an unknown table to be refused")
+			}
+		})
+	}
+}
+
+func TestParityChatSettings(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if s, err := mem.GetChatSettings(ctx, "a"); err != nil || s != (ChatSettings{}) {
+				t.Fatalf("GetChatSettings with none saved = %+v, %v", s, err)
+			}
+			mem.SetChatSettings(ctx, "a", ChatSettings{SystemPrompt: "You are a poet."})
+			mem.SetChatSettings(ctx, "a", ChatSettings{SystemPrompt: "You are a coder."})
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: "hi"})
+			mem.ClearConversation(ctx, "a")
+
+			if s, _ := mem.GetChatSettings(ctx, "a"); s.SystemPrompt != "You are a coder." {
+				t.Fatalf("settings not kept: %+v", s)
+			}
+			zero := 0.0
+			mem.SetChatSettings(ctx, "a", ChatSettings{SystemPrompt: "You are a coder.", Model: "gpt-4o", Temperature: &zero, MaxTokens: 512})
+			if s, _ := mem.GetChatSettings(ctx, "a"); s.Model != "gpt-4o" || s.Temperature == nil || *s.Temperature != 0 || s.MaxTokens != 512 {
+				t.Fatalf("overrides not stored: %+v", s)
+			}
+			hot := 2.5
+			if err := mem.SetChatSettings(ctx, "a", ChatSettings{Temperature: &hot}); err == nil {
+				t.Fatal("expected temperature above 2 to be rejected")
+			}
+			if err := mem.SetChatSettings(ctx, "a", ChatSettings{MaxTokens: -1}); err == nil {
+				t.Fatal("expected negative max tokens to be rejected")
+			}
+			if s, _ := mem.GetChatSettings(ctx, "b"); s != (ChatSettings{}) {
+				t.Fatalf("settings leaked to another chat: %+v", s)
+			}
+			mem.SetChatSettings(ctx, "a", ChatSettings{})
+			if s, _ := mem.GetChatSettings(ctx, "a"); s != (ChatSettings{}) {
+				t.Fatalf("settings not cleared: %+v", s)
+			}
+		})
+	}
+}
+
+func TestParityUsageStats(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			primary := &llm.ResponseMetadata{Provider: "openai", Model: "gpt-4o"}
+			backup := &llm.ResponseMetadata{Provider: "anthropic", Model: "claude", Fallback: true, Retries: 2}
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: "q"})
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "assistant", Content: "r1", Metadata: primary})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "assistant", Content: "r2", Metadata: primary})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "assistant", Content: "r3", Metadata: backup})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "assistant", Content: "proactive"})
+
+			stats, err := mem.UsageStats(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []ProviderUsage{
+				{Provider: "openai", Model: "gpt-4o", Messages: 2},
+				{Provider: "anthropic", Model: "claude", Messages: 1, Fallbacks: 1, Retries: 2},
+			}
+			if !slices.Equal(stats, want) {
+				t.Fatalf("got %+v, want %+v", stats, want)
+			}
+		})
+	}
+}
+
+func TestParitySearchChat(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: "the launch code is blue"})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "user", Content: "the launch is delayed"})
+
+			results, err := mem.SearchChat(ctx, "b", "launch", 10)
+			if err != nil || len(results) != 1 || results[0].ChatID != "b" {
+				t.Fatalf("SearchChat = %+v, %v", results, err)
+			}
+			if results, _ := mem.SearchChat(ctx, "", "launch", 10); len(results) != 0 {
+				t.Fatalf("empty chat ID matched %+v", results)
+			}
+		})
+	}
+}
+
+// topicEmbedder embeds text as counts of words about pets and vehicles, so
+// similarity follows topic rather than shared words.
+type topicEmbedder struct{}
+
+func (topicEmbedder) Model() string { return "topics" }
+
+func (topicEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	topics := []string{"kitten cat feline pet", "truck car tyres vehicle"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(topics))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for d, topic := range topics {
+				if slices.Contains(strings.Fields(topic), word) {
+					v[d]++
+				}
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+// flushEmbeddings waits for messages saved so far to be embedded.
+func flushEmbeddings(mem Memory) {
+	switch m := mem.(type) {
+	case *SQLiteMemory:
+		m.embeds.flush()
+	case *InMemory:
+		m.embeds.flush()
+	}
+}
+
+func TestParitySearchSimilar(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := mem.SearchSimilar(ctx, "a", "feline", 5); !errors.Is(err, ErrNoEmbedder) {
+				t.Fatalf("expected ErrNoEmbedder, got %v", err)
+			}
+
+			mem.(interface{ SetEmbedder(llm.Embedder, int) }).SetEmbedder(topicEmbedder{}, 2)
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: "my kitten sleeps all day"})
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "assistant", Content: "the truck needs new tyres"})
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "tool", Content: "cat", ToolCallID: "1"})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "user", Content: "a cat is a pet"})
+			flushEmbeddings(mem)
+
+			results, err := mem.SearchSimilar(ctx, "a", "feline", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 2 || results[0].Snippet != "my kitten sleeps all day" || results[0].Rank <= results[1].Rank {
+				t.Fatalf("unexpected results: %+v", results)
+			}
+			if results, _ := mem.SearchSimilar(ctx, "a", "feline", 1); len(results) != 1 {
+				t.Fatalf("limit ignored: %+v", results)
+			}
+
+			if err := mem.ClearConversation(ctx, "a"); err != nil {
+				t.Fatal(err)
+			}
+			if results, _ := mem.SearchSimilar(ctx, "a", "feline", 5); len(results) != 0 {
+				t.Fatalf("cleared chat still matched: %+v", results)
+			}
+			if results, _ := mem.SearchSimilar(ctx, "b", "feline", 5); len(results) != 1 {
+				t.Fatalf("other chat lost its embeddings: %+v", results)
+			}
+		})
+	}
+}
+
+func TestParitySearchAndPrune(t *testing.T) {
+	for name, mem := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			mem.SaveMessage(ctx, "a", llm.Message{Role: "user", Content: "Deploy the Rocket service"})
+			mem.SaveMessage(ctx, "b", llm.Message{Role: "user", Content: "unrelated"})
+			for i := 0; i < 4; i++ {
+				mem.SaveMessage(ctx, "b", llm.Message{Role: "user", Content: fmt.Sprintf("b%d", i)})
+			}
+
+			results, _ := mem.SearchMessages(ctx, "rocket", 10)
+			if len(results) != 1 || results[0].ChatID != "a" {
+				t.Fatalf("unexpected search results: %+v", results)
+			}
+
+			mem.(interface{ SetRetention(config.MemoryConfig) }).SetRetention(config.MemoryConfig{MaxMessagesPerChat: 2})
+			deleted, err := mem.Prune(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != 3 {
+				t.Fatalf("expected 3 deleted, got %d", deleted)
+			}
+			b, _ := mem.GetHistory(ctx, "b", 10)
+			if len(b) != 2 || b[0].Content != "b2" {
+				t.Fatalf("unexpected history after prune: %+v", b)
+			}
+		})
+	}
+}
+
+func TestInMemoryConcurrentAccess(t *testing.T) {
+	mem := NewInMemory()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			chatID := fmt.Sprintf("chat%d", i%2)
+			for j := 0; j < 50; j++ {
+				mem.SaveMessage(ctx, chatID, llm.Message{Role: "user", Content: "x"})
+				mem.GetHistory(ctx, chatID, 10)
+				mem.ListChats(ctx)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, chatID := range []string{"chat0", "chat1"} {
+		all, _ := mem.GetHistory(ctx, chatID, -1)
+		if len(all) != 200 {
+			t.Fatalf("expected 200 messages in %s, got %d", chatID, len(all))
+		}
+	}
+}
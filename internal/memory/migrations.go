@@ -1,6 +1,13 @@
 package memory
 
-// migrations is the ordered list of SQL migration statements.
+// schemaVersionTable tracks which migrations have been applied.
+const schemaVersionTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY
+)`
+
+// migrations is the ordered list of SQL migration statements. Migration i
+// brings the schema to version i+1; append new entries, never edit or
+// reorder applied ones.
 var migrations = []string{
 	`CREATE TABLE IF NOT EXISTS messages (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -17,7 +24,65 @@ var migrations = []string{
 		summary TEXT NOT NULL,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	)`,
-	`CREATE TABLE IF NOT EXISTS schema_version (
-		version INTEGER PRIMARY KEY
+	`CREATE TABLE IF NOT EXISTS pii_mappings (
+		placeholder TEXT PRIMARY KEY,
+		original TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS pii_counters (
+		prefix TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS facts (
+		chat_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, key)
+	)`,
+	`ALTER TABLE messages ADD COLUMN metadata TEXT`,
+	`CREATE TABLE IF NOT EXISTS kv (
+		chat_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, key)
+	)`,
+	// Placeholder numbers are unique across chats, so placeholder stays the
+	// key. Mappings saved before they were scoped keep their old, shared
+	// behaviour.
+	`ALTER TABLE pii_mappings ADD COLUMN chat_id TEXT NOT NULL DEFAULT '*'`,
+	`CREATE INDEX IF NOT EXISTS idx_pii_mappings_chat_id ON pii_mappings(chat_id)`,
+	`CREATE TABLE IF NOT EXISTS chat_settings (
+		chat_id TEXT PRIMARY KEY,
+		system_prompt TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`ALTER TABLE chat_settings ADD COLUMN model TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE chat_settings ADD COLUMN temperature REAL`,
+	`ALTER TABLE chat_settings ADD COLUMN max_tokens INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS notes (
+		chat_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, key)
+	)`,
+	// Embedding vectors for semantic recall, one per embedded message.
+	`CREATE TABLE IF NOT EXISTS embeddings (
+		message_id INTEGER PRIMARY KEY,
+		chat_id TEXT NOT NULL,
+		model TEXT NOT NULL,
+		vector TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_embeddings_chat_id ON embeddings(chat_id)`,
+}
+
+// ftsMigrations set up full-text search. They are applied separately because
+// SQLite builds without FTS5 reject them; search then falls back to LIKE.
+var ftsMigrations = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+		content,
+		content='messages',
+		content_rowid='id'
 	)`,
 }
@@ -0,0 +1,83 @@
+package memory
+
+// The methods below let SQLiteMemory back the PII sanitizer's placeholder
+// state. Original values are sealed like message content.
+
+// LoadPII returns all persisted placeholder mappings, by chat, and counters.
+func (m *SQLiteMemory) LoadPII() (map[string]map[string]string, map[string]int, error) {
+	mappings := make(map[string]map[string]string)
+	rows, err := m.db.Query(`SELECT chat_id, placeholder, original FROM pii_mappings`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chatID, placeholder, original string
+		if err := rows.Scan(&chatID, &placeholder, &original); err != nil {
+			return nil, nil, err
+		}
+		if original, err = m.open(original); err != nil {
+			return nil, nil, err
+		}
+		if mappings[chatID] == nil {
+			mappings[chatID] = make(map[string]string)
+		}
+		mappings[chatID][placeholder] = original
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	counters := make(map[string]int)
+	crows, err := m.db.Query(`SELECT prefix, value FROM pii_counters`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer crows.Close()
+	for crows.Next() {
+		var prefix string
+		var n int
+		if err := crows.Scan(&prefix, &n); err != nil {
+			return nil, nil, err
+		}
+		counters[prefix] = n
+	}
+	return mappings, counters, crows.Err()
+}
+
+// SavePII records a new placeholder in chatID and advances its prefix
+// counter. The counter only ever moves forward.
+func (m *SQLiteMemory) SavePII(chatID, placeholder, original, prefix string, counter int) error {
+	original, err := m.seal(original)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT OR REPLACE INTO pii_mappings (chat_id, placeholder, original) VALUES (?, ?, ?)`,
+		chatID, placeholder, original,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO pii_counters (prefix, value) VALUES (?, ?)
+		ON CONFLICT(prefix) DO UPDATE SET value = MAX(value, excluded.value)`,
+		prefix, counter,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ClearPIIMappings deletes the persisted mappings of chatID but keeps the
+// counters.
+func (m *SQLiteMemory) ClearPIIMappings(chatID string) error {
+	_, err := m.db.Exec(`DELETE FROM pii_mappings WHERE chat_id = ?`, chatID)
+	return err
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Scratch names a per-chat key-value table the agent writes to through a
+// tool. Each is a table of the same shape in SQLite.
+type Scratch string
+
+const (
+	ScratchKV    Scratch = "kv"    // intermediate results across tool calls
+	ScratchNotes Scratch = "notes" // conclusions that survive summarization
+)
+
+func (s Scratch) check() error {
+	switch s {
+	case ScratchKV, ScratchNotes:
+		return nil
+	}
+	return fmt.Errorf("unknown scratch table %q", string(s))
+}
+
+// ScratchEntry is one value in a chat's Scratch table.
+type ScratchEntry struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScratchSet stores value under key in chatID's entries in table, replacing
+// any previous value.
+func (m *SQLiteMemory) ScratchSet(ctx context.Context, table Scratch, chatID, key, value string) error {
+	if err := table.check(); err != nil {
+		return err
+	}
+	value, err := m.seal(value)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx,
+		`INSERT INTO `+string(table)+` (chat_id, key, value, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(chat_id, key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+		chatID, key, value,
+	)
+	return err
+}
+
+// ScratchGet returns the value stored under key for chatID in table. ok is
+// false if the key isn't set.
+func (m *SQLiteMemory) ScratchGet(ctx context.Context, table Scratch, chatID, key string) (value string, ok bool, err error) {
+	if err := table.check(); err != nil {
+		return "", false, err
+	}
+	err = m.db.QueryRowContext(ctx, `SELECT value FROM `+string(table)+` WHERE chat_id = ? AND key = ?`, chatID, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	value, err = m.open(value)
+	return value, err == nil, err
+}
+
+// ScratchDelete removes key from chatID's entries in table. Deleting a
+// missing key is not an error.
+func (m *SQLiteMemory) ScratchDelete(ctx context.Context, table Scratch, chatID, key string) error {
+	if err := table.check(); err != nil {
+		return err
+	}
+	_, err := m.db.ExecContext(ctx, `DELETE FROM `+string(table)+` WHERE chat_id = ? AND key = ?`, chatID, key)
+	return err
+}
+
+// ScratchList returns chatID's entries in table, sorted by key.
+func (m *SQLiteMemory) ScratchList(ctx context.Context, table Scratch, chatID string) ([]ScratchEntry, error) {
+	if err := table.check(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT key, value, updated_at FROM `+string(table)+` WHERE chat_id = ? ORDER BY key`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ScratchEntry
+	for rows.Next() {
+		var e ScratchEntry
+		if err := rows.Scan(&e.Key, &e.Value, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if e.Value, err = m.open(e.Value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ScratchSet stores value under key in chatID's entries in table.
+func (m *InMemory) ScratchSet(_ context.Context, table Scratch, chatID, key, value string) error {
+	if err := table.check(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.scratch[table] == nil {
+		m.scratch[table] = make(map[string]map[string]ScratchEntry)
+	}
+	if m.scratch[table][chatID] == nil {
+		m.scratch[table][chatID] = make(map[string]ScratchEntry)
+	}
+	m.scratch[table][chatID][key] = ScratchEntry{Key: key, Value: value, UpdatedAt: time.Now()}
+	return nil
+}
+
+// ScratchGet returns the value stored under key for chatID in table.
+func (m *InMemory) ScratchGet(_ context.Context, table Scratch, chatID, key string) (string, bool, error) {
+	if err := table.check(); err != nil {
+		return "", false, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.scratch[table][chatID][key]
+	return e.Value, ok, nil
+}
+
+// ScratchDelete removes key from chatID's entries in table.
+func (m *InMemory) ScratchDelete(_ context.Context, table Scratch, chatID, key string) error {
+	if err := table.check(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.scratch[table][chatID], key)
+	return nil
+}
+
+// ScratchList returns chatID's entries in table, sorted by key.
+func (m *InMemory) ScratchList(_ context.Context, table Scratch, chatID string) ([]ScratchEntry, error) {
+	if err := table.check(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var entries []ScratchEntry
+	for _, e := range m.scratch[table][chatID] {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
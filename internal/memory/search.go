@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+const snippetRadius = 60
+
+// setupFTS creates the full-text index if the SQLite build supports FTS5.
+// A freshly created index is backfilled from existing messages. A database
+// holding encrypted messages gets no index, even while locked: indexing or
+// deleting ciphertext would corrupt it.
+func (m *SQLiteMemory) setupFTS() {
+	var encrypted int
+	_ = m.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM messages WHERE content LIKE 'enc:v1:%')`).Scan(&encrypted)
+	if encrypted != 0 {
+		if _, err := m.db.Exec(`DROP TABLE IF EXISTS messages_fts`); err != nil {
+			log.Printf("[memory] failed to drop search index: %v", err)
+		}
+		return
+	}
+
+	var existing int
+	_ = m.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'messages_fts'`).Scan(&existing)
+
+	for _, stmt := range ftsMigrations {
+		if _, err := m.db.Exec(stmt); err != nil {
+			log.Printf("[memory] full-text search unavailable, using LIKE fallback: %v", err)
+			return
+		}
+	}
+
+	if existing == 0 {
+		if _, err := m.db.Exec(`INSERT INTO messages_fts (messages_fts) VALUES ('rebuild')`); err != nil {
+			log.Printf("[memory] failed to build search index: %v", err)
+			return
+		}
+	}
+	m.fts = true
+}
+
+func (m *SQLiteMemory) SearchMessages(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return m.search(ctx, "", query, limit)
+}
+
+// SearchChat is SearchMessages limited to chatID's messages.
+func (m *SQLiteMemory) SearchChat(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	if chatID == "" {
+		return nil, nil
+	}
+	return m.search(ctx, chatID, query, limit)
+}
+
+// search finds messages matching query in chatID, or in every chat if
+// chatID is empty.
+func (m *SQLiteMemory) search(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if m.encrypted() {
+		return m.searchDecrypted(ctx, chatID, query, limit)
+	}
+	if m.fts {
+		return m.searchFTS(ctx, chatID, query, limit)
+	}
+	return m.searchLike(ctx, chatID, query, limit)
+}
+
+func (m *SQLiteMemory) searchFTS(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT m.chat_id, m.role, snippet(messages_fts, 0, '', '', '...', 16), bm25(messages_fts)
+		FROM messages_fts JOIN messages m ON m.id = messages_fts.rowid
+		WHERE messages_fts MATCH ? AND (? = '' OR m.chat_id = ?)
+		ORDER BY bm25(messages_fts) LIMIT ?`,
+		ftsQuery(query), chatID, chatID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var score float64
+		if err := rows.Scan(&r.ChatID, &r.Role, &r.Snippet, &score); err != nil {
+			return nil, err
+		}
+		r.Rank = -score // bm25 is lower-is-better
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (m *SQLiteMemory) searchLike(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT chat_id, role, content FROM messages
+		WHERE content LIKE ? ESCAPE '\' AND (? = '' OR chat_id = ?)
+		ORDER BY id DESC LIMIT ?`,
+		"%"+escapeLike(query)+"%", chatID, chatID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var content string
+		if err := rows.Scan(&r.ChatID, &r.Role, &content); err != nil {
+			return nil, err
+		}
+		r.Snippet = likeSnippet(content, query)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// searchDecrypted scans messages newest first, matching against decrypted
+// content. SQL can't see through the ciphertext, so this is the only option
+// once the database is encrypted.
+func (m *SQLiteMemory) searchDecrypted(ctx context.Context, chatID, query string, limit int) ([]SearchResult, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT chat_id, role, content FROM messages WHERE ? = '' OR chat_id = ? ORDER BY id DESC`,
+		chatID, chatID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	needle := strings.ToLower(query)
+	var results []SearchResult
+	for rows.Next() && len(results) < limit {
+		var r SearchResult
+		var content string
+		if err := rows.Scan(&r.ChatID, &r.Role, &content); err != nil {
+			return nil, err
+		}
+		content, err := m.open(content)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(strings.ToLower(content), needle) {
+			continue
+		}
+		r.Snippet = likeSnippet(content, query)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// ftsQuery quotes each term so user input can't inject FTS5 query syntax.
+func ftsQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, t := range terms {
+		terms[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// likeSnippet returns the text surrounding the first match of query.
+func likeSnippet(content, query string) string {
+	i := strings.Index(strings.ToLower(content), strings.ToLower(query))
+	if i < 0 {
+		i = 0
+	}
+	start := max(0, i-snippetRadius)
+	end := min(len(content), i+len(query)+snippetRadius)
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	snippet := content[start:end]
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(content) {
+		snippet += "..."
+	}
+	return snippet
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ChatSettings are per-conversation overrides of the agent's defaults.
+// Empty fields fall back to the defaults.
+type ChatSettings struct {
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty"` // nil: default; 0 is a valid setting
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+}
+
+// Validate checks that the overrides are usable in a request.
+func (s ChatSettings) Validate() error {
+	if t := s.Temperature; t != nil && !(*t >= 0 && *t <= 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *s.Temperature)
+	}
+	if s.MaxTokens < 0 {
+		return fmt.Errorf("max tokens must be positive, got %d", s.MaxTokens)
+	}
+	return nil
+}
+
+// isZero reports whether s overrides nothing.
+func (s ChatSettings) isZero() bool {
+	return s.SystemPrompt == "" && s.Model == "" && s.Temperature == nil && s.MaxTokens == 0
+}
+
+// GetChatSettings returns chatID's overrides, or zero settings if it has none.
+func (m *SQLiteMemory) GetChatSettings(ctx context.Context, chatID string) (ChatSettings, error) {
+	var s ChatSettings
+	var temp sql.NullFloat64
+	err := m.db.QueryRowContext(ctx,
+		`SELECT system_prompt, model, temperature, max_tokens FROM chat_settings WHERE chat_id = ?`, chatID,
+	).Scan(&s.SystemPrompt, &s.Model, &temp, &s.MaxTokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ChatSettings{}, nil
+	}
+	if err != nil {
+		return ChatSettings{}, err
+	}
+	if temp.Valid {
+		s.Temperature = &temp.Float64
+	}
+	s.SystemPrompt, err = m.open(s.SystemPrompt)
+	return s, err
+}
+
+// SetChatSettings replaces chatID's overrides. Zero settings remove them.
+func (m *SQLiteMemory) SetChatSettings(ctx context.Context, chatID string, s ChatSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.isZero() {
+		_, err := m.db.ExecContext(ctx, `DELETE FROM chat_settings WHERE chat_id = ?`, chatID)
+		return err
+	}
+	prompt, err := m.seal(s.SystemPrompt)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx,
+		`INSERT INTO chat_settings (chat_id, system_prompt, model, temperature, max_tokens, updated_at)
+		 VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(chat_id) DO UPDATE SET
+		   system_prompt = excluded.system_prompt, model = excluded.model,
+		   temperature = excluded.temperature, max_tokens = excluded.max_tokens,
+		   updated_at = CURRENT_TIMESTAMP`,
+		chatID, prompt, s.Model, s.Temperature, s.MaxTokens,
+	)
+	return err
+}
+
+// GetChatSettings returns chatID's overrides, or zero settings if it has none.
+func (m *InMemory) GetChatSettings(_ context.Context, chatID string) (ChatSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.settings[chatID], nil
+}
+
+// SetChatSettings replaces chatID's overrides. Zero settings remove them.
+func (m *InMemory) SetChatSettings(_ context.Context, chatID string, s ChatSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s.isZero() {
+		delete(m.settings, chatID)
+		return nil
+	}
+	if s.Temperature != nil {
+		t := *s.Temperature
+		s.Temperature = &t
+	}
+	m.settings[chatID] = s
+	return nil
+}
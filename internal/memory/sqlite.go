@@ -4,49 +4,103 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	_ "modernc.org/sqlite"
 
+	"open-dan/internal/config"
 	"open-dan/internal/llm"
 )
 
 // SQLiteMemory implements Memory using SQLite.
 type SQLiteMemory struct {
-	db *sql.DB
+	db        *sql.DB
+	fts       bool // FTS5 index available
+	retention config.MemoryConfig
+	embeds    *embedQueue
+
+	mu  sync.RWMutex
+	key []byte // encrypts content and summaries at rest; nil stores plaintext
 }
 
 // NewSQLiteMemory opens (or creates) a SQLite database at the given path.
-func NewSQLiteMemory(dbPath string) (*SQLiteMemory, error) {
+// If key is non-nil, message content and summaries are encrypted at rest
+// (see Unlock); a nil key keeps the database in plaintext.
+func NewSQLiteMemory(dbPath string, key []byte) (*SQLiteMemory, error) {
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite", dbPath+"?_journal_mode=WAL&_busy_timeout=5000")
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
 
 	m := &SQLiteMemory{db: db}
+	m.embeds = newEmbedQueue(m.saveEmbeddings)
 	if err := m.migrate(); err != nil {
 		db.Close()
 		return nil, err
 	}
+	m.setupFTS()
+
+	if key != nil {
+		if err := m.Unlock(key); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 
 	return m, nil
 }
 
+// migrate applies migrations newer than the recorded schema version, each in
+// its own transaction together with its version row.
 func (m *SQLiteMemory) migrate() error {
-	for _, stmt := range migrations {
-		if _, err := m.db.Exec(stmt); err != nil {
+	if _, err := m.db.Exec(schemaVersionTable); err != nil {
+		return err
+	}
+
+	current, err := m.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		tx, err := m.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// schemaVersion returns the highest applied migration, or 0 for a new database.
+func (m *SQLiteMemory) schemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := m.db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
 func (m *SQLiteMemory) SaveMessage(ctx context.Context, chatID string, msg llm.Message) error {
 	var toolCallsJSON *string
 	if len(msg.ToolCalls) > 0 {
@@ -60,20 +114,65 @@ func (m *SQLiteMemory) SaveMessage(ctx context.Context, chatID string, msg llm.M
 		toolCallID = &msg.ToolCallID
 	}
 
-	_, err := m.db.ExecContext(ctx,
-		`INSERT INTO messages (chat_id, role, content, tool_calls, tool_call_id) VALUES (?, ?, ?, ?, ?)`,
-		chatID, msg.Role, msg.Content, toolCallsJSON, toolCallID,
+	var metadataJSON *string
+	if msg.Metadata != nil {
+		data, _ := json.Marshal(msg.Metadata)
+		s := string(data)
+		metadataJSON = &s
+	}
+
+	content, err := m.seal(msg.Content)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO messages (chat_id, role, content, tool_calls, tool_call_id, metadata) VALUES (?, ?, ?, ?, ?, ?)`,
+		chatID, msg.Role, content, toolCallsJSON, toolCallID, metadataJSON,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if m.fts {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO messages_fts (rowid, content) VALUES (?, ?)`,
+			id, msg.Content,
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.embeds.add(id, chatID, msg)
+	return nil
 }
 
 func (m *SQLiteMemory) GetHistory(ctx context.Context, chatID string, limit int) ([]llm.Message, error) {
+	return m.GetHistoryPage(ctx, chatID, limit, 0)
+}
+
+// GetHistoryPage pages backward through a chat: offset counts messages back
+// from the newest, and the page is returned in chronological order.
+func (m *SQLiteMemory) GetHistoryPage(ctx context.Context, chatID string, limit, offset int) ([]llm.Message, error) {
 	rows, err := m.db.QueryContext(ctx,
-		`SELECT role, content, tool_calls, tool_call_id FROM (
-			SELECT role, content, tool_calls, tool_call_id, id
-			FROM messages WHERE chat_id = ? ORDER BY id DESC LIMIT ?
+		`SELECT role, content, tool_calls, tool_call_id, metadata FROM (
+			SELECT role, content, tool_calls, tool_call_id, metadata, id
+			FROM messages WHERE chat_id = ? ORDER BY id DESC LIMIT ? OFFSET ?
 		) sub ORDER BY id ASC`,
-		chatID, limit,
+		chatID, limit, max(offset, 0),
 	)
 	if err != nil {
 		return nil, err
@@ -83,11 +182,16 @@ func (m *SQLiteMemory) GetHistory(ctx context.Context, chatID string, limit int)
 	var messages []llm.Message
 	for rows.Next() {
 		var msg llm.Message
-		var toolCallsJSON, toolCallID sql.NullString
+		var toolCallsJSON, toolCallID, metadataJSON sql.NullString
 
-		if err := rows.Scan(&msg.Role, &msg.Content, &toolCallsJSON, &toolCallID); err != nil {
+		if err := rows.Scan(&msg.Role, &msg.Content, &toolCallsJSON, &toolCallID, &metadataJSON); err != nil {
 			return nil, err
 		}
+		content, err := m.open(msg.Content)
+		if err != nil {
+			return nil, err
+		}
+		msg.Content = content
 
 		if toolCallsJSON.Valid {
 			_ = json.Unmarshal([]byte(toolCallsJSON.String), &msg.ToolCalls)
@@ -95,6 +199,10 @@ func (m *SQLiteMemory) GetHistory(ctx context.Context, chatID string, limit int)
 		if toolCallID.Valid {
 			msg.ToolCallID = toolCallID.String
 		}
+		if metadataJSON.Valid {
+			msg.Metadata = &llm.ResponseMetadata{}
+			_ = json.Unmarshal([]byte(metadataJSON.String), msg.Metadata)
+		}
 
 		messages = append(messages, msg)
 	}
@@ -103,7 +211,11 @@ func (m *SQLiteMemory) GetHistory(ctx context.Context, chatID string, limit int)
 }
 
 func (m *SQLiteMemory) SaveSummary(ctx context.Context, chatID string, summary string) error {
-	_, err := m.db.ExecContext(ctx,
+	summary, err := m.seal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx,
 		`INSERT OR REPLACE INTO summaries (chat_id, summary, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)`,
 		chatID, summary,
 	)
@@ -119,9 +231,137 @@ func (m *SQLiteMemory) GetSummary(ctx context.Context, chatID string) (string, e
 	if err == sql.ErrNoRows {
 		return "", nil
 	}
-	return summary, err
+	if err != nil {
+		return "", err
+	}
+	return m.open(summary)
+}
+
+func (m *SQLiteMemory) ListChats(ctx context.Context) ([]ChatSummary, error) {
+	// Summaries are included so chats whose messages were pruned still show up.
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT chat_id, SUM(cnt), MAX(last_at) FROM (
+			SELECT chat_id, COUNT(*) AS cnt, MAX(created_at) AS last_at
+			FROM messages GROUP BY chat_id
+			UNION ALL
+			SELECT chat_id, 0 AS cnt, updated_at AS last_at FROM summaries
+		) sub GROUP BY chat_id ORDER BY MAX(last_at) DESC, chat_id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []ChatSummary
+	for rows.Next() {
+		var c ChatSummary
+		var lastAt sql.NullString
+		if err := rows.Scan(&c.ChatID, &c.MessageCount, &lastAt); err != nil {
+			return nil, err
+		}
+		if lastAt.Valid {
+			c.LastActivity = parseTimestamp(lastAt.String)
+		}
+		chats = append(chats, c)
+	}
+
+	return chats, rows.Err()
+}
+
+// SetRetention configures the limits applied by Prune.
+func (m *SQLiteMemory) SetRetention(cfg config.MemoryConfig) {
+	m.retention = cfg
+}
+
+// Prune deletes messages beyond the configured per-chat count or older than
+// the configured age. Summaries are never deleted.
+func (m *SQLiteMemory) Prune(ctx context.Context) (int, error) {
+	var conds []string
+	var args []any
+	if m.retention.MaxAgeDays > 0 {
+		conds = append(conds, `created_at < datetime('now', ?)`)
+		args = append(args, fmt.Sprintf("-%d days", m.retention.MaxAgeDays))
+	}
+	if m.retention.MaxMessagesPerChat > 0 {
+		conds = append(conds, `id IN (SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY chat_id ORDER BY id DESC) AS rn FROM messages
+		) ranked WHERE rn > ?)`)
+		args = append(args, m.retention.MaxMessagesPerChat)
+	}
+	if len(conds) == 0 {
+		return 0, nil
+	}
+	where := strings.Join(conds, " OR ")
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if m.fts {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO messages_fts (messages_fts, rowid, content) SELECT 'delete', id, content FROM messages WHERE `+where,
+			args...,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM embeddings WHERE message_id NOT IN (SELECT id FROM messages)`); err != nil {
+		return 0, err
+	}
+
+	return int(deleted), tx.Commit()
+}
+
+func (m *SQLiteMemory) ClearConversation(ctx context.Context, chatID string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if m.fts {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO messages_fts (messages_fts, rowid, content) SELECT 'delete', id, content FROM messages WHERE chat_id = ?`,
+			chatID,
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE chat_id = ?`, chatID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM summaries WHERE chat_id = ?`, chatID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM embeddings WHERE chat_id = ?`, chatID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// parseTimestamp parses a CURRENT_TIMESTAMP value. Aggregates such as MAX()
+// lose the column's DATETIME type, so the driver hands them back as text.
+func parseTimestamp(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
 
 func (m *SQLiteMemory) Close() error {
+	m.embeds.close()
 	return m.db.Close()
 }
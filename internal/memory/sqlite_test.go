@@ -2,15 +2,19 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"open-dan/internal/config"
 	"open-dan/internal/llm"
 )
 
 func newTestMemory(t *testing.T) *SQLiteMemory {
 	dbPath := filepath.Join(t.TempDir(), "test.db")
-	mem, err := NewSQLiteMemory(dbPath)
+	mem, err := NewSQLiteMemory(dbPath, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -114,3 +118,454 @@ func TestIsolatedChats(t *testing.T) {
 		t.Fatal("chat2 history incorrect")
 	}
 }
+
+func TestListChatsOrderedByActivity(t *testing.T) {
+	mem := newTestMemory(t)
+	ctx := context.Background()
+
+	mem.SaveMessage(ctx, "old", llm.Message{Role: "user", Content: "a"})
+	mem.SaveMessage(ctx, "newest", llm.Message{Role: "user", Content: "b"})
+	mem.SaveMessage(ctx, "newest", llm.Message{Role: "assistant", Content: "c"})
+	mem.SaveMessage(ctx, "middle", llm.Message{Role: "user", Content: "d"})
+	mem.SaveSummary(ctx, "summary_only", "just a summary")
+
+	// CURRENT_TIMESTAMP has second resolution, so pin timestamps explicitly.
+	stamps := map[string]string{
+		"old":    "2024-01-01 10:00:00",
+		"middle": "2024-01-02 10:00:00",
+		"newest": "2024-01-03 10:00:00",
+	}
+	for chatID, ts := range stamps {
+		if _, err := mem.db.Exec(`UPDATE messages SET created_at = ? WHERE chat_id = ?`, ts, chatID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mem.db.Exec(`UPDATE summaries SET updated_at = '2023-12-31 10:00:00'`); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, err := mem.ListChats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"newest", "middle", "old", "summary_only"}
+	if len(chats) != len(want) {
+		t.Fatalf("expected %d chats, got %d", len(want), len(chats))
+	}
+	for i, id := range want {
+		if chats[i].ChatID != id {
+			t.Fatalf("position %d: expected %q, got %q", i, id, chats[i].ChatID)
+		}
+	}
+	if chats[0].MessageCount != 2 {
+		t.Fatalf("expected 2 messages in newest, got %d", chats[0].MessageCount)
+	}
+	if chats[3].MessageCount != 0 {
+		t.Fatalf("expected 0 messages in summary_only, got %d", chats[3].MessageCount)
+	}
+	if chats[0].LastActivity.Format("2006-01-02") != "2024-01-03" {
+		t.Fatalf("unexpected last activity: %v", chats[0].LastActivity)
+	}
+}
+
+func TestSearchMessages(t *testing.T) {
+	mem := newTestMemory(t)
+	ctx := context.Background()
+
+	if !mem.fts {
+		t.Fatal("expected FTS5 to be available in the bundled SQLite")
+	}
+
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: "We decided to deploy on Friday"})
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "assistant", Content: "Noted, deployment is scheduled"})
+	mem.SaveMessage(ctx, "chat2", llm.Message{Role: "user", Content: "What is the weather in Paris?"})
+
+	results, err := mem.SearchMessages(ctx, "deploy friday", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].ChatID != "chat1" || results[0].Role != "user" {
+		t.Fatalf("unexpected result: %+v", results[0])
+	}
+	if !strings.Contains(results[0].Snippet, "Friday") {
+		t.Fatalf("expected snippet to contain match, got %q", results[0].Snippet)
+	}
+
+	// FTS syntax characters in user input must not cause errors
+	if _, err := mem.SearchMessages(ctx, `weather" OR (`, 10); err != nil {
+		t.Fatalf("unexpected error for special characters: %v", err)
+	}
+}
+
+func TestSearchMessagesLikeFallback(t *testing.T) {
+	mem := newTestMemory(t)
+	ctx := context.Background()
+	mem.fts = false
+
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: "The 100% solution"})
+	mem.SaveMessage(ctx, "chat2", llm.Message{Role: "user", Content: "Something else"})
+
+	results, err := mem.SearchMessages(ctx, "100%", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ChatID != "chat1" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestPruneRetention(t *testing.T) {
+	mem := newTestMemory(t)
+	ctx := context.Background()
+	mem.SetRetention(config.MemoryConfig{MaxMessagesPerChat: 3, MaxAgeDays: 30})
+
+	for i := 0; i < 5; i++ {
+		mem.SaveMessage(ctx, "busy", llm.Message{Role: "user", Content: fmt.Sprintf("busy %d", i)})
+	}
+	mem.SaveMessage(ctx, "stale", llm.Message{Role: "user", Content: "ancient"})
+	mem.SaveMessage(ctx, "stale", llm.Message{Role: "user", Content: "recent"})
+	mem.SaveSummary(ctx, "stale", "stale summary")
+
+	if _, err := mem.db.Exec(`UPDATE messages SET created_at = datetime('now', '-90 days') WHERE content = 'ancient'`); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := mem.Prune(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Fatalf("expected 3 deleted messages, got %d", deleted)
+	}
+
+	busy, _ := mem.GetHistory(ctx, "busy", 10)
+	if len(busy) != 3 || busy[0].Content != "busy 2" || busy[2].Content != "busy 4" {
+		t.Fatalf("expected the 3 most recent busy messages, got %+v", busy)
+	}
+
+	stale, _ := mem.GetHistory(ctx, "stale", 10)
+	if len(stale) != 1 || stale[0].Content != "recent" {
+		t.Fatalf("expected only the recent stale message, got %+v", stale)
+	}
+
+	summary, _ := mem.GetSummary(ctx, "stale")
+	if summary != "stale summary" {
+		t.Fatalf("summary should survive pruning, got %q", summary)
+	}
+
+	// Pruned rows must also leave the search index
+	results, err := mem.SearchMessages(ctx, "ancient", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected pruned message to be unsearchable, got %+v", results)
+	}
+}
+
+func TestEncryptedMemory(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "enc.db")
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	ctx := context.Background()
+
+	// Plaintext rows written before encryption was enabled
+	plain, err := NewSQLiteMemory(dbPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: "my secret plan"})
+	plain.SaveSummary(ctx, "chat1", "secret summary")
+	plain.Close()
+
+	mem, err := NewSQLiteMemory(dbPath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "assistant", Content: "another secret"})
+
+	var raw int
+	mem.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE content LIKE '%secret%'`).Scan(&raw)
+	if raw != 0 {
+		t.Fatalf("expected no plaintext content on disk, found %d rows", raw)
+	}
+	mem.db.QueryRow(`SELECT COUNT(*) FROM summaries WHERE summary LIKE '%secret%'`).Scan(&raw)
+	if raw != 0 {
+		t.Fatal("expected summary to be encrypted on disk")
+	}
+
+	history, err := mem.GetHistory(ctx, "chat1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Content != "my secret plan" || history[1].Content != "another secret" {
+		t.Fatalf("unexpected decrypted history: %+v", history)
+	}
+	summary, err := mem.GetSummary(ctx, "chat1")
+	if err != nil || summary != "secret summary" {
+		t.Fatalf("expected decrypted summary, got %q (%v)", summary, err)
+	}
+
+	results, err := mem.SearchMessages(ctx, "plan", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Snippet, "my secret plan") {
+		t.Fatalf("expected search over decrypted content, got %+v", results)
+	}
+}
+
+func TestEncryptedMemoryLocked(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "enc.db")
+	key := make([]byte, 32)
+	ctx := context.Background()
+
+	mem, err := NewSQLiteMemory(dbPath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: "hidden"})
+	mem.Close()
+
+	locked, err := NewSQLiteMemory(dbPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer locked.Close()
+	if _, err := locked.GetHistory(ctx, "chat1", 10); err != ErrLocked {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+
+	if err := locked.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	history, err := locked.GetHistory(ctx, "chat1", 10)
+	if err != nil || len(history) != 1 || history[0].Content != "hidden" {
+		t.Fatalf("expected history after unlock, got %+v (%v)", history, err)
+	}
+}
+
+func TestEncryptedMemoryRestartLocked(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "enc.db")
+	key := make([]byte, 32)
+	ctx := context.Background()
+
+	mem, err := NewSQLiteMemory(dbPath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: fmt.Sprintf("secret %d", i)})
+	}
+	mem.SaveMessage(ctx, "chat2", llm.Message{Role: "user", Content: "other secret"})
+	mem.Close()
+
+	// Retention runs at startup, before the master password unlocks memory
+	locked, err := NewSQLiteMemory(dbPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked.fts {
+		t.Fatal("expected no search index over encrypted messages")
+	}
+	locked.SetRetention(config.MemoryConfig{MaxMessagesPerChat: 2})
+	deleted, err := locked.Prune(ctx)
+	if err != nil {
+		t.Fatalf("prune while locked: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted message, got %d", deleted)
+	}
+	if err := locked.ClearConversation(ctx, "chat2"); err != nil {
+		t.Fatalf("clear while locked: %v", err)
+	}
+	locked.Close()
+
+	mem, err = NewSQLiteMemory(dbPath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	history, err := mem.GetHistory(ctx, "chat1", 10)
+	if err != nil || len(history) != 2 || history[0].Content != "secret 1" {
+		t.Fatalf("expected the 2 most recent messages, got %+v (%v)", history, err)
+	}
+	if other, _ := mem.GetHistory(ctx, "chat2", 10); len(other) != 0 {
+		t.Fatalf("expected chat2 cleared, got %+v", other)
+	}
+	if results, err := mem.SearchMessages(ctx, "secret", 10); err != nil || len(results) != 2 {
+		t.Fatalf("expected search over decrypted content, got %+v (%v)", results, err)
+	}
+}
+
+func TestPlaintextLookingLikeCiphertext(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "enc.db")
+	ctx := context.Background()
+
+	mem, err := NewSQLiteMemory(dbPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	for _, text := range []string{"enc:v1:hello", "enc:plain:x", "enc:"} {
+		mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: text})
+	}
+	mem.SetChatSettings(ctx, "chat1", ChatSettings{SystemPrompt: "be brief"})
+
+	check := func(when string) {
+		t.Helper()
+		history, err := mem.GetHistory(ctx, "chat1", 10)
+		if err != nil {
+			t.Fatalf("%s: %v", when, err)
+		}
+		if len(history) != 3 || history[0].Content != "enc:v1:hello" || history[1].Content != "enc:plain:x" || history[2].Content != "enc:" {
+			t.Fatalf("%s: unexpected history: %+v", when, history)
+		}
+	}
+	check("without a key")
+
+	// Encrypting existing rows must not double-escape them, and must cover
+	// per-chat system prompts.
+	if err := mem.Unlock(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	check("after unlock")
+	var raw int
+	mem.db.QueryRow(`SELECT COUNT(*) FROM chat_settings WHERE system_prompt LIKE '%brief%'`).Scan(&raw)
+	if raw != 0 {
+		t.Fatal("expected the system prompt to be encrypted on disk")
+	}
+	if s, err := mem.GetChatSettings(ctx, "chat1"); err != nil || s.SystemPrompt != "be brief" {
+		t.Fatalf("expected decrypted system prompt, got %+v (%v)", s, err)
+	}
+}
+
+func TestMigrateIdempotent(t *testing.T) {
+	mem := newTestMemory(t)
+
+	if err := mem.migrate(); err != nil {
+		t.Fatalf("second migrate failed: %v", err)
+	}
+
+	version, err := mem.schemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != len(migrations) {
+		t.Fatalf("expected schema version %d, got %d", len(migrations), version)
+	}
+
+	var rows int
+	mem.db.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&rows)
+	if rows != len(migrations) {
+		t.Fatalf("expected one version row per migration, got %d", rows)
+	}
+}
+
+func TestExportConversation(t *testing.T) {
+	mem := newTestMemory(t)
+	ctx := context.Background()
+
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: "List files"})
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "call_1", Name: "shell", Arguments: []byte(`{"command":"ls"}`)},
+	}})
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "tool", Content: "a.txt\nb.txt", ToolCallID: "call_1"})
+	mem.SaveMessage(ctx, "chat1", llm.Message{Role: "assistant", Content: "Two files: a.txt and b.txt"})
+
+	md, err := mem.ExportConversation(ctx, "chat1", "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## User\n\nList files", "**Tool call:** `shell`", "```json\n{\n  \"command\": \"ls\"\n}\n```", "## Tool result (call_1)\n\n```\na.txt\nb.txt\n```", "## Assistant\n\nTwo files"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("markdown export missing %q:\n%s", want, md)
+		}
+	}
+
+	data, err := mem.ExportConversation(ctx, "chat1", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conv Conversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		t.Fatal(err)
+	}
+	if conv.ChatID != "chat1" || len(conv.Messages) != 4 || conv.Messages[1].ToolCalls[0].Name != "shell" {
+		t.Fatalf("unexpected json export: %+v", conv)
+	}
+
+	if _, err := mem.ExportConversation(ctx, "chat1", "pdf"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestPIIStore(t *testing.T) {
+	mem := newTestMemory(t)
+
+	if err := mem.SavePII("chat1", "[EMAIL_1]", "a@example.com", "EMAIL", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SavePII("chat1", "[EMAIL_2]", "b@example.com", "EMAIL", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SavePII("chat2", "[EMAIL_3]", "d@example.com", "EMAIL", 3); err != nil {
+		t.Fatal(err)
+	}
+	mem.ClearPIIMappings("chat1")
+	// A lower counter never moves the stored one backwards
+	mem.SavePII("chat1", "[EMAIL_1]", "c@example.com", "EMAIL", 1)
+
+	mappings, counters, err := mem.LoadPII()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings["chat1"]) != 1 || mappings["chat1"]["[EMAIL_1]"] != "c@example.com" {
+		t.Fatalf("unexpected chat1 mappings: %v", mappings)
+	}
+	if mappings["chat2"]["[EMAIL_3]"] != "d@example.com" {
+		t.Fatalf("clearing chat1 touched chat2: %v", mappings)
+	}
+	if counters["EMAIL"] != 3 {
+		t.Fatalf("expected counter 3, got %d", counters["EMAIL"])
+	}
+}
+
+func TestGetHistoryPage(t *testing.T) {
+	mem := newTestMemory(t)
+	ctx := context.Background()
+
+	for i := 0; i < 25; i++ {
+		mem.SaveMessage(ctx, "chat1", llm.Message{Role: "user", Content: fmt.Sprintf("msg %d", i)})
+	}
+
+	var pages [][]llm.Message
+	for offset := 0; ; offset += 10 {
+		page, err := mem.GetHistoryPage(ctx, "chat1", 10, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+	}
+
+	if len(pages) != 3 || len(pages[0]) != 10 || len(pages[1]) != 10 || len(pages[2]) != 5 {
+		t.Fatalf("expected pages of 10, 10, 5; got %d pages", len(pages))
+	}
+	// First page is the newest, each page is chronological
+	if pages[0][0].Content != "msg 15" || pages[0][9].Content != "msg 24" {
+		t.Fatalf("unexpected first page: %s .. %s", pages[0][0].Content, pages[0][9].Content)
+	}
+	if pages[2][0].Content != "msg 0" || pages[2][4].Content != "msg 4" {
+		t.Fatalf("unexpected last page: %s .. %s", pages[2][0].Content, pages[2][4].Content)
+	}
+}
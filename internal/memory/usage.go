@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"open-dan/internal/llm"
+)
+
+// ProviderUsage summarizes the stored assistant replies served by one
+// provider and model.
+type ProviderUsage struct {
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Messages  int    `json:"messages"`
+	Fallbacks int    `json:"fallbacks"` // replies served after the primary provider failed
+	Retries   int    `json:"retries"`   // failed attempts before those replies succeeded
+}
+
+// usageTally aggregates reply metadata into ProviderUsage rows.
+type usageTally map[[2]string]*ProviderUsage
+
+func (t usageTally) add(md *llm.ResponseMetadata) {
+	if md == nil || md.Provider == "" {
+		return
+	}
+	key := [2]string{md.Provider, md.Model}
+	u, ok := t[key]
+	if !ok {
+		u = &ProviderUsage{Provider: md.Provider, Model: md.Model}
+		t[key] = u
+	}
+	u.Messages++
+	u.Retries += md.Retries
+	if md.Fallback {
+		u.Fallbacks++
+	}
+}
+
+// rows returns the tallies, most used first.
+func (t usageTally) rows() []ProviderUsage {
+	out := make([]ProviderUsage, 0, len(t))
+	for _, u := range t {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Messages != out[j].Messages {
+			return out[i].Messages > out[j].Messages
+		}
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// UsageStats reports which providers and models served the stored
+// assistant replies, with fallback and retry counts.
+func (m *SQLiteMemory) UsageStats(ctx context.Context) ([]ProviderUsage, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT metadata FROM messages WHERE role = 'assistant' AND metadata IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tally := make(usageTally)
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var md llm.ResponseMetadata
+		if json.Unmarshal([]byte(raw), &md) == nil {
+			tally.add(&md)
+		}
+	}
+	return tally.rows(), rows.Err()
+}
+
+// UsageStats reports which providers and models served the stored
+// assistant replies, with fallback and retry counts.
+func (m *InMemory) UsageStats(_ context.Context) ([]ProviderUsage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tally := make(usageTally)
+	for _, stored := range m.messages {
+		for _, s := range stored {
+			if s.msg.Role == "assistant" {
+				tally.add(s.msg.Metadata)
+			}
+		}
+	}
+	return tally.rows(), nil
+}
@@ -1,10 +1,12 @@
 package security
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 
@@ -19,9 +21,29 @@ const (
 	saltLen      = 16
 )
 
-// DeriveKey derives an AES-256 key from a password using Argon2id.
+// KDFParams are the Argon2id costs a key was derived with.
+type KDFParams struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// DefaultKDF is used for new keys. Raising it is safe: master password
+// records carry the parameters they were made with.
+var DefaultKDF = KDFParams{Time: argonTime, MemoryKiB: argonMemory, Threads: argonThreads}
+
+// legacyKDF is what keys were derived with before parameters were recorded.
+var legacyKDF = KDFParams{Time: 3, MemoryKiB: 64 * 1024, Threads: 4}
+
+// DeriveKey derives an AES-256 key from a password using Argon2id with
+// DefaultKDF.
 func DeriveKey(password string, salt []byte) []byte {
-	return argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return DefaultKDF.DeriveKey(password, salt)
+}
+
+// DeriveKey derives an AES-256 key from a password using Argon2id with p.
+func (p KDFParams) DeriveKey(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, p.Time, p.MemoryKiB, p.Threads, argonKeyLen)
 }
 
 // GenerateSalt creates a random salt.
@@ -33,55 +55,91 @@ func GenerateSalt() ([]byte, error) {
 	return salt, nil
 }
 
-// Encrypt encrypts plaintext using AES-256-GCM.
-// Returns base64-encoded ciphertext (nonce prepended).
+// Envelope format. Version 1 was the bare base64 of nonce+ciphertext.
+// Version 2 prefixes a header naming the version, authenticated along with
+// the ciphertext. The header describes only the cipher: keys may be random
+// rather than derived, and a derived key's KDF parameters are kept in its
+// master password record.
+const (
+	envelopeMagic    = "ODEV"
+	envelopeV2       = 2
+	envelopeHeaderV2 = len(envelopeMagic) + 1
+)
+
+// Encrypt encrypts plaintext using AES-256-GCM in a version 2 envelope.
+// Returns base64-encoded header, nonce, and ciphertext.
 func Encrypt(plaintext []byte, key []byte) (string, error) {
-	block, err := aes.NewCipher(key)
+	gcm, err := newGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("create cipher: %w", err)
+		return "", err
 	}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", fmt.Errorf("create GCM: %w", err)
-	}
+	header := append([]byte(envelopeMagic), envelopeV2)
 
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", fmt.Errorf("generate nonce: %w", err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	out := append(header, nonce...)
+	out = gcm.Seal(out, nonce, plaintext, header)
+	return base64.StdEncoding.EncodeToString(out), nil
 }
 
-// Decrypt decrypts base64-encoded AES-256-GCM ciphertext.
+// Decrypt decrypts base64-encoded AES-256-GCM ciphertext in either envelope
+// version.
 func Decrypt(encoded string, key []byte) ([]byte, error) {
 	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, fmt.Errorf("decode base64: %w", err)
 	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isEnvelopeV2(data) {
+		return openGCM(gcm, data, nil)
+	}
+	plaintext, err := openGCM(gcm, data[envelopeHeaderV2:], data[:envelopeHeaderV2])
+	if err != nil {
+		// A version 1 nonce can, very rarely, start like a header.
+		if v1, v1err := openGCM(gcm, data, nil); v1err == nil {
+			return v1, nil
+		}
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// isEnvelopeV2 reports whether data starts with a version 2 header.
+func isEnvelopeV2(data []byte) bool {
+	return len(data) >= envelopeHeaderV2 && bytes.HasPrefix(data, []byte(envelopeMagic)) && data[len(envelopeMagic)] == envelopeV2
+}
 
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("create cipher: %w", err)
 	}
-
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return nil, fmt.Errorf("create GCM: %w", err)
 	}
+	return gcm, nil
+}
 
+// openGCM decrypts nonce+ciphertext, authenticating additionalData.
+func openGCM(gcm cipher.AEAD, data, additionalData []byte) ([]byte, error) {
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
+		return nil, errors.New("ciphertext too short")
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt: %w", err)
 	}
-
 	return plaintext, nil
 }
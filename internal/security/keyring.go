@@ -1,10 +1,13 @@
 package security
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/zalando/go-keyring"
 )
@@ -17,7 +20,8 @@ const (
 // KeyStore manages secure storage of API keys.
 // Primary: OS Keychain. Fallback: encrypted file.
 type KeyStore struct {
-	encryptionKey []byte // derived from master password
+	mu            sync.RWMutex // protects encryptionKey
+	encryptionKey []byte       // derived from master password
 	vaultPath     string
 }
 
@@ -60,12 +64,58 @@ func (ks *KeyStore) Get(name string) (string, error) {
 	return ks.getFromVault(name)
 }
 
-// Delete removes a secret.
+// Delete removes a secret. Removing one that isn't stored is not an error.
 func (ks *KeyStore) Delete(name string) error {
 	_ = keyring.Delete(keyringService, name)
 	return ks.deleteFromVault(name)
 }
 
+// SetMasterKey sets the key, derived from the master password, that
+// encrypts the vault file.
+func (ks *KeyStore) SetMasterKey(key []byte) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.encryptionKey = key
+}
+
+// IsLocked reports whether the vault key has not been set, so secrets that
+// are not in the OS keyring can't be read or stored.
+func (ks *KeyStore) IsLocked() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.encryptionKey == nil
+}
+
+// VaultKey returns the random 32-byte key stored under name in the vault,
+// generating it the first time. Unlike Set it never uses the OS keyring, so
+// the key can only be read with the master password, and it survives
+// Rotate when the password changes.
+func (ks *KeyStore) VaultKey(name string) ([]byte, error) {
+	if ks.IsLocked() {
+		return nil, fmt.Errorf("vault is locked")
+	}
+	vault, err := ks.loadVault()
+	if err != nil {
+		return nil, err
+	}
+	if encoded, ok := vault[name]; ok {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("vault key %s is corrupt", name)
+		}
+		return key, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	vault[name] = base64.StdEncoding.EncodeToString(key)
+	if err := ks.saveVault(vault); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 // MaskKey returns a masked version of an API key for display.
 func MaskKey(key string) string {
 	if len(key) <= 8 {
@@ -84,11 +134,14 @@ func (ks *KeyStore) loadVault() (map[string]string, error) {
 		return nil, err
 	}
 
-	if ks.encryptionKey == nil {
+	ks.mu.RLock()
+	key := ks.encryptionKey
+	ks.mu.RUnlock()
+	if key == nil {
 		return nil, fmt.Errorf("no encryption key set")
 	}
 
-	plaintext, err := Decrypt(string(data), ks.encryptionKey)
+	plaintext, err := Decrypt(string(data), key)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt vault: %w", err)
 	}
@@ -101,7 +154,10 @@ func (ks *KeyStore) loadVault() (map[string]string, error) {
 }
 
 func (ks *KeyStore) saveVault(vault map[string]string) error {
-	if ks.encryptionKey == nil {
+	ks.mu.RLock()
+	key := ks.encryptionKey
+	ks.mu.RUnlock()
+	if key == nil {
 		return fmt.Errorf("no encryption key set")
 	}
 
@@ -110,12 +166,68 @@ func (ks *KeyStore) saveVault(vault map[string]string) error {
 		return err
 	}
 
-	encrypted, err := Encrypt(data, ks.encryptionKey)
+	encrypted, err := Encrypt(data, key)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(ks.vaultPath, []byte(encrypted), 0600)
+	return writeFileAtomic(ks.vaultPath, []byte(encrypted))
+}
+
+// Rotate re-encrypts the vault from oldKey to newKey and makes newKey the
+// key in use. The vault is replaced atomically, so a failure part way leaves
+// the old one intact.
+func (ks *KeyStore) Rotate(oldKey, newKey []byte) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	data, err := os.ReadFile(ks.vaultPath)
+	if os.IsNotExist(err) {
+		ks.encryptionKey = newKey
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	plaintext, err := Decrypt(string(data), oldKey)
+	if err != nil {
+		return fmt.Errorf("decrypt vault: %w", err)
+	}
+	encrypted, err := Encrypt(plaintext, newKey)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(ks.vaultPath, []byte(encrypted)); err != nil {
+		return err
+	}
+	ks.encryptionKey = newKey
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
 }
 
 func (ks *KeyStore) setInVault(name, value string) error {
@@ -144,6 +256,9 @@ func (ks *KeyStore) deleteFromVault(name string) error {
 	if err != nil {
 		return nil // nothing to delete
 	}
+	if _, ok := vault[name]; !ok {
+		return nil // also covers a missing vault, which has no key to save with
+	}
 	delete(vault, name)
 	return ks.saveVault(vault)
 }
@@ -0,0 +1,106 @@
+package security
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateKeepsSecrets(t *testing.T) {
+	_, oldKey, err := NewMasterPassword("old password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := &KeyStore{encryptionKey: oldKey, vaultPath: filepath.Join(t.TempDir(), vaultFile)}
+	if err := ks.setInVault("llm_api_key", "sk-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	newRecord, newKey, err := NewMasterPassword("new password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Rotate(oldKey, newKey); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ks.getFromVault("llm_api_key"); err != nil || got != "sk-secret" {
+		t.Fatalf("secret lost in rotation: %q, %v", got, err)
+	}
+
+	// The old password no longer verifies against the new record, and the
+	// old key no longer opens the vault.
+	if _, err := VerifyMasterPassword(newRecord, "old password"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("old password still accepted: %v", err)
+	}
+	stale := &KeyStore{encryptionKey: oldKey, vaultPath: ks.vaultPath}
+	if _, err := stale.getFromVault("llm_api_key"); err == nil {
+		t.Fatal("old key still decrypts the vault")
+	}
+
+	// Rotating from the wrong key fails and leaves the vault readable.
+	if err := ks.Rotate(oldKey, oldKey); err == nil {
+		t.Fatal("expected rotation from the wrong key to fail")
+	}
+	if got, _ := ks.getFromVault("llm_api_key"); got != "sk-secret" {
+		t.Fatal("failed rotation damaged the vault")
+	}
+}
+
+func TestDeleteFromVaultIgnoresMissingSecrets(t *testing.T) {
+	// No key and no vault file: nothing to delete, and no error.
+	ks := &KeyStore{vaultPath: filepath.Join(t.TempDir(), vaultFile)}
+	if err := ks.deleteFromVault("telegram_token"); err != nil {
+		t.Fatalf("deleting from a missing vault failed: %v", err)
+	}
+
+	_, key, _ := NewMasterPassword("a long password")
+	ks.SetMasterKey(key)
+	if err := ks.setInVault("telegram_token", "123:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.deleteFromVault("llm_api_key"); err != nil {
+		t.Fatalf("deleting an absent secret failed: %v", err)
+	}
+	if err := ks.deleteFromVault("telegram_token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.getFromVault("telegram_token"); err == nil {
+		t.Fatal("secret still in the vault after delete")
+	}
+}
+
+func TestVaultKey(t *testing.T) {
+	ks := &KeyStore{vaultPath: filepath.Join(t.TempDir(), vaultFile)}
+	if _, err := ks.VaultKey("memory_key"); err == nil {
+		t.Fatal("expected a locked vault to refuse")
+	}
+
+	_, oldKey, _ := NewMasterPassword("old password")
+	ks.SetMasterKey(oldKey)
+	key, err := ks.VaultKey("memory_key")
+	if err != nil || len(key) != 32 {
+		t.Fatalf("VaultKey = %x, %v", key, err)
+	}
+	if again, _ := ks.VaultKey("memory_key"); !bytes.Equal(again, key) {
+		t.Fatal("VaultKey generated a second key")
+	}
+
+	// Changing the master password keeps the key.
+	_, newKey, _ := NewMasterPassword("new password")
+	if err := ks.Rotate(oldKey, newKey); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ks.VaultKey("memory_key"); !bytes.Equal(again, key) {
+		t.Fatal("key changed with the master password")
+	}
+
+	// A wrong master key must fail rather than replace the stored key.
+	wrong := &KeyStore{encryptionKey: oldKey, vaultPath: ks.vaultPath}
+	if _, err := wrong.VaultKey("memory_key"); err == nil {
+		t.Fatal("expected the wrong master key to fail")
+	}
+	if again, _ := ks.VaultKey("memory_key"); !bytes.Equal(again, key) {
+		t.Fatal("stored key was replaced")
+	}
+}
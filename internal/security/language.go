@@ -0,0 +1,116 @@
+package security
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLanguageScore is how many stopword hits a Latin-script message needs
+// before a language is reported. Below it, DetectLanguage returns "".
+const minLanguageScore = 2
+
+// stopwords holds a handful of very common words per language. They are
+// chosen to overlap as little as possible between the languages listed.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "what", "this", "with", "have", "my", "for", "please", "can", "it"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "mit", "bitte", "mein", "meine", "ein", "eine", "wie", "sie"},
+	"fr": {"le", "la", "les", "et", "est", "je", "vous", "pas", "avec", "mon", "ma", "une", "pour", "merci", "bonjour"},
+	"es": {"el", "los", "las", "y", "es", "yo", "usted", "por", "con", "mi", "una", "para", "gracias", "hola", "qué"},
+}
+
+// languageNames maps the codes returned by DetectLanguage to English names.
+var languageNames = map[string]string{
+	"en": "English",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"ru": "Russian",
+	"zh": "Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"ar": "Arabic",
+}
+
+// DetectLanguage guesses the language of text and returns its ISO 639-1
+// code, or "" when it can't tell. Non-Latin scripts are recognized by their
+// characters; Latin-script languages by counting common stopwords.
+func DetectLanguage(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	scores := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for lang, words := range stopwords {
+			for _, w := range words {
+				if word == w {
+					scores[lang]++
+				}
+			}
+		}
+	}
+
+	best, bestScore, tie := "", 0, false
+	for lang, n := range scores {
+		switch {
+		case n > bestScore:
+			best, bestScore, tie = lang, n, false
+		case n == bestScore:
+			tie = true
+		}
+	}
+	if bestScore < minLanguageScore || tie {
+		return ""
+	}
+	return best
+}
+
+// LanguageName returns the English name for a code from DetectLanguage,
+// or "" for unknown codes.
+func LanguageName(code string) string {
+	return languageNames[code]
+}
+
+// detectScript returns the language implied by the dominant non-Latin
+// script in text, or "" if most letters are Latin.
+func detectScript(text string) string {
+	var latin, cyrillic, han, kana, hangul, arabic int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	// Japanese mixes kanji with kana, so any kana decides it over Chinese.
+	if kana > 0 && kana+han > latin {
+		return "ja"
+	}
+	scripts := []struct {
+		lang  string
+		count int
+	}{
+		{"ru", cyrillic},
+		{"zh", han},
+		{"ko", hangul},
+		{"ar", arabic},
+	}
+	for _, s := range scripts {
+		if s.count > latin {
+			return s.lang
+		}
+	}
+	return ""
+}
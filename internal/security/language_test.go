@@ -0,0 +1,79 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"open-dan/internal/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Can you tell me what the weather is like?", "en"},
+		{"Ich habe eine Frage, bitte hilf mir mit der Rechnung", "de"},
+		{"Bonjour, je voudrais changer mon rendez-vous avec le médecin", "fr"},
+		{"Hola, necesito ayuda con mi pedido por favor, gracias", "es"},
+		{"Привет, как дела?", "ru"},
+		{"今日はいい天気ですね", "ja"},
+		{"你好，今天天气怎么样", "zh"},
+		{"안녕하세요", "ko"},
+		{"ok", ""},
+		{"12345", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeSelectsLocalePatterns(t *testing.T) {
+	cfg := config.PIIFilterConfig{
+		Enabled:        true,
+		FilterPhones:   true,
+		FilterSSN:      true,
+		DetectLanguage: true,
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		pii   string
+	}{
+		{"french phone", "Bonjour, je suis joignable au 06 12 34 56 78 merci", "06 12 34 56 78"},
+		{"german phone", "Ich bin unter 030/1234567 erreichbar, bitte ruf an", "030/1234567"},
+		{"spanish DNI", "Hola, mi DNI es 12345678Z, gracias por la ayuda", "12345678Z"},
+		{"russian phone", "Мой номер 8 (912) 345-67-89, позвоните", "8 (912) 345-67-89"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSanitizer(cfg)
+			result := s.Sanitize("chat", tt.input)
+			if strings.Contains(result, tt.pii) {
+				t.Errorf("%q not sanitized: %s", tt.pii, result)
+			}
+			if s.Restore("chat", result) != tt.input {
+				t.Errorf("restore mismatch: %s", s.Restore("chat", result))
+			}
+		})
+	}
+}
+
+func TestLocalePatternsRequireDetection(t *testing.T) {
+	input := "Hola, mi DNI es 12345678Z, gracias por la ayuda"
+
+	off := NewSanitizer(config.PIIFilterConfig{Enabled: true, FilterSSN: true})
+	if got := off.Sanitize("chat", input); got != input {
+		t.Errorf("locale pattern applied without detection: %s", got)
+	}
+
+	// The Spanish DNI pattern must not apply to a message in another language.
+	on := NewSanitizer(config.PIIFilterConfig{Enabled: true, FilterSSN: true, DetectLanguage: true})
+	english := "Please ship the order with reference 12345678Z to my address"
+	if got := on.Sanitize("chat", english); got != english {
+		t.Errorf("spanish pattern applied to english text: %s", got)
+	}
+}
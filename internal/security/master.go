@@ -0,0 +1,72 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	masterRecordScheme   = "argon2id"
+	minMasterPasswordLen = 8
+)
+
+// ErrWrongPassword is returned when a master password does not match.
+var ErrWrongPassword = errors.New("wrong master password")
+
+// NewMasterPassword derives the vault key for password with a fresh salt.
+// It returns the key and a record to keep in config; the record holds the
+// KDF parameters, the salt, and a hash of the key, never the key itself.
+func NewMasterPassword(password string) (record string, key []byte, err error) {
+	if len(password) < minMasterPasswordLen {
+		return "", nil, fmt.Errorf("master password must be at least %d characters", minMasterPasswordLen)
+	}
+	salt, err := GenerateSalt()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate salt: %w", err)
+	}
+	key = DefaultKDF.DeriveKey(password, salt)
+	verifier := sha256.Sum256(key)
+	record = strings.Join([]string{
+		masterRecordScheme,
+		fmt.Sprintf("t=%d,m=%d,p=%d", DefaultKDF.Time, DefaultKDF.MemoryKiB, DefaultKDF.Threads),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(verifier[:]),
+	}, "$")
+	return record, key, nil
+}
+
+// VerifyMasterPassword checks password against a record made by
+// NewMasterPassword and returns the vault key if it matches.
+func VerifyMasterPassword(record, password string) ([]byte, error) {
+	parts := strings.Split(record, "$")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] != masterRecordScheme {
+		return nil, fmt.Errorf("unrecognized master password record")
+	}
+	// Records without parameters predate them and used legacyKDF.
+	params := legacyKDF
+	if len(parts) == 4 {
+		if _, err := fmt.Sscanf(parts[1], "t=%d,m=%d,p=%d", &params.Time, &params.MemoryKiB, &params.Threads); err != nil {
+			return nil, fmt.Errorf("parse KDF parameters: %w", err)
+		}
+		parts = append(parts[:1], parts[2:]...)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode salt: %w", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode hash: %w", err)
+	}
+
+	key := params.DeriveKey(password, salt)
+	got := sha256.Sum256(key)
+	if subtle.ConstantTimeCompare(got[:], want) != 1 {
+		return nil, ErrWrongPassword
+	}
+	return key, nil
+}
@@ -0,0 +1,57 @@
+package security
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMasterPasswordRoundTrip(t *testing.T) {
+	record, key, err := NewMasterPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(record, "correct horse") {
+		t.Fatal("record contains the password")
+	}
+
+	got, err := VerifyMasterPassword(record, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Fatal("verified key differs from the derived key")
+	}
+
+	if _, err := VerifyMasterPassword(record, "wrong horse"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("expected ErrWrongPassword, got %v", err)
+	}
+}
+
+func TestMasterPasswordRejectsShortPasswords(t *testing.T) {
+	if _, _, err := NewMasterPassword("short"); err == nil {
+		t.Fatal("expected a short password to be rejected")
+	}
+}
+
+func TestMasterPasswordSaltsDiffer(t *testing.T) {
+	a, _, _ := NewMasterPassword("same password")
+	b, _, _ := NewMasterPassword("same password")
+	if a == b {
+		t.Fatal("records for the same password should use different salts")
+	}
+}
+
+func TestMasterPasswordLegacyRecord(t *testing.T) {
+	// Records made before KDF parameters were stored have three fields.
+	salt := []byte("0123456789abcdef")
+	verifier := sha256.Sum256(legacyKDF.DeriveKey("correct horse", salt))
+	record := "argon2id$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(verifier[:])
+
+	if _, err := VerifyMasterPassword(record, "correct horse"); err != nil {
+		t.Fatalf("legacy record rejected: %v", err)
+	}
+}
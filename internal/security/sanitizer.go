@@ -2,34 +2,70 @@ package security
 
 import (
 	"fmt"
+	"log"
 	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"unicode"
 
 	"open-dan/internal/config"
 )
 
+// maxPIIMappings caps the mappings kept per chat.
 const maxPIIMappings = 1000
 
+// SharedScope holds mappings every chat may restore, such as those for
+// global facts, which appear in every conversation's prompt. It matches
+// memory.GlobalScope.
+const SharedScope = "*"
+
+// placeholderPattern matches the placeholders Sanitize produces.
+var placeholderPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9]*_\d+\]`)
+
 // Sanitizer replaces PII in text with placeholders.
 type Sanitizer struct {
 	mu       sync.RWMutex
 	filters  []piiFilter
-	mappings map[string]string // placeholder → original value
-	counter  map[string]int
+	redact   []piiFilter                  // for Redact: the filters in use, plus API keys
+	mappings map[string]map[string]string // chat ID → placeholder → original value
+	counter  map[string]int               // never reset, so placeholder indices aren't reused
 	enabled  bool
+	store    PIIStore
+
+	// locales holds extra filters per language code, used when language
+	// detection is on and a message is detected as that language.
+	locales map[string][]piiFilter
+}
+
+// PIIStore persists placeholder mappings and counters so placeholders keep
+// their meaning, and indices stay unique, across restarts. Mappings are
+// kept per chat.
+type PIIStore interface {
+	LoadPII() (mappings map[string]map[string]string, counters map[string]int, err error)
+	SavePII(chatID, placeholder, original, prefix string, counter int) error
+	ClearPIIMappings(chatID string) error
 }
 
 type piiFilter struct {
 	name    string
 	pattern *regexp.Regexp
 	prefix  string
+	check   func(string) bool // optional; rejects false positives
 }
 
+// defaultFilters are applied in order. Patterns with a capture group named
+// "pii" replace only that group, leaving the context that identified it.
+// API keys and IBANs come first so the phone and card patterns don't take
+// digits out of them.
 var defaultFilters = []struct {
 	name    string
 	pattern string
 	prefix  string
 }{
+	{"apikey", `\b(?:sk-[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,}|xox[abpr]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`, "APIKEY"},
+	{"iban", `\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`, "IBAN"},
+	{"passport", `\b(?i:passport)(?:\s*(?i:no\.?|nr\.?|number|#))?(?:\s+(?i:is))?\s*[:#]?\s*(?P<pii>[A-Z0-9]{6,9})\b`, "PASSPORT"},
 	{"email", `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`, "EMAIL"},
 	{"phone", `(?:\+?\d{1,3}[-.\s]?)?\(?\d{2,4}\)?[-.\s]?\d{3,4}[-.\s]?\d{3,4}`, "PHONE"},
 	{"card", `\b\d{4}[-\s]?\d{4}[-\s]?\d{4}[-\s]?\d{4}\b`, "CARD"},
@@ -37,37 +73,151 @@ var defaultFilters = []struct {
 	{"ssn", `\b\d{3}-\d{2}-\d{4}\b`, "SSN"},
 }
 
+// filterChecks validate matches of the default filters whose patterns alone
+// would catch too much.
+var filterChecks = map[string]func(string) bool{
+	"apikey":   plausibleAPIKey,
+	"iban":     validIBAN,
+	"passport": hasDigit,
+}
+
+// localeFilters are national formats the default patterns miss. Names match
+// the default filter they extend, so the same config flag enables them.
+var localeFilters = map[string][]struct {
+	name    string
+	pattern string
+	prefix  string
+}{
+	"de": {
+		{"phone", `(?:\+49|\b0)[\s/-]?\d{2,5}[\s/-]?\d{3,9}\b`, "PHONE"},
+		{"ssn", `\b\d{2}\s?\d{6}\s?[A-Z]\s?\d{3}\b`, "SSN"}, // Sozialversicherungsnummer
+	},
+	"fr": {
+		{"phone", `(?:\+33\s?|\b0)[1-9](?:[\s.-]?\d{2}){4}\b`, "PHONE"},
+		{"ssn", `\b[12]\s?\d{2}\s?\d{2}\s?\d{2}\s?\d{3}\s?\d{3}\s?\d{2}\b`, "SSN"}, // numéro de sécurité sociale
+	},
+	"es": {
+		{"phone", `(?:\+34\s?)?\b[6-9]\d{2}\s?\d{3}\s?\d{3}\b`, "PHONE"},
+		{"ssn", `\b\d{8}-?[A-HJ-NP-TV-Z]\b`, "SSN"}, // DNI
+	},
+	"ru": {
+		{"phone", `(?:\+7|\b8)[\s-]?\(?\d{3}\)?[\s-]?\d{3}[\s-]?\d{2}[\s-]?\d{2}\b`, "PHONE"},
+		{"ssn", `\b\d{3}-\d{3}-\d{3}\s?\d{2}\b`, "SSN"}, // СНИЛС
+	},
+}
+
 // NewSanitizer creates a PII sanitizer from config.
 func NewSanitizer(cfg config.PIIFilterConfig) *Sanitizer {
 	s := &Sanitizer{
-		mappings: make(map[string]string),
+		mappings: make(map[string]map[string]string),
 		counter:  make(map[string]int),
 		enabled:  cfg.Enabled,
 	}
 
 	enableMap := map[string]bool{
-		"email": cfg.FilterEmails,
-		"phone": cfg.FilterPhones,
-		"card":  cfg.FilterCards,
-		"ip":    cfg.FilterIPs,
-		"ssn":   cfg.FilterSSN,
+		"email":    cfg.FilterEmails,
+		"phone":    cfg.FilterPhones,
+		"card":     cfg.FilterCards,
+		"ip":       cfg.FilterIPs,
+		"ssn":      cfg.FilterSSN,
+		"iban":     cfg.FilterIBANs,
+		"passport": cfg.FilterPassports,
+		"apikey":   cfg.FilterAPIKeys,
 	}
 
+	var apiKeys piiFilter
 	for _, f := range defaultFilters {
+		filter := piiFilter{
+			name:    f.name,
+			pattern: regexp.MustCompile(f.pattern),
+			prefix:  f.prefix,
+			check:   filterChecks[f.name],
+		}
 		if enableMap[f.name] {
-			s.filters = append(s.filters, piiFilter{
-				name:    f.name,
-				pattern: regexp.MustCompile(f.pattern),
-				prefix:  f.prefix,
-			})
+			s.filters = append(s.filters, filter)
+		}
+		if f.name == "apikey" {
+			apiKeys = filter
+		}
+	}
+
+	for _, p := range cfg.CustomPatterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			// The config loader rejects these, so this only catches configs
+			// built in code.
+			log.Printf("[security] skipping custom PII pattern %q: %v", p.Name, err)
+			continue
+		}
+		s.filters = append(s.filters, piiFilter{name: p.Name, pattern: re, prefix: p.Prefix})
+	}
+
+	// Redact masks API keys even when PII filtering is off.
+	if cfg.Enabled {
+		s.redact = slices.Clone(s.filters)
+	}
+	if !cfg.Enabled || !cfg.FilterAPIKeys {
+		s.redact = append([]piiFilter{apiKeys}, s.redact...)
+	}
+
+	if cfg.DetectLanguage {
+		s.locales = make(map[string][]piiFilter)
+		for lang, filters := range localeFilters {
+			for _, f := range filters {
+				if enableMap[f.name] {
+					s.locales[lang] = append(s.locales[lang], piiFilter{
+						name:    f.name,
+						pattern: regexp.MustCompile(f.pattern),
+						prefix:  f.prefix,
+					})
+				}
+			}
 		}
 	}
 
 	return s
 }
 
-// Sanitize replaces PII in text with placeholders.
-func (s *Sanitizer) Sanitize(text string) string {
+// filtersFor returns the filters to apply to text: the locale-specific ones
+// for its detected language, if any, ahead of the defaults.
+func (s *Sanitizer) filtersFor(text string) []piiFilter {
+	if s.locales == nil {
+		return s.filters
+	}
+	locale := s.locales[DetectLanguage(text)]
+	if len(locale) == 0 {
+		return s.filters
+	}
+	return append(slices.Clone(locale), s.filters...)
+}
+
+// SetStore attaches persistent storage and loads the state saved in it.
+func (s *Sanitizer) SetStore(store PIIStore) error {
+	mappings, counters, err := store.LoadPII()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+	for chatID, chat := range mappings {
+		if s.mappings[chatID] == nil {
+			s.mappings[chatID] = make(map[string]string, len(chat))
+		}
+		for placeholder, original := range chat {
+			s.mappings[chatID][placeholder] = original
+		}
+	}
+	for prefix, n := range counters {
+		s.counter[prefix] = max(s.counter[prefix], n)
+	}
+	return nil
+}
+
+// Sanitize replaces PII in text with placeholders. The placeholders can only
+// be restored in the same chat.
+func (s *Sanitizer) Sanitize(chatID, text string) string {
 	if !s.enabled || len(s.filters) == 0 {
 		return text
 	}
@@ -75,76 +225,151 @@ func (s *Sanitizer) Sanitize(text string) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Evict old mappings if limit reached to prevent unbounded growth
-	if len(s.mappings) >= maxPIIMappings {
-		s.mappings = make(map[string]string)
-		s.counter = make(map[string]int)
+	// Evict old mappings if limit reached to prevent unbounded growth.
+	// Counters are kept so evicted placeholders are never handed out again.
+	chat := s.mappings[chatID]
+	if len(chat) >= maxPIIMappings {
+		s.clearMappings(chatID)
+		chat = nil
+	}
+	if chat == nil {
+		chat = make(map[string]string)
+		s.mappings[chatID] = chat
 	}
 
 	result := text
-	for _, f := range s.filters {
-		result = f.pattern.ReplaceAllStringFunc(result, func(match string) string {
+	for _, f := range s.filtersFor(text) {
+		result = f.replace(result, func(match string) string {
 			// Check if already mapped
-			for placeholder, original := range s.mappings {
+			for placeholder, original := range chat {
 				if original == match {
 					return placeholder
 				}
 			}
 			s.counter[f.prefix]++
 			placeholder := fmt.Sprintf("[%s_%d]", f.prefix, s.counter[f.prefix])
-			s.mappings[placeholder] = match
+			chat[placeholder] = match
+			if s.store != nil {
+				if err := s.store.SavePII(chatID, placeholder, match, f.prefix, s.counter[f.prefix]); err != nil {
+					log.Printf("[security] failed to persist PII mapping: %v", err)
+				}
+			}
 			return placeholder
 		})
 	}
 	return result
 }
 
-// Restore replaces placeholders back with original values.
-func (s *Sanitizer) Restore(text string) string {
-	if !s.enabled {
+// Redact masks PII and API keys in text without recording mappings, for
+// text such as logs that is never restored.
+func (s *Sanitizer) Redact(text string) string {
+	for _, f := range s.redact {
+		text = f.replace(text, func(string) string { return "[" + f.prefix + "]" })
+	}
+	return text
+}
+
+// replace calls fn for each match of f in text, or for its "pii" group if
+// the pattern has one, and substitutes the result. Matches the filter's
+// check rejects are left alone.
+func (f piiFilter) replace(text string, fn func(string) string) string {
+	group := max(f.pattern.SubexpIndex("pii"), 0)
+
+	var b strings.Builder
+	last, replaced := 0, false
+	for _, m := range f.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			continue
+		}
+		if f.check != nil && !f.check(text[start:end]) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(fn(text[start:end]))
+		last, replaced = end, true
+	}
+	if !replaced {
 		return text
 	}
+	b.WriteString(text[last:])
+	return b.String()
+}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// validIBAN reports whether s, spaces allowed, passes the IBAN mod-97 check.
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	// Move the country code and check digits to the end, turn letters
+	// into numbers (A=10 … Z=35), and take the remainder digit by digit.
+	rem := 0
+	for _, r := range s[4:] + s[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			rem = (rem*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			rem = (rem*100 + int(r-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return rem == 1
+}
 
-	result := text
-	for placeholder, original := range s.mappings {
-		result = replaceAll(result, placeholder, original)
+// plausibleAPIKey rejects "sk-" matches that look like ordinary hyphenated
+// words rather than random keys. The other key shapes have prefixes
+// distinctive enough on their own.
+func plausibleAPIKey(s string) bool {
+	if !strings.HasPrefix(s, "sk-") {
+		return true
 	}
-	return result
+	body := s[len("sk-"):]
+	return hasDigit(body) && strings.ContainsFunc(body, unicode.IsUpper) && strings.ContainsFunc(body, unicode.IsLower)
 }
 
-// Reset clears all stored mappings (e.g., between conversations).
-func (s *Sanitizer) Reset() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.mappings = make(map[string]string)
-	s.counter = make(map[string]int)
+func hasDigit(s string) bool {
+	return strings.ContainsFunc(s, unicode.IsDigit)
 }
 
-func replaceAll(s, old, new string) string {
-	if old == "" {
-		return s
+// Restore replaces placeholders created in chatID, or in SharedScope, back
+// with their original values. Placeholders from other chats are left as
+// they are.
+func (s *Sanitizer) Restore(chatID, text string) string {
+	if !s.enabled {
+		return text
 	}
-	result := ""
-	for {
-		i := indexOf(s, old)
-		if i < 0 {
-			result += s
-			break
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// One scan with exact lookups, so [EMAIL_1] can't eat the start of
+	// [EMAIL_12] whatever order the mappings are in.
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if original, ok := s.mappings[chatID][placeholder]; ok {
+			return original
 		}
-		result += s[:i] + new
-		s = s[i+len(old):]
-	}
-	return result
+		if original, ok := s.mappings[SharedScope][placeholder]; ok {
+			return original
+		}
+		return placeholder
+	})
+}
+
+// Reset clears the mappings of one chat. Counters are kept so placeholder
+// indices stay unique.
+func (s *Sanitizer) Reset(chatID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clearMappings(chatID)
 }
 
-func indexOf(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
+func (s *Sanitizer) clearMappings(chatID string) {
+	delete(s.mappings, chatID)
+	if s.store != nil {
+		if err := s.store.ClearPIIMappings(chatID); err != nil {
+			log.Printf("[security] failed to clear persisted PII mappings: %v", err)
 		}
 	}
-	return -1
 }
@@ -0,0 +1,62 @@
+package skill
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"open-dan/internal/tool"
+)
+
+// maxCachedResults bounds the memory used by one deterministic skill.
+const maxCachedResults = 256
+
+// resultCache holds successful outputs of a deterministic skill keyed by
+// cacheKey.
+type resultCache struct {
+	mu      sync.Mutex
+	results map[string]tool.Result
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{results: make(map[string]tool.Result)}
+}
+
+func (c *resultCache) get(key string) (*tool.Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res, ok := c.results[key]
+	if !ok {
+		return nil, false
+	}
+	return &res, true
+}
+
+func (c *resultCache) put(key string, res *tool.Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.results) >= maxCachedResults {
+		// Drop an arbitrary entry; pure skills can always recompute it.
+		for k := range c.results {
+			delete(c.results, k)
+			break
+		}
+	}
+	c.results[key] = *res
+}
+
+// cacheKey hashes the scope and the arguments. Arguments are re-encoded
+// first so key order and whitespace don't produce different keys.
+func cacheKey(scope string, args json.RawMessage) string {
+	canonical := []byte(args)
+	var v any
+	if err := json.Unmarshal(args, &v); err == nil {
+		canonical, _ = json.Marshal(v)
+	}
+	h := sha256.New()
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
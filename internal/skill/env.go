@@ -0,0 +1,85 @@
+package skill
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+)
+
+// envName is what a declared environment variable may be called.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// baseEnv lists the variables passed from the app's environment to every
+// skill, so interpreters and common tools work. Anything else a skill needs
+// must be declared in its manifest.
+var baseEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// windowsEnv is added to baseEnv on Windows, where programs fail without it.
+var windowsEnv = []string{"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE", "APPDATA", "LOCALAPPDATA"}
+
+// EnvVar is an environment variable a skill declares, such as an API key.
+// Its value is set by the user and kept in the key store.
+type EnvVar struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Required skills refuse to run until the value is set.
+	Required bool `json:"required,omitempty"`
+}
+
+// SecretStore holds the values of skills' environment variables, under
+// the names given by EnvSecretName. security.KeyStore implements it.
+type SecretStore interface {
+	Get(name string) (string, error)
+}
+
+// EnvSecretName is the key-store name of the value of a skill's
+// environment variable.
+func EnvSecretName(skill, name string) string {
+	return "skill_env." + skill + "." + name
+}
+
+// validateEnv checks the environment variables a manifest declares.
+func validateEnv(vars []EnvVar) error {
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if !envName.MatchString(v.Name) {
+			return fmt.Errorf("invalid env name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("env %s declared twice", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// skillEnv builds a skill's environment: the allowlisted variables of the
+// app's own environment plus the declared ones that have a value. It fails
+// if a required variable isn't set.
+func skillEnv(m *Manifest, secrets SecretStore) ([]string, error) {
+	names := baseEnv
+	if runtime.GOOS == "windows" {
+		names = append(names[:len(names):len(names)], windowsEnv...)
+	}
+	var env []string
+	for _, name := range names {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	for _, v := range m.Env {
+		var value string
+		if secrets != nil {
+			value, _ = secrets.Get(EnvSecretName(m.Name, v.Name))
+		}
+		if value == "" {
+			if v.Required {
+				return nil, fmt.Errorf("skill %s needs %s; set it in Settings → Skills & Plugins", m.Name, v.Name)
+			}
+			continue
+		}
+		env = append(env, v.Name+"="+value)
+	}
+	return env, nil
+}
@@ -0,0 +1,77 @@
+package skill
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"open-dan/internal/tool"
+)
+
+const maxSkillRedirects = 5
+
+// newHTTPClient returns the client an HTTP skill calls its URL with.
+// Redirects must pass the same URL policy as the URL itself.
+func (s *SkillTool) newHTTPClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxSkillRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxSkillRedirects)
+			}
+			if s.policy == nil {
+				return fmt.Errorf("HTTP skills are not allowed")
+			}
+			return s.policy.Check(req.Context(), req.URL.String())
+		},
+	}
+}
+
+// runHTTP POSTs args as JSON to the skill's URL and returns the response
+// body, with the same timeout and size cap as a command skill. A non-2xx
+// response is an error.
+func (s *SkillTool) runHTTP(ctx context.Context, args json.RawMessage) (*tool.Result, error) {
+	if s.policy == nil {
+		return &tool.Result{Error: "HTTP skills can only call hosts in http_request.allowed_hosts, and none are set", IsError: true}, nil
+	}
+	if err := s.policy.Check(ctx, s.manifest.URL); err != nil {
+		return &tool.Result{Error: "skill URL not allowed: " + err.Error(), IsError: true}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.timeoutSec)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.manifest.URL, bytes.NewReader(args))
+	if err != nil {
+		return &tool.Result{Error: "failed to create request: " + err.Error(), IsError: true}, nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/plain;q=0.9, */*;q=0.8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &tool.Result{Error: "request failed: " + err.Error(), IsError: true}, nil
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSkillOutput+1))
+	if err != nil {
+		return &tool.Result{Error: "failed to read response: " + err.Error(), IsError: true}, nil
+	}
+	body := string(data)
+	if len(data) > maxSkillOutput {
+		body = body[:maxSkillOutput] + "\n... (output truncated)"
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := "HTTP " + resp.Status
+		if body != "" {
+			msg += ": " + body
+		}
+		return &tool.Result{Error: msg, IsError: true}, nil
+	}
+	return &tool.Result{Output: body}, nil
+}
@@ -0,0 +1,369 @@
+package skill
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	maxDownloadSize  = 50 << 20  // 50 MB archive
+	maxExtractedSize = 100 << 20 // 100 MB unpacked
+	maxArchiveFiles  = 2000
+	installTimeout   = 5 * time.Minute
+)
+
+// skillName is what an installed skill's name may look like; it becomes a
+// directory name and part of the tool name.
+var skillName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Install downloads a skill into the skills directory and returns it. The
+// source is an https URL of a .zip, .tar.gz, .tgz, or .tar archive, or of a
+// git repository. The skill must have a valid manifest.json, at the top
+// level or in the archive's only directory, whose name no installed skill
+// uses, and must pass verification. Nothing from the source is run.
+func (l *Loader) Install(ctx context.Context, source string) (*SkillInfo, error) {
+	if l.skillsDir == "" {
+		return nil, fmt.Errorf("no skills directory")
+	}
+	u, err := url.Parse(strings.TrimSpace(source))
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("skill source must be an https URL")
+	}
+	ctx, cancel := context.WithTimeout(ctx, installTimeout)
+	defer cancel()
+
+	if err := os.MkdirAll(l.skillsDir, 0755); err != nil {
+		return nil, err
+	}
+	// Staged inside the skills dir so the final move is a rename. The
+	// loader skips dot-directories.
+	tmp, err := os.MkdirTemp(l.skillsDir, ".install-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	dest := filepath.Join(tmp, "skill")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		return nil, err
+	}
+	switch archiveKind(u.Path) {
+	case "zip":
+		err = l.downloadAndExtract(ctx, u.String(), tmp, dest, extractZip)
+	case "tar.gz":
+		err = l.downloadAndExtract(ctx, u.String(), tmp, dest, extractTarGz)
+	case "tar":
+		err = l.downloadAndExtract(ctx, u.String(), tmp, dest, extractTar)
+	default:
+		err = cloneRepo(ctx, u.String(), dest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := findSkillRoot(dest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readManifest(filepath.Join(root, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest.json: %w", err)
+	}
+	m, err := decodeManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if !skillName.MatchString(m.Name) {
+		return nil, fmt.Errorf("invalid skill name %q: use letters, digits, _ and -", m.Name)
+	}
+	for _, s := range l.ListInstalled(nil) {
+		if s.Name == m.Name {
+			return nil, fmt.Errorf("a skill named %s is already installed", m.Name)
+		}
+	}
+	if err := l.verifier.verify(root, data, m); err != nil {
+		return nil, fmt.Errorf("skill failed verification: %w", err)
+	}
+
+	target := filepath.Join(l.skillsDir, m.Name)
+	if _, err := os.Lstat(target); err == nil {
+		return nil, fmt.Errorf("%s already exists in the skills directory", m.Name)
+	}
+	if err := os.Rename(root, target); err != nil {
+		return nil, fmt.Errorf("installing skill: %w", err)
+	}
+	return &SkillInfo{
+		Name:        m.Name,
+		Version:     m.Version,
+		Description: m.Description,
+		Author:      m.Author,
+		Enabled:     true,
+		Env:         m.Env,
+	}, nil
+}
+
+// newInstallClient returns the client Install downloads with, which only
+// follows redirects to https URLs.
+func newInstallClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to non-https URL refused")
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// archiveKind names the archive format of a URL path, or returns "" for a
+// git repository.
+func archiveKind(p string) string {
+	p = strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(p, ".zip"):
+		return "zip"
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(p, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// downloadAndExtract saves the archive at rawURL in tmp and unpacks it
+// into dest.
+func (l *Loader) downloadAndExtract(ctx context.Context, rawURL, tmp, dest string, extract func(archive, dest string) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("downloading skill: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading skill: %s", resp.Status)
+	}
+
+	archive := filepath.Join(tmp, "archive")
+	f, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxDownloadSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("downloading skill: %w", err)
+	}
+	if n > maxDownloadSize {
+		return fmt.Errorf("skill archive is larger than %d MB", maxDownloadSize>>20)
+	}
+	return extract(archive, dest)
+}
+
+// cloneRepo makes a shallow clone of the git repository at rawURL in dest.
+// Hooks and submodules are not fetched, so cloning runs nothing from the
+// repository, and git is never asked for credentials.
+func cloneRepo(ctx context.Context, rawURL, dest string) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("installing from a git repository needs git on PATH")
+	}
+	cmd := exec.CommandContext(ctx, "git",
+		"-c", "core.hooksPath=/dev/null",
+		"-c", "protocol.allow=never", "-c", "protocol.https.allow=always",
+		"clone", "--depth", "1", "--quiet", "--", rawURL, dest)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.RemoveAll(filepath.Join(dest, ".git"))
+}
+
+// findSkillRoot returns dir if it holds manifest.json, or else its only
+// subdirectory if that does, as in archives of a repository.
+func findSkillRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); err == nil {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		sub := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(sub, "manifest.json")); err == nil {
+			return sub, nil
+		}
+	}
+	return "", fmt.Errorf("no manifest.json found in the skill")
+}
+
+// entryPath checks an archive entry name and returns where it unpacks in
+// dest. Absolute names and names that climb out of dest (zip-slip) are
+// rejected.
+func entryPath(dest, name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("archive entry has an absolute path: %s", name)
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("archive entry escapes the skill directory: %s", name)
+	}
+	return filepath.Join(dest, filepath.FromSlash(clean)), nil
+}
+
+// extractLimits tracks the size and number of files unpacked so far.
+type extractLimits struct {
+	files int
+	bytes int64
+}
+
+func (x *extractLimits) add(size int64) error {
+	x.files++
+	x.bytes += size
+	if x.files > maxArchiveFiles {
+		return fmt.Errorf("skill archive has more than %d files", maxArchiveFiles)
+	}
+	if x.bytes > maxExtractedSize {
+		return fmt.Errorf("skill archive unpacks to more than %d MB", maxExtractedSize>>20)
+	}
+	return nil
+}
+
+// writeEntry writes a regular file from an archive, keeping only its
+// permission bits, and stops at the size limit even if the entry's
+// declared size is wrong.
+func writeEntry(target string, mode os.FileMode, r io.Reader, limits *extractLimits) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode.Perm()&0755|0600)
+	if err != nil {
+		return err
+	}
+	remaining := maxExtractedSize - limits.bytes
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return limits.add(n)
+}
+
+func extractZip(archive, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	var limits extractLimits
+	for _, f := range zr.File {
+		target, err := entryPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = writeEntry(target, mode, rc, &limits)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("archive entry %s is not a regular file or directory", f.Name)
+		}
+	}
+	return nil
+}
+
+func extractTarGz(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("invalid gzip archive: %w", err)
+	}
+	defer gz.Close()
+	return untar(gz, dest)
+}
+
+func extractTar(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return untar(f, dest)
+}
+
+func untar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	var limits extractLimits
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeXGlobalHeader:
+			continue // pax metadata, as in GitHub's archives
+		case tar.TypeDir:
+			target, err := entryPath(dest, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			target, err := entryPath(dest, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := writeEntry(target, hdr.FileInfo().Mode(), tr, &limits); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("archive entry %s is not a regular file or directory", hdr.Name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package skill
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type archiveFile struct {
+	name string
+	body string
+	mode int64
+}
+
+func zipArchive(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+		hdr.SetMode(os.FileMode(f.mode))
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzArchive(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: f.mode, Size: int64(len(f.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f.body))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// serveArchives serves each archive at its path over TLS and returns a
+// loader for a fresh skills directory that trusts the server.
+func serveArchives(t *testing.T, archives map[string][]byte) (*Loader, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	l := NewLoader(dir, 10, false)
+	l.client = srv.Client()
+	return l, srv.URL
+}
+
+const helloManifest = `{"name": "hello", "version": "1.0.0", "description": "Says hello", "command": "sh run.sh"}`
+
+func TestInstallFromArchives(t *testing.T) {
+	files := []archiveFile{
+		{name: "hello-main/manifest.json", body: helloManifest, mode: 0644},
+		{name: "hello-main/run.sh", body: "#!/bin/sh\necho hello\n", mode: 0755},
+		{name: "hello-main/lib/util.sh", body: "true\n", mode: 0644},
+	}
+	for _, tc := range []struct {
+		path string
+		data []byte
+	}{
+		{"/hello.zip", zipArchive(t, files)},
+		{"/hello.tar.gz", tarGzArchive(t, files)},
+	} {
+		l, base := serveArchives(t, map[string][]byte{tc.path: tc.data})
+		info, err := l.Install(context.Background(), base+tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		if info.Name != "hello" || info.Version != "1.0.0" {
+			t.Errorf("%s: info = %+v", tc.path, info)
+		}
+		st, err := os.Stat(filepath.Join(l.skillsDir, "hello", "run.sh"))
+		if err != nil || st.Mode().Perm()&0100 == 0 {
+			t.Errorf("%s: run.sh not installed as executable: %v %v", tc.path, st, err)
+		}
+		if _, err := os.Stat(filepath.Join(l.skillsDir, "hello", "lib", "util.sh")); err != nil {
+			t.Errorf("%s: nested file missing: %v", tc.path, err)
+		}
+		if entries, _ := os.ReadDir(l.skillsDir); len(entries) != 1 {
+			t.Errorf("%s: staging files left behind: %v", tc.path, entries)
+		}
+		tools, _ := l.LoadAll(nil)
+		if len(tools) != 1 || tools[0].Name() != "skill_hello" {
+			t.Errorf("%s: installed skill doesn't load: %v", tc.path, tools)
+		}
+
+		// Installing it again collides.
+		if _, err := l.Install(context.Background(), base+tc.path); err == nil || !strings.Contains(err.Error(), "already installed") {
+			t.Errorf("%s: expected a name collision, got %v", tc.path, err)
+		}
+	}
+}
+
+func TestInstallRejectsUnsafeArchives(t *testing.T) {
+	manifest := archiveFile{name: "manifest.json", body: helloManifest, mode: 0644}
+	l, base := serveArchives(t, map[string][]byte{
+		"/slip.zip":       zipArchive(t, []archiveFile{manifest, {name: "../../evil.sh", body: "pwned", mode: 0755}}),
+		"/abs.zip":        zipArchive(t, []archiveFile{manifest, {name: "/tmp/evil.sh", body: "pwned", mode: 0755}}),
+		"/slip.tar.gz":    tarGzArchive(t, []archiveFile{manifest, {name: "lib/../../evil.sh", body: "pwned", mode: 0755}}),
+		"/nomanifest.zip": zipArchive(t, []archiveFile{{name: "run.sh", body: "echo", mode: 0755}}),
+		"/badname.zip":    zipArchive(t, []archiveFile{{name: "manifest.json", body: `{"name": "../x", "command": "sh run.sh"}`, mode: 0644}}),
+	})
+
+	for path, want := range map[string]string{
+		"/slip.zip":       "escapes the skill directory",
+		"/abs.zip":        "absolute path",
+		"/slip.tar.gz":    "escapes the skill directory",
+		"/nomanifest.zip": "no manifest.json",
+		"/badname.zip":    "invalid skill name",
+		"/missing.zip":    "404",
+	} {
+		_, err := l.Install(context.Background(), base+path)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", path, want, err)
+		}
+	}
+	if _, err := l.Install(context.Background(), "http://example.com/hello.zip"); err == nil {
+		t.Error("expected plain http to be refused")
+	}
+
+	if entries, _ := os.ReadDir(l.skillsDir); len(entries) != 0 {
+		t.Errorf("failed installs left files behind: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(l.skillsDir), "evil.sh")); err == nil {
+		t.Error("zip-slip entry was written outside the skills directory")
+	}
+}
@@ -0,0 +1,120 @@
+package skill
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// systemDirs are mounted read-only inside the sandbox so interpreters and
+// common tools work. Everything else, including the home directory, is
+// hidden.
+var systemDirs = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc"}
+
+// Isolation runs skill commands inside bubblewrap or nsjail. Only the
+// skill's directory and the workspace are visible and writable, /tmp is
+// private, and the network is cut off unless the manifest asks for it.
+type Isolation struct {
+	tool      string // "bwrap" or "nsjail"
+	path      string
+	workspace string
+	exempt    map[string]bool
+}
+
+// NewIsolation finds the sandbox for mode ("bwrap", "nsjail", or "auto" for
+// either) and returns an Isolation that mounts workspaceDir for every skill
+// except those in exempt. It returns an error when isolation is requested
+// but not available, in which case skills should run as before.
+func NewIsolation(mode, workspaceDir string, exempt []string) (*Isolation, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("skill isolation is only supported on Linux")
+	}
+	candidates := []string{mode}
+	if mode == "auto" {
+		candidates = []string{"bwrap", "nsjail"}
+	}
+	for _, name := range candidates {
+		if name != "bwrap" && name != "nsjail" {
+			return nil, fmt.Errorf("unknown skill isolation %q", name)
+		}
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		iso := &Isolation{tool: name, path: path, workspace: workspaceDir, exempt: make(map[string]bool)}
+		for _, s := range exempt {
+			iso.exempt[s] = true
+		}
+		return iso, nil
+	}
+	return nil, fmt.Errorf("%s not found on PATH", strings.Join(candidates, " or "))
+}
+
+// wrap returns the command line that runs argv for the skill m in dir
+// inside the sandbox. A nil *Isolation, or a skill exempted from it, runs
+// argv unchanged.
+func (iso *Isolation) wrap(m *Manifest, dir string, argv []string) []string {
+	if iso == nil || iso.exempt[m.Name] {
+		return argv
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	var writable []string
+	for _, d := range []string{dir, iso.workspace} {
+		if d != "" {
+			writable = append(writable, d)
+		}
+	}
+	var args []string
+	if iso.tool == "nsjail" {
+		args = iso.nsjailArgs(m, dir, writable)
+		// nsjail doesn't search PATH for the program.
+		if !strings.ContainsRune(argv[0], '/') {
+			if path, err := exec.LookPath(argv[0]); err == nil {
+				argv = append([]string{path}, argv[1:]...)
+			}
+		}
+	} else {
+		args = iso.bwrapArgs(m, dir, writable)
+	}
+	args = append(args, "--")
+	return append(append([]string{iso.path}, args...), argv...)
+}
+
+func (iso *Isolation) bwrapArgs(m *Manifest, dir string, writable []string) []string {
+	args := []string{"--die-with-parent", "--new-session", "--unshare-all"}
+	if m.Network {
+		args = append(args, "--share-net")
+	}
+	for _, d := range systemDirs {
+		args = append(args, "--ro-bind-try", d, d)
+	}
+	args = append(args, "--proc", "/proc", "--dev", "/dev", "--tmpfs", "/tmp")
+	for _, d := range writable {
+		args = append(args, "--bind", d, d)
+	}
+	return append(args, "--chdir", dir)
+}
+
+func (iso *Isolation) nsjailArgs(m *Manifest, dir string, writable []string) []string {
+	// Limits are left to the skill timeout; nsjail's defaults are small.
+	args := []string{"--mode", "o", "--really_quiet", "--time_limit", "0", "--disable_rlimits", "--keep_env"}
+	if m.Network {
+		args = append(args, "--disable_clone_newnet")
+	}
+	// nsjail fails on mounts whose source doesn't exist.
+	for _, d := range systemDirs {
+		if _, err := os.Stat(d); err == nil {
+			args = append(args, "--bindmount_ro", d)
+		}
+	}
+	args = append(args, "--bindmount", "/dev/null", "--bindmount_ro", "/dev/urandom", "--tmpfsmount", "/tmp")
+	for _, d := range writable {
+		args = append(args, "--bindmount", d)
+	}
+	return append(args, "--cwd", dir)
+}
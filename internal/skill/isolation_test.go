@@ -0,0 +1,93 @@
+package skill
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIsolationWrap(t *testing.T) {
+	dir := t.TempDir()
+	argv := []string{"./run", "--flag"}
+	m := &Manifest{Name: "s"}
+
+	var none *Isolation
+	if got := none.wrap(m, dir, argv); strings.Join(got, " ") != "./run --flag" {
+		t.Errorf("nil isolation changed the command: %q", got)
+	}
+
+	bwrap := &Isolation{tool: "bwrap", path: "/usr/bin/bwrap", workspace: "/ws", exempt: map[string]bool{"trusted": true}}
+	got := strings.Join(bwrap.wrap(m, dir, argv), " ")
+	for _, want := range []string{
+		"/usr/bin/bwrap ",
+		"--unshare-all",
+		"--ro-bind-try /usr /usr",
+		"--bind " + dir + " " + dir,
+		"--bind /ws /ws",
+		"--chdir " + dir,
+		" -- ./run --flag",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("bwrap command lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "--share-net") {
+		t.Errorf("network shared without being requested:\n%s", got)
+	}
+	if got := strings.Join(bwrap.wrap(&Manifest{Name: "s", Network: true}, dir, argv), " "); !strings.Contains(got, "--share-net") {
+		t.Errorf("requested network not shared:\n%s", got)
+	}
+	if got := bwrap.wrap(&Manifest{Name: "trusted"}, dir, argv); strings.Join(got, " ") != "./run --flag" {
+		t.Errorf("exempt skill was isolated: %q", got)
+	}
+
+	nsjail := &Isolation{tool: "nsjail", path: "/usr/bin/nsjail", workspace: "/ws"}
+	got = strings.Join(nsjail.wrap(m, dir, argv), " ")
+	for _, want := range []string{"--bindmount " + dir, "--bindmount /ws", "--cwd " + dir, " -- ./run --flag"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("nsjail command lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "--disable_clone_newnet") {
+		t.Errorf("network shared without being requested:\n%s", got)
+	}
+}
+
+func TestIsolatedSkillRunsThroughSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("isolation is Linux-only")
+	}
+	bin := t.TempDir()
+	path := os.Getenv("PATH")
+
+	// Without bwrap or nsjail, isolation is unavailable.
+	t.Setenv("PATH", bin)
+	if _, err := NewIsolation("auto", "", nil); err == nil {
+		t.Fatal("expected an error without a sandbox installed")
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+
+	// A stand-in bwrap that records its arguments and runs the command.
+	record := filepath.Join(t.TempDir(), "args")
+	fake := "#!/bin/sh\necho \"$@\" > " + record + "\nwhile [ \"$1\" != -- ]; do shift; done\nshift\nexec \"$@\"\n"
+	os.WriteFile(filepath.Join(bin, "bwrap"), []byte(fake), 0755)
+	iso, err := NewIsolation("bwrap", "/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := NewSkillTool(Manifest{Name: "echo", Command: "echo hello"}, t.TempDir(), 10, false)
+	st.isolation = iso
+	res, _ := st.Execute(context.Background(), json.RawMessage(`{}`))
+	if res.IsError || strings.TrimSpace(res.Output) != "hello" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	args, _ := os.ReadFile(record)
+	if !strings.Contains(string(args), "--unshare-all") || !strings.Contains(string(args), "-- echo hello") {
+		t.Errorf("skill didn't run through the sandbox: %s", args)
+	}
+}
@@ -1,12 +1,21 @@
 package skill
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"open-dan/internal/eventbus"
 	"open-dan/internal/tool"
 )
 
@@ -17,6 +26,17 @@ type Loader struct {
 	skillsDir      string
 	defaultTimeout int
 	sandbox        bool
+	verifier       *Verifier
+	secrets        SecretStore
+	policy         *tool.URLPolicy
+	bus            *eventbus.Bus
+	isolation      *Isolation
+	client         *http.Client  // for Install; overridable in tests
+	reloadDelay    time.Duration // for Watch; overridable in tests
+
+	mu         sync.Mutex
+	registered map[string]bool // tool names added by Sync
+	stopWatch  context.CancelFunc
 }
 
 // NewLoader creates a new skill loader.
@@ -28,9 +48,41 @@ func NewLoader(skillsDir string, defaultTimeout int, sandbox bool) *Loader {
 		skillsDir:      skillsDir,
 		defaultTimeout: defaultTimeout,
 		sandbox:        sandbox,
+		client:         newInstallClient(),
+		reloadDelay:    defaultReloadDelay,
 	}
 }
 
+// SetVerifier sets how skills are verified before they load. Without one,
+// only checksums the skills declare are checked.
+func (l *Loader) SetVerifier(v *Verifier) {
+	l.verifier = v
+}
+
+// SetSecrets sets where the values of skills' declared environment
+// variables are read from.
+func (l *Loader) SetSecrets(s SecretStore) {
+	l.secrets = s
+}
+
+// SetURLPolicy sets which URLs HTTP skills may call. Without one, HTTP
+// skills load but refuse to run.
+func (l *Loader) SetURLPolicy(p *tool.URLPolicy) {
+	l.policy = p
+}
+
+// SetEventBus sets the bus skills' stderr is published to, as
+// eventbus.TopicSkillLog events.
+func (l *Loader) SetEventBus(bus *eventbus.Bus) {
+	l.bus = bus
+}
+
+// SetIsolation sets the sandbox command skills run in. Without one, they
+// run directly.
+func (l *Loader) SetIsolation(iso *Isolation) {
+	l.isolation = iso
+}
+
 // LoadAll scans the skills directory and returns Tool implementations for enabled skills.
 // If enabledSkills is nil or empty, all discovered skills are loaded.
 func (l *Loader) LoadAll(enabledSkills []string) ([]tool.Tool, error) {
@@ -54,7 +106,8 @@ func (l *Loader) LoadAll(enabledSkills []string) ([]tool.Tool, error) {
 	var tools []tool.Tool
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Dot-directories include skills still being installed.
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
@@ -66,14 +119,25 @@ func (l *Loader) LoadAll(enabledSkills []string) ([]tool.Tool, error) {
 		}
 
 		dir := filepath.Join(l.skillsDir, name)
-		manifestPath := filepath.Join(dir, "manifest.json")
-
-		manifest, err := parseManifest(manifestPath)
+		manifest, err := l.load(dir)
+		if errors.Is(err, errUnverified) {
+			log.Printf("[skill] not loading %s: %v", name, err)
+			continue
+		}
 		if err != nil {
 			continue // Skip invalid skills
 		}
+		if missing := missingBinaries(manifest); len(missing) > 0 {
+			log.Printf("[skill] not loading %s: missing %s", name, strings.Join(missing, ", "))
+			continue
+		}
 
-		tools = append(tools, NewSkillTool(*manifest, dir, l.defaultTimeout, l.sandbox))
+		st := NewSkillTool(*manifest, dir, l.defaultTimeout, l.sandbox)
+		st.secrets = l.secrets
+		st.policy = l.policy
+		st.bus = l.bus
+		st.isolation = l.isolation
+		tools = append(tools, st)
 	}
 
 	return tools, nil
@@ -98,21 +162,24 @@ func (l *Loader) ListInstalled(enabledSkills []string) []SkillInfo {
 	var skills []SkillInfo
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Dot-directories include skills still being installed.
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
 		name := entry.Name()
 		dir := filepath.Join(l.skillsDir, name)
-		manifestPath := filepath.Join(dir, "manifest.json")
-
-		manifest, err := parseManifest(manifestPath)
-		if err != nil {
+		manifest, err := l.load(dir)
+		var verifyErr string
+		if errors.Is(err, errUnverified) {
+			verifyErr = err.Error()
+		} else if err != nil {
 			continue
 		}
 
 		// If no enabledSkills filter, all are enabled
 		enabled := len(enabledSet) == 0 || enabledSet[name]
+		missing := missingBinaries(manifest)
 
 		skills = append(skills, SkillInfo{
 			Name:        manifest.Name,
@@ -120,13 +187,46 @@ func (l *Loader) ListInstalled(enabledSkills []string) []SkillInfo {
 			Description: manifest.Description,
 			Author:      manifest.Author,
 			Enabled:     enabled,
+			Available:   verifyErr == "" && len(missing) == 0,
+			Missing:     missing,
+			Env:         manifest.Env,
+			Error:       verifyErr,
 		})
 	}
 
 	return skills
 }
 
+// errUnverified wraps the reason a skill with a valid manifest failed
+// verification.
+var errUnverified = errors.New("verification failed")
+
+// load reads and verifies the skill in dir. A skill that fails
+// verification returns its manifest along with an errUnverified error.
+func (l *Loader) load(dir string) (*Manifest, error) {
+	data, err := readManifest(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	m, err := decodeManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.verifier.verify(dir, data, m); err != nil {
+		return m, fmt.Errorf("%w: %v", errUnverified, err)
+	}
+	return m, nil
+}
+
 func parseManifest(path string) (*Manifest, error) {
+	data, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeManifest(data)
+}
+
+func readManifest(path string) ([]byte, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -138,14 +238,38 @@ func parseManifest(path string) (*Manifest, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading manifest: %w", err)
 	}
+	return data, nil
+}
 
+func decodeManifest(data []byte) (*Manifest, error) {
 	var m Manifest
 	if err := json.Unmarshal(data, &m); err != nil {
 		return nil, fmt.Errorf("invalid manifest: %w", err)
 	}
 
-	if m.Name == "" || m.Command == "" {
-		return nil, fmt.Errorf("manifest missing required fields (name, command)")
+	switch m.Type {
+	case "", TypeCommand:
+		if m.Name == "" || m.Command == "" {
+			return nil, fmt.Errorf("manifest missing required fields (name, command)")
+		}
+	case TypeHTTP:
+		if m.Name == "" || m.URL == "" {
+			return nil, fmt.Errorf("manifest missing required fields (name, url)")
+		}
+		if u, err := url.Parse(m.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid manifest: url must be an http(s) URL")
+		}
+	default:
+		return nil, fmt.Errorf("invalid manifest: unknown type %q", m.Type)
+	}
+	if err := validateEnv(m.Env); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := validateRequires(m.Requires); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if _, err := compileArgsSchema(m.Parameters); err != nil {
+		return nil, fmt.Errorf("invalid manifest: parameters: %w", err)
 	}
 
 	return &m, nil
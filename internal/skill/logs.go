@@ -0,0 +1,70 @@
+package skill
+
+import (
+	"bytes"
+	"strings"
+
+	"open-dan/internal/eventbus"
+)
+
+// maxLogLine caps one stderr line published as a LogLine; longer lines are
+// split.
+const maxLogLine = 4096
+
+// LogLine is published on eventbus.TopicSkillLog for each line a skill
+// writes to stderr while it runs.
+type LogLine struct {
+	Skill  string `json:"skill"`
+	ChatID string `json:"chat_id,omitempty"`
+	Line   string `json:"line"`
+}
+
+// logWriter publishes what is written to it as LogLines, one per line.
+// Call flush when the skill exits to publish a last line without a newline.
+type logWriter struct {
+	bus     *eventbus.Bus
+	skill   string
+	chatID  string
+	pending []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			if len(w.pending) >= maxLogLine {
+				w.publish(w.pending[:maxLogLine])
+				w.pending = w.pending[maxLogLine:]
+				continue
+			}
+			break
+		}
+		if i > maxLogLine {
+			i = maxLogLine
+			w.publish(w.pending[:i])
+			w.pending = w.pending[i:]
+			continue
+		}
+		w.publish(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	// Don't keep the consumed prefix alive.
+	w.pending = append([]byte(nil), w.pending...)
+	return len(p), nil
+}
+
+func (w *logWriter) flush() {
+	if len(w.pending) > 0 {
+		w.publish(w.pending)
+		w.pending = nil
+	}
+}
+
+func (w *logWriter) publish(line []byte) {
+	w.bus.Publish(eventbus.TopicSkillLog, LogLine{
+		Skill:  w.skill,
+		ChatID: w.chatID,
+		Line:   strings.TrimRight(string(line), "\r"),
+	})
+}
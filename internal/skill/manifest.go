@@ -2,6 +2,12 @@ package skill
 
 import "encoding/json"
 
+// Skill types for Manifest.Type.
+const (
+	TypeCommand = "command"
+	TypeHTTP    = "http"
+)
+
 // Manifest describes a skill plugin loaded from disk.
 type Manifest struct {
 	Name        string          `json:"name"`
@@ -11,6 +17,30 @@ type Manifest struct {
 	Parameters  json.RawMessage `json:"parameters"`
 	Command     string          `json:"command"`
 	TimeoutSecs int             `json:"timeout_secs,omitempty"`
+	// Type is "command" (default) to run Command, or "http" to POST the
+	// arguments to URL instead.
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url,omitempty"`
+	// Deterministic skills always give the same output for the same input,
+	// so successful results are cached and reused instead of re-running.
+	Deterministic bool `json:"deterministic,omitempty"`
+	// CacheScope is "chat" (default) to cache per conversation or "global"
+	// to share results across chats. Only used when Deterministic is set.
+	CacheScope string `json:"cache_scope,omitempty"`
+	// SHA256 is the hex SHA-256 of the skill's script, checked before the
+	// skill loads and before every run.
+	SHA256 string `json:"sha256,omitempty"`
+	// Script is the file, relative to the skill directory, that SHA256
+	// covers. By default it is the first file named in Command.
+	Script string `json:"script,omitempty"`
+	// Env declares environment variables the skill reads, such as API keys.
+	// Their values are set by the user; see EnvVar.
+	Env []EnvVar `json:"env,omitempty"`
+	// Requires lists binaries, such as jq or ffmpeg, that must be on PATH
+	// for the skill to load.
+	Requires []string `json:"requires,omitempty"`
+	// Network lets the skill reach the network when it runs isolated.
+	Network bool `json:"network,omitempty"`
 }
 
 // SkillInfo is a summary of an installed skill (exposed to UI).
@@ -20,4 +50,13 @@ type SkillInfo struct {
 	Description string `json:"description"`
 	Author      string `json:"author"`
 	Enabled     bool   `json:"enabled"`
+	// Available is false when the skill can't load, because a required
+	// binary is missing or it failed verification.
+	Available bool `json:"available"`
+	// Missing lists the required binaries that aren't on PATH.
+	Missing []string `json:"missing,omitempty"`
+	// Env lists the environment variables the skill needs set.
+	Env []EnvVar `json:"env,omitempty"`
+	// Error says why the skill failed verification and isn't loaded.
+	Error string `json:"error,omitempty"`
 }
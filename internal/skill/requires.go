@@ -0,0 +1,29 @@
+package skill
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// validateRequires checks the binaries a manifest declares.
+func validateRequires(names []string) error {
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, "\x00\n") {
+			return fmt.Errorf("invalid requires entry %q", name)
+		}
+	}
+	return nil
+}
+
+// missingBinaries returns the binaries the manifest requires that aren't
+// on PATH.
+func missingBinaries(m *Manifest) []string {
+	var missing []string
+	for _, name := range m.Requires {
+		if _, err := exec.LookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,74 @@
+package skill
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// argsSchema checks a skill's arguments against its manifest's parameters.
+type argsSchema struct {
+	schema *jsonschema.Schema
+}
+
+// compileArgsSchema compiles a manifest's parameters schema. It returns nil
+// if the manifest has none, so arguments aren't checked. References to
+// other documents are not loaded.
+func compileArgsSchema(params json.RawMessage) (*argsSchema, error) {
+	if len(bytes.TrimSpace(params)) == 0 || string(bytes.TrimSpace(params)) == "null" {
+		return nil, nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(params))
+	if err != nil {
+		return nil, err
+	}
+	c := jsonschema.NewCompiler()
+	c.UseLoader(jsonschema.SchemeURLLoader{})
+	if err := c.AddResource("parameters.json", doc); err != nil {
+		return nil, err
+	}
+	sch, err := c.Compile("parameters.json")
+	if err != nil {
+		return nil, err
+	}
+	return &argsSchema{schema: sch}, nil
+}
+
+// validate checks args, returning an error listing every violation. A nil
+// *argsSchema accepts anything.
+func (s *argsSchema) validate(args json.RawMessage) error {
+	if s == nil {
+		return nil
+	}
+	if len(bytes.TrimSpace(args)) == 0 {
+		args = json.RawMessage(`{}`)
+	}
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(args))
+	if err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	err = s.schema.Validate(inst)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	var problems []string
+	collectViolations(verr, &problems)
+	return fmt.Errorf("arguments don't match the parameters schema:\n- %s", strings.Join(problems, "\n- "))
+}
+
+// collectViolations appends the leaf errors of e, each of the form
+// "at '/path': what is wrong".
+func collectViolations(e *jsonschema.ValidationError, out *[]string) {
+	if len(e.Causes) == 0 {
+		*out = append(*out, e.Error())
+		return
+	}
+	for _, c := range e.Causes {
+		collectViolations(c, out)
+	}
+}
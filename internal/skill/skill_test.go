@@ -2,12 +2,24 @@ package skill
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"open-dan/internal/config"
+	"open-dan/internal/eventbus"
+	"open-dan/internal/tool"
 )
 
 func TestManifestParsing(t *testing.T) {
@@ -184,3 +196,485 @@ func TestSkillToolTimeout(t *testing.T) {
 		t.Fatalf("timeout took too long: %v", elapsed)
 	}
 }
+
+func TestDeterministicSkillCache(t *testing.T) {
+	dir := t.TempDir()
+
+	// Each run appends a line to runs.log, so we can count executions
+	script := filepath.Join(dir, "count.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\necho run >> runs.log\ncat\n"), 0755)
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(dir, "runs.log"))
+		return strings.Count(string(data), "run\n")
+	}
+
+	manifest := Manifest{
+		Name:          "pure",
+		Version:       "1.0.0",
+		Command:       "sh count.sh",
+		Deterministic: true,
+	}
+	st := NewSkillTool(manifest, dir, 10, false)
+	ctx := tool.WithChatID(context.Background(), "chat1")
+
+	first, _ := st.Execute(ctx, json.RawMessage(`{"a":1,"b":2}`))
+	second, _ := st.Execute(ctx, json.RawMessage(`{ "b": 2, "a": 1 }`))
+	if runs() != 1 {
+		t.Fatalf("expected identical input to be served from cache, got %d runs", runs())
+	}
+	if first.Output != second.Output {
+		t.Fatalf("cached output differs: %q vs %q", first.Output, second.Output)
+	}
+
+	st.Execute(ctx, json.RawMessage(`{"a":2}`))
+	if runs() != 2 {
+		t.Fatalf("expected different input to re-run, got %d runs", runs())
+	}
+
+	// The default scope is per chat
+	st.Execute(tool.WithChatID(context.Background(), "chat2"), json.RawMessage(`{"a":1,"b":2}`))
+	if runs() != 3 {
+		t.Fatalf("expected another chat to re-run, got %d runs", runs())
+	}
+}
+
+func TestDeterministicSkillGlobalScope(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "count.sh"), []byte("#!/bin/sh\necho run >> runs.log\ncat\n"), 0755)
+
+	manifest := Manifest{Name: "pure", Version: "1.0.0", Command: "sh count.sh", Deterministic: true, CacheScope: "global"}
+	st := NewSkillTool(manifest, dir, 10, false)
+	st.Execute(tool.WithChatID(context.Background(), "chat1"), json.RawMessage(`{}`))
+	st.Execute(tool.WithChatID(context.Background(), "chat2"), json.RawMessage(`{}`))
+
+	data, _ := os.ReadFile(filepath.Join(dir, "runs.log"))
+	if n := strings.Count(string(data), "run\n"); n != 1 {
+		t.Fatalf("expected a global cache hit across chats, got %d runs", n)
+	}
+}
+
+func TestNonDeterministicSkillAlwaysRuns(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "count.sh"), []byte("#!/bin/sh\necho run >> runs.log\ncat\n"), 0755)
+
+	st := NewSkillTool(Manifest{Name: "impure", Version: "1.0.0", Command: "sh count.sh"}, dir, 10, false)
+	st.Execute(context.Background(), json.RawMessage(`{}`))
+	st.Execute(context.Background(), json.RawMessage(`{}`))
+
+	data, _ := os.ReadFile(filepath.Join(dir, "runs.log"))
+	if n := strings.Count(string(data), "run\n"); n != 2 {
+		t.Fatalf("expected 2 runs, got %d", n)
+	}
+}
+
+// writeSkill installs a skill running hello.sh under dir/name and returns
+// its directory and manifest bytes.
+func writeSkill(t *testing.T, dir, name string, m Manifest) (string, []byte) {
+	t.Helper()
+	skillDir := filepath.Join(dir, name)
+	os.MkdirAll(skillDir, 0755)
+	os.WriteFile(filepath.Join(skillDir, "hello.sh"), []byte("#!/bin/sh\necho hello\n"), 0755)
+	m.Name, m.Version, m.Command = name, "1.0.0", "sh hello.sh"
+	data, _ := json.Marshal(m)
+	os.WriteFile(filepath.Join(skillDir, "manifest.json"), data, 0644)
+	return skillDir, data
+}
+
+func helloChecksum() string {
+	sum := sha256.Sum256([]byte("#!/bin/sh\necho hello\n"))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSkillChecksums(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, dir, "matching", Manifest{SHA256: helloChecksum()})
+	writeSkill(t, dir, "mismatching", Manifest{SHA256: strings.Repeat("0", 64)})
+	writeSkill(t, dir, "missing", Manifest{})
+
+	loaded := func(v *Verifier) []string {
+		l := NewLoader(dir, 10, false)
+		l.SetVerifier(v)
+		tools, err := l.LoadAll(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, tl := range tools {
+			names = append(names, tl.Name())
+		}
+		return names
+	}
+
+	if got := strings.Join(loaded(nil), ","); got != "skill_matching,skill_missing" {
+		t.Errorf("default mode loaded %s, want the matching and missing skills", got)
+	}
+	strict, _ := NewVerifier("strict", nil)
+	if got := strings.Join(loaded(strict), ","); got != "skill_matching" {
+		t.Errorf("strict mode loaded %s, want only the matching skill", got)
+	}
+
+	l := NewLoader(dir, 10, false)
+	l.SetVerifier(strict)
+	for _, info := range l.ListInstalled(nil) {
+		if wantErr := info.Name != "matching"; (info.Error != "") != wantErr {
+			t.Errorf("%s: Error = %q", info.Name, info.Error)
+		}
+	}
+}
+
+func TestSkillChecksumCheckedBeforeRun(t *testing.T) {
+	skillDir, _ := writeSkill(t, t.TempDir(), "hello", Manifest{})
+	m := Manifest{Name: "hello", Version: "1.0.0", Command: "sh hello.sh", SHA256: helloChecksum()}
+	st := NewSkillTool(m, skillDir, 10, false)
+
+	res, _ := st.Execute(context.Background(), json.RawMessage(`{}`))
+	if res.IsError || res.Output != "hello\n" {
+		t.Fatalf("matching checksum: %+v", res)
+	}
+
+	// Tampered after loading.
+	os.WriteFile(filepath.Join(skillDir, "hello.sh"), []byte("#!/bin/sh\necho pwned\n"), 0755)
+	res, _ = st.Execute(context.Background(), json.RawMessage(`{}`))
+	if !res.IsError || !strings.Contains(res.Error, "checksum mismatch") {
+		t.Fatalf("tampered script ran: %+v", res)
+	}
+}
+
+func TestSkillSignatures(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+	dir := t.TempDir()
+	sign := func(name string, key ed25519.PrivateKey) {
+		skillDir, data := writeSkill(t, dir, name, Manifest{SHA256: helloChecksum()})
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+		os.WriteFile(filepath.Join(skillDir, signatureFile), []byte(sig+"\n"), 0644)
+	}
+	sign("signed", priv)
+	sign("untrusted", otherPriv)
+	writeSkill(t, dir, "unsigned", Manifest{SHA256: helloChecksum()})
+
+	// Tamper with a signed manifest.
+	sign("tampered", priv)
+	manifestPath := filepath.Join(dir, "tampered", "manifest.json")
+	data, _ := os.ReadFile(manifestPath)
+	os.WriteFile(manifestPath, []byte(strings.Replace(string(data), "1.0.0", "1.0.1", 1)), 0644)
+
+	for _, tc := range []struct {
+		mode string
+		want string
+	}{
+		{"", "skill_signed,skill_unsigned"},
+		{"strict", "skill_signed"},
+	} {
+		v, err := NewVerifier(tc.mode, []string{base64.StdEncoding.EncodeToString(pub)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := NewLoader(dir, 10, false)
+		l.SetVerifier(v)
+		tools, _ := l.LoadAll(nil)
+		var names []string
+		for _, tl := range tools {
+			names = append(names, tl.Name())
+		}
+		if got := strings.Join(names, ","); got != tc.want {
+			t.Errorf("mode %q loaded %s, want %s", tc.mode, got, tc.want)
+		}
+	}
+
+	if _, err := NewVerifier("", []string{"c2hvcnQ="}); err == nil {
+		t.Error("expected an error for a short key")
+	}
+}
+
+// mapSecrets is a SecretStore backed by a map.
+type mapSecrets map[string]string
+
+func (m mapSecrets) Get(name string) (string, error) {
+	if v, ok := m[name]; ok {
+		return v, nil
+	}
+	return "", os.ErrNotExist
+}
+
+func TestSkillEnvironment(t *testing.T) {
+	t.Setenv("OPENDAN_TEST_PARENT_SECRET", "leaked")
+	dir := t.TempDir()
+	manifest := Manifest{
+		Name:    "env_skill",
+		Version: "1.0.0",
+		Command: "env",
+		Env: []EnvVar{
+			{Name: "WEATHER_API_KEY", Required: true},
+			{Name: "WEATHER_UNITS"},
+		},
+	}
+	st := NewSkillTool(manifest, dir, 10, false)
+
+	res, _ := st.Execute(context.Background(), json.RawMessage(`{}`))
+	if !res.IsError || !strings.Contains(res.Error, "WEATHER_API_KEY") {
+		t.Fatalf("expected the missing required variable to stop the run, got %+v", res)
+	}
+
+	st.secrets = mapSecrets{EnvSecretName("env_skill", "WEATHER_API_KEY"): "k-123"}
+	res, _ = st.Execute(context.Background(), json.RawMessage(`{}`))
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if !strings.Contains(res.Output, "WEATHER_API_KEY=k-123\n") {
+		t.Errorf("declared secret not passed:\n%s", res.Output)
+	}
+	if !strings.Contains(res.Output, "PATH=") {
+		t.Errorf("PATH not passed:\n%s", res.Output)
+	}
+	if strings.Contains(res.Output, "OPENDAN_TEST_PARENT_SECRET") || strings.Contains(res.Output, "WEATHER_UNITS") {
+		t.Errorf("undeclared or unset variable passed:\n%s", res.Output)
+	}
+}
+
+func TestManifestRejectsInvalidEnv(t *testing.T) {
+	for _, env := range []string{
+		`[{"name": "BAD-NAME"}]`,
+		`[{"name": "KEY"}, {"name": "KEY"}]`,
+	} {
+		_, err := decodeManifest([]byte(`{"name": "s", "command": "echo", "env": ` + env + `}`))
+		if err == nil {
+			t.Errorf("env %s: expected an error", env)
+		}
+	}
+}
+
+func TestHTTPSkill(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "want a JSON POST", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "upstream down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("got " + string(body)))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	policy := tool.NewURLPolicy(config.HTTPRequestConfig{
+		AllowedHosts:        []string{u.Hostname()},
+		AllowPrivateNetwork: true,
+	})
+
+	newSkill := func(path string) *SkillTool {
+		st := NewSkillTool(Manifest{Name: "web", Type: TypeHTTP, URL: srv.URL + path}, t.TempDir(), 10, false)
+		st.policy = policy
+		return st
+	}
+
+	res, _ := newSkill("/ok").Execute(context.Background(), json.RawMessage(`{"q":1}`))
+	if res.IsError || res.Output != `got {"q":1}` {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	res, _ = newSkill("/fail").Execute(context.Background(), json.RawMessage(`{}`))
+	if !res.IsError || !strings.Contains(res.Error, "502") || !strings.Contains(res.Error, "upstream down") {
+		t.Errorf("expected the error status to be reported, got %+v", res)
+	}
+
+	st := newSkill("/ok")
+	st.policy = tool.NewURLPolicy(config.HTTPRequestConfig{AllowedHosts: []string{"api.example.com"}, AllowPrivateNetwork: true})
+	res, _ = st.Execute(context.Background(), json.RawMessage(`{}`))
+	if !res.IsError || !strings.Contains(res.Error, "allowed_hosts") {
+		t.Errorf("expected a host not on the allowlist to be refused, got %+v", res)
+	}
+
+	st = newSkill("/ok")
+	st.policy = nil
+	res, _ = st.Execute(context.Background(), json.RawMessage(`{}`))
+	if !res.IsError {
+		t.Error("expected an HTTP skill without a URL policy to be refused")
+	}
+}
+
+func TestManifestTypes(t *testing.T) {
+	for _, tc := range []struct {
+		manifest string
+		ok       bool
+	}{
+		{`{"name": "s", "command": "echo"}`, true},
+		{`{"name": "s", "type": "command", "command": "echo"}`, true},
+		{`{"name": "s", "type": "http", "url": "https://api.example.com/run"}`, true},
+		{`{"name": "s", "type": "http"}`, false},
+		{`{"name": "s", "type": "http", "url": "file:///etc/passwd"}`, false},
+		{`{"name": "s", "type": "grpc", "command": "echo"}`, false},
+	} {
+		_, err := decodeManifest([]byte(tc.manifest))
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.manifest, err)
+		}
+	}
+}
+
+func TestStrictModeRequiresSignedHTTPSkills(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, Manifest{Name: "web", Type: TypeHTTP, URL: "https://api.example.com/run"})
+	data, _ := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	m, err := decodeManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (*Verifier)(nil).verify(dir, data, m); err != nil {
+		t.Errorf("unexpected error outside strict mode: %v", err)
+	}
+	strict, _ := NewVerifier("strict", nil)
+	if err := strict.verify(dir, data, m); err == nil {
+		t.Error("expected strict mode to refuse an unsigned HTTP skill")
+	}
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	strict, _ = NewVerifier("strict", []string{base64.StdEncoding.EncodeToString(pub)})
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+	os.WriteFile(filepath.Join(dir, signatureFile), []byte(sig), 0644)
+	if err := strict.verify(dir, data, m); err != nil {
+		t.Errorf("signed HTTP skill refused: %v", err)
+	}
+}
+
+func TestSkillRequiredBinaries(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	os.WriteFile(filepath.Join(bin, "present-tool"), []byte("#!/bin/sh\n"), 0755)
+
+	dir := t.TempDir()
+	writeManifest(t, filepath.Join(dir, "ok"), Manifest{Name: "ok", Command: "present-tool", Requires: []string{"present-tool"}})
+	writeManifest(t, filepath.Join(dir, "needs"), Manifest{Name: "needs", Command: "absent-tool", Requires: []string{"present-tool", "absent-tool"}})
+	loader := NewLoader(dir, 30, false)
+
+	tools, err := loader.LoadAll(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Name() != "skill_ok" {
+		t.Fatalf("expected only the skill with its binaries present to load, got %d tools", len(tools))
+	}
+
+	info := make(map[string]SkillInfo)
+	for _, s := range loader.ListInstalled(nil) {
+		info[s.Name] = s
+	}
+	if s := info["ok"]; !s.Available || len(s.Missing) != 0 {
+		t.Errorf("ok: %+v", s)
+	}
+	if s := info["needs"]; s.Available || strings.Join(s.Missing, ",") != "absent-tool" {
+		t.Errorf("needs: %+v", s)
+	}
+
+	// Installing the binary makes the skill load on the next scan.
+	os.WriteFile(filepath.Join(bin, "absent-tool"), []byte("#!/bin/sh\n"), 0755)
+	if tools, _ := loader.LoadAll(nil); len(tools) != 2 {
+		t.Errorf("expected both skills to load, got %d", len(tools))
+	}
+
+	if _, err := decodeManifest([]byte(`{"name": "s", "command": "echo", "requires": [""]}`)); err == nil {
+		t.Error("expected an empty requires entry to be rejected")
+	}
+}
+
+func TestSkillArgumentsValidated(t *testing.T) {
+	manifest := Manifest{
+		Name:    "weather",
+		Command: "cat",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"location": {"type": "string"},
+				"days": {"type": "integer", "minimum": 1}
+			},
+			"required": ["location"]
+		}`),
+	}
+	st := NewSkillTool(manifest, t.TempDir(), 10, false)
+
+	res, _ := st.Execute(context.Background(), json.RawMessage(`{"location": "Oslo", "days": 3}`))
+	if res.IsError || !strings.Contains(res.Output, "Oslo") {
+		t.Fatalf("valid call failed: %+v", res)
+	}
+
+	for _, tc := range []struct {
+		args string
+		want []string
+	}{
+		{`{}`, []string{"location"}},
+		{`{"location": 5, "days": 0}`, []string{"/location", "/days"}},
+		{`not json`, []string{"not valid JSON"}},
+	} {
+		res, _ := st.Execute(context.Background(), json.RawMessage(tc.args))
+		if !res.IsError {
+			t.Errorf("%s: expected a validation error, got %+v", tc.args, res)
+			continue
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(res.Error, w) {
+				t.Errorf("%s: error doesn't mention %s:\n%s", tc.args, w, res.Error)
+			}
+		}
+	}
+
+	// Without parameters, any arguments are passed through.
+	st = NewSkillTool(Manifest{Name: "free", Command: "cat"}, t.TempDir(), 10, false)
+	res, _ = st.Execute(context.Background(), json.RawMessage(`{"anything": true}`))
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+
+	if _, err := decodeManifest([]byte(`{"name": "s", "command": "echo", "parameters": {"type": 5}}`)); err == nil {
+		t.Error("expected an invalid parameters schema to be rejected")
+	}
+}
+
+func TestSkillStderrPublished(t *testing.T) {
+	dir := t.TempDir()
+	script := "echo starting >&2\necho ok\nprintf 'failed: %s' \"$(cat)\" >&2\nexit 1\n"
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte(script), 0755)
+	st := NewSkillTool(Manifest{Name: "noisy", Command: "sh run.sh"}, dir, 10, false)
+	bus := eventbus.New()
+	st.bus = bus
+
+	var lines []LogLine
+	bus.Subscribe(eventbus.TopicSkillLog, func(e eventbus.Event) {
+		lines = append(lines, e.Payload.(LogLine))
+	})
+
+	ctx := tool.WithChatID(context.Background(), "chat-1")
+	res, _ := st.Execute(ctx, json.RawMessage(`{"x":1}`))
+	if !res.IsError || res.Error != "starting\nfailed: {\"x\":1}" {
+		t.Errorf("error result changed: %+v", res)
+	}
+	want := []LogLine{
+		{Skill: "noisy", ChatID: "chat-1", Line: "starting"},
+		{Skill: "noisy", ChatID: "chat-1", Line: `failed: {"x":1}`},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("published %+v, want %+v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestLogWriterSplitsLongLines(t *testing.T) {
+	bus := eventbus.New()
+	var lines []string
+	bus.Subscribe(eventbus.TopicSkillLog, func(e eventbus.Event) {
+		lines = append(lines, e.Payload.(LogLine).Line)
+	})
+	w := &logWriter{bus: bus, skill: "s"}
+	w.Write([]byte("a\r\nb"))
+	w.Write([]byte("c\n" + strings.Repeat("x", maxLogLine+10)))
+	w.flush()
+	if len(lines) != 4 || lines[0] != "a" || lines[1] != "bc" || len(lines[2]) != maxLogLine || len(lines[3]) != 10 {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+}
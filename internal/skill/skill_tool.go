@@ -5,20 +5,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"open-dan/internal/eventbus"
 	"open-dan/internal/tool"
 )
 
+// maxSkillOutput caps the output or error text a skill returns.
+const maxSkillOutput = 10000
+
 // SkillTool wraps an external skill script as a tool.Tool.
 type SkillTool struct {
 	manifest   Manifest
 	dir        string
 	timeoutSec int
 	sandbox    bool
+	cache      *resultCache    // nil unless the skill is deterministic
+	secrets    SecretStore     // values of the manifest's env; may be nil
+	policy     *tool.URLPolicy // URLs HTTP skills may call; nil refuses all
+	client     *http.Client    // for HTTP skills
+	schema     *argsSchema     // nil if the manifest has no parameters
+	bus        *eventbus.Bus   // receives stderr as TopicSkillLog; may be nil
+	isolation  *Isolation      // nil runs the command directly
 }
 
 // NewSkillTool creates a SkillTool from a manifest and its directory.
@@ -30,12 +44,24 @@ func NewSkillTool(manifest Manifest, dir string, defaultTimeout int, sandbox boo
 	if timeout <= 0 {
 		timeout = 60
 	}
-	return &SkillTool{
+	st := &SkillTool{
 		manifest:   manifest,
 		dir:        dir,
 		timeoutSec: timeout,
 		sandbox:    sandbox,
 	}
+	if manifest.Deterministic {
+		st.cache = newResultCache()
+	}
+	if manifest.Type == TypeHTTP {
+		st.client = st.newHTTPClient()
+	}
+	schema, err := compileArgsSchema(manifest.Parameters)
+	if err != nil {
+		log.Printf("[skill] %s: not checking arguments, parameters schema is invalid: %v", manifest.Name, err)
+	}
+	st.schema = schema
+	return st
 }
 
 func (s *SkillTool) Name() string { return "skill_" + s.manifest.Name }
@@ -52,6 +78,37 @@ func (s *SkillTool) Parameters() json.RawMessage {
 }
 
 func (s *SkillTool) Execute(ctx context.Context, args json.RawMessage) (*tool.Result, error) {
+	if s.cache == nil {
+		return s.run(ctx, args)
+	}
+
+	scope := tool.ChatIDFromContext(ctx)
+	if s.manifest.CacheScope == "global" {
+		scope = ""
+	}
+	key := cacheKey(scope, args)
+	if res, ok := s.cache.get(key); ok {
+		return res, nil
+	}
+	res, err := s.run(ctx, args)
+	if err == nil && !res.IsError {
+		s.cache.put(key, res)
+	}
+	return res, err
+}
+
+// run executes the skill process with args on stdin.
+func (s *SkillTool) run(ctx context.Context, args json.RawMessage) (*tool.Result, error) {
+	// Reject a malformed call here, where the model can be told what to fix,
+	// rather than let the skill fail on it.
+	if err := s.schema.validate(args); err != nil {
+		return &tool.Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	if s.manifest.Type == TypeHTTP {
+		return s.runHTTP(ctx, args)
+	}
+
 	// Sandbox validation: block dangerous commands
 	if s.sandbox {
 		if err := validateSkillCommand(s.manifest.Command); err != nil {
@@ -59,6 +116,18 @@ func (s *SkillTool) Execute(ctx context.Context, args json.RawMessage) (*tool.Re
 		}
 	}
 
+	// The script may have changed since the skill loaded.
+	if s.manifest.SHA256 != "" {
+		if err := verifyChecksum(s.dir, &s.manifest); err != nil {
+			return &tool.Result{Error: "skill not run: " + err.Error(), IsError: true}, nil
+		}
+	}
+
+	env, err := skillEnv(&s.manifest, s.secrets)
+	if err != nil {
+		return &tool.Result{Error: err.Error(), IsError: true}, nil
+	}
+
 	timeout := time.Duration(s.timeoutSec) * time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -67,9 +136,12 @@ func (s *SkillTool) Execute(ctx context.Context, args json.RawMessage) (*tool.Re
 	if len(parts) == 0 {
 		return &tool.Result{Error: "skill command is empty", IsError: true}, nil
 	}
+	parts = s.isolation.wrap(&s.manifest, s.dir, parts)
 
 	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
 	cmd.Dir = s.dir
+	// Never the app's whole environment, which may hold credentials.
+	cmd.Env = env
 	cmd.WaitDelay = 2 * time.Second
 
 	// Pass arguments via stdin as JSON
@@ -78,21 +150,26 @@ func (s *SkillTool) Execute(ctx context.Context, args json.RawMessage) (*tool.Re
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if s.bus != nil {
+		logs := &logWriter{bus: s.bus, skill: s.manifest.Name, chatID: tool.ChatIDFromContext(ctx)}
+		defer logs.flush()
+		cmd.Stderr = io.MultiWriter(&stderr, logs)
+	}
 
 	if err := cmd.Run(); err != nil {
 		errMsg := stderr.String()
 		if errMsg == "" {
 			errMsg = err.Error()
 		}
-		if len(errMsg) > 10000 {
-			errMsg = errMsg[:10000] + "\n... (truncated)"
+		if len(errMsg) > maxSkillOutput {
+			errMsg = errMsg[:maxSkillOutput] + "\n... (truncated)"
 		}
 		return &tool.Result{Error: errMsg, IsError: true}, nil
 	}
 
 	output := stdout.String()
-	if len(output) > 10000 {
-		output = output[:10000] + "\n... (output truncated)"
+	if len(output) > maxSkillOutput {
+		output = output[:maxSkillOutput] + "\n... (output truncated)"
 	}
 
 	return &tool.Result{Output: output}, nil
@@ -0,0 +1,136 @@
+package skill
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// signatureFile holds a skill's detached signature: the base64 Ed25519
+// signature of the exact bytes of its manifest.json. Since the manifest
+// carries the script's sha256, the signature covers the script too.
+const signatureFile = "manifest.sig"
+
+// Verifier decides which skills may load, by their checksums and
+// signatures. A nil *Verifier checks only what a skill declares.
+type Verifier struct {
+	strict bool
+	keys   []ed25519.PublicKey
+}
+
+// NewVerifier creates a verifier for the given mode ("" or "strict") that
+// accepts signatures by any of trustedKeys, given as base64 Ed25519 public
+// keys.
+func NewVerifier(mode string, trustedKeys []string) (*Verifier, error) {
+	v := &Verifier{strict: mode == "strict"}
+	for i, k := range trustedKeys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(k))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("trusted key %d is not a base64 Ed25519 public key", i)
+		}
+		v.keys = append(v.keys, ed25519.PublicKey(key))
+	}
+	return v, nil
+}
+
+// verify checks the skill in dir, whose manifest.json holds manifestData,
+// before it is loaded. A declared checksum or a signature that doesn't
+// match always fails. In strict mode the skill must also declare a
+// sha256 and, if trusted keys are configured, be signed by one of them.
+// HTTP skills have no sha256, so strict mode requires them to be signed.
+func (v *Verifier) verify(dir string, manifestData []byte, m *Manifest) error {
+	strict := v != nil && v.strict
+
+	sig, err := os.ReadFile(filepath.Join(dir, signatureFile))
+	switch {
+	case err == nil && v != nil && len(v.keys) > 0:
+		if !v.signedByTrustedKey(manifestData, sig) {
+			return fmt.Errorf("%s doesn't match manifest.json or no trusted key signed it", signatureFile)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if strict && len(v.keys) > 0 {
+			return fmt.Errorf("no %s; strict mode requires skills signed by a trusted key", signatureFile)
+		}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("reading %s: %w", signatureFile, err)
+	}
+
+	if m.Type == TypeHTTP {
+		// There is no local script to checksum; only a signature vouches
+		// for the URL.
+		if strict && len(v.keys) == 0 {
+			return fmt.Errorf("strict mode requires HTTP skills to be signed by a trusted key")
+		}
+		return nil
+	}
+
+	if m.SHA256 == "" {
+		if strict {
+			return fmt.Errorf("manifest has no sha256; strict mode requires one")
+		}
+		return nil
+	}
+	return verifyChecksum(dir, m)
+}
+
+func (v *Verifier) signedByTrustedKey(data, sig []byte) bool {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return false
+	}
+	for _, key := range v.keys {
+		if ed25519.Verify(key, data, decoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyChecksum checks the skill's script against the manifest's sha256.
+func verifyChecksum(dir string, m *Manifest) error {
+	path, err := scriptPath(dir, m)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, m.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s: manifest has %s, file has %s", filepath.Base(path), m.SHA256, got)
+	}
+	return nil
+}
+
+// scriptPath returns the file the manifest's sha256 covers: Script if set,
+// otherwise the first word of the command that names a file in the skill
+// directory, such as ./run or the main.py of "python3 main.py".
+func scriptPath(dir string, m *Manifest) (string, error) {
+	if m.Script != "" {
+		if filepath.IsAbs(m.Script) || strings.Contains(m.Script, "..") {
+			return "", fmt.Errorf("script must be a path inside the skill directory: %s", m.Script)
+		}
+		return filepath.Join(dir, m.Script), nil
+	}
+	for _, part := range splitCommand(m.Command) {
+		if filepath.IsAbs(part) || strings.Contains(part, "..") {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, part)); err == nil && info.Mode().IsRegular() {
+			return filepath.Join(dir, part), nil
+		}
+	}
+	return "", fmt.Errorf("no file in the skill directory for the sha256 to check; set script in the manifest")
+}
@@ -0,0 +1,123 @@
+package skill
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"open-dan/internal/tool"
+)
+
+// defaultReloadDelay is how long the watcher waits for changes to settle.
+const defaultReloadDelay = 500 * time.Millisecond
+
+// Sync makes the skill tools in registry match the skills directory. Every
+// skill that loads is registered again, so an edited manifest or script
+// takes effect and cached results are dropped, and skills registered by an
+// earlier Sync that no longer load or aren't in enabledSkills (when it is
+// non-empty) are unregistered. It returns the number of skills loaded.
+func (l *Loader) Sync(registry *tool.Registry, enabledSkills []string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tools, err := l.LoadAll(enabledSkills)
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		registry.Register(t)
+		loaded[t.Name()] = true
+	}
+	for name := range l.registered {
+		if !loaded[name] {
+			registry.Unregister(name)
+			log.Printf("[skill] unloaded %s", name)
+		}
+	}
+	l.registered = loaded
+	return len(tools), nil
+}
+
+// Watch keeps registry in sync with the skills directory until ctx is done
+// or Close is called. Each burst of changes, such as an editor saving or an
+// install unpacking, triggers one Sync with the skills enabled at the time.
+// The skills directory and each skill's top-level directory are watched.
+func (l *Loader) Watch(ctx context.Context, registry *tool.Registry, enabledSkills func() []string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(l.skillsDir); err != nil {
+		w.Close()
+		return err
+	}
+	entries, _ := os.ReadDir(l.skillsDir)
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			w.Add(filepath.Join(l.skillsDir, e.Name()))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l.mu.Lock()
+	if l.stopWatch != nil {
+		l.stopWatch()
+	}
+	l.stopWatch = cancel
+	l.mu.Unlock()
+
+	reload := time.AfterFunc(time.Hour, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := l.Sync(registry, enabledSkills()); err != nil {
+			log.Printf("[skill] reload failed: %v", err)
+		}
+	})
+	reload.Stop()
+
+	go func() {
+		defer w.Close()
+		defer reload.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				// fsnotify isn't recursive, so watch new skill directories.
+				if ev.Has(fsnotify.Create) && filepath.Dir(ev.Name) == filepath.Clean(l.skillsDir) &&
+					!strings.HasPrefix(filepath.Base(ev.Name), ".") {
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						w.Add(ev.Name)
+					}
+				}
+				reload.Reset(l.reloadDelay)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("[skill] watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops Watch.
+func (l *Loader) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopWatch != nil {
+		l.stopWatch()
+		l.stopWatch = nil
+	}
+}
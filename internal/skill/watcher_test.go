@@ -0,0 +1,143 @@
+package skill
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"open-dan/internal/tool"
+)
+
+func writeManifest(t *testing.T, dir string, m Manifest) {
+	t.Helper()
+	os.MkdirAll(dir, 0755)
+	data, _ := json.Marshal(m)
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncRegistersAndUnregistersSkills(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, filepath.Join(dir, "a"), Manifest{Name: "a", Command: "echo a"})
+	writeManifest(t, filepath.Join(dir, "b"), Manifest{Name: "b", Command: "echo b"})
+	registry := tool.NewRegistry()
+	registry.Register(tool.NewCalculatorTool())
+	l := NewLoader(dir, 10, false)
+
+	if n, err := l.Sync(registry, nil); err != nil || n != 2 {
+		t.Fatalf("Sync = %d, %v", n, err)
+	}
+	if len(registry.List()) != 3 {
+		t.Fatalf("expected calc and two skills, got %d tools", len(registry.List()))
+	}
+
+	os.RemoveAll(filepath.Join(dir, "a"))
+	if _, err := l.Sync(registry, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Get("skill_a"); err == nil {
+		t.Error("removed skill still registered")
+	}
+	if _, err := registry.Get("skill_b"); err != nil {
+		t.Error("remaining skill unregistered")
+	}
+	if _, err := registry.Get("calc"); err != nil {
+		t.Error("Sync unregistered a tool it didn't add")
+	}
+}
+
+func TestSyncFollowsEnabledSkills(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, filepath.Join(dir, "a"), Manifest{Name: "a", Command: "echo a"})
+	writeManifest(t, filepath.Join(dir, "b"), Manifest{Name: "b", Command: "echo b"})
+	registry := tool.NewRegistry()
+	l := NewLoader(dir, 10, false)
+
+	defined := func() string {
+		var names []string
+		for _, d := range registry.Definitions() {
+			names = append(names, d.Name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+
+	for _, tc := range []struct {
+		enabled []string
+		want    string
+	}{
+		{[]string{"a"}, "skill_a"},
+		{[]string{"b"}, "skill_b"},
+		{[]string{"a", "b"}, "skill_a,skill_b"},
+		{[]string{"a"}, "skill_a"},
+		{nil, "skill_a,skill_b"}, // no filter enables all
+	} {
+		if _, err := l.Sync(registry, tc.enabled); err != nil {
+			t.Fatal(err)
+		}
+		if got := defined(); got != tc.want {
+			t.Errorf("enabled %v: definitions = %s, want %s", tc.enabled, got, tc.want)
+		}
+	}
+}
+
+func TestWatchReloadsChangedSkills(t *testing.T) {
+	dir := t.TempDir()
+	registry := tool.NewRegistry()
+	l := NewLoader(dir, 10, false)
+	l.reloadDelay = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := l.Watch(ctx, registry, func() []string { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor := func(what string, ok func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !ok() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	description := func() string {
+		st, err := registry.Get("skill_w")
+		if err != nil {
+			return ""
+		}
+		return st.Description()
+	}
+
+	skillDir := filepath.Join(dir, "w")
+	writeManifest(t, skillDir, Manifest{Name: "w", Version: "1", Description: "first", Command: "echo"})
+	waitFor("the new skill to load", func() bool { return strings.Contains(description(), "first") })
+
+	writeManifest(t, skillDir, Manifest{Name: "w", Version: "2", Description: "second", Command: "echo"})
+	waitFor("the edited skill to reload", func() bool { return strings.Contains(description(), "second") })
+
+	// An invalid manifest unloads the skill until it is fixed.
+	os.WriteFile(filepath.Join(skillDir, "manifest.json"), []byte("{"), 0644)
+	waitFor("the broken skill to unload", func() bool { return description() == "" })
+
+	writeManifest(t, skillDir, Manifest{Name: "w", Version: "3", Description: "third", Command: "echo"})
+	waitFor("the fixed skill to load", func() bool { return strings.Contains(description(), "third") })
+
+	os.RemoveAll(skillDir)
+	waitFor("the removed skill to unload", func() bool { return description() == "" })
+
+	// Nothing reloads after Close.
+	l.Close()
+	writeManifest(t, skillDir, Manifest{Name: "w", Version: "4", Description: "fourth", Command: "echo"})
+	time.Sleep(300 * time.Millisecond)
+	if description() != "" {
+		t.Fatal("skill reloaded after Close")
+	}
+}
@@ -5,8 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"net"
-	"net/url"
+	"log"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +24,23 @@ type BrowserTool struct {
 	browser *rod.Browser
 	pages   map[string]*rod.Page
 	nextID  int
+	eval    func(page *rod.Page, js string) (*proto.RuntimeRemoteObject, error) // overridable in tests
+	// waitFor blocks until selector matches an element or ctx ends; overridable in tests.
+	waitFor func(ctx context.Context, page *rod.Page, selector string) error
+	// open loads a URL in a new tab with t.mu held; overridable in tests.
+	open func(url string) (*rod.Page, error)
+	// capture takes a screenshot as described by shot; overridable in tests.
+	capture func(ctx context.Context, page *rod.Page, shot screenshotSpec) ([]byte, error)
+
+	guard urlGuard
+
+	// lastUsed records when each page was last acted on, for idle reaping
+	// and least-recently-used eviction.
+	lastUsed   map[string]time.Time
+	stopReaper chan struct{}
+
+	cookieFile string // encrypted cookie jar; empty disables persistence
+	cookieKey  []byte
 }
 
 // NewBrowserTool creates a new browser tool.
@@ -38,15 +54,37 @@ func NewBrowserTool(cfg config.BrowserConfig) *BrowserTool {
 	if cfg.MaxPageSizeKB <= 0 {
 		cfg.MaxPageSizeKB = 2048
 	}
-	return &BrowserTool{
-		cfg:   cfg,
-		pages: make(map[string]*rod.Page),
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = config.DefaultBrowserUserAgent
 	}
+	if cfg.ViewportWidth <= 0 || cfg.ViewportHeight <= 0 {
+		cfg.ViewportWidth, cfg.ViewportHeight = 1920, 1080
+	}
+	t := &BrowserTool{
+		cfg:      cfg,
+		pages:    make(map[string]*rod.Page),
+		lastUsed: make(map[string]time.Time),
+		eval:     evalPage,
+		waitFor:  waitForElement,
+		capture:  captureScreenshot,
+		guard:    newURLGuard(cfg.AllowPrivateNetwork),
+	}
+	t.open = t.launchPage
+	return t
+}
+
+func evalPage(page *rod.Page, js string) (*proto.RuntimeRemoteObject, error) {
+	return page.Eval(js)
+}
+
+func waitForElement(ctx context.Context, page *rod.Page, selector string) error {
+	_, err := page.Context(ctx).Element(selector)
+	return err
 }
 
 func (t *BrowserTool) Name() string { return "browser" }
 func (t *BrowserTool) Description() string {
-	return "Control a web browser. Actions: navigate (open URL), get_content (page text), click (CSS selector), fill (type text into input), screenshot (capture page), eval_js (run JavaScript), get_links (list all links), close (close tab)."
+	return "Control a web browser. Actions: navigate (open URL), get_content (page text; set readability=true for just the main article), click (CSS selector), fill (type text into input), screenshot (JPEG or PNG of the viewport, the full page, or one element), eval_js (run JavaScript), get_links (list all links), scroll (to top, bottom, or by pixels, optionally repeated to load infinite-scroll lists), extract (text or an attribute of every element matching a selector, as a JSON array), wait_for_selector (wait until a CSS selector matches, up to timeout_ms), save_cookies/load_cookies (keep logins across restarts), list_pages (open tabs with their URL and title, oldest first), close (close tab)."
 }
 
 func (t *BrowserTool) Parameters() json.RawMessage {
@@ -55,7 +93,7 @@ func (t *BrowserTool) Parameters() json.RawMessage {
 		"properties": {
 			"action": {
 				"type": "string",
-				"enum": ["navigate", "get_content", "click", "fill", "screenshot", "eval_js", "get_links", "close"],
+				"enum": ["navigate", "get_content", "click", "fill", "screenshot", "eval_js", "get_links", "scroll", "extract", "wait_for_selector", "save_cookies", "load_cookies", "list_pages", "close"],
 				"description": "The browser action to perform"
 			},
 			"url": {
@@ -68,7 +106,32 @@ func (t *BrowserTool) Parameters() json.RawMessage {
 			},
 			"selector": {
 				"type": "string",
-				"description": "CSS selector (for click and fill actions)"
+				"description": "CSS selector (for click, fill, extract, and wait_for_selector actions; for screenshot, captures just that element)"
+			},
+			"full_page": {
+				"type": "boolean",
+				"description": "Capture the whole scrollable page instead of the viewport (for screenshot action)"
+			},
+			"format": {
+				"type": "string",
+				"enum": ["jpeg", "png"],
+				"description": "Image format (for screenshot action, default jpeg)"
+			},
+			"to": {
+				"type": ["string", "integer"],
+				"description": "Where to scroll: top, bottom (default), or a number of pixels to scroll by, negative for up (for scroll action)"
+			},
+			"repeat": {
+				"type": "integer",
+				"description": "How many times to scroll, with a short pause between each (for scroll action, default 1, max 20)"
+			},
+			"attribute": {
+				"type": "string",
+				"description": "What to extract from each element: text (default) or an attribute name such as href or src (for extract action)"
+			},
+			"timeout_ms": {
+				"type": "integer",
+				"description": "How long wait_for_selector waits, in milliseconds (capped at the tool timeout)"
 			},
 			"text": {
 				"type": "string",
@@ -77,6 +140,10 @@ func (t *BrowserTool) Parameters() json.RawMessage {
 			"script": {
 				"type": "string",
 				"description": "JavaScript code to execute (for eval_js action)"
+			},
+			"readability": {
+				"type": "boolean",
+				"description": "Return only the main article title and text, without navigation and boilerplate (for get_content action)"
 			}
 		},
 		"required": ["action"]
@@ -84,12 +151,19 @@ func (t *BrowserTool) Parameters() json.RawMessage {
 }
 
 type browserParams struct {
-	Action   string `json:"action"`
-	URL      string `json:"url"`
-	PageID   string `json:"page_id"`
-	Selector string `json:"selector"`
-	Text     string `json:"text"`
-	Script   string `json:"script"`
+	Action      string `json:"action"`
+	URL         string `json:"url"`
+	PageID      string `json:"page_id"`
+	Selector    string `json:"selector"`
+	Text        string `json:"text"`
+	Script      string `json:"script"`
+	Readability bool   `json:"readability"`
+	TimeoutMS   int    `json:"timeout_ms"`
+	To          any    `json:"to,omitempty"`
+	Repeat      int    `json:"repeat,omitempty"`
+	Attribute   string `json:"attribute,omitempty"`
+	FullPage    bool   `json:"full_page,omitempty"`
+	Format      string `json:"format,omitempty"`
 }
 
 func (t *BrowserTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
@@ -117,6 +191,18 @@ func (t *BrowserTool) Execute(ctx context.Context, args json.RawMessage) (*Resul
 		return t.evalJS(ctx, params)
 	case "get_links":
 		return t.getLinks(ctx, params)
+	case "scroll":
+		return t.scroll(ctx, params)
+	case "extract":
+		return t.extract(ctx, params)
+	case "wait_for_selector":
+		return t.waitForSelector(ctx, params)
+	case "save_cookies":
+		return t.saveCookies()
+	case "load_cookies":
+		return t.loadCookies()
+	case "list_pages":
+		return t.listPages(ctx)
 	case "close":
 		return t.closePage(params)
 	default:
@@ -141,32 +227,35 @@ func (t *BrowserTool) ensureBrowser() error {
 	}
 
 	t.browser = browser
-	return nil
-}
 
-// validateURL checks the URL scheme, private IPs, and domain allow/deny lists.
-func (t *BrowserTool) validateURL(rawURL string) error {
-	u, err := url.Parse(rawURL)
-	if err != nil {
-		return fmt.Errorf("invalid URL: %w", err)
+	if t.cfg.TabIdleSecs > 0 {
+		t.stopReaper = make(chan struct{})
+		go t.reapLoop(t.stopReaper)
 	}
 
-	// Only allow http and https
-	switch u.Scheme {
-	case "http", "https":
-	default:
-		return fmt.Errorf("only http/https schemes are allowed, got: %s", u.Scheme)
+	if t.cfg.PersistCookies && t.cookieFile != "" {
+		if n, err := t.restoreCookies(); err != nil {
+			log.Printf("[browser] failed to restore cookies: %v", err)
+		} else if n > 0 {
+			log.Printf("[browser] restored %d cookies", n)
+		}
 	}
+	return nil
+}
 
-	host := u.Hostname()
-
-	// Block private/loopback/link-local addresses (SSRF protection)
-	if isPrivateHost(host) {
-		return fmt.Errorf("access to private/loopback addresses is denied: %s", host)
+// validateURL checks the URL scheme, private addresses, and domain allow/deny lists.
+func (t *BrowserTool) validateURL(ctx context.Context, rawURL string) error {
+	u, err := t.guard.check(ctx, rawURL)
+	if err != nil {
+		return err
 	}
 
-	// Domain allow/deny checks
-	domain := strings.ToLower(host)
+	return t.checkDomain(u.Hostname())
+}
+
+// checkDomain applies the domain allow/deny lists.
+func (t *BrowserTool) checkDomain(domain string) error {
+	domain = strings.ToLower(domain)
 
 	for _, d := range t.cfg.DeniedDomains {
 		dl := strings.ToLower(d)
@@ -192,58 +281,33 @@ func (t *BrowserTool) validateURL(rawURL string) error {
 	return nil
 }
 
-// isPrivateHost returns true for loopback, private, and link-local addresses.
-func isPrivateHost(host string) bool {
-	// Check common localhost names
-	lower := strings.ToLower(host)
-	if lower == "localhost" || lower == "ip6-localhost" || lower == "ip6-loopback" {
-		return true
-	}
-
-	ip := net.ParseIP(host)
-	if ip == nil {
-		// Could be a hostname that resolves to a private IP.
-		// We can't do DNS resolution here without risk, so rely on domain checks.
-		return false
-	}
-
-	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
-}
-
 func (t *BrowserTool) navigate(ctx context.Context, params browserParams) (*Result, error) {
 	if params.URL == "" {
 		return &Result{Error: "url is required for navigate action", IsError: true}, nil
 	}
 
-	if err := t.validateURL(params.URL); err != nil {
+	if err := t.validateURL(ctx, params.URL); err != nil {
 		return &Result{Error: err.Error(), IsError: true}, nil
 	}
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if len(t.pages) >= t.cfg.MaxTabs {
-		return &Result{Error: fmt.Sprintf("max tabs limit reached (%d)", t.cfg.MaxTabs), IsError: true}, nil
-	}
-
-	if err := t.ensureBrowser(); err != nil {
+	if err := t.makeRoom(); err != nil {
 		return &Result{Error: err.Error(), IsError: true}, nil
 	}
 
-	page, err := t.browser.Page(proto.TargetCreateTarget{URL: params.URL})
+	page, err := t.open(params.URL)
 	if err != nil {
-		return &Result{Error: "failed to open page: " + err.Error(), IsError: true}, nil
-	}
-
-	if err := page.WaitLoad(); err != nil {
-		return &Result{Error: "page load timeout: " + err.Error(), IsError: true}, nil
+		return &Result{Error: err.Error(), IsError: true}, nil
 	}
 
 	t.nextID++
 	pageID := fmt.Sprintf("page_%d", t.nextID)
 	t.pages[pageID] = page
+	t.lastUsed[pageID] = time.Now()
 
-	title, _ := page.Eval(`() => document.title`)
+	title, _ := t.eval(page, `() => document.title`)
 	titleStr := ""
 	if title != nil {
 		titleStr = title.Value.Str()
@@ -252,6 +316,59 @@ func (t *BrowserTool) navigate(ctx context.Context, params browserParams) (*Resu
 	return &Result{Output: fmt.Sprintf("Opened page %s: %s (title: %s)", pageID, params.URL, titleStr)}, nil
 }
 
+// launchPage opens url in a new tab and waits for it to load. t.mu must be held.
+func (t *BrowserTool) launchPage(url string) (*rod.Page, error) {
+	if err := t.ensureBrowser(); err != nil {
+		return nil, err
+	}
+
+	// Start blank so the user agent and viewport apply to the first request.
+	page, err := t.browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open page: %w", err)
+	}
+	if err := t.emulate(page); err != nil {
+		page.Close()
+		return nil, fmt.Errorf("failed to set up page: %w", err)
+	}
+	if err := page.Navigate(url); err != nil {
+		page.Close()
+		return nil, fmt.Errorf("failed to open page: %w", err)
+	}
+
+	if err := page.WaitLoad(); err != nil {
+		page.Close()
+		return nil, fmt.Errorf("page load timeout: %w", err)
+	}
+
+	// Redirects must land somewhere the original URL would have been allowed.
+	if info, err := page.Info(); err == nil && info.URL != url {
+		if err := t.validateURL(context.Background(), info.URL); err != nil {
+			page.Close()
+			return nil, fmt.Errorf("redirected to a blocked URL: %w", err)
+		}
+	}
+	return page, nil
+}
+
+// emulate applies the configured user agent and viewport to page.
+func (t *BrowserTool) emulate(page *rod.Page) error {
+	ua, viewport := t.pageEmulation()
+	if err := page.SetUserAgent(ua); err != nil {
+		return err
+	}
+	return page.SetViewport(viewport)
+}
+
+func (t *BrowserTool) pageEmulation() (*proto.NetworkSetUserAgentOverride, *proto.EmulationSetDeviceMetricsOverride) {
+	return &proto.NetworkSetUserAgentOverride{UserAgent: t.cfg.UserAgent},
+		&proto.EmulationSetDeviceMetricsOverride{
+			Width:             t.cfg.ViewportWidth,
+			Height:            t.cfg.ViewportHeight,
+			DeviceScaleFactor: 1,
+		}
+}
+
 func (t *BrowserTool) getPage(pageID string) (*rod.Page, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -259,6 +376,7 @@ func (t *BrowserTool) getPage(pageID string) (*rod.Page, error) {
 	if !ok {
 		return nil, fmt.Errorf("page not found: %s", pageID)
 	}
+	t.lastUsed[pageID] = time.Now()
 	return page, nil
 }
 
@@ -272,12 +390,20 @@ func (t *BrowserTool) getContent(_ context.Context, params browserParams) (*Resu
 		return &Result{Error: err.Error(), IsError: true}, nil
 	}
 
-	text, err := page.Eval(`() => document.body.innerText`)
+	script := `() => document.body.innerText`
+	if params.Readability {
+		script = readabilityScript
+	}
+
+	text, err := t.eval(page, script)
 	if err != nil {
 		return &Result{Error: "failed to get content: " + err.Error(), IsError: true}, nil
 	}
 
 	content := text.Value.Str()
+	if params.Readability {
+		content = fmt.Sprintf("Title: %s\n\n%s", text.Value.Get("title").Str(), text.Value.Get("text").Str())
+	}
 	maxChars := t.cfg.MaxPageSizeKB * 1024
 	if len(content) > maxChars {
 		content = content[:maxChars] + "\n... (content truncated)"
@@ -286,6 +412,31 @@ func (t *BrowserTool) getContent(_ context.Context, params browserParams) (*Resu
 	return &Result{Output: content}, nil
 }
 
+// readabilityScript extracts the main article from a page. It prefers semantic
+// containers and otherwise picks the block with the most paragraph text,
+// then drops navigation, ads, and other boilerplate from a clone of it.
+const readabilityScript = `() => {
+	const pick = () => {
+		const semantic = document.querySelector('article, main, [role="main"]');
+		if (semantic && semantic.innerText.trim().length > 200) return semantic;
+		let best = document.body, bestScore = 0;
+		for (const el of document.querySelectorAll('div, section')) {
+			let score = 0;
+			for (const p of el.querySelectorAll(':scope > p')) score += p.innerText.length;
+			if (score > bestScore) { best = el; bestScore = score; }
+		}
+		return best;
+	};
+	const root = pick().cloneNode(true);
+	root.querySelectorAll('script, style, noscript, nav, aside, footer, header, form, iframe, [role="navigation"], [aria-hidden="true"], .ad, .ads, .advert, .sidebar, .comments').forEach(el => el.remove());
+	const blocks = Array.from(root.querySelectorAll('h1, h2, h3, h4, p, li, pre, blockquote'))
+		.map(el => el.textContent.replace(/\s+/g, ' ').trim())
+		.filter(s => s.length > 0);
+	const text = blocks.length > 0 ? blocks.join('\n\n') : root.textContent.replace(/\s+/g, ' ').trim();
+	const h1 = document.querySelector('h1');
+	return { title: document.title || (h1 ? h1.innerText.trim() : ''), text: text };
+}`
+
 func (t *BrowserTool) click(_ context.Context, params browserParams) (*Result, error) {
 	if params.PageID == "" || params.Selector == "" {
 		return &Result{Error: "page_id and selector are required", IsError: true}, nil
@@ -334,27 +485,67 @@ func (t *BrowserTool) fill(_ context.Context, params browserParams) (*Result, er
 	return &Result{Output: fmt.Sprintf("Filled '%s' with text (%d chars)", params.Selector, len(params.Text))}, nil
 }
 
-func (t *BrowserTool) screenshot(_ context.Context, params browserParams) (*Result, error) {
+// maxScreenshotChars caps the data URL a screenshot returns, so a very long
+// page can't produce a result too large to pass around.
+const maxScreenshotChars = 5 << 20
+
+// screenshotSpec describes what to capture.
+type screenshotSpec struct {
+	Format   proto.PageCaptureScreenshotFormat
+	FullPage bool
+	Selector string
+}
+
+func captureScreenshot(ctx context.Context, page *rod.Page, shot screenshotSpec) ([]byte, error) {
+	quality := 80 // ignored for png
+	if shot.Selector != "" {
+		el, err := page.Context(ctx).Element(shot.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("element %s not found: %w", shot.Selector, err)
+		}
+		return el.Screenshot(shot.Format, quality)
+	}
+	req := &proto.PageCaptureScreenshot{Format: shot.Format}
+	if shot.Format == proto.PageCaptureScreenshotFormatJpeg {
+		req.Quality = &quality
+	}
+	return page.Screenshot(shot.FullPage, req)
+}
+
+func (t *BrowserTool) screenshot(ctx context.Context, params browserParams) (*Result, error) {
 	if params.PageID == "" {
 		return &Result{Error: "page_id is required", IsError: true}, nil
 	}
 
+	shot := screenshotSpec{FullPage: params.FullPage, Selector: params.Selector}
+	switch params.Format {
+	case "", "jpeg":
+		shot.Format = proto.PageCaptureScreenshotFormatJpeg
+	case "png":
+		shot.Format = proto.PageCaptureScreenshotFormatPng
+	default:
+		return &Result{Error: fmt.Sprintf("unknown format: %s (use jpeg or png)", params.Format), IsError: true}, nil
+	}
+	if shot.FullPage && shot.Selector != "" {
+		return &Result{Error: "full_page and selector cannot be combined", IsError: true}, nil
+	}
+
 	page, err := t.getPage(params.PageID)
 	if err != nil {
 		return &Result{Error: err.Error(), IsError: true}, nil
 	}
 
-	quality := 80
-	data, err := page.Screenshot(true, &proto.PageCaptureScreenshot{
-		Format:  proto.PageCaptureScreenshotFormatJpeg,
-		Quality: &quality,
-	})
+	data, err := t.capture(ctx, page, shot)
 	if err != nil {
 		return &Result{Error: "screenshot failed: " + err.Error(), IsError: true}, nil
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(data)
-	return &Result{Output: fmt.Sprintf("data:image/jpeg;base64,%s", encoded)}, nil
+	dataURL := fmt.Sprintf("data:image/%s;base64,%s", shot.Format, base64.StdEncoding.EncodeToString(data))
+	if len(dataURL) > maxScreenshotChars {
+		return &Result{Error: fmt.Sprintf("screenshot is too large (%d KB encoded, limit %d KB); use jpeg, capture the viewport, or pass a selector",
+			len(dataURL)/1024, maxScreenshotChars/1024), IsError: true}, nil
+	}
+	return &Result{Output: dataURL}, nil
 }
 
 func (t *BrowserTool) evalJS(_ context.Context, params browserParams) (*Result, error) {
@@ -407,6 +598,37 @@ func (t *BrowserTool) getLinks(_ context.Context, params browserParams) (*Result
 	return &Result{Output: s}, nil
 }
 
+// waitForSelector waits until an element matching the selector exists, so
+// content loaded after the page's load event can be read reliably.
+func (t *BrowserTool) waitForSelector(ctx context.Context, params browserParams) (*Result, error) {
+	if params.PageID == "" || params.Selector == "" {
+		return &Result{Error: "page_id and selector are required", IsError: true}, nil
+	}
+
+	page, err := t.getPage(params.PageID)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	// ctx already carries the tool timeout, so a longer timeout_ms is capped by it.
+	wait := time.Duration(t.cfg.TimeoutSecs) * time.Second
+	if params.TimeoutMS > 0 {
+		wait = min(wait, time.Duration(params.TimeoutMS)*time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	start := time.Now()
+	if err := t.waitFor(ctx, page, params.Selector); err != nil {
+		if ctx.Err() != nil {
+			return &Result{Error: fmt.Sprintf("selector %q did not appear within %s", params.Selector, wait), IsError: true}, nil
+		}
+		return &Result{Error: "wait failed: " + err.Error(), IsError: true}, nil
+	}
+
+	return &Result{Output: fmt.Sprintf("Element %s appeared after %s", params.Selector, time.Since(start).Round(time.Millisecond))}, nil
+}
+
 func (t *BrowserTool) closePage(params browserParams) (*Result, error) {
 	if params.PageID == "" {
 		return &Result{Error: "page_id is required", IsError: true}, nil
@@ -415,15 +637,10 @@ func (t *BrowserTool) closePage(params browserParams) (*Result, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	page, ok := t.pages[params.PageID]
-	if !ok {
+	if _, ok := t.pages[params.PageID]; !ok {
 		return &Result{Error: "page not found: " + params.PageID, IsError: true}, nil
 	}
-
-	if page != nil {
-		page.Close()
-	}
-	delete(t.pages, params.PageID)
+	t.closeTab(params.PageID)
 
 	return &Result{Output: fmt.Sprintf("Closed page %s", params.PageID)}, nil
 }
@@ -433,14 +650,20 @@ func (t *BrowserTool) Close() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	for id, page := range t.pages {
-		if page != nil {
-			page.Close()
-		}
-		delete(t.pages, id)
+	if t.stopReaper != nil {
+		close(t.stopReaper)
+		t.stopReaper = nil
+	}
+	for id := range t.pages {
+		t.closeTab(id)
 	}
 
 	if t.browser != nil {
+		if t.cfg.PersistCookies && t.cookieFile != "" {
+			if _, err := t.persistCookies(); err != nil {
+				log.Printf("[browser] failed to save cookies: %v", err)
+			}
+		}
 		t.browser.Close()
 		t.browser = nil
 	}
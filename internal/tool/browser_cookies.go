@@ -0,0 +1,142 @@
+package tool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+
+	"open-dan/internal/security"
+)
+
+// SetCookieStore enables the save_cookies and load_cookies actions, keeping
+// cookies in path encrypted with key (32 bytes). With PersistCookies set in
+// the config they are also restored when the browser starts and saved when
+// it closes, so logins survive restarts.
+func (t *BrowserTool) SetCookieStore(path string, key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("cookie key must be 32 bytes, got %d", len(key))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cookieFile = path
+	t.cookieKey = key
+	return nil
+}
+
+func (t *BrowserTool) saveCookies() (*Result, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cookieFile == "" {
+		return &Result{Error: "cookie persistence is not configured", IsError: true}, nil
+	}
+	if t.browser == nil {
+		return &Result{Error: "browser is not running; navigate to a page first", IsError: true}, nil
+	}
+	n, err := t.persistCookies()
+	if err != nil {
+		return &Result{Error: "failed to save cookies: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: fmt.Sprintf("Saved %d cookies", n)}, nil
+}
+
+func (t *BrowserTool) loadCookies() (*Result, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cookieFile == "" {
+		return &Result{Error: "cookie persistence is not configured", IsError: true}, nil
+	}
+	if err := t.ensureBrowser(); err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+	n, err := t.restoreCookies()
+	if err != nil {
+		return &Result{Error: "failed to load cookies: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: fmt.Sprintf("Loaded %d cookies", n)}, nil
+}
+
+// persistCookies writes the browser's cookies for permitted domains to the
+// cookie file. t.mu must be held and the browser running.
+func (t *BrowserTool) persistCookies() (int, error) {
+	cookies, err := t.browser.GetCookies()
+	if err != nil {
+		return 0, err
+	}
+	cookies = t.permittedCookies(cookies)
+	return len(cookies), writeCookieFile(t.cookieFile, t.cookieKey, cookies)
+}
+
+// restoreCookies loads the cookie file into the browser. Cookies for domains
+// the allow/deny lists now reject, and expired ones, are skipped. t.mu must
+// be held and the browser running.
+func (t *BrowserTool) restoreCookies() (int, error) {
+	cookies, err := readCookieFile(t.cookieFile, t.cookieKey)
+	if err != nil {
+		return 0, err
+	}
+	cookies = t.permittedCookies(cookies)
+	if len(cookies) == 0 {
+		return 0, nil
+	}
+	return len(cookies), t.browser.SetCookies(proto.CookiesToParams(cookies))
+}
+
+// permittedCookies drops expired cookies and those whose domain fails the
+// allow/deny lists.
+func (t *BrowserTool) permittedCookies(cookies []*proto.NetworkCookie) []*proto.NetworkCookie {
+	now := time.Now()
+	var kept []*proto.NetworkCookie
+	for _, c := range cookies {
+		if !c.Session && c.Expires > 0 && c.Expires.Time().Before(now) {
+			continue
+		}
+		if t.checkDomain(strings.TrimPrefix(c.Domain, ".")) != nil {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+func writeCookieFile(path string, key []byte, cookies []*proto.NetworkCookie) error {
+	data, err := json.Marshal(cookies)
+	if err != nil {
+		return err
+	}
+	enc, err := security.Encrypt(data, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(enc), 0600)
+}
+
+// readCookieFile returns the cookies saved at path, or none if the file
+// doesn't exist yet.
+func readCookieFile(path string, key []byte) ([]*proto.NetworkCookie, error) {
+	enc, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := security.Decrypt(string(enc), key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt cookie file: %w", err)
+	}
+	var cookies []*proto.NetworkCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
@@ -0,0 +1,151 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	maxScrollRepeat   = 20
+	scrollDelay       = 500 * time.Millisecond // lets lazy-loaded content arrive between scrolls
+	maxExtractItems   = 500
+	maxExtractedChars = 10000
+)
+
+// scrollTarget turns the to parameter into a script argument: "top",
+// "bottom" (the default), or a pixel offset given as a number or a string.
+func scrollTarget(to any) (any, error) {
+	switch v := to.(type) {
+	case nil:
+		return "bottom", nil
+	case float64:
+		return int(v), nil
+	case string:
+		if v == "" {
+			return "bottom", nil
+		}
+		if v == "top" || v == "bottom" {
+			return v, nil
+		}
+		if px, err := strconv.Atoi(v); err == nil {
+			return px, nil
+		}
+	}
+	return nil, fmt.Errorf("to must be top, bottom, or a number of pixels, got %v", to)
+}
+
+// scroll moves the page and reports where it ended up, so the agent can
+// tell whether an infinite-scroll list loaded more content.
+func (t *BrowserTool) scroll(ctx context.Context, params browserParams) (*Result, error) {
+	if params.PageID == "" {
+		return &Result{Error: "page_id is required", IsError: true}, nil
+	}
+	target, err := scrollTarget(params.To)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+	repeat := clampLimit(params.Repeat, 1, maxScrollRepeat)
+
+	page, err := t.getPage(params.PageID)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	arg, _ := json.Marshal(target)
+	script := fmt.Sprintf(`() => {
+		const to = %s;
+		if (to === 'top') window.scrollTo(0, 0);
+		else if (to === 'bottom') window.scrollTo(0, document.documentElement.scrollHeight);
+		else window.scrollBy(0, to);
+		return { y: Math.round(window.scrollY), height: document.documentElement.scrollHeight };
+	}`, arg)
+
+	var pos struct {
+		Y      int `json:"y"`
+		Height int `json:"height"`
+	}
+	startHeight := -1
+	done := 0
+	for done < repeat {
+		if done > 0 {
+			select {
+			case <-ctx.Done():
+				return &Result{Output: fmt.Sprintf("Scrolled %d of %d times before the timeout; now at %dpx of %dpx", done, repeat, pos.Y, pos.Height)}, nil
+			case <-time.After(scrollDelay):
+			}
+		}
+		obj, err := t.eval(page, script)
+		if err != nil {
+			return &Result{Error: "scroll failed: " + err.Error(), IsError: true}, nil
+		}
+		raw, _ := json.Marshal(obj.Value)
+		if err := json.Unmarshal(raw, &pos); err != nil {
+			return &Result{Error: "unexpected scroll result: " + err.Error(), IsError: true}, nil
+		}
+		if startHeight < 0 {
+			startHeight = pos.Height
+		}
+		done++
+	}
+
+	out := fmt.Sprintf("Scrolled %d time(s); now at %dpx of %dpx", done, pos.Y, pos.Height)
+	if pos.Height > startHeight {
+		out += fmt.Sprintf(" (page grew by %dpx)", pos.Height-startHeight)
+	}
+	return &Result{Output: out}, nil
+}
+
+// extract returns the text or an attribute of every element matching the
+// selector as a JSON array, which is much smaller than the page's full text.
+func (t *BrowserTool) extract(_ context.Context, params browserParams) (*Result, error) {
+	if params.PageID == "" || params.Selector == "" {
+		return &Result{Error: "page_id and selector are required", IsError: true}, nil
+	}
+
+	page, err := t.getPage(params.PageID)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	attr := params.Attribute
+	if attr == "" {
+		attr = "text"
+	}
+	// Arguments are passed as JSON, never spliced into code.
+	spec, _ := json.Marshal(map[string]any{"selector": params.Selector, "attr": attr, "max": maxExtractItems})
+	obj, err := t.eval(page, fmt.Sprintf(`() => {
+		const spec = %s;
+		const els = Array.from(document.querySelectorAll(spec.selector));
+		const values = els.map(el => spec.attr === 'text' ? el.innerText.trim() : el.getAttribute(spec.attr))
+			.filter(v => v !== null && v !== '');
+		return { total: values.length, values: values.slice(0, spec.max) };
+	}`, spec))
+	if err != nil {
+		return &Result{Error: "extract failed: " + err.Error(), IsError: true}, nil
+	}
+
+	var found struct {
+		Total  int      `json:"total"`
+		Values []string `json:"values"`
+	}
+	raw, _ := json.Marshal(obj.Value)
+	if err := json.Unmarshal(raw, &found); err != nil {
+		return &Result{Error: "unexpected extract result: " + err.Error(), IsError: true}, nil
+	}
+	if found.Values == nil {
+		found.Values = []string{}
+	}
+
+	output, _ := json.MarshalIndent(found.Values, "", "  ")
+	s := string(output)
+	if len(s) > maxExtractedChars {
+		s = s[:maxExtractedChars] + "\n... (truncated)"
+	}
+	if found.Total > len(found.Values) {
+		s += fmt.Sprintf("\n(showing %d of %d matches)", len(found.Values), found.Total)
+	}
+	return &Result{Output: s}, nil
+}
@@ -0,0 +1,95 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
+
+	"open-dan/internal/config"
+)
+
+func TestBrowserScroll(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5})
+	bt.pages["page_1"] = nil
+
+	// Each scroll to the bottom loads another 1000px of content.
+	height, calls := 2000, 0
+	var scripts []string
+	bt.eval = func(_ *rod.Page, js string) (*proto.RuntimeRemoteObject, error) {
+		scripts = append(scripts, js)
+		calls++
+		y := height - 800
+		height += 1000
+		return &proto.RuntimeRemoteObject{Value: gson.New(map[string]any{"y": y, "height": height})}, nil
+	}
+
+	res := execTool(t, context.Background(), bt, browserParams{Action: "scroll", PageID: "page_1", Repeat: 3})
+	if res.IsError {
+		t.Fatal(res.Error)
+	}
+	if calls != 3 || !strings.Contains(res.Output, "Scrolled 3 time(s)") || !strings.Contains(res.Output, "grew by 2000px") {
+		t.Fatalf("unexpected scroll result after %d calls: %s", calls, res.Output)
+	}
+	if !strings.Contains(scripts[0], `const to = "bottom"`) {
+		t.Fatalf("scroll should default to bottom:\n%s", scripts[0])
+	}
+
+	scripts = nil
+	for _, to := range []any{"-400", 250.0} {
+		if res := execTool(t, context.Background(), bt, browserParams{Action: "scroll", PageID: "page_1", To: to}); res.IsError {
+			t.Fatalf("to=%v: %s", to, res.Error)
+		}
+	}
+	if !strings.Contains(scripts[0], "const to = -400") || !strings.Contains(scripts[1], "const to = 250") {
+		t.Fatalf("pixel offsets not passed through: %q", scripts)
+	}
+
+	if res := execTool(t, context.Background(), bt, browserParams{Action: "scroll", PageID: "page_1", To: "sideways"}); !res.IsError {
+		t.Fatal("expected an invalid scroll target to be rejected")
+	}
+}
+
+func TestBrowserExtract(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5})
+	bt.pages["page_1"] = nil
+
+	var script string
+	values := []string{"/a", "/b"}
+	total := 2
+	bt.eval = func(_ *rod.Page, js string) (*proto.RuntimeRemoteObject, error) {
+		script = js
+		return &proto.RuntimeRemoteObject{Value: gson.New(map[string]any{"total": total, "values": values})}, nil
+	}
+
+	if res := execTool(t, context.Background(), bt, browserParams{Action: "extract", PageID: "page_1"}); !res.IsError {
+		t.Fatal("expected error without selector")
+	}
+
+	res := execTool(t, context.Background(), bt, browserParams{Action: "extract", PageID: "page_1", Selector: `a[data-x="1"]`, Attribute: "href"})
+	if res.IsError {
+		t.Fatal(res.Error)
+	}
+	var got []string
+	if err := json.Unmarshal([]byte(res.Output), &got); err != nil || len(got) != 2 || got[0] != "/a" {
+		t.Fatalf("expected a JSON array of values, got %q (%v)", res.Output, err)
+	}
+	if !strings.Contains(script, `"selector":"a[data-x=\"1\"]"`) || !strings.Contains(script, `"attr":"href"`) {
+		t.Fatalf("selector and attribute should be passed as JSON:\n%s", script)
+	}
+
+	// More matches than the cap are reported, and oversized output is truncated.
+	values = []string{strings.Repeat("x", maxExtractedChars)}
+	total = maxExtractItems + 10
+	res = execTool(t, context.Background(), bt, browserParams{Action: "extract", PageID: "page_1", Selector: "li"})
+	if !strings.Contains(res.Output, "... (truncated)") || !strings.Contains(res.Output, "showing 1 of 510 matches") {
+		t.Fatalf("expected truncation notes, got %q", res.Output[len(res.Output)-80:])
+	}
+	if !strings.Contains(script, `"attr":"text"`) {
+		t.Fatal("extract should default to text")
+	}
+}
@@ -0,0 +1,139 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// pageInfo describes an open tab for the list_pages action.
+type pageInfo struct {
+	PageID string `json:"page_id"`
+	Order  int    `json:"order"` // creation order; lower is older
+	URL    string `json:"url,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// pageOrder returns the sequence number in an ID like "page_3".
+func pageOrder(id string) int {
+	n, _ := strconv.Atoi(id[strings.LastIndexByte(id, '_')+1:])
+	return n
+}
+
+// listPages reports every open tab, oldest first, so the agent can reuse or
+// close tabs instead of running into the tab limit.
+func (t *BrowserTool) listPages(_ context.Context) (*Result, error) {
+	t.mu.Lock()
+	pages := make(map[string]*rod.Page, len(t.pages))
+	for id, p := range t.pages {
+		pages[id] = p
+	}
+	maxTabs := t.cfg.MaxTabs
+	t.mu.Unlock()
+
+	infos := make([]pageInfo, 0, len(pages))
+	for id, page := range pages {
+		info := pageInfo{PageID: id, Order: pageOrder(id)}
+		if strings.HasPrefix(id, "scrape_") {
+			info.Note = "in use by a running scrape"
+		} else if obj, err := t.eval(page, `() => ({ url: location.href, title: document.title })`); err != nil {
+			info.Note = "unavailable: " + err.Error()
+		} else {
+			info.URL = obj.Value.Get("url").Str()
+			info.Title = obj.Value.Get("title").Str()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Order < infos[j].Order })
+
+	out, _ := json.MarshalIndent(map[string]any{
+		"pages":    infos,
+		"max_tabs": maxTabs,
+	}, "", "  ")
+	return &Result{Output: string(out)}, nil
+}
+
+// makeRoom checks that another tab fits under MaxTabs, closing the least
+// recently used one when EvictLRU is set. t.mu must be held.
+func (t *BrowserTool) makeRoom() error {
+	if len(t.pages) < t.cfg.MaxTabs {
+		return nil
+	}
+	if t.cfg.EvictLRU {
+		if id := t.leastRecentlyUsed(); id != "" {
+			t.closeTab(id)
+			log.Printf("[browser] closed least recently used tab %s to stay under %d tabs", id, t.cfg.MaxTabs)
+			return nil
+		}
+	}
+	return fmt.Errorf("max tabs limit reached (%d)", t.cfg.MaxTabs)
+}
+
+// leastRecentlyUsed returns the tab idle the longest, ignoring tabs held by
+// a running scrape. Ties go to the older tab. t.mu must be held.
+func (t *BrowserTool) leastRecentlyUsed() string {
+	best := ""
+	for id := range t.pages {
+		if strings.HasPrefix(id, "scrape_") {
+			continue
+		}
+		if best == "" || t.lastUsed[id].Before(t.lastUsed[best]) ||
+			(t.lastUsed[id].Equal(t.lastUsed[best]) && pageOrder(id) < pageOrder(best)) {
+			best = id
+		}
+	}
+	return best
+}
+
+// closeTab closes a page and forgets it. t.mu must be held.
+func (t *BrowserTool) closeTab(id string) {
+	if page := t.pages[id]; page != nil {
+		page.Close()
+	}
+	delete(t.pages, id)
+	delete(t.lastUsed, id)
+}
+
+// reapIdle closes tabs unused for longer than TabIdleSecs as of now and
+// returns their IDs. t.mu must be held.
+func (t *BrowserTool) reapIdle(now time.Time) []string {
+	idle := time.Duration(t.cfg.TabIdleSecs) * time.Second
+	var reaped []string
+	for id := range t.pages {
+		if strings.HasPrefix(id, "scrape_") || now.Sub(t.lastUsed[id]) <= idle {
+			continue
+		}
+		t.closeTab(id)
+		reaped = append(reaped, id)
+	}
+	sort.Strings(reaped)
+	return reaped
+}
+
+// reapLoop periodically closes idle tabs until stop is closed.
+func (t *BrowserTool) reapLoop(stop <-chan struct{}) {
+	interval := max(time.Duration(t.cfg.TabIdleSecs)*time.Second/2, time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.mu.Lock()
+			reaped := t.reapIdle(now)
+			t.mu.Unlock()
+			if len(reaped) > 0 {
+				log.Printf("[browser] closed idle tabs: %s", strings.Join(reaped, ", "))
+			}
+		}
+	}
+}
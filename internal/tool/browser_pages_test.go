@@ -0,0 +1,115 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
+
+	"open-dan/internal/config"
+)
+
+func TestBrowserListPages(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5, MaxTabs: 5})
+	bt.guard.resolver = publicResolver
+
+	urls := map[*rod.Page]string{}
+	bt.open = func(url string) (*rod.Page, error) {
+		p := &rod.Page{}
+		urls[p] = url
+		return p, nil
+	}
+	bt.eval = func(page *rod.Page, _ string) (*proto.RuntimeRemoteObject, error) {
+		return &proto.RuntimeRemoteObject{Value: gson.New(map[string]any{"url": urls[page], "title": "Title of " + urls[page]})}, nil
+	}
+
+	for _, u := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		if res := execTool(t, context.Background(), bt, browserParams{Action: "navigate", URL: u}); res.IsError {
+			t.Fatal(res.Error)
+		}
+	}
+	bt.pages["page_10"] = bt.pages["page_1"]
+	delete(bt.pages, "page_1")
+
+	res := execTool(t, context.Background(), bt, browserParams{Action: "list_pages"})
+	var out struct {
+		Pages   []pageInfo `json:"pages"`
+		MaxTabs int        `json:"max_tabs"`
+	}
+	if err := json.Unmarshal([]byte(res.Output), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, res.Output)
+	}
+	if out.MaxTabs != 5 || len(out.Pages) != 3 {
+		t.Fatalf("unexpected listing: %+v", out)
+	}
+	// Sorted by creation order, numerically rather than as strings.
+	want := []string{"page_2", "page_3", "page_10"}
+	for i, p := range out.Pages {
+		if p.PageID != want[i] {
+			t.Fatalf("page %d is %s, want %s", i, p.PageID, want[i])
+		}
+	}
+	if out.Pages[0].URL != "https://example.com/2" || out.Pages[0].Title != "Title of https://example.com/2" {
+		t.Fatalf("missing url or title: %+v", out.Pages[0])
+	}
+}
+
+func TestBrowserEvictsLeastRecentlyUsedTab(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5, MaxTabs: 2, EvictLRU: true})
+	bt.guard.resolver = publicResolver
+	bt.open = func(string) (*rod.Page, error) { return nil, nil }
+	bt.eval = func(*rod.Page, string) (*proto.RuntimeRemoteObject, error) {
+		return &proto.RuntimeRemoteObject{Value: gson.New("")}, nil
+	}
+
+	execTool(t, context.Background(), bt, browserParams{Action: "navigate", URL: "https://example.com/1"})
+	execTool(t, context.Background(), bt, browserParams{Action: "navigate", URL: "https://example.com/2"})
+	// Using page_1 makes page_2 the least recently used.
+	bt.lastUsed["page_2"] = time.Now().Add(-time.Minute)
+	execTool(t, context.Background(), bt, browserParams{Action: "get_content", PageID: "page_1"})
+
+	res := execTool(t, context.Background(), bt, browserParams{Action: "navigate", URL: "https://example.com/3"})
+	if res.IsError {
+		t.Fatalf("navigate should evict instead of failing: %s", res.Error)
+	}
+	if _, ok := bt.pages["page_2"]; ok || len(bt.pages) != 2 {
+		t.Fatalf("expected page_2 to be evicted, have %v", bt.pages)
+	}
+	if _, ok := bt.lastUsed["page_2"]; ok {
+		t.Fatal("evicted page should be forgotten")
+	}
+
+	// Tabs held by a scrape are never evicted.
+	bt.pages = map[string]*rod.Page{"scrape_7": nil, "scrape_8": nil}
+	res = execTool(t, context.Background(), bt, browserParams{Action: "navigate", URL: "https://example.com/4"})
+	if !res.IsError || !strings.Contains(res.Error, "max tabs") {
+		t.Fatalf("expected max tabs error, got %+v", res)
+	}
+}
+
+func TestBrowserReapsIdleTabs(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5, TabIdleSecs: 60})
+	now := time.Now()
+	bt.pages = map[string]*rod.Page{"page_1": nil, "page_2": nil, "page_3": nil, "scrape_4": nil}
+	bt.lastUsed = map[string]time.Time{
+		"page_1": now.Add(-2 * time.Minute),
+		"page_2": now.Add(-30 * time.Second),
+		"page_3": now.Add(-61 * time.Second),
+	}
+
+	reaped := bt.reapIdle(now)
+	if strings.Join(reaped, ",") != "page_1,page_3" {
+		t.Fatalf("reaped %v", reaped)
+	}
+	if len(bt.pages) != 2 || bt.pages["page_2"] != nil {
+		t.Fatalf("unexpected remaining pages: %v", bt.pages)
+	}
+	if _, ok := bt.pages["scrape_4"]; !ok {
+		t.Fatal("scrape tabs must not be reaped")
+	}
+}
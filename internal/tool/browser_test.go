@@ -1,10 +1,19 @@
 package tool
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
 
 	"open-dan/internal/config"
 )
@@ -105,7 +114,9 @@ func TestBrowserDomainValidation(t *testing.T) {
 				DeniedDomains:  tt.deniedDomains,
 			})
 
-			err := bt.validateURL(tt.url)
+			bt.guard.resolver = publicResolver
+
+			err := bt.validateURL(context.Background(), tt.url)
 			if tt.expectError && err == nil {
 				t.Error("expected error, got nil")
 			}
@@ -123,6 +134,7 @@ func TestBrowserMaxTabs(t *testing.T) {
 		MaxTabs:       2,
 		MaxPageSizeKB: 1024,
 	})
+	bt.guard.resolver = publicResolver
 
 	// Simulate having max tabs already open
 	bt.pages["page_1"] = nil
@@ -158,3 +170,246 @@ func TestBrowserUnknownAction(t *testing.T) {
 		t.Fatal("expected error for unknown action")
 	}
 }
+
+func TestBrowserGetContentReadability(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{
+		Headless:      true,
+		TimeoutSecs:   10,
+		MaxTabs:       3,
+		MaxPageSizeKB: 1024,
+	})
+	bt.pages["page_1"] = nil
+
+	var scripts []string
+	bt.eval = func(_ *rod.Page, js string) (*proto.RuntimeRemoteObject, error) {
+		scripts = append(scripts, js)
+		if js == readabilityScript {
+			return &proto.RuntimeRemoteObject{Value: gson.New(map[string]any{
+				"title": "Article Title",
+				"text":  "Main body text.",
+			})}, nil
+		}
+		return &proto.RuntimeRemoteObject{Value: gson.New("Menu Home About Main body text. Footer")}, nil
+	}
+
+	// Raw mode stays the default
+	args, _ := json.Marshal(browserParams{Action: "get_content", PageID: "page_1"})
+	result, err := bt.Execute(context.Background(), args)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %s", err, result.Error)
+	}
+	if scripts[0] == readabilityScript {
+		t.Fatal("raw get_content should not use the readability script")
+	}
+	if !strings.Contains(result.Output, "Footer") {
+		t.Fatalf("expected raw page text, got: %s", result.Output)
+	}
+
+	args, _ = json.Marshal(browserParams{Action: "get_content", PageID: "page_1", Readability: true})
+	result, err = bt.Execute(context.Background(), args)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %s", err, result.Error)
+	}
+	if scripts[1] != readabilityScript {
+		t.Fatal("readability mode should request the extraction script")
+	}
+	if result.Output != "Title: Article Title\n\nMain body text." {
+		t.Fatalf("unexpected readability output: %q", result.Output)
+	}
+}
+
+func TestBrowserWaitForSelector(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 1})
+	bt.pages["page_1"] = nil
+
+	var appeared bool
+	var deadline time.Time
+	bt.waitFor = func(ctx context.Context, _ *rod.Page, selector string) error {
+		deadline, _ = ctx.Deadline()
+		if appeared && selector == "#results" {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	run := func(p browserParams) *Result {
+		p.Action = "wait_for_selector"
+		args, _ := json.Marshal(p)
+		res, err := bt.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := run(browserParams{PageID: "page_1"}); !res.IsError {
+		t.Error("expected error without selector")
+	}
+
+	res := run(browserParams{PageID: "page_1", Selector: "#results", TimeoutMS: 50})
+	if !res.IsError || !strings.Contains(res.Error, "did not appear") {
+		t.Fatalf("expected timeout error, got %+v", res)
+	}
+	if time.Until(deadline) > 0 {
+		t.Error("wait should stop at timeout_ms")
+	}
+
+	// timeout_ms can't extend past the tool timeout.
+	start := time.Now()
+	run(browserParams{PageID: "page_1", Selector: "#results", TimeoutMS: 60000})
+	if d := deadline.Sub(start); d > 1100*time.Millisecond {
+		t.Errorf("wait deadline %s exceeds the tool timeout", d)
+	}
+
+	appeared = true
+	if res := run(browserParams{PageID: "page_1", Selector: "#results", TimeoutMS: 50}); res.IsError {
+		t.Fatalf("expected success once the element appears: %s", res.Error)
+	}
+}
+
+func TestBrowserCookieFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.enc")
+	key := bytes.Repeat([]byte{7}, 32)
+	cookies := []*proto.NetworkCookie{
+		{Name: "sid", Value: "secret-session", Domain: ".example.com", Path: "/", Session: true},
+		{Name: "pref", Value: "dark", Domain: "news.site.org", Path: "/", Expires: proto.TimeSinceEpoch(time.Now().Add(time.Hour).Unix())},
+	}
+
+	if err := writeCookieFile(path, key, cookies); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("secret-session")) {
+		t.Fatal("cookie file is not encrypted")
+	}
+
+	got, err := readCookieFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Value != "secret-session" || got[1].Name != "pref" {
+		t.Fatalf("unexpected cookies: %+v", got)
+	}
+	if _, err := readCookieFile(path, bytes.Repeat([]byte{8}, 32)); err == nil {
+		t.Fatal("expected wrong key to fail")
+	}
+	if got, err := readCookieFile(filepath.Join(t.TempDir(), "missing.enc"), key); err != nil || got != nil {
+		t.Fatalf("missing file should yield no cookies, got %v %v", got, err)
+	}
+}
+
+func TestBrowserCookiesRespectDomainLists(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{
+		AllowedDomains: []string{"example.com", "site.org"},
+		DeniedDomains:  []string{"ads.site.org"},
+	})
+	cookies := []*proto.NetworkCookie{
+		{Name: "a", Domain: ".example.com", Session: true},
+		{Name: "b", Domain: "ads.site.org", Session: true},
+		{Name: "c", Domain: "evil.test", Session: true},
+		{Name: "d", Domain: "www.site.org", Expires: proto.TimeSinceEpoch(time.Now().Add(-time.Hour).Unix())},
+		{Name: "e", Domain: "www.site.org", Expires: proto.TimeSinceEpoch(time.Now().Add(time.Hour).Unix())},
+	}
+
+	var names []string
+	for _, c := range bt.permittedCookies(cookies) {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "a,e" {
+		t.Fatalf("kept cookies %v, want [a e]", names)
+	}
+}
+
+func TestBrowserCookieActionsNeedStore(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{})
+	for _, action := range []string{"save_cookies", "load_cookies"} {
+		args, _ := json.Marshal(browserParams{Action: action})
+		res, _ := bt.Execute(context.Background(), args)
+		if !res.IsError || !strings.Contains(res.Error, "not configured") {
+			t.Errorf("%s without a store: %+v", action, res)
+		}
+	}
+	if err := bt.SetCookieStore("cookies.enc", []byte("short")); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+}
+
+func TestBrowserUserAgentAndViewport(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{})
+	ua, vp := bt.pageEmulation()
+	if ua.UserAgent != config.DefaultBrowserUserAgent || vp.Width != 1920 || vp.Height != 1080 {
+		t.Fatalf("expected desktop defaults, got %q %dx%d", ua.UserAgent, vp.Width, vp.Height)
+	}
+
+	bt = NewBrowserTool(config.BrowserConfig{Headless: true, UserAgent: "OpenDanTest/1.0", ViewportWidth: 800, ViewportHeight: 600})
+	ua, vp = bt.pageEmulation()
+	if ua.UserAgent != "OpenDanTest/1.0" || vp.Width != 800 || vp.Height != 600 {
+		t.Fatalf("config not applied: %q %dx%d", ua.UserAgent, vp.Width, vp.Height)
+	}
+
+	// The rest needs a locally installed browser.
+	if _, ok := launcher.LookPath(); !ok {
+		t.Skip("no browser installed")
+	}
+	defer bt.Close()
+	bt.mu.Lock()
+	page, err := bt.open("about:blank")
+	bt.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := bt.eval(page, `() => navigator.userAgent + ' ' + window.innerWidth + 'x' + window.innerHeight`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.Value.Str(); got != "OpenDanTest/1.0 800x600" {
+		t.Fatalf("page sees %q", got)
+	}
+}
+
+func TestBrowserScreenshotOptions(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5})
+	bt.pages["page_1"] = nil
+
+	var got screenshotSpec
+	size := 3
+	bt.capture = func(_ context.Context, _ *rod.Page, shot screenshotSpec) ([]byte, error) {
+		got = shot
+		return bytes.Repeat([]byte{0xff}, size), nil
+	}
+	shoot := func(p browserParams) *Result {
+		p.Action, p.PageID = "screenshot", "page_1"
+		args, _ := json.Marshal(p)
+		res, err := bt.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := shoot(browserParams{})
+	if res.Output != "data:image/jpeg;base64,////" || got.FullPage || got.Format != proto.PageCaptureScreenshotFormatJpeg {
+		t.Fatalf("default should be a viewport jpeg, got %+v %q", got, res.Output)
+	}
+
+	res = shoot(browserParams{FullPage: true, Format: "png"})
+	if !strings.HasPrefix(res.Output, "data:image/png;base64,") || !got.FullPage {
+		t.Fatalf("expected full-page png, got %+v %q", got, res.Output)
+	}
+
+	if shoot(browserParams{Selector: "#chart"}); got.Selector != "#chart" {
+		t.Fatalf("selector not passed through: %+v", got)
+	}
+
+	for _, p := range []browserParams{{Format: "gif"}, {FullPage: true, Selector: "#chart"}} {
+		if res := shoot(p); !res.IsError {
+			t.Errorf("expected %+v to be rejected", p)
+		}
+	}
+
+	size = maxScreenshotChars
+	if res := shoot(browserParams{FullPage: true}); !res.IsError || !strings.Contains(res.Error, "too large") {
+		t.Fatalf("expected size cap error, got %+v", res)
+	}
+}
@@ -0,0 +1,74 @@
+package tool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ResultCache holds successful results of cacheable tools for a TTL, keyed
+// by chat, tool name, and a hash of the arguments. Entries from one chat
+// are never served to another.
+type ResultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	now     func() time.Time // overridable in tests
+}
+
+type cacheEntry struct {
+	result  Result
+	expires time.Time
+}
+
+// NewResultCache creates a cache whose entries live for ttl.
+func NewResultCache(ttl time.Duration) *ResultCache {
+	return &ResultCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// cacheKey identifies a call. Arguments are hashed as sent, so calls that
+// differ only in key order or spacing are cached separately.
+func cacheKey(chatID, name string, args json.RawMessage) string {
+	sum := sha256.Sum256(args)
+	return chatID + "\x00" + name + "\x00" + hex.EncodeToString(sum[:])
+}
+
+// Get returns a copy of the cached result for the call, if it hasn't expired.
+func (c *ResultCache) Get(chatID, name string, args json.RawMessage) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := cacheKey(chatID, name, args)
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	res := e.result
+	return &res, true
+}
+
+// Put stores res for the call. Errors are never cached, so a failed call
+// is retried for real. Expired entries are swept on each write.
+func (c *ResultCache) Put(chatID, name string, args json.RawMessage, res *Result) {
+	if res == nil || res.IsError {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[cacheKey(chatID, name, args)] = cacheEntry{result: *res, expires: now.Add(c.ttl)}
+}
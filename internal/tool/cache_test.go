@@ -0,0 +1,52 @@
+package tool
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestResultCacheExpiresAndIsolatesChats(t *testing.T) {
+	c := NewResultCache(time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	args := json.RawMessage(`{"query":"go"}`)
+
+	c.Put("c1", "web_search", args, &Result{Output: "results"})
+	c.Put("c1", "fetch", args, &Result{IsError: true, Error: "timeout"})
+
+	if res, ok := c.Get("c1", "web_search", args); !ok || res.Output != "results" {
+		t.Fatalf("Get = %+v, %v", res, ok)
+	}
+	if _, ok := c.Get("c2", "web_search", args); ok {
+		t.Fatal("result served to another chat")
+	}
+	if _, ok := c.Get("c1", "web_search", json.RawMessage(`{"query":"rust"}`)); ok {
+		t.Fatal("result served for different arguments")
+	}
+	if _, ok := c.Get("c1", "fetch", args); ok {
+		t.Fatal("error result was cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("c1", "web_search", args); ok {
+		t.Fatal("expired result served")
+	}
+}
+
+func TestOnlyReadOnlyToolsAreCacheable(t *testing.T) {
+	for _, tc := range []struct {
+		tool Tool
+		want bool
+	}{
+		{&FetchTool{}, true},
+		{&WebSearchTool{}, true},
+		{&ShellTool{}, false},
+		{&FilesystemTool{}, false},
+		{&KVTool{}, false},
+	} {
+		if got := IsCacheable(tc.tool); got != tc.want {
+			t.Errorf("IsCacheable(%T) = %v, want %v", tc.tool, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxCalcExprLen = 1000
+	maxCalcDepth   = 64
+)
+
+// calcConstants are the names an expression may use as values.
+var calcConstants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+}
+
+// calcFunc is a function an expression may call, with a fixed arity.
+type calcFunc struct {
+	arity int
+	fn    func(args []float64) float64
+}
+
+func calcUnary(f func(float64) float64) calcFunc {
+	return calcFunc{1, func(a []float64) float64 { return f(a[0]) }}
+}
+
+func calcBinary(f func(float64, float64) float64) calcFunc {
+	return calcFunc{2, func(a []float64) float64 { return f(a[0], a[1]) }}
+}
+
+var calcFuncs = map[string]calcFunc{
+	"sqrt":  calcUnary(math.Sqrt),
+	"cbrt":  calcUnary(math.Cbrt),
+	"abs":   calcUnary(math.Abs),
+	"sin":   calcUnary(math.Sin),
+	"cos":   calcUnary(math.Cos),
+	"tan":   calcUnary(math.Tan),
+	"asin":  calcUnary(math.Asin),
+	"acos":  calcUnary(math.Acos),
+	"atan":  calcUnary(math.Atan),
+	"ln":    calcUnary(math.Log),
+	"log":   calcUnary(math.Log10),
+	"log2":  calcUnary(math.Log2),
+	"exp":   calcUnary(math.Exp),
+	"floor": calcUnary(math.Floor),
+	"ceil":  calcUnary(math.Ceil),
+	"round": calcUnary(math.Round),
+	"pow":   calcBinary(math.Pow),
+	"atan2": calcBinary(math.Atan2),
+	"min":   calcBinary(math.Min),
+	"max":   calcBinary(math.Max),
+}
+
+// CalculatorTool evaluates arithmetic expressions with its own small parser,
+// so the agent gets exact answers without going through the shell.
+type CalculatorTool struct{}
+
+func NewCalculatorTool() *CalculatorTool {
+	return &CalculatorTool{}
+}
+
+func (t *CalculatorTool) Name() string { return "calc" }
+func (t *CalculatorTool) Description() string {
+	return "Evaluate an arithmetic expression exactly instead of working it out yourself. Supports + - * / % (remainder), ^ (power), parentheses, the constants pi, e, and tau, and the functions sqrt, cbrt, abs, sin, cos, tan, asin, acos, atan, atan2, ln, log (base 10), log2, exp, floor, ceil, round, pow, min, and max. Angles are in radians. Write numbers without thousands separators, e.g. 0.18 * 2340."
+}
+
+func (t *CalculatorTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"expression": {
+				"type": "string",
+				"description": "The expression to evaluate, e.g. (2 + 3) * sqrt(16)"
+			},
+			"show_expression": {
+				"type": "boolean",
+				"description": "Also return the expression as parsed, fully parenthesized, to check operator precedence"
+			}
+		},
+		"required": ["expression"]
+	}`)
+}
+
+func (t *CalculatorTool) Execute(_ context.Context, args json.RawMessage) (*Result, error) {
+	var params struct {
+		Expression     string `json:"expression"`
+		ShowExpression bool   `json:"show_expression"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	if strings.TrimSpace(params.Expression) == "" {
+		return &Result{Error: "expression is required", IsError: true}, nil
+	}
+	if len(params.Expression) > maxCalcExprLen {
+		return &Result{Error: fmt.Sprintf("expression too long (max %d characters)", maxCalcExprLen), IsError: true}, nil
+	}
+
+	value, parsed, err := evalExpression(params.Expression)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+	out := formatNumber(value)
+	if params.ShowExpression {
+		out = parsed + " = " + out
+	}
+	return &Result{Output: out}, nil
+}
+
+// formatNumber prints v with 15 significant digits, which hides float
+// noise such as 0.1 + 0.2 = 0.30000000000000004.
+func formatNumber(v float64) string {
+	if v == 0 {
+		return "0" // not "-0"
+	}
+	return strconv.FormatFloat(v, 'g', 15, 64)
+}
+
+// evalExpression parses and evaluates expr, returning the value and the
+// expression as parsed.
+func evalExpression(expr string) (float64, string, error) {
+	p := &calcParser{src: []rune(expr)}
+	v, err := p.expr()
+	if err != nil {
+		return 0, "", err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return 0, "", p.errorf("unexpected %q", p.src[p.pos])
+	}
+	if math.IsNaN(v.value) || math.IsInf(v.value, 0) {
+		return 0, "", fmt.Errorf("result is not a finite number")
+	}
+	return v.value, v.text, nil
+}
+
+// operand is a parsed subexpression: its value and its text, fully
+// parenthesized.
+type operand struct {
+	value float64
+	text  string
+}
+
+// calcParser is a recursive-descent parser that evaluates as it goes.
+// Precedence, loosest first: + -, then * / %, then unary minus, then ^
+// (right-associative), so -2^2 is -4.
+type calcParser struct {
+	src   []rune
+	pos   int
+	depth int
+}
+
+func (p *calcParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *calcParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek skips whitespace and returns the next rune, or 0 at the end.
+func (p *calcParser) peek() rune {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *calcParser) expr() (operand, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxCalcDepth {
+		return operand{}, p.errorf("expression nested too deeply")
+	}
+
+	left, err := p.term()
+	if err != nil {
+		return operand{}, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return operand{}, err
+		}
+		v := left.value + right.value
+		if op == '-' {
+			v = left.value - right.value
+		}
+		left = operand{v, "(" + left.text + " " + string(op) + " " + right.text + ")"}
+	}
+}
+
+func (p *calcParser) term() (operand, error) {
+	left, err := p.unary()
+	if err != nil {
+		return operand{}, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		// ** is power, handled below unary.
+		if op == '*' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*' {
+			return left, nil
+		}
+		start := p.pos
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return operand{}, err
+		}
+		var v float64
+		switch op {
+		case '*':
+			v = left.value * right.value
+		case '/':
+			if right.value == 0 {
+				p.pos = start
+				return operand{}, p.errorf("division by zero")
+			}
+			v = left.value / right.value
+		case '%':
+			if right.value == 0 {
+				p.pos = start
+				return operand{}, p.errorf("remainder by zero")
+			}
+			v = math.Mod(left.value, right.value)
+		}
+		left = operand{v, "(" + left.text + " " + string(op) + " " + right.text + ")"}
+	}
+}
+
+func (p *calcParser) unary() (operand, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		v, err := p.unary()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{-v.value, "(-" + v.text + ")"}, nil
+	case '+':
+		p.pos++
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *calcParser) power() (operand, error) {
+	base, err := p.primary()
+	if err != nil {
+		return operand{}, err
+	}
+	switch {
+	case p.peek() == '^':
+		p.pos++
+	case p.peek() == '*' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*':
+		p.pos += 2
+	default:
+		return base, nil
+	}
+	exp, err := p.unary() // right-associative, and allows 2^-1
+	if err != nil {
+		return operand{}, err
+	}
+	return operand{math.Pow(base.value, exp.value), "(" + base.text + " ^ " + exp.text + ")"}, nil
+}
+
+func (p *calcParser) primary() (operand, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return operand{}, p.errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return operand{}, err
+		}
+		if p.peek() != ')' {
+			return operand{}, p.errorf("expected )")
+		}
+		p.pos++
+		return v, nil
+	case c == '.' || unicode.IsDigit(c):
+		return p.number()
+	case unicode.IsLetter(c):
+		return p.name()
+	}
+	return operand{}, p.errorf("unexpected %q", c)
+}
+
+func (p *calcParser) number() (operand, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	// Exponent, as in 1.5e3 or 2E-4.
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		end := p.pos + 1
+		if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+			end++
+		}
+		if end < len(p.src) && unicode.IsDigit(p.src[end]) {
+			for end < len(p.src) && unicode.IsDigit(p.src[end]) {
+				end++
+			}
+			p.pos = end
+		}
+	}
+	text := string(p.src[start:p.pos])
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		p.pos = start
+		return operand{}, p.errorf("invalid number %q", text)
+	}
+	return operand{v, text}, nil
+}
+
+func (p *calcParser) name() (operand, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (unicode.IsLetter(p.src[p.pos]) || unicode.IsDigit(p.src[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(string(p.src[start:p.pos]))
+
+	if v, ok := calcConstants[name]; ok {
+		return operand{v, name}, nil
+	}
+	f, ok := calcFuncs[name]
+	if !ok {
+		p.pos = start
+		return operand{}, p.errorf("unknown name %q", name)
+	}
+	if p.peek() != '(' {
+		return operand{}, p.errorf("expected ( after %s", name)
+	}
+	p.pos++
+
+	var args []float64
+	var texts []string
+	if p.peek() != ')' {
+		for {
+			v, err := p.expr()
+			if err != nil {
+				return operand{}, err
+			}
+			args = append(args, v.value)
+			texts = append(texts, v.text)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+	}
+	if p.peek() != ')' {
+		return operand{}, p.errorf("expected ) to close %s(", name)
+	}
+	p.pos++
+	if len(args) != f.arity {
+		return operand{}, fmt.Errorf("%s takes %d argument(s), got %d", name, f.arity, len(args))
+	}
+	return operand{f.fn(args), name + "(" + strings.Join(texts, ", ") + ")"}, nil
+}
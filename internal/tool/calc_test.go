@@ -0,0 +1,65 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCalculatorEvaluates(t *testing.T) {
+	cases := map[string]string{
+		"0.18 * 2340":             "421.2",
+		"1 + 2 * 3":               "7",
+		"(1 + 2) * 3":             "9",
+		"10 - 4 - 3":              "3",
+		"2 ^ 3 ^ 2":               "512",
+		"2 ** 10":                 "1024",
+		"-2 ^ 2":                  "-4",
+		"2 ^ -1":                  "0.5",
+		"17 % 5":                  "2",
+		"0.1 + 0.2":               "0.3",
+		"sqrt(16) + abs(-3)":      "7",
+		"max(3, pow(2, 2))":       "4",
+		"round(sin(pi / 2))":      "1",
+		"log(1000) + ln(e)":       "4",
+		"1.5e3 / 3":               "500",
+		"  PI * 0 ":               "0",
+		"floor(-2.5) + ceil(2.1)": "0",
+	}
+	for expr, want := range cases {
+		res := execTool(t, context.Background(), NewCalculatorTool(), map[string]any{"expression": expr})
+		if res.IsError || res.Output != want {
+			t.Errorf("%s = %+v, want %s", expr, res, want)
+		}
+	}
+}
+
+func TestCalculatorShowsParsedExpression(t *testing.T) {
+	res := execTool(t, context.Background(), NewCalculatorTool(), map[string]any{"expression": "1 + 2 * -3", "show_expression": true})
+	if res.Output != "(1 + (2 * (-3))) = -5" {
+		t.Fatalf("got %+v", res)
+	}
+}
+
+func TestCalculatorRejectsNonMath(t *testing.T) {
+	cases := map[string]string{
+		"1 / 0":      "division by zero",
+		"sqrt(-1)":   "not a finite number",
+		"os.exit(1)": "unknown name",
+		"rm -rf /":   "unknown name",
+		"1 +":        "unexpected end",
+		"(1 + 2":     "expected )",
+		"pow(2)":     "takes 2 argument",
+		"2 3":        "unexpected",
+		"1; 2":       "unexpected",
+		"1.2.3":      "invalid number",
+		"":           "required",
+		strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100): "nested too deeply",
+	}
+	for expr, want := range cases {
+		res := execTool(t, context.Background(), NewCalculatorTool(), map[string]any{"expression": expr})
+		if !res.IsError || !strings.Contains(res.Error, want) {
+			t.Errorf("%q: got %+v, want error containing %q", expr, res, want)
+		}
+	}
+}
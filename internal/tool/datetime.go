@@ -0,0 +1,244 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the formats parse accepts, tried in order. Layouts without
+// an offset are read in the requested time zone.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+	time.RFC822Z,
+	time.RFC822,
+	"2 January 2006 15:04",
+	"2 January 2006",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 15:04",
+	"January 2, 2006",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+}
+
+// DateTimeTool does date and time arithmetic with the time package, so the
+// model doesn't have to count days or remember DST rules.
+type DateTimeTool struct {
+	loc *time.Location
+	now func() time.Time
+}
+
+// NewDateTimeTool creates a datetime tool that works in the IANA time zone
+// named by zone when a call doesn't name one. Empty or unknown names use
+// the local time zone.
+func NewDateTimeTool(zone string) *DateTimeTool {
+	loc, err := time.LoadLocation(zone)
+	if zone == "" || err != nil {
+		loc = time.Local
+	}
+	return &DateTimeTool{loc: loc, now: time.Now}
+}
+
+func (t *DateTimeTool) Name() string { return "datetime" }
+func (t *DateTimeTool) Description() string {
+	return "Date and time arithmetic with correct time zones and daylight saving. Use 'now' for the current time, 'parse' to normalize a date or convert it to another time zone, 'add' to move a time by years, months, days, and/or a duration such as 90m, and 'diff' for the time between two dates. Time zones are IANA names such as Europe/Berlin; the user's zone is " + t.loc.String() + "."
+}
+
+func (t *DateTimeTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["now", "parse", "add", "diff"],
+				"description": "The operation to perform"
+			},
+			"time": {
+				"type": "string",
+				"description": "A date or time, e.g. 2026-03-08T09:30:00Z, 2026-03-08 09:30, or 8 March 2026 (for 'parse', 'add', and as the start for 'diff'; 'add' and 'diff' default to now)"
+			},
+			"end": {
+				"type": "string",
+				"description": "The end time (only for 'diff')"
+			},
+			"tz": {
+				"type": "string",
+				"description": "IANA time zone for times without an offset and for the result (default: the user's zone)"
+			},
+			"to_tz": {
+				"type": "string",
+				"description": "IANA time zone to show the result in, if different from tz"
+			},
+			"years": {"type": "integer", "description": "Calendar years to add (only for 'add')"},
+			"months": {"type": "integer", "description": "Calendar months to add (only for 'add')"},
+			"days": {"type": "integer", "description": "Calendar days to add, keeping the wall-clock time across DST changes (only for 'add')"},
+			"duration": {
+				"type": "string",
+				"description": "Elapsed time to add, e.g. 90m, 36h, or -1h30m (only for 'add')"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+type dateTimeParams struct {
+	Action   string `json:"action"`
+	Time     string `json:"time"`
+	End      string `json:"end"`
+	TZ       string `json:"tz"`
+	ToTZ     string `json:"to_tz"`
+	Years    int    `json:"years"`
+	Months   int    `json:"months"`
+	Days     int    `json:"days"`
+	Duration string `json:"duration"`
+}
+
+func (t *DateTimeTool) Execute(_ context.Context, args json.RawMessage) (*Result, error) {
+	var params dateTimeParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	loc, err := t.zone(params.TZ, t.loc)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+	out, err := t.zone(params.ToTZ, loc)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	switch params.Action {
+	case "now":
+		return &Result{Output: formatDateTime(t.now().In(out))}, nil
+	case "parse":
+		if params.Time == "" {
+			return &Result{Error: "time is required for parse", IsError: true}, nil
+		}
+		tm, err := t.parse(params.Time, loc)
+		if err != nil {
+			return &Result{Error: err.Error(), IsError: true}, nil
+		}
+		return &Result{Output: formatDateTime(tm.In(out))}, nil
+	case "add":
+		tm, err := t.parse(params.Time, loc)
+		if err != nil {
+			return &Result{Error: err.Error(), IsError: true}, nil
+		}
+		var d time.Duration
+		if params.Duration != "" {
+			if d, err = time.ParseDuration(params.Duration); err != nil {
+				return &Result{Error: "invalid duration: " + params.Duration + " (use units h, m, s; days go in 'days')", IsError: true}, nil
+			}
+		}
+		// Calendar units first, in the input's zone, so a day is a day on
+		// the clock even when DST makes it 23 or 25 hours long.
+		tm = tm.In(loc).AddDate(params.Years, params.Months, params.Days).Add(d)
+		return &Result{Output: formatDateTime(tm.In(out))}, nil
+	case "diff":
+		if params.End == "" {
+			return &Result{Error: "end is required for diff", IsError: true}, nil
+		}
+		start, err := t.parse(params.Time, loc)
+		if err != nil {
+			return &Result{Error: err.Error(), IsError: true}, nil
+		}
+		end, err := t.parse(params.End, loc)
+		if err != nil {
+			return &Result{Error: err.Error(), IsError: true}, nil
+		}
+		return &Result{Output: formatElapsed(end.Sub(start))}, nil
+	default:
+		return &Result{Error: "unknown action: " + params.Action, IsError: true}, nil
+	}
+}
+
+// zone loads the named time zone, or returns def if name is empty.
+func (t *DateTimeTool) zone(name string, def *time.Location) (*time.Location, error) {
+	if name == "" {
+		return def, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q (use an IANA name such as Europe/Berlin)", name)
+	}
+	return loc, nil
+}
+
+// parse reads s in one of dateLayouts, in loc unless s has an offset. An
+// empty s is the current time, and "now" is accepted too. So are Unix
+// timestamps in seconds.
+func (t *DateTimeTool) parse(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "now") {
+		return t.now().In(loc), nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil && len(s) >= 9 {
+		return time.Unix(secs, 0).In(loc), nil
+	}
+	for _, layout := range dateLayouts {
+		if tm, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return tm, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse time %q; use a form like 2026-03-08T09:30:00Z or 2026-03-08 09:30", s)
+}
+
+// formatDateTime shows tm as RFC 3339 with its weekday and zone.
+func formatDateTime(tm time.Time) string {
+	zone, _ := tm.Zone()
+	name := tm.Location().String()
+	if name == "Local" || name == zone {
+		return fmt.Sprintf("%s (%s, %s)", tm.Format(time.RFC3339), tm.Format("Monday"), zone)
+	}
+	return fmt.Sprintf("%s (%s, %s %s)", tm.Format(time.RFC3339), tm.Format("Monday"), name, zone)
+}
+
+// formatElapsed shows d in days, hours, minutes, and seconds, plus its
+// totals in hours (to two decimals) and seconds. Days here are 24 hours.
+func formatElapsed(d time.Duration) string {
+	sign := ""
+	abs := d
+	if d < 0 {
+		sign, abs = "-", -d
+	}
+	var parts []string
+	for _, unit := range []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+		{time.Second, "second"},
+	} {
+		if n := abs / unit.size; n > 0 {
+			name := unit.name
+			if n != 1 {
+				name += "s"
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", n, name))
+			abs -= n * unit.size
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"0 seconds"}
+	}
+	return fmt.Sprintf("%s%s (%s hours, %d seconds)", sign, strings.Join(parts, " "),
+		strconv.FormatFloat(math.Round(d.Hours()*100)/100, 'f', -1, 64), int64(d.Seconds()))
+}
@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTestDateTime(t *testing.T) *DateTimeTool {
+	t.Helper()
+	dt := NewDateTimeTool("America/New_York")
+	if dt.loc.String() != "America/New_York" {
+		t.Skip("time zone database not available")
+	}
+	dt.now = func() time.Time { return time.Date(2026, 3, 7, 17, 0, 0, 0, time.UTC) }
+	return dt
+}
+
+func TestDateTimeNowAndParse(t *testing.T) {
+	dt := newTestDateTime(t)
+	for _, c := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "now"}, "2026-03-07T12:00:00-05:00 (Saturday, America/New_York EST)"},
+		{map[string]any{"action": "now", "tz": "Asia/Tokyo"}, "2026-03-08T02:00:00+09:00 (Sunday, Asia/Tokyo JST)"},
+		{map[string]any{"action": "parse", "time": "2026-07-04 09:30"}, "2026-07-04T09:30:00-04:00 (Saturday, America/New_York EDT)"},
+		{map[string]any{"action": "parse", "time": "4 July 2026"}, "2026-07-04T00:00:00-04:00 (Saturday, America/New_York EDT)"},
+		// An explicit offset wins over tz; to_tz converts.
+		{map[string]any{"action": "parse", "time": "2026-07-04T09:30:00Z", "to_tz": "Europe/Berlin"}, "2026-07-04T11:30:00+02:00 (Saturday, Europe/Berlin CEST)"},
+		{map[string]any{"action": "parse", "time": "2026-07-04 09:30", "tz": "Europe/London", "to_tz": "UTC"}, "2026-07-04T08:30:00Z (Saturday, UTC)"},
+	} {
+		if res := execTool(t, context.Background(), dt, c.args); res.IsError || res.Output != c.want {
+			t.Errorf("%v = %+v, want %q", c.args, res, c.want)
+		}
+	}
+}
+
+func TestDateTimeAddAcrossDST(t *testing.T) {
+	dt := newTestDateTime(t)
+	// Clocks in New York spring forward at 2:00 on 8 March 2026 and fall
+	// back at 2:00 on 1 November 2026.
+	for _, c := range []struct {
+		args map[string]any
+		want string
+	}{
+		// A calendar day keeps the wall-clock time: it is only 23 hours long.
+		{map[string]any{"action": "add", "time": "2026-03-07 12:00", "days": 1}, "2026-03-08T12:00:00-04:00 (Sunday, America/New_York EDT)"},
+		// 24 elapsed hours land an hour later on the clock.
+		{map[string]any{"action": "add", "time": "2026-03-07 12:00", "duration": "24h"}, "2026-03-08T13:00:00-04:00 (Sunday, America/New_York EDT)"},
+		{map[string]any{"action": "add", "time": "2026-03-08 01:30", "duration": "1h"}, "2026-03-08T03:30:00-04:00 (Sunday, America/New_York EDT)"},
+		{map[string]any{"action": "add", "time": "2026-10-31 12:00", "days": 1}, "2026-11-01T12:00:00-05:00 (Sunday, America/New_York EST)"},
+		{map[string]any{"action": "add", "time": "2026-10-31 12:00", "duration": "24h"}, "2026-11-01T11:00:00-05:00 (Sunday, America/New_York EST)"},
+		{map[string]any{"action": "add", "time": "2026-01-31", "months": 1, "duration": "-1h30m"}, "2026-03-02T22:30:00-05:00 (Monday, America/New_York EST)"},
+		// Without a time, add counts from now.
+		{map[string]any{"action": "add", "days": 1}, "2026-03-08T12:00:00-04:00 (Sunday, America/New_York EDT)"},
+	} {
+		if res := execTool(t, context.Background(), dt, c.args); res.IsError || res.Output != c.want {
+			t.Errorf("%v = %+v, want %q", c.args, res, c.want)
+		}
+	}
+}
+
+func TestDateTimeDiff(t *testing.T) {
+	dt := newTestDateTime(t)
+	for _, c := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "diff", "time": "2026-03-07 12:00", "end": "2026-03-08 12:00"}, "23 hours (23 hours, 82800 seconds)"},
+		{map[string]any{"action": "diff", "time": "2026-01-01", "end": "2026-01-04T06:30:15-05:00"}, "3 days 6 hours 30 minutes 15 seconds (78.5 hours, 282615 seconds)"},
+		{map[string]any{"action": "diff", "time": "2026-03-07 12:10", "end": "2026-03-07 12:00"}, "-10 minutes (-0.17 hours, -600 seconds)"},
+	} {
+		if res := execTool(t, context.Background(), dt, c.args); res.IsError || res.Output != c.want {
+			t.Errorf("%v = %+v, want %q", c.args, res, c.want)
+		}
+	}
+}
+
+func TestDateTimeRejectsBadInput(t *testing.T) {
+	dt := newTestDateTime(t)
+	for _, args := range []map[string]any{
+		{"action": "now", "tz": "Mars/Olympus_Mons"},
+		{"action": "parse", "time": "next tuesday-ish"},
+		{"action": "parse"},
+		{"action": "add", "duration": "3d"},
+		{"action": "diff", "time": "2026-01-01"},
+		{"action": "sleep"},
+	} {
+		raw, _ := json.Marshal(args)
+		if res, _ := dt.Execute(context.Background(), raw); !res.IsError {
+			t.Errorf("%v: expected an error, got %q", args, res.Output)
+		}
+	}
+}
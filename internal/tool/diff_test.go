@@ -0,0 +1,54 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, fmt.Sprint(i))
+		switch i {
+		case 2:
+			newLines = append(newLines, "two")
+		case 18:
+			// deleted
+		default:
+			newLines = append(newLines, fmt.Sprint(i))
+		}
+	}
+	got := unifiedDiff("a", "b", strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n")
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -15,6 +15,5 @@
+ 15
+ 16
+ 17
+-18
+ 19
+ 20
+`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffTrailingNewline(t *testing.T) {
+	got := unifiedDiff("a", "b", "x\n", "x")
+	want := "--- a\n+++ b\n@@ -1 +1 @@\n-x\n+x\n\\ No newline at end of file\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if unifiedDiff("a", "b", "same\n", "same\n") != "" {
+		t.Fatal("expected empty diff for equal input")
+	}
+}
@@ -0,0 +1,193 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"open-dan/internal/config"
+)
+
+const (
+	fetchUserAgent       = "Mozilla/5.0 (compatible; OpenDan/1.0; +https://github.com/PrinceLoren/open-dan)"
+	defaultFetchMaxChars = 20000
+	maxFetchRedirects    = 5
+)
+
+// FetchTool retrieves a single URL over HTTP without launching a browser.
+type FetchTool struct {
+	cfg    config.FetchConfig
+	client *http.Client
+	guard  urlGuard
+}
+
+// NewFetchTool creates a new fetch tool.
+func NewFetchTool(cfg config.FetchConfig) *FetchTool {
+	if cfg.TimeoutSecs <= 0 {
+		cfg.TimeoutSecs = 20
+	}
+	if cfg.MaxBodyKB <= 0 {
+		cfg.MaxBodyKB = 1024
+	}
+	t := &FetchTool{
+		cfg:   cfg,
+		guard: newURLGuard(cfg.AllowPrivateNetwork),
+	}
+	t.client = &http.Client{
+		Timeout: time.Duration(cfg.TimeoutSecs) * time.Second,
+		// Redirects must pass the same checks as the original URL.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+			}
+			_, err := t.guard.check(req.Context(), req.URL.String())
+			return err
+		},
+	}
+	return t
+}
+
+func (t *FetchTool) Name() string    { return "fetch" }
+func (t *FetchTool) Cacheable() bool { return true }
+func (t *FetchTool) Description() string {
+	return "Fetch the content of a specific URL over HTTP (GET). Returns readable text by default, or raw HTML with format=html. Faster than the browser but does not run JavaScript."
+}
+
+func (t *FetchTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"url": {
+				"type": "string",
+				"description": "The http(s) URL to fetch"
+			},
+			"max_chars": {
+				"type": "integer",
+				"description": "Maximum characters to return (default 20000)"
+			},
+			"format": {
+				"type": "string",
+				"enum": ["text", "html"],
+				"description": "text strips HTML to readable text (default); html returns the raw body"
+			}
+		},
+		"required": ["url"]
+	}`)
+}
+
+type fetchParams struct {
+	URL      string `json:"url"`
+	MaxChars int    `json:"max_chars"`
+	Format   string `json:"format"`
+}
+
+func (t *FetchTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
+	var params fetchParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	if params.URL == "" {
+		return &Result{Error: "url is required", IsError: true}, nil
+	}
+	if params.Format == "" {
+		params.Format = "text"
+	}
+	if params.Format != "text" && params.Format != "html" {
+		return &Result{Error: fmt.Sprintf("unknown format: %s", params.Format), IsError: true}, nil
+	}
+	if params.MaxChars <= 0 {
+		params.MaxChars = defaultFetchMaxChars
+	}
+
+	if _, err := t.guard.check(ctx, params.URL); err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", params.URL, nil)
+	if err != nil {
+		return &Result{Error: "failed to create request: " + err.Error(), IsError: true}, nil
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain,application/json;q=0.9,*/*;q=0.8")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &Result{Error: "fetch failed: " + err.Error(), IsError: true}, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.cfg.MaxBodyKB)*1024))
+	if err != nil {
+		return &Result{Error: "failed to read response: " + err.Error(), IsError: true}, nil
+	}
+	if resp.StatusCode >= 400 {
+		return &Result{Error: fmt.Sprintf("HTTP %d from %s", resp.StatusCode, params.URL), IsError: true}, nil
+	}
+
+	content := string(body)
+	if params.Format == "text" && strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		content = htmlToText(content)
+	}
+	if len(content) > params.MaxChars {
+		content = content[:params.MaxChars] + "\n... (content truncated)"
+	}
+
+	return &Result{Output: content}, nil
+}
+
+// htmlToText extracts visible text from an HTML document, one line per block
+// element, skipping scripts, styles, and other non-content elements.
+func htmlToText(doc string) string {
+	z := html.NewTokenizer(strings.NewReader(doc))
+	var b strings.Builder
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return collapseBlankLines(b.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "template", "svg", "head":
+				skip++
+			case "br", "p", "div", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6", "section", "article", "pre", "blockquote":
+				b.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "template", "svg", "head":
+				if skip > 0 {
+					skip--
+				}
+			case "p", "div", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6", "section", "article", "pre", "blockquote":
+				b.WriteByte('\n')
+			}
+		case html.TextToken:
+			if skip == 0 {
+				text := strings.Join(strings.Fields(string(z.Text())), " ")
+				if text != "" {
+					b.WriteString(text)
+					b.WriteByte(' ')
+				}
+			}
+		}
+	}
+}
+
+// collapseBlankLines trims each line and drops empty ones.
+func collapseBlankLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
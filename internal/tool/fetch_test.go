@@ -0,0 +1,47 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"open-dan/internal/config"
+)
+
+func TestFetchRejectsPrivateAndNonHTTP(t *testing.T) {
+	ft := NewFetchTool(config.FetchConfig{})
+
+	for _, u := range []string{
+		"http://127.0.0.1:8080/",
+		"http://localhost/admin",
+		"http://192.168.1.1/",
+		"http://[::1]/",
+		"file:///etc/passwd",
+		"ftp://example.com/",
+	} {
+		args, _ := json.Marshal(map[string]string{"url": u})
+		res, err := ft.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.IsError {
+			t.Errorf("expected %s to be rejected", u)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	doc := `<html><head><title>T</title><style>p{color:red}</style></head>
+<body><nav>Home</nav><h1>Heading</h1><p>First   paragraph
+with <b>bold</b> text.</p><script>alert(1)</script><ul><li>one</li><li>two</li></ul></body></html>`
+
+	got := htmlToText(doc)
+	want := "Home\nHeading\nFirst paragraph with bold text.\none\ntwo"
+	if got != want {
+		t.Fatalf("unexpected text:\n%q\nwant:\n%q", got, want)
+	}
+	if strings.Contains(got, "alert") || strings.Contains(got, "color") {
+		t.Fatal("script and style content should be dropped")
+	}
+}
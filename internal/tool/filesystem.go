@@ -1,26 +1,48 @@
 package tool
 
 import (
+	"bufio"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// defaultMaxFileSize caps files created by write and append.
+const defaultMaxFileSize = 10 << 20
+
+// maxBase64ReadBytes caps base64 reads so the encoding stays within the
+// 50,000 characters a text read returns.
+const maxBase64ReadBytes = 37500
+
 // FilesystemTool provides sandboxed file read/write operations.
 type FilesystemTool struct {
 	workspaceDir string
+	maxFileSize  int64
 }
 
 func NewFilesystemTool(workspaceDir string) *FilesystemTool {
-	return &FilesystemTool{workspaceDir: workspaceDir}
+	return &FilesystemTool{workspaceDir: workspaceDir, maxFileSize: defaultMaxFileSize}
+}
+
+// SetMaxFileSize limits the size in bytes of files produced by write and
+// append. Zero or less restores the default.
+func (t *FilesystemTool) SetMaxFileSize(n int64) {
+	if n <= 0 {
+		n = defaultMaxFileSize
+	}
+	t.maxFileSize = n
 }
 
 func (t *FilesystemTool) Name() string        { return "filesystem" }
 func (t *FilesystemTool) Description() string  {
-	return "Read or write files within the workspace directory. Use action 'read' to read a file, 'write' to create/overwrite a file, 'list' to list directory contents."
+	return "Read or write files within the workspace directory. Use action 'read' to read a file (optionally only start_line to end_line), 'write' to create/overwrite a file, 'append' to add to the end of a file, 'list' to list directory contents, 'delete' to remove a file or directory, 'mkdir' to create a directory, and 'move' or 'copy' to a 'dest' path. Set preview with 'write' to get a diff of the change without writing. Set encoding to 'base64' for binary files."
 }
 
 func (t *FilesystemTool) Parameters() json.RawMessage {
@@ -29,7 +51,7 @@ func (t *FilesystemTool) Parameters() json.RawMessage {
 		"properties": {
 			"action": {
 				"type": "string",
-				"enum": ["read", "write", "list"],
+				"enum": ["read", "write", "append", "list", "delete", "mkdir", "move", "copy"],
 				"description": "The file operation to perform"
 			},
 			"path": {
@@ -38,7 +60,32 @@ func (t *FilesystemTool) Parameters() json.RawMessage {
 			},
 			"content": {
 				"type": "string",
-				"description": "Content to write (only for 'write' action)"
+				"description": "Content to write (only for 'write' and 'append' actions)"
+			},
+			"dest": {
+				"type": "string",
+				"description": "Destination path within workspace (only for 'move' and 'copy')"
+			},
+			"recursive": {
+				"type": "boolean",
+				"description": "Delete or copy a directory with its contents"
+			},
+			"preview": {
+				"type": "boolean",
+				"description": "With 'write', return a unified diff against the current file instead of writing"
+			},
+			"start_line": {
+				"type": "integer",
+				"description": "With 'read', first line to return (1-indexed, inclusive)"
+			},
+			"end_line": {
+				"type": "integer",
+				"description": "With 'read', last line to return (inclusive); defaults to the end of the file"
+			},
+			"encoding": {
+				"type": "string",
+				"enum": ["text", "base64"],
+				"description": "Use 'base64' to read, write, or append binary files such as images or PDFs (default 'text'). Data URLs are accepted when writing."
 			}
 		},
 		"required": ["action", "path"]
@@ -50,11 +97,34 @@ func (t *FilesystemTool) Execute(ctx context.Context, args json.RawMessage) (*Re
 		Action  string `json:"action"`
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		Preview bool   `json:"preview"`
+		Dest    string `json:"dest"`
+		// Recursive allows deleting non-empty directories and copying directories.
+		Recursive bool   `json:"recursive"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		Encoding  string `json:"encoding"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
 	}
 
+	binary := false
+	switch params.Encoding {
+	case "", "text":
+	case "base64":
+		binary = true
+		if params.Action == "write" || params.Action == "append" {
+			data, err := decodeBase64(params.Content)
+			if err != nil {
+				return &Result{Error: "invalid base64 content: " + err.Error(), IsError: true}, nil
+			}
+			params.Content = string(data)
+		}
+	default:
+		return &Result{Error: "unknown encoding: " + params.Encoding, IsError: true}, nil
+	}
+
 	// Resolve and validate path
 	fullPath, err := t.resolvePath(params.Path)
 	if err != nil {
@@ -63,11 +133,44 @@ func (t *FilesystemTool) Execute(ctx context.Context, args json.RawMessage) (*Re
 
 	switch params.Action {
 	case "read":
+		if binary {
+			return t.readFileBase64(fullPath)
+		}
+		if params.StartLine > 0 || params.EndLine > 0 {
+			return t.readLines(fullPath, params.StartLine, params.EndLine)
+		}
 		return t.readFile(fullPath)
 	case "write":
+		if params.Preview {
+			if binary {
+				return &Result{Error: "preview is not supported for base64 content", IsError: true}, nil
+			}
+			return t.previewWrite(fullPath, params.Path, params.Content)
+		}
 		return t.writeFile(fullPath, params.Content)
+	case "append":
+		return t.appendFile(fullPath, params.Content)
 	case "list":
 		return t.listDir(fullPath)
+	case "delete":
+		return t.deletePath(fullPath, params.Recursive)
+	case "mkdir":
+		if err := os.MkdirAll(fullPath, 0755); err != nil {
+			return &Result{Error: "failed to create directory: " + err.Error(), IsError: true}, nil
+		}
+		return &Result{Output: "Directory created: " + fullPath}, nil
+	case "move", "copy":
+		if params.Dest == "" {
+			return &Result{Error: "dest is required for " + params.Action, IsError: true}, nil
+		}
+		destPath, err := t.resolvePath(params.Dest)
+		if err != nil {
+			return &Result{Error: "dest: " + err.Error(), IsError: true}, nil
+		}
+		if params.Action == "move" {
+			return t.movePath(fullPath, destPath)
+		}
+		return t.copyPath(fullPath, destPath, params.Recursive)
 	default:
 		return &Result{Error: "unknown action: " + params.Action, IsError: true}, nil
 	}
@@ -83,25 +186,79 @@ func (t *FilesystemTool) resolvePath(relPath string) (string, error) {
 		return "", fmt.Errorf("path traversal not allowed")
 	}
 
-	fullPath := filepath.Join(t.workspaceDir, filepath.Clean(relPath))
+	absWorkspace, err := filepath.Abs(t.workspaceDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve workspace: %w", err)
+	}
+	fullPath := filepath.Join(absWorkspace, filepath.Clean(relPath))
 
-	// Verify the resolved path is within workspace
-	absWorkspace, _ := filepath.Abs(t.workspaceDir)
-	absPath, _ := filepath.Abs(fullPath)
-	if !strings.HasPrefix(absPath, absWorkspace) {
+	// Verify the path is within workspace. filepath.Rel rather than a string
+	// prefix, so a sibling like "workspace-evil" doesn't match "workspace".
+	if !within(absWorkspace, fullPath) {
 		return "", fmt.Errorf("path outside workspace")
 	}
 
-	// Check symlinks
-	if resolved, err := filepath.EvalSymlinks(filepath.Dir(fullPath)); err == nil {
-		if !strings.HasPrefix(resolved, absWorkspace) {
-			return "", fmt.Errorf("symlink escapes workspace")
-		}
+	// Check symlinks on the whole path, including the final element, so a
+	// link inside the workspace can't point reads or writes outside it.
+	realWorkspace, err := filepath.EvalSymlinks(absWorkspace)
+	if err != nil {
+		return "", fmt.Errorf("resolve workspace: %w", err)
+	}
+	resolved, err := resolveExisting(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("resolve path: %w", err)
+	}
+	if !within(realWorkspace, resolved) {
+		return "", fmt.Errorf("symlink escapes workspace")
 	}
 
 	return fullPath, nil
 }
 
+// within reports whether path is root or inside it. Both must be absolute
+// and clean.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveExisting evaluates symlinks in path. Trailing elements that don't
+// exist yet, such as a file about to be written, are joined back onto the
+// resolved nearest existing ancestor.
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	for hops := 0; ; hops++ {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		// A dangling symlink doesn't exist to EvalSymlinks either, but it
+		// would be followed on write, so its target must be checked.
+		if target, lerr := os.Readlink(path); lerr == nil {
+			if hops > 255 {
+				return "", fmt.Errorf("too many levels of symbolic links")
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			path = target
+			continue
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
 func (t *FilesystemTool) readFile(path string) (*Result, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -114,7 +271,95 @@ func (t *FilesystemTool) readFile(path string) (*Result, error) {
 	return &Result{Output: output}, nil
 }
 
+// readFileBase64 returns the file's bytes base64-encoded. The size limit
+// applies to the raw bytes and is the smaller of maxBase64ReadBytes and the
+// file size limit; unlike text reads, a larger file is an error rather than
+// truncated, since a partial encoding would be useless.
+func (t *FilesystemTool) readFileBase64(path string) (*Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return &Result{Error: "failed to read file: " + err.Error(), IsError: true}, nil
+	}
+	limit := min(t.maxFileSize, maxBase64ReadBytes)
+	if info.Size() > limit {
+		return &Result{Error: fmt.Sprintf("file is %d bytes, over the %d byte limit for base64 reads", info.Size(), limit), IsError: true}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &Result{Error: "failed to read file: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+// decodeBase64 decodes standard base64, with or without padding, and
+// accepts a data URL such as a browser screenshot.
+func decodeBase64(s string) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(s, "data:"); ok {
+		_, payload, found := strings.Cut(rest, ",")
+		if !found {
+			return nil, fmt.Errorf("malformed data URL")
+		}
+		s = payload
+	}
+	s = strings.Join(strings.Fields(s), "")
+	return base64.StdEncoding.DecodeString(s + strings.Repeat("=", (4-len(s)%4)%4))
+}
+
+// readLines returns lines start through end (1-indexed, inclusive), each
+// prefixed with its number. The range is clamped to the file, and the
+// header reports the total line count so the caller can page further.
+func (t *FilesystemTool) readLines(path string, start, end int) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return &Result{Error: "failed to read file: " + err.Error(), IsError: true}, nil
+	}
+	defer f.Close()
+
+	start = max(start, 1)
+	if end > 0 && end < start {
+		return &Result{Error: fmt.Sprintf("end_line %d is before start_line %d", end, start), IsError: true}, nil
+	}
+
+	var b strings.Builder
+	total, truncated := 0, false
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			total++
+			if total >= start && (end <= 0 || total <= end) && !truncated {
+				if b.Len() > 50000 {
+					truncated = true
+				} else {
+					fmt.Fprintf(&b, "%6d\t%s\n", total, strings.TrimRight(line, "\r\n"))
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &Result{Error: "failed to read file: " + err.Error(), IsError: true}, nil
+		}
+	}
+
+	if start > total {
+		return &Result{Output: fmt.Sprintf("(start_line %d is past the end of the file; it has %d lines)", start, total)}, nil
+	}
+	if end <= 0 || end > total {
+		end = total
+	}
+	output := fmt.Sprintf("Lines %d-%d of %d:\n%s", start, end, total, b.String())
+	if truncated {
+		output += "... (range truncated)\n"
+	}
+	return &Result{Output: strings.TrimRight(output, "\n")}, nil
+}
+
 func (t *FilesystemTool) writeFile(path, content string) (*Result, error) {
+	if int64(len(content)) > t.maxFileSize {
+		return &Result{Error: fmt.Sprintf("content exceeds the %d byte file size limit", t.maxFileSize), IsError: true}, nil
+	}
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return &Result{Error: "failed to create directory: " + err.Error(), IsError: true}, nil
@@ -125,6 +370,166 @@ func (t *FilesystemTool) writeFile(path, content string) (*Result, error) {
 	return &Result{Output: fmt.Sprintf("File written: %s (%d bytes)", path, len(content))}, nil
 }
 
+// appendFile adds content to the end of the file, creating it if needed,
+// and reports the new size.
+func (t *FilesystemTool) appendFile(path, content string) (*Result, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return &Result{Error: "failed to create directory: " + err.Error(), IsError: true}, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return &Result{Error: "failed to open file: " + err.Error(), IsError: true}, nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return &Result{Error: "failed to stat file: " + err.Error(), IsError: true}, nil
+	}
+	if info.Size()+int64(len(content)) > t.maxFileSize {
+		return &Result{Error: fmt.Sprintf("append would exceed the %d byte file size limit (file is %d bytes)", t.maxFileSize, info.Size()), IsError: true}, nil
+	}
+	if _, err := f.WriteString(content); err != nil {
+		return &Result{Error: "failed to append: " + err.Error(), IsError: true}, nil
+	}
+	info, err = f.Stat()
+	if err != nil {
+		return &Result{Error: "failed to stat file: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: fmt.Sprintf("Appended %d bytes to %s (now %d bytes)", len(content), path, info.Size())}, nil
+}
+
+// previewWrite returns the diff a write would make, without writing.
+func (t *FilesystemTool) previewWrite(path, relPath, content string) (*Result, error) {
+	oldName := "a/" + filepath.ToSlash(relPath)
+	current, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		oldName = "/dev/null"
+	} else if err != nil {
+		return &Result{Error: "failed to read file: " + err.Error(), IsError: true}, nil
+	}
+
+	diff := unifiedDiff(oldName, "b/"+filepath.ToSlash(relPath), string(current), content)
+	if diff == "" {
+		return &Result{Output: "No changes."}, nil
+	}
+	return &Result{Output: diff}, nil
+}
+
+// deletePath removes a file or directory. Directories must be empty unless
+// recursive is set. The workspace root itself can't be deleted.
+func (t *FilesystemTool) deletePath(path string, recursive bool) (*Result, error) {
+	if t.isWorkspaceRoot(path) {
+		return &Result{Error: "cannot delete the workspace root", IsError: true}, nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return &Result{Error: "failed to delete: " + err.Error(), IsError: true}, nil
+	}
+	if info.IsDir() && recursive {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		if info.IsDir() && !recursive {
+			return &Result{Error: "directory is not empty; set recursive to delete it with its contents", IsError: true}, nil
+		}
+		return &Result{Error: "failed to delete: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: "Deleted: " + path}, nil
+}
+
+// movePath renames src to dest, refusing to overwrite an existing dest.
+func (t *FilesystemTool) movePath(src, dest string) (*Result, error) {
+	if t.isWorkspaceRoot(src) {
+		return &Result{Error: "cannot move the workspace root", IsError: true}, nil
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return &Result{Error: "destination already exists", IsError: true}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return &Result{Error: "failed to create directory: " + err.Error(), IsError: true}, nil
+	}
+	if err := os.Rename(src, dest); err != nil {
+		return &Result{Error: "failed to move: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: fmt.Sprintf("Moved: %s -> %s", src, dest)}, nil
+}
+
+// copyPath copies a file, or a directory tree when recursive is set,
+// refusing to overwrite an existing dest. Symlinks are not followed or
+// copied, so a copy can't pull in files from outside the workspace.
+func (t *FilesystemTool) copyPath(src, dest string, recursive bool) (*Result, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return &Result{Error: "failed to copy: " + err.Error(), IsError: true}, nil
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return &Result{Error: "destination already exists", IsError: true}, nil
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return &Result{Error: "cannot copy a symlink", IsError: true}, nil
+	}
+	if info.IsDir() && !recursive {
+		return &Result{Error: "source is a directory; set recursive to copy it", IsError: true}, nil
+	}
+	if info.IsDir() && strings.HasPrefix(dest+string(filepath.Separator), src+string(filepath.Separator)) {
+		return &Result{Error: "cannot copy a directory into itself", IsError: true}, nil
+	}
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			return nil
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type().IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return copyFile(path, target)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return &Result{Error: "failed to copy: " + err.Error(), IsError: true}, nil
+	}
+	return &Result{Output: fmt.Sprintf("Copied: %s -> %s", src, dest)}, nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (t *FilesystemTool) isWorkspaceRoot(path string) bool {
+	absWorkspace, _ := filepath.Abs(t.workspaceDir)
+	absPath, _ := filepath.Abs(path)
+	return absPath == absWorkspace
+}
+
 func (t *FilesystemTool) listDir(path string) (*Result, error) {
 	entries, err := os.ReadDir(path)
 	if err != nil {
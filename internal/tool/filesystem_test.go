@@ -0,0 +1,338 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemPreviewWrite(t *testing.T) {
+	dir := t.TempDir()
+	original := "one\ntwo\nthree\n"
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(original), 0600)
+	fst := NewFilesystemTool(dir)
+
+	args, _ := json.Marshal(map[string]any{
+		"action":  "write",
+		"path":    "notes.txt",
+		"content": "one\n2\nthree\nfour\n",
+		"preview": true,
+	})
+	res, err := fst.Execute(context.Background(), args)
+	if err != nil || res.IsError {
+		t.Fatalf("unexpected error: %v %s", err, res.Error)
+	}
+	want := `--- a/notes.txt
++++ b/notes.txt
+@@ -1,3 +1,4 @@
+ one
+-two
++2
+ three
++four
+`
+	if res.Output != want {
+		t.Fatalf("got diff:\n%s\nwant:\n%s", res.Output, want)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "notes.txt")); string(data) != original {
+		t.Fatalf("preview modified the file: %q", data)
+	}
+}
+
+func TestFilesystemPreviewNewFile(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+
+	args, _ := json.Marshal(map[string]any{"action": "write", "path": "sub/new.txt", "content": "hello", "preview": true})
+	res, _ := fst.Execute(context.Background(), args)
+	want := "--- /dev/null\n+++ b/sub/new.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n"
+	if res.Output != want {
+		t.Fatalf("got %q, want %q", res.Output, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(err) {
+		t.Fatal("preview created a directory")
+	}
+
+	args, _ = json.Marshal(map[string]any{"action": "write", "path": "same.txt", "content": "", "preview": true})
+	os.WriteFile(filepath.Join(dir, "same.txt"), nil, 0600)
+	if res, _ := fst.Execute(context.Background(), args); res.Output != "No changes." {
+		t.Fatalf("expected no changes, got %q", res.Output)
+	}
+}
+
+func TestFilesystemMkdirDelete(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "mkdir", "path": "a/b"}); res.IsError {
+		t.Fatal(res.Error)
+	}
+	os.WriteFile(filepath.Join(dir, "a/b/f.txt"), []byte("x"), 0600)
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "delete", "path": "a"}); !res.IsError {
+		t.Fatal("expected non-empty directory delete to fail without recursive")
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "delete", "path": "a/b/f.txt"}); res.IsError {
+		t.Fatal(res.Error)
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "delete", "path": "a/b"}); res.IsError {
+		t.Fatalf("empty directory should delete: %s", res.Error)
+	}
+	os.MkdirAll(filepath.Join(dir, "c/d"), 0755)
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "delete", "path": "c", "recursive": true}); res.IsError {
+		t.Fatal(res.Error)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c")); !os.IsNotExist(err) {
+		t.Fatal("expected recursive delete")
+	}
+
+	for _, p := range []string{".", ""} {
+		if res := execTool(t, context.Background(), fst, map[string]any{"action": "delete", "path": p, "recursive": true}); !res.IsError {
+			t.Fatalf("deleting workspace root %q should fail", p)
+		}
+	}
+}
+
+func TestFilesystemMoveCopy(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+	os.MkdirAll(filepath.Join(dir, "src/sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "src/sub/f.txt"), []byte("data"), 0600)
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "copy", "path": "src", "dest": "dup"}); !res.IsError {
+		t.Fatal("expected directory copy to require recursive")
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "copy", "path": "src", "dest": "dup", "recursive": true}); res.IsError {
+		t.Fatal(res.Error)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "dup/sub/f.txt")); string(data) != "data" {
+		t.Fatalf("copy missing file content: %q", data)
+	}
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "move", "path": "src/sub/f.txt", "dest": "moved/f.txt"}); res.IsError {
+		t.Fatal(res.Error)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "moved/f.txt")); err != nil {
+		t.Fatal("expected moved file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src/sub/f.txt")); !os.IsNotExist(err) {
+		t.Fatal("expected source removed after move")
+	}
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "move", "path": "dup", "dest": "moved/f.txt"}); !res.IsError {
+		t.Fatal("expected move onto existing destination to fail")
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "move", "path": "moved"}); !res.IsError {
+		t.Fatal("expected move without dest to fail")
+	}
+}
+
+func TestFilesystemMoveCopyConfined(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("x"), 0600)
+
+	for _, action := range []string{"move", "copy"} {
+		for _, dest := range []string{"../escape.txt", "/tmp/../../etc/x"} {
+			res := execTool(t, context.Background(), fst, map[string]any{"action": action, "path": "f.txt", "dest": dest})
+			if !res.IsError {
+				t.Errorf("%s to %q should be rejected", action, dest)
+			}
+		}
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "copy", "path": "../x", "dest": "y"}); !res.IsError {
+		t.Error("copy from outside the workspace should be rejected")
+	}
+}
+
+func TestFilesystemAppend(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+	fst.SetMaxFileSize(10)
+
+	res := execTool(t, context.Background(), fst, map[string]any{"action": "append", "path": "logs/run.log", "content": "abc\n"})
+	if res.IsError || !strings.Contains(res.Output, "now 4 bytes") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "append", "path": "logs/run.log", "content": "def\n"})
+	if res.IsError || !strings.Contains(res.Output, "now 8 bytes") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "logs/run.log")); string(data) != "abc\ndef\n" {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "append", "path": "logs/run.log", "content": "ghi\n"}); !res.IsError {
+		t.Fatal("expected append past the size limit to fail")
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "big.txt", "content": "0123456789x"}); !res.IsError {
+		t.Fatal("expected write past the size limit to fail")
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "append", "path": "../outside.log", "content": "x"}); !res.IsError {
+		t.Fatal("expected append outside the workspace to fail")
+	}
+}
+
+func TestFilesystemReadLines(t *testing.T) {
+	dir := t.TempDir()
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(content.String()), 0600)
+	fst := NewFilesystemTool(dir)
+
+	res := execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "big.txt", "start_line": 3, "end_line": 4})
+	want := "Lines 3-4 of 10:\n     3\tline 3\n     4\tline 4"
+	if res.Output != want {
+		t.Errorf("got:\n%q\nwant:\n%q", res.Output, want)
+	}
+
+	// An end past the file clamps to the last line.
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "big.txt", "start_line": 9, "end_line": 50})
+	if !strings.HasPrefix(res.Output, "Lines 9-10 of 10:") || !strings.HasSuffix(res.Output, "line 10") {
+		t.Errorf("unexpected clamped output:\n%s", res.Output)
+	}
+
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "big.txt", "start_line": 20})
+	if res.IsError || !strings.Contains(res.Output, "has 10 lines") {
+		t.Errorf("expected total line count for out-of-range start, got %+v", res)
+	}
+
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "big.txt", "start_line": 5, "end_line": 2})
+	if !res.IsError {
+		t.Error("expected error for end_line before start_line")
+	}
+}
+
+func TestFilesystemRejectsSiblingPrefix(t *testing.T) {
+	parent := t.TempDir()
+	workspace := filepath.Join(parent, "workspace")
+	evil := filepath.Join(parent, "workspace-evil")
+	os.Mkdir(workspace, 0755)
+	os.Mkdir(evil, 0755)
+	os.WriteFile(filepath.Join(evil, "secret.txt"), []byte("secret"), 0600)
+
+	// A link to the sibling shares the workspace's string prefix.
+	os.Symlink(evil, filepath.Join(workspace, "sib"))
+	fst := NewFilesystemTool(workspace)
+
+	res := execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "sib/secret.txt"})
+	if !res.IsError || strings.Contains(res.Output, "secret") {
+		t.Fatalf("read through sibling-prefix link should fail, got %+v", res)
+	}
+	if within(workspace, evil) {
+		t.Fatal("within treated a sibling with a shared prefix as inside")
+	}
+}
+
+func TestFilesystemRejectsOutwardSymlink(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	target := filepath.Join(outside, "target.txt")
+	os.WriteFile(target, []byte("outside"), 0600)
+
+	os.Symlink(target, filepath.Join(workspace, "link.txt"))
+	os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(workspace, "dangling.txt"))
+	fst := NewFilesystemTool(workspace)
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "link.txt"}); !res.IsError {
+		t.Fatalf("read through outward file link should fail, got %q", res.Output)
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "link.txt", "content": "pwned"}); !res.IsError {
+		t.Fatal("write through outward file link should fail")
+	}
+	if data, _ := os.ReadFile(target); string(data) != "outside" {
+		t.Fatalf("file outside workspace was modified: %q", data)
+	}
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "dangling.txt", "content": "pwned"}); !res.IsError {
+		t.Fatal("write through dangling outward link should fail")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Fatal("dangling link created a file outside the workspace")
+	}
+
+	// New files and links that stay inside are still fine.
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "sub/new.txt", "content": "ok"}); res.IsError {
+		t.Fatalf("write of new file failed: %s", res.Error)
+	}
+	os.Symlink(filepath.Join(workspace, "sub", "new.txt"), filepath.Join(workspace, "inner.txt"))
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "inner.txt"}); res.IsError || res.Output != "ok" {
+		t.Fatalf("read through inner link failed: %+v", res)
+	}
+}
+
+func TestFilesystemBase64RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe, '\r', '\n', 0x1a}
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "img.png", "content": encoded, "encoding": "base64"})
+	if res.IsError {
+		t.Fatalf("write: %s", res.Error)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "img.png")); !bytes.Equal(got, data) {
+		t.Fatalf("written bytes = %v, want %v", got, data)
+	}
+
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "img.png", "encoding": "base64"})
+	if res.Output != encoded {
+		t.Fatalf("read = %q, want %q", res.Output, encoded)
+	}
+
+	// Screenshot data URLs decode to the same bytes.
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "shot.png", "content": "data:image/png;base64," + encoded, "encoding": "base64"})
+	if res.IsError {
+		t.Fatalf("write data URL: %s", res.Error)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "shot.png")); !bytes.Equal(got, data) {
+		t.Fatalf("data URL bytes = %v", got)
+	}
+
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "bad.bin", "content": "not base64!", "encoding": "base64"}); !res.IsError {
+		t.Fatal("expected invalid base64 to be rejected")
+	}
+}
+
+func TestFilesystemBase64SizeLimitIsRaw(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+	fst.SetMaxFileSize(12)
+
+	// 12 raw bytes encode to 16 base64 characters but fit the limit.
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0}, 12))
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "a.bin", "content": encoded, "encoding": "base64"}); res.IsError {
+		t.Fatalf("write at limit: %s", res.Error)
+	}
+	encoded = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0}, 13))
+	if res := execTool(t, context.Background(), fst, map[string]any{"action": "write", "path": "b.bin", "content": encoded, "encoding": "base64"}); !res.IsError {
+		t.Fatal("expected write over the raw limit to fail")
+	}
+}
+
+func TestFilesystemBase64ReadCap(t *testing.T) {
+	dir := t.TempDir()
+	fst := NewFilesystemTool(dir)
+	os.WriteFile(filepath.Join(dir, "ok.bin"), bytes.Repeat([]byte{1}, maxBase64ReadBytes), 0644)
+	os.WriteFile(filepath.Join(dir, "big.bin"), bytes.Repeat([]byte{1}, maxBase64ReadBytes+1), 0644)
+
+	res := execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "ok.bin", "encoding": "base64"})
+	if res.IsError {
+		t.Fatalf("read at cap: %s", res.Error)
+	}
+	if len(res.Output) > 50000 {
+		t.Errorf("encoded output is %d chars, want at most the 50000 of a text read", len(res.Output))
+	}
+	res = execTool(t, context.Background(), fst, map[string]any{"action": "read", "path": "big.bin", "encoding": "base64"})
+	if !res.IsError || res.Output != "" || !strings.Contains(res.Error, "limit for base64 reads") {
+		t.Fatalf("expected base64 read over the cap to fail, got %+v", res)
+	}
+}
@@ -0,0 +1,230 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"open-dan/internal/config"
+)
+
+const defaultHTTPMaxChars = 20000
+
+// httpMethods are the methods http_request accepts.
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// reservedHeaders are set by the HTTP client and can't be overridden.
+var reservedHeaders = []string{"Host", "Content-Length", "Transfer-Encoding", "Connection"}
+
+// HTTPTool makes HTTP requests with any method, headers, and body, for
+// talking to APIs. It only calls hosts on the configured allowlist, and
+// those must pass the same SSRF checks as fetch.
+type HTTPTool struct {
+	cfg    config.HTTPRequestConfig
+	client *http.Client
+	policy *URLPolicy
+}
+
+// NewHTTPTool creates an http_request tool.
+func NewHTTPTool(cfg config.HTTPRequestConfig) *HTTPTool {
+	if cfg.TimeoutSecs <= 0 {
+		cfg.TimeoutSecs = 30
+	}
+	if cfg.MaxBodyKB <= 0 {
+		cfg.MaxBodyKB = 256
+	}
+	t := &HTTPTool{
+		cfg:    cfg,
+		policy: NewURLPolicy(cfg),
+	}
+	t.client = &http.Client{
+		Timeout: time.Duration(cfg.TimeoutSecs) * time.Second,
+		// Redirects must pass the same checks as the original URL.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+			}
+			return t.policy.Check(req.Context(), req.URL.String())
+		},
+	}
+	return t
+}
+
+func (t *HTTPTool) Name() string { return "http_request" }
+func (t *HTTPTool) Description() string {
+	return fmt.Sprintf("Make an HTTP request to an API, with any method, headers, and body. Returns the status, response headers, and body. Only these hosts may be called: %s. Use fetch instead to read a web page.", strings.Join(t.cfg.AllowedHosts, ", "))
+}
+
+func (t *HTTPTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"method": {
+				"type": "string",
+				"enum": ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"],
+				"description": "HTTP method (default GET)"
+			},
+			"url": {
+				"type": "string",
+				"description": "The http(s) URL to call"
+			},
+			"headers": {
+				"type": "object",
+				"additionalProperties": {"type": "string"},
+				"description": "Request headers, e.g. {\"Authorization\": \"Bearer ...\"}"
+			},
+			"body": {
+				"type": "string",
+				"description": "Request body. Sent as application/json if it is JSON and no Content-Type is given"
+			},
+			"max_chars": {
+				"type": "integer",
+				"description": "Maximum characters of the response body to return (default 20000)"
+			}
+		},
+		"required": ["url"]
+	}`)
+}
+
+type httpRequestParams struct {
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	MaxChars int               `json:"max_chars"`
+}
+
+func (t *HTTPTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
+	var params httpRequestParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	if params.URL == "" {
+		return &Result{Error: "url is required", IsError: true}, nil
+	}
+	method := strings.ToUpper(params.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if !slices.Contains(httpMethods, method) {
+		return &Result{Error: "unsupported method: " + params.Method, IsError: true}, nil
+	}
+	if params.MaxChars <= 0 {
+		params.MaxChars = defaultHTTPMaxChars
+	}
+
+	if err := t.policy.Check(ctx, params.URL); err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	var body io.Reader
+	if params.Body != "" {
+		body = strings.NewReader(params.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, params.URL, body)
+	if err != nil {
+		return &Result{Error: "failed to create request: " + err.Error(), IsError: true}, nil
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	for name, value := range params.Headers {
+		if slices.Contains(reservedHeaders, http.CanonicalHeaderKey(name)) {
+			return &Result{Error: "header can't be set: " + name, IsError: true}, nil
+		}
+		req.Header.Set(name, value)
+	}
+	if params.Body != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(params.Body)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &Result{Error: "request failed: " + err.Error(), IsError: true}, nil
+	}
+	defer resp.Body.Close()
+
+	limit := int64(t.cfg.MaxBodyKB) << 10
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return &Result{Error: "failed to read response: " + err.Error(), IsError: true}, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP %s\n", resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+
+	content := string(data)
+	switch {
+	case int64(len(data)) > limit:
+		content = string(data[:limit])
+		if len(content) > params.MaxChars {
+			content = content[:params.MaxChars]
+		}
+		content += fmt.Sprintf("\n... (response larger than %d KB, truncated)", t.cfg.MaxBodyKB)
+	case len(content) > params.MaxChars:
+		content = content[:params.MaxChars] + "\n... (content truncated)"
+	}
+	b.WriteString(content)
+
+	return &Result{Output: b.String()}, nil
+}
+
+// URLPolicy decides which URLs http_request, and HTTP skills, may call:
+// those that pass the same SSRF checks as fetch and whose host is on the
+// http_request allowlist.
+type URLPolicy struct {
+	allowedHosts []string
+	guard        urlGuard
+}
+
+// NewURLPolicy creates the policy set by cfg's allowed hosts and private
+// network setting.
+func NewURLPolicy(cfg config.HTTPRequestConfig) *URLPolicy {
+	return &URLPolicy{
+		allowedHosts: cfg.AllowedHosts,
+		guard:        newURLGuard(cfg.AllowPrivateNetwork),
+	}
+}
+
+// Check applies the SSRF checks and the host allowlist to rawURL.
+func (p *URLPolicy) Check(ctx context.Context, rawURL string) error {
+	u, err := p.guard.check(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	if !hostAllowed(p.allowedHosts, u.Hostname()) {
+		return fmt.Errorf("host %s is not in http_request.allowed_hosts", u.Hostname())
+	}
+	return nil
+}
+
+// hostAllowed reports whether host matches an allowlist entry: an exact
+// name, "*.domain" for its subdomains, or "*" for any host.
+func hostAllowed(allowed []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "*" || pattern == host:
+			return true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			return true
+		}
+	}
+	return false
+}
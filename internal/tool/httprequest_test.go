@@ -0,0 +1,109 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"open-dan/internal/config"
+)
+
+func TestHTTPRequestSendsMethodHeadersAndBody(t *testing.T) {
+	var method, auth, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, auth, contentType = r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("X-Request-Id", "42")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	ht := NewHTTPTool(config.HTTPRequestConfig{AllowedHosts: []string{u.Hostname()}, AllowPrivateNetwork: true})
+	res := execTool(t, context.Background(), ht, map[string]any{
+		"method":  "post",
+		"url":     srv.URL + "/items",
+		"headers": map[string]string{"Authorization": "Bearer t0ken"},
+		"body":    `{"name": "x"}`,
+	})
+	if res.IsError {
+		t.Fatal(res.Error)
+	}
+	if method != "POST" || auth != "Bearer t0ken" || contentType != "application/json" || body != `{"name": "x"}` {
+		t.Fatalf("unexpected request: %s auth=%q type=%q body=%q", method, auth, contentType, body)
+	}
+	if !strings.HasPrefix(res.Output, "HTTP 201 Created\n") || !strings.Contains(res.Output, "X-Request-Id: 42\n") || !strings.HasSuffix(res.Output, "\n\n"+`{"id": 7}`) {
+		t.Fatalf("unexpected output:\n%s", res.Output)
+	}
+}
+
+func TestHTTPRequestTruncatesLargeResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 3000)))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	ht := NewHTTPTool(config.HTTPRequestConfig{AllowedHosts: []string{u.Hostname()}, AllowPrivateNetwork: true, MaxBodyKB: 1})
+	res := execTool(t, context.Background(), ht, map[string]any{"url": srv.URL})
+	if !strings.Contains(res.Output, strings.Repeat("a", 1024)+"\n... (response larger than 1 KB, truncated)") {
+		t.Fatalf("unexpected output:\n%s", res.Output)
+	}
+}
+
+func TestHTTPRequestEnforcesAllowlistAndSSRF(t *testing.T) {
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:1/", http.StatusFound)
+	}))
+	defer redirect.Close()
+	u, _ := url.Parse(redirect.URL)
+
+	ht := NewHTTPTool(config.HTTPRequestConfig{AllowedHosts: []string{"api.example.com", "*.example.org"}})
+	for _, target := range []string{
+		"https://evil.example.net/",
+		"http://127.0.0.1/",
+		"file:///etc/passwd",
+	} {
+		if res := execTool(t, context.Background(), ht, map[string]any{"url": target}); !res.IsError {
+			t.Errorf("expected %s to be rejected", target)
+		}
+	}
+	if res := execTool(t, context.Background(), ht, map[string]any{"url": "https://api.example.com/", "method": "TRACE"}); !res.IsError {
+		t.Error("expected TRACE to be rejected")
+	}
+	if res := execTool(t, context.Background(), ht, map[string]any{"url": "https://api.example.com/", "headers": map[string]string{"host": "internal"}}); !res.IsError {
+		t.Error("expected the Host header to be rejected")
+	}
+
+	// A redirect off the allowlist is not followed.
+	ht = NewHTTPTool(config.HTTPRequestConfig{AllowedHosts: []string{u.Hostname()}, AllowPrivateNetwork: true})
+	if res := execTool(t, context.Background(), ht, map[string]any{"url": redirect.URL}); !res.IsError || !strings.Contains(res.Error, "allowed_hosts") {
+		t.Fatalf("redirect followed: %+v", res)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"api.github.com", "*.example.org"}
+	for host, want := range map[string]bool{
+		"api.github.com":      true,
+		"API.GitHub.com":      true,
+		"github.com":          false,
+		"a.b.example.org":     true,
+		"example.org":         false,
+		"notexample.org":      false,
+		"api.github.com.evil": false,
+	} {
+		if got := hostAllowed(allowed, host); got != want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+	if !hostAllowed([]string{"*"}, "anything.test") {
+		t.Error("* should allow any host")
+	}
+}
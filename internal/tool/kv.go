@@ -0,0 +1,57 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"open-dan/internal/memory"
+)
+
+const (
+	maxKVValueSize = 64 << 10
+	maxKVKeys      = 100
+)
+
+// KVTool gives the agent a small scratch key-value store scoped to the
+// current chat, for intermediate results it needs across tool calls.
+type KVTool struct {
+	*scratchTool
+}
+
+func NewKVTool(store ScratchStore) *KVTool {
+	return &KVTool{&scratchTool{
+		store:      store,
+		table:      memory.ScratchKV,
+		noun:       "key",
+		maxEntries: maxKVKeys,
+		maxValue:   maxKVValueSize,
+		listEntry:  func(e memory.ScratchEntry) string { return e.Key },
+	}}
+}
+
+func (t *KVTool) Name() string { return "kv" }
+func (t *KVTool) Description() string {
+	return fmt.Sprintf("Scratch key-value store for this conversation. Use 'set' to save a value, 'get' to read it back, 'delete' to remove it, and 'list' to see stored keys. Values are limited to %d KB and %d keys per conversation.", maxKVValueSize>>10, maxKVKeys)
+}
+
+func (t *KVTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["set", "get", "delete", "list"],
+				"description": "The operation to perform"
+			},
+			"key": {
+				"type": "string",
+				"description": "Key to operate on (not used by 'list')"
+			},
+			"value": {
+				"type": "string",
+				"description": "Value to store (only for 'set')"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"open-dan/internal/memory"
+)
+
+func TestKVRoundTrip(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	kv := NewKVTool(memory.NewInMemory())
+
+	if res := execTool(t, c1, kv, map[string]any{"action": "set", "key": "plan", "value": "step 1"}); res.IsError {
+		t.Fatalf("set: %s", res.Error)
+	}
+	if res := execTool(t, c1, kv, map[string]any{"action": "get", "key": "plan"}); res.Output != "step 1" {
+		t.Fatalf("get = %+v", res)
+	}
+	if res := execTool(t, c1, kv, map[string]any{"action": "list"}); res.Output != "plan" {
+		t.Fatalf("list = %q", res.Output)
+	}
+	if res := execTool(t, c1, kv, map[string]any{"action": "delete", "key": "plan"}); res.IsError {
+		t.Fatalf("delete: %s", res.Error)
+	}
+	if res := execTool(t, c1, kv, map[string]any{"action": "get", "key": "plan"}); !res.IsError {
+		t.Fatalf("expected missing key after delete, got %+v", res)
+	}
+}
+
+func TestKVIsolatedPerChat(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	c2 := WithChatID(context.Background(), "c2")
+	kv := NewKVTool(memory.NewInMemory())
+	execTool(t, c1, kv, map[string]any{"action": "set", "key": "k", "value": "one"})
+	execTool(t, c2, kv, map[string]any{"action": "set", "key": "k", "value": "two"})
+
+	if res := execTool(t, c1, kv, map[string]any{"action": "get", "key": "k"}); res.Output != "one" {
+		t.Errorf("c1 got %q", res.Output)
+	}
+	if res := execTool(t, c2, kv, map[string]any{"action": "get", "key": "k"}); res.Output != "two" {
+		t.Errorf("c2 got %q", res.Output)
+	}
+	execTool(t, c2, kv, map[string]any{"action": "delete", "key": "k"})
+	if res := execTool(t, c1, kv, map[string]any{"action": "get", "key": "k"}); res.Output != "one" {
+		t.Errorf("delete in c2 affected c1: %+v", res)
+	}
+}
+
+func TestKVLimits(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	kv := NewKVTool(memory.NewInMemory())
+
+	res := execTool(t, c1, kv, map[string]any{"action": "set", "key": "big", "value": strings.Repeat("x", maxKVValueSize+1)})
+	if !res.IsError || !strings.Contains(res.Error, "too large") {
+		t.Errorf("expected size error, got %+v", res)
+	}
+	res = execTool(t, c1, kv, map[string]any{"action": "set", "key": strings.Repeat("k", maxScratchKeyLen+1), "value": "v"})
+	if !res.IsError {
+		t.Error("expected long key to be rejected")
+	}
+
+	for i := range maxKVKeys {
+		if res := execTool(t, c1, kv, map[string]any{"action": "set", "key": "k" + strings.Repeat("0", i), "value": "v"}); res.IsError {
+			t.Fatalf("set %d: %s", i, res.Error)
+		}
+	}
+	res = execTool(t, c1, kv, map[string]any{"action": "set", "key": "extra", "value": "v"})
+	if !res.IsError || !strings.Contains(res.Error, "too many keys") {
+		t.Errorf("expected key-count error, got %+v", res)
+	}
+	// Overwriting an existing key is still allowed at the limit.
+	if res := execTool(t, c1, kv, map[string]any{"action": "set", "key": "k0", "value": "new"}); res.IsError {
+		t.Errorf("overwrite at limit failed: %s", res.Error)
+	}
+}
@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxMetricsErrorLen caps the last error kept per tool.
+const maxMetricsErrorLen = 500
+
+// ToolStats summarizes the calls of one tool since the agent started.
+type ToolStats struct {
+	Name        string    `json:"name"`
+	Calls       int       `json:"calls"`
+	Errors      int       `json:"errors"`
+	TotalMs     int64     `json:"total_ms"`
+	AvgMs       int64     `json:"avg_ms"`
+	MaxMs       int64     `json:"max_ms"`
+	LastMs      int64     `json:"last_ms"`
+	LastCallAt  time.Time `json:"last_call_at"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at"`
+}
+
+// Metrics counts tool calls, errors, and execution time per tool. It is
+// safe for concurrent use.
+type Metrics struct {
+	mu    sync.Mutex
+	stats map[string]*ToolStats
+	now   func() time.Time // overridable in tests
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{stats: make(map[string]*ToolStats), now: time.Now}
+}
+
+// Record adds a call of the named tool that took d. A non-empty errText
+// counts the call as an error and keeps errText as the tool's last error.
+func (m *Metrics) Record(name string, d time.Duration, errText string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.stats[name]
+	if !ok {
+		s = &ToolStats{Name: name}
+		m.stats[name] = s
+	}
+	ms := d.Milliseconds()
+	s.Calls++
+	s.TotalMs += ms
+	s.AvgMs = s.TotalMs / int64(s.Calls)
+	s.MaxMs = max(s.MaxMs, ms)
+	s.LastMs = ms
+	s.LastCallAt = m.now()
+	if errText != "" {
+		if r := []rune(errText); len(r) > maxMetricsErrorLen {
+			errText = string(r[:maxMetricsErrorLen]) + "..."
+		}
+		s.Errors++
+		s.LastError = errText
+		s.LastErrorAt = s.LastCallAt
+	}
+}
+
+// Snapshot returns a copy of the stats of every tool called so far, sorted
+// by name.
+func (m *Metrics) Snapshot() []ToolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ToolStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
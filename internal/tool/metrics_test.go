@@ -0,0 +1,46 @@
+package tool
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsRecordsCallsAndErrors(t *testing.T) {
+	m := NewMetrics()
+	now := time.Date(2026, 3, 8, 9, 30, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	m.Record("shell", 100*time.Millisecond, "")
+	m.Record("shell", 300*time.Millisecond, "exit status 1")
+	now = now.Add(time.Minute)
+	m.Record("shell", 200*time.Millisecond, "")
+	m.Record("calc", time.Millisecond, strings.Repeat("x", 2*maxMetricsErrorLen))
+
+	stats := m.Snapshot()
+	if len(stats) != 2 || stats[0].Name != "calc" || stats[1].Name != "shell" {
+		t.Fatalf("Snapshot = %+v, want calc then shell", stats)
+	}
+	shell := stats[1]
+	if shell.Calls != 3 || shell.Errors != 1 {
+		t.Errorf("calls/errors = %d/%d, want 3/1", shell.Calls, shell.Errors)
+	}
+	if shell.TotalMs != 600 || shell.AvgMs != 200 || shell.MaxMs != 300 || shell.LastMs != 200 {
+		t.Errorf("durations = %+v", shell)
+	}
+	if shell.LastError != "exit status 1" || !shell.LastErrorAt.Equal(now.Add(-time.Minute)) {
+		t.Errorf("last error = %q at %v", shell.LastError, shell.LastErrorAt)
+	}
+	if !shell.LastCallAt.Equal(now) {
+		t.Errorf("LastCallAt = %v, want %v", shell.LastCallAt, now)
+	}
+	if n := len([]rune(stats[0].LastError)); n != maxMetricsErrorLen+3 {
+		t.Errorf("long error kept %d runes, want it truncated", n)
+	}
+
+	// Snapshots are copies.
+	stats[1].Calls = 99
+	if m.Snapshot()[1].Calls != 3 {
+		t.Error("Snapshot shares state with Metrics")
+	}
+}
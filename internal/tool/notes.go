@@ -0,0 +1,75 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"open-dan/internal/memory"
+)
+
+const (
+	maxNotes       = 100
+	defaultNotesKB = 64
+	notePreviewLen = 80
+)
+
+// NotesTool gives the agent a scratchpad scoped to the current chat for
+// conclusions it must not lose on long tasks. Notes live in the memory
+// database, so they survive summarization, /reset, and restarts.
+type NotesTool struct {
+	*scratchTool
+}
+
+// NewNotesTool creates a notes tool whose notes take up at most maxKB per
+// chat; 0 uses the default of 64 KB.
+func NewNotesTool(store ScratchStore, maxKB int) *NotesTool {
+	if maxKB <= 0 {
+		maxKB = defaultNotesKB
+	}
+	return &NotesTool{&scratchTool{
+		store:      store,
+		table:      memory.ScratchNotes,
+		noun:       "note",
+		appendable: true,
+		maxEntries: maxNotes,
+		maxTotal:   maxKB << 10,
+		listEntry:  notePreview,
+	}}
+}
+
+func (t *NotesTool) Name() string { return "notes" }
+func (t *NotesTool) Description() string {
+	return fmt.Sprintf("Durable notes for this conversation that survive summarization and restarts. Record decisions, findings, and progress on multi-step tasks here. Use 'set' to write a note, 'append' to add a line to one, 'get' to read it, 'list' to see all notes, and 'delete' to remove one. Notes are limited to %d KB in total and %d per conversation.", t.maxTotal>>10, maxNotes)
+}
+
+func (t *NotesTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["set", "get", "append", "list", "delete"],
+				"description": "The operation to perform"
+			},
+			"key": {
+				"type": "string",
+				"description": "Name of the note (not used by 'list')"
+			},
+			"value": {
+				"type": "string",
+				"description": "Text to store ('set') or add as a new line ('append')"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+// notePreview shows a note's key, size, and first line.
+func notePreview(n memory.ScratchEntry) string {
+	preview, _, _ := strings.Cut(n.Value, "\n")
+	if r := []rune(preview); len(r) > notePreviewLen {
+		preview = string(r[:notePreviewLen]) + "…"
+	}
+	return fmt.Sprintf("%s (%d bytes): %s", n.Key, len(n.Value), preview)
+}
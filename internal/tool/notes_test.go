@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"open-dan/internal/memory"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	notes := NewNotesTool(memory.NewInMemory(), 0)
+
+	if res := execTool(t, c1, notes, map[string]any{"action": "set", "key": "plan", "value": "1. read the logs"}); res.IsError {
+		t.Fatalf("set: %s", res.Error)
+	}
+	if res := execTool(t, c1, notes, map[string]any{"action": "append", "key": "plan", "value": "2. fix the bug"}); res.IsError {
+		t.Fatalf("append: %s", res.Error)
+	}
+	if res := execTool(t, c1, notes, map[string]any{"action": "get", "key": "plan"}); res.Output != "1. read the logs\n2. fix the bug" {
+		t.Fatalf("get = %+v", res)
+	}
+	if res := execTool(t, c1, notes, map[string]any{"action": "append", "key": "found", "value": "nil map in parser"}); res.IsError {
+		t.Fatalf("append to a new note: %s", res.Error)
+	}
+	want := "found (17 bytes): nil map in parser\nplan (31 bytes): 1. read the logs"
+	if res := execTool(t, c1, notes, map[string]any{"action": "list"}); res.Output != want {
+		t.Fatalf("list = %q", res.Output)
+	}
+	execTool(t, c1, notes, map[string]any{"action": "delete", "key": "plan"})
+	if res := execTool(t, c1, notes, map[string]any{"action": "get", "key": "plan"}); !res.IsError {
+		t.Fatalf("expected missing note after delete, got %+v", res)
+	}
+}
+
+func TestNotesIsolatedPerChat(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	c2 := WithChatID(context.Background(), "c2")
+	notes := NewNotesTool(memory.NewInMemory(), 0)
+	execTool(t, c1, notes, map[string]any{"action": "set", "key": "k", "value": "one"})
+	execTool(t, c2, notes, map[string]any{"action": "append", "key": "k", "value": "two"})
+
+	if res := execTool(t, c1, notes, map[string]any{"action": "get", "key": "k"}); res.Output != "one" {
+		t.Errorf("c1 got %q", res.Output)
+	}
+	if res := execTool(t, c2, notes, map[string]any{"action": "list"}); res.Output != "k (3 bytes): two" {
+		t.Errorf("c2 sees %q", res.Output)
+	}
+}
+
+func TestNotesSizeCap(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	c2 := WithChatID(context.Background(), "c2")
+	notes := NewNotesTool(memory.NewInMemory(), 1)
+	half := strings.Repeat("x", 512)
+
+	execTool(t, c1, notes, map[string]any{"action": "set", "key": "a", "value": half})
+	if res := execTool(t, c1, notes, map[string]any{"action": "set", "key": "b", "value": half}); res.IsError {
+		t.Fatalf("filling to the cap failed: %s", res.Error)
+	}
+	res := execTool(t, c1, notes, map[string]any{"action": "append", "key": "b", "value": "more"})
+	if !res.IsError || !strings.Contains(res.Error, "max 1024") {
+		t.Fatalf("expected size error, got %+v", res)
+	}
+	// Replacing a note only counts its new size, and other chats have their own cap.
+	if res := execTool(t, c1, notes, map[string]any{"action": "set", "key": "b", "value": "short"}); res.IsError {
+		t.Fatalf("shrinking a note failed: %s", res.Error)
+	}
+	if res := execTool(t, c2, notes, map[string]any{"action": "set", "key": "a", "value": half + half}); res.IsError {
+		t.Fatalf("c2 affected by c1's notes: %s", res.Error)
+	}
+}
@@ -0,0 +1,146 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"open-dan/internal/llm"
+	"open-dan/internal/memory"
+)
+
+const (
+	defaultRecallLimit = 10
+	maxRecallLimit     = 50
+	// maxRecalledMessage caps each message shown by 'read', so a page of
+	// old tool output doesn't flood the context.
+	maxRecalledMessage = 2000
+)
+
+// RecallStore is the message storage behind MemorySearchTool.
+// memory.Memory satisfies it.
+type RecallStore interface {
+	SearchChat(ctx context.Context, chatID, query string, limit int) ([]memory.SearchResult, error)
+	SearchSimilar(ctx context.Context, chatID, query string, limit int) ([]memory.SearchResult, error)
+	GetHistoryPage(ctx context.Context, chatID string, limit, offset int) ([]llm.Message, error)
+}
+
+// MemorySearchTool lets the agent look up older turns of the current chat
+// that have scrolled out of its context or been summarized. It can only see
+// the chat it is called from.
+type MemorySearchTool struct {
+	store RecallStore
+}
+
+func NewMemorySearchTool(store RecallStore) *MemorySearchTool {
+	return &MemorySearchTool{store: store}
+}
+
+func (t *MemorySearchTool) Name() string { return "recall" }
+func (t *MemorySearchTool) Description() string {
+	return "Look up earlier messages of this conversation that are no longer in context, such as what was decided or said last week. Use 'search' with a query to find related messages, or 'read' to page back through the history: offset counts messages back from the newest."
+}
+
+func (t *MemorySearchTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["search", "read"],
+				"description": "The operation to perform"
+			},
+			"query": {
+				"type": "string",
+				"description": "What to look for, in words or as a question (only for 'search')"
+			},
+			"offset": {
+				"type": "integer",
+				"description": "How many of the newest messages to skip (only for 'read')"
+			},
+			"limit": {
+				"type": "integer",
+				"description": "Maximum number of messages to return (default 10, max 50)"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+func (t *MemorySearchTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
+	var params struct {
+		Action string `json:"action"`
+		Query  string `json:"query"`
+		Offset int    `json:"offset"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	chatID := ChatIDFromContext(ctx)
+	if chatID == "" {
+		return &Result{Error: "recall is only available within a conversation", IsError: true}, nil
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultRecallLimit
+	}
+	limit = min(limit, maxRecallLimit)
+
+	switch params.Action {
+	case "search":
+		if strings.TrimSpace(params.Query) == "" {
+			return &Result{Error: "query is required for search", IsError: true}, nil
+		}
+		results, err := t.search(ctx, chatID, params.Query, limit)
+		if err != nil {
+			return &Result{Error: "search failed: " + err.Error(), IsError: true}, nil
+		}
+		if len(results) == 0 {
+			return &Result{Output: "No earlier messages match " + params.Query + "."}, nil
+		}
+		var b strings.Builder
+		for _, r := range results {
+			fmt.Fprintf(&b, "[%s] %s\n", r.Role, r.Snippet)
+		}
+		return &Result{Output: strings.TrimSuffix(b.String(), "\n")}, nil
+	case "read":
+		if params.Offset < 0 {
+			return &Result{Error: "offset must not be negative", IsError: true}, nil
+		}
+		messages, err := t.store.GetHistoryPage(ctx, chatID, limit, params.Offset)
+		if err != nil {
+			return &Result{Error: "failed to read history: " + err.Error(), IsError: true}, nil
+		}
+		if len(messages) == 0 {
+			return &Result{Output: "No messages that far back."}, nil
+		}
+		var b strings.Builder
+		for _, m := range messages {
+			content := m.Content
+			if r := []rune(content); len(r) > maxRecalledMessage {
+				content = string(r[:maxRecalledMessage]) + "... (truncated)"
+			}
+			fmt.Fprintf(&b, "[%s] %s\n", m.Role, content)
+		}
+		return &Result{Output: strings.TrimSuffix(b.String(), "\n")}, nil
+	default:
+		return &Result{Error: "unknown action: " + params.Action, IsError: true}, nil
+	}
+}
+
+// search finds messages by meaning when semantic recall is on, and by
+// keyword when it is off, fails, or has nothing embedded yet.
+func (t *MemorySearchTool) search(ctx context.Context, chatID, query string, limit int) ([]memory.SearchResult, error) {
+	results, err := t.store.SearchSimilar(ctx, chatID, query, limit)
+	if err != nil && !errors.Is(err, memory.ErrNoEmbedder) {
+		log.Printf("[recall] semantic search failed, searching by keyword: %v", err)
+	}
+	if err == nil && len(results) > 0 {
+		return results, nil
+	}
+	return t.store.SearchChat(ctx, chatID, query, limit)
+}
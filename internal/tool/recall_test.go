@@ -0,0 +1,78 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"open-dan/internal/llm"
+	"open-dan/internal/memory"
+)
+
+func TestRecallSearchesOnlyTheCurrentChat(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	c3 := WithChatID(context.Background(), "c3")
+	noChat := WithChatID(context.Background(), "")
+	mem := memory.NewInMemory()
+	ctx := context.Background()
+	mem.SaveMessage(ctx, "c1", llm.Message{Role: "user", Content: "Let's ship the release on Friday."})
+	mem.SaveMessage(ctx, "c1", llm.Message{Role: "assistant", Content: "Agreed, Friday it is."})
+	mem.SaveMessage(ctx, "c2", llm.Message{Role: "user", Content: "My bank PIN for Friday is 1234."})
+	recall := NewMemorySearchTool(mem)
+
+	res := execTool(t, c1, recall, map[string]any{"action": "search", "query": "friday"})
+	if res.IsError || strings.Count(res.Output, "\n") != 1 || strings.Contains(res.Output, "PIN") {
+		t.Fatalf("search = %+v", res)
+	}
+	if res := execTool(t, c3, recall, map[string]any{"action": "search", "query": "friday"}); strings.Contains(res.Output, "Friday") {
+		t.Fatalf("another chat's messages leaked: %q", res.Output)
+	}
+	if res := execTool(t, noChat, recall, map[string]any{"action": "search", "query": "friday"}); !res.IsError {
+		t.Fatalf("expected an error outside a conversation, got %+v", res)
+	}
+}
+
+func TestRecallReadsOlderPages(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	mem := memory.NewInMemory()
+	ctx := context.Background()
+	for _, text := range []string{"one", "two", "three", "four"} {
+		mem.SaveMessage(ctx, "c1", llm.Message{Role: "user", Content: text})
+	}
+	recall := NewMemorySearchTool(mem)
+
+	res := execTool(t, c1, recall, map[string]any{"action": "read", "offset": 2, "limit": 2})
+	if res.Output != "[user] one\n[user] two" {
+		t.Fatalf("read = %+v", res)
+	}
+	if res := execTool(t, c1, recall, map[string]any{"action": "read", "offset": 10}); res.Output != "No messages that far back." {
+		t.Fatalf("read past the start = %+v", res)
+	}
+}
+
+// similarStore answers SearchSimilar with fixed results.
+type similarStore struct {
+	*memory.InMemory
+	results []memory.SearchResult
+}
+
+func (s similarStore) SearchSimilar(context.Context, string, string, int) ([]memory.SearchResult, error) {
+	return s.results, nil
+}
+
+func TestRecallPrefersSemanticSearch(t *testing.T) {
+	c1 := WithChatID(context.Background(), "c1")
+	mem := memory.NewInMemory()
+	mem.SaveMessage(context.Background(), "c1", llm.Message{Role: "user", Content: "We picked PostgreSQL."})
+
+	recall := NewMemorySearchTool(similarStore{mem, []memory.SearchResult{{ChatID: "c1", Role: "user", Snippet: "We picked PostgreSQL.", Rank: 0.8}}})
+	if res := execTool(t, c1, recall, map[string]any{"action": "search", "query": "which database?"}); res.Output != "[user] We picked PostgreSQL." {
+		t.Fatalf("semantic search = %+v", res)
+	}
+
+	// Nothing embedded: keyword search still finds it.
+	recall = NewMemorySearchTool(similarStore{InMemory: mem})
+	if res := execTool(t, c1, recall, map[string]any{"action": "search", "query": "postgresql"}); res.Output != "[user] We picked PostgreSQL." {
+		t.Fatalf("keyword fallback = %+v", res)
+	}
+}
@@ -0,0 +1,252 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+const (
+	defaultScrapePages = 5
+	maxScrapePages     = 20
+	defaultScrapeItems = 200
+	maxScrapeItems     = 1000
+)
+
+// ScrapeTool collects structured items from paginated listings, following
+// a next-page link. It drives the browser tool's browser, so the same URL
+// checks, domain lists, timeouts, and tab limit apply.
+type ScrapeTool struct {
+	browser *BrowserTool
+}
+
+func NewScrapeTool(browser *BrowserTool) *ScrapeTool {
+	return &ScrapeTool{browser: browser}
+}
+
+func (t *ScrapeTool) Name() string { return "scrape" }
+func (t *ScrapeTool) Description() string {
+	return "Extract structured items from a web page and the pages after it. Give the URL, a CSS selector matching each item, selectors for the fields to pull from each item (append @attr to read an attribute, e.g. 'a@href'), and a selector for the next-page link. Returns the items from all pages as JSON."
+}
+
+func (t *ScrapeTool) Parameters() json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"url": {
+				"type": "string",
+				"description": "URL of the first page"
+			},
+			"item_selector": {
+				"type": "string",
+				"description": "CSS selector matching each item on a page"
+			},
+			"fields": {
+				"type": "object",
+				"additionalProperties": {"type": "string"},
+				"description": "Field name to CSS selector within the item. Append @attr to read an attribute; an empty selector means the item itself. Without fields, each item's text is returned."
+			},
+			"next_selector": {
+				"type": "string",
+				"description": "CSS selector of the next-page link; omit to scrape only the first page"
+			},
+			"max_pages": {
+				"type": "integer",
+				"description": "Maximum pages to visit (default %d, at most %d)"
+			},
+			"max_items": {
+				"type": "integer",
+				"description": "Stop after this many items (default %d, at most %d)"
+			}
+		},
+		"required": ["url", "item_selector"]
+	}`, defaultScrapePages, maxScrapePages, defaultScrapeItems, maxScrapeItems))
+}
+
+type scrapeParams struct {
+	URL          string            `json:"url"`
+	ItemSelector string            `json:"item_selector"`
+	Fields       map[string]string `json:"fields,omitempty"`
+	NextSelector string            `json:"next_selector,omitempty"`
+	MaxPages     int               `json:"max_pages,omitempty"`
+	MaxItems     int               `json:"max_items,omitempty"`
+}
+
+// scrapeResult is returned to the model as JSON.
+type scrapeResult struct {
+	Items []map[string]any `json:"items"`
+	Pages int              `json:"pages"`
+	// Next is the page that would have been visited next when a limit
+	// stopped the scrape, so the model can continue from there.
+	Next    string `json:"next,omitempty"`
+	Stopped string `json:"stopped,omitempty"` // why scraping ended early, if it did
+}
+
+func (t *ScrapeTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
+	var params scrapeParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	if params.URL == "" || params.ItemSelector == "" {
+		return &Result{Error: "url and item_selector are required", IsError: true}, nil
+	}
+	maxPages := clampLimit(params.MaxPages, defaultScrapePages, maxScrapePages)
+	maxItems := clampLimit(params.MaxItems, defaultScrapeItems, maxScrapeItems)
+
+	b := t.browser
+	// Each page gets the browser's per-action timeout.
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(b.cfg.TimeoutSecs*maxPages)*time.Second)
+	defer cancel()
+
+	script, err := scrapeScript(params)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	// Hold one tab for the whole scrape so it counts against the tab limit.
+	b.mu.Lock()
+	if err := b.makeRoom(); err != nil {
+		b.mu.Unlock()
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+	b.nextID++
+	slot := fmt.Sprintf("scrape_%d", b.nextID)
+	b.pages[slot] = nil
+	b.mu.Unlock()
+
+	var page *rod.Page
+	defer func() {
+		b.mu.Lock()
+		delete(b.pages, slot)
+		b.mu.Unlock()
+		if page != nil {
+			page.Close()
+		}
+	}()
+
+	res := scrapeResult{Items: []map[string]any{}}
+	visited := make(map[string]bool)
+	next := params.URL
+	for next != "" {
+		switch {
+		case res.Pages >= maxPages:
+			res.Stopped = "page limit reached"
+		case len(res.Items) >= maxItems:
+			res.Stopped = "item limit reached"
+		case ctx.Err() != nil:
+			res.Stopped = "time limit reached"
+		case visited[next]:
+			res.Stopped = "next page was already visited"
+			next = ""
+		}
+		if res.Stopped != "" {
+			break
+		}
+		if err := b.validateURL(ctx, next); err != nil {
+			if res.Pages == 0 {
+				return &Result{Error: err.Error(), IsError: true}, nil
+			}
+			res.Stopped = "next page not allowed: " + err.Error()
+			break
+		}
+		visited[next] = true
+
+		if page != nil {
+			page.Close()
+		}
+		b.mu.Lock()
+		page, err = b.open(next)
+		b.mu.Unlock()
+		if err != nil {
+			if res.Pages == 0 {
+				return &Result{Error: err.Error(), IsError: true}, nil
+			}
+			res.Stopped = "failed to load next page: " + err.Error()
+			break
+		}
+
+		obj, err := b.eval(page, script)
+		if err != nil {
+			return &Result{Error: fmt.Sprintf("extraction failed on %s: %v", next, err), IsError: true}, nil
+		}
+		var found struct {
+			Items []map[string]any `json:"items"`
+			Next  string           `json:"next"`
+		}
+		raw, _ := json.Marshal(obj.Value)
+		if err := json.Unmarshal(raw, &found); err != nil {
+			return &Result{Error: "unexpected extraction result: " + err.Error(), IsError: true}, nil
+		}
+
+		res.Pages++
+		room := maxItems - len(res.Items)
+		res.Items = append(res.Items, found.Items[:min(room, len(found.Items))]...)
+		next = found.Next
+		if params.NextSelector == "" {
+			next = ""
+		}
+	}
+	if res.Stopped != "" {
+		res.Next = next
+	}
+
+	out, _ := json.MarshalIndent(res, "", "  ")
+	if maxChars := b.cfg.MaxPageSizeKB * 1024; len(out) > maxChars {
+		return &Result{Output: string(out[:maxChars]) + "\n... (output truncated; lower max_items or select fewer fields)"}, nil
+	}
+	return &Result{Output: string(out)}, nil
+}
+
+// clampLimit returns n, or def when n is unset, capped at most.
+func clampLimit(n, def, most int) int {
+	if n <= 0 {
+		return def
+	}
+	return min(n, most)
+}
+
+// scrapeScript builds the page function that extracts items and the
+// next-page URL. Selectors are passed as JSON, never spliced into code.
+func scrapeScript(params scrapeParams) (string, error) {
+	spec, err := json.Marshal(map[string]any{
+		"item":   params.ItemSelector,
+		"fields": params.Fields,
+		"next":   params.NextSelector,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(scrapeScriptTemplate, spec), nil
+}
+
+const scrapeScriptTemplate = `() => {
+	const spec = %s;
+	const abs = (v) => { try { return new URL(v, document.baseURI).href; } catch (e) { return v; } };
+	const pick = (root, sel) => {
+		const at = sel.lastIndexOf('@');
+		const css = at >= 0 ? sel.slice(0, at) : sel;
+		const attr = at >= 0 ? sel.slice(at + 1) : '';
+		const el = css ? root.querySelector(css) : root;
+		if (!el) return null;
+		if (!attr) return el.innerText.trim();
+		const v = el.getAttribute(attr);
+		return v !== null && (attr === 'href' || attr === 'src') ? abs(v) : v;
+	};
+	const items = Array.from(document.querySelectorAll(spec.item)).map(el => {
+		const fields = spec.fields || {};
+		if (Object.keys(fields).length === 0) return { text: el.innerText.trim() };
+		const out = {};
+		for (const [name, sel] of Object.entries(fields)) out[name] = pick(el, sel);
+		return out;
+	});
+	let next = '';
+	if (spec.next) {
+		const link = document.querySelector(spec.next);
+		const href = link && link.getAttribute('href');
+		if (href && !href.startsWith('javascript:')) next = abs(href);
+	}
+	return { items: items, next: next };
+}`
@@ -0,0 +1,129 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
+
+	"open-dan/internal/config"
+)
+
+// stubListing serves a fake paginated listing: page n has two items and
+// links to page n+1.
+func stubListing(bt *BrowserTool) *[]string {
+	var opened []string
+	current := ""
+	bt.guard.resolver = publicResolver
+	bt.open = func(url string) (*rod.Page, error) {
+		opened = append(opened, url)
+		current = url
+		return nil, nil
+	}
+	bt.eval = func(_ *rod.Page, _ string) (*proto.RuntimeRemoteObject, error) {
+		var n int
+		fmt.Sscanf(current, "https://shop.example.com/list?page=%d", &n)
+		return &proto.RuntimeRemoteObject{Value: gson.New(map[string]any{
+			"items": []map[string]any{
+				{"title": fmt.Sprintf("item %d-a", n)},
+				{"title": fmt.Sprintf("item %d-b", n)},
+			},
+			"next": fmt.Sprintf("https://shop.example.com/list?page=%d", n+1),
+		})}, nil
+	}
+	return &opened
+}
+
+func runScrape(t *testing.T, st *ScrapeTool, params scrapeParams) (*Result, scrapeResult) {
+	t.Helper()
+	res := execTool(t, context.Background(), st, params)
+	var out scrapeResult
+	if !res.IsError {
+		if err := json.Unmarshal([]byte(res.Output), &out); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, res.Output)
+		}
+	}
+	return res, out
+}
+
+func TestScrapeFollowsNextPageUpToLimit(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5, MaxTabs: 2})
+	opened := stubListing(bt)
+	st := NewScrapeTool(bt)
+
+	res, out := runScrape(t, st, scrapeParams{
+		URL:          "https://shop.example.com/list?page=1",
+		ItemSelector: ".product",
+		Fields:       map[string]string{"title": "h2"},
+		NextSelector: "a.next",
+		MaxPages:     3,
+	})
+	if res.IsError {
+		t.Fatal(res.Error)
+	}
+	if len(*opened) != 3 || out.Pages != 3 {
+		t.Fatalf("visited %v (%d pages), want 3", *opened, out.Pages)
+	}
+	var titles []string
+	for _, item := range out.Items {
+		titles = append(titles, item["title"].(string))
+	}
+	if got := strings.Join(titles, ","); got != "item 1-a,item 1-b,item 2-a,item 2-b,item 3-a,item 3-b" {
+		t.Fatalf("aggregated items = %s", got)
+	}
+	if out.Next != "https://shop.example.com/list?page=4" || out.Stopped != "page limit reached" {
+		t.Fatalf("expected continuation at page 4, got next=%q stopped=%q", out.Next, out.Stopped)
+	}
+	if len(bt.pages) != 0 {
+		t.Fatalf("scrape left tabs reserved: %v", bt.pages)
+	}
+}
+
+func TestScrapeItemLimit(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5, MaxTabs: 2})
+	opened := stubListing(bt)
+
+	_, out := runScrape(t, NewScrapeTool(bt), scrapeParams{
+		URL:          "https://shop.example.com/list?page=1",
+		ItemSelector: ".product",
+		NextSelector: "a.next",
+		MaxItems:     3,
+	})
+	if len(out.Items) != 3 || len(*opened) != 2 || out.Stopped != "item limit reached" {
+		t.Fatalf("got %d items from %d pages (%s)", len(out.Items), len(*opened), out.Stopped)
+	}
+}
+
+func TestScrapeRespectsDomainRulesAndTabs(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{TimeoutSecs: 5, MaxTabs: 1, AllowedDomains: []string{"shop.example.com"}})
+	opened := stubListing(bt)
+	st := NewScrapeTool(bt)
+
+	for _, url := range []string{"http://127.0.0.1/list", "https://other.example.org/list"} {
+		if res, _ := runScrape(t, st, scrapeParams{URL: url, ItemSelector: "li"}); !res.IsError {
+			t.Errorf("expected %s to be rejected", url)
+		}
+	}
+
+	// Links leading off the allowed domains stop the scrape without following them.
+	bt.eval = func(_ *rod.Page, _ string) (*proto.RuntimeRemoteObject, error) {
+		return &proto.RuntimeRemoteObject{Value: gson.New(map[string]any{
+			"items": []map[string]any{{"text": "one"}},
+			"next":  "http://169.254.169.254/latest/meta-data",
+		})}, nil
+	}
+	_, out := runScrape(t, st, scrapeParams{URL: "https://shop.example.com/list?page=1", ItemSelector: "li", NextSelector: "a.next"})
+	if out.Pages != 1 || len(*opened) != 1 || !strings.HasPrefix(out.Stopped, "next page not allowed") {
+		t.Fatalf("followed a disallowed link: %+v, opened %v", out, *opened)
+	}
+
+	bt.pages["page_1"] = nil
+	if res, _ := runScrape(t, st, scrapeParams{URL: "https://shop.example.com/list?page=1", ItemSelector: "li"}); !res.IsError || !strings.Contains(res.Error, "max tabs") {
+		t.Fatalf("expected max tabs error, got %+v", res)
+	}
+}
@@ -0,0 +1,141 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"open-dan/internal/memory"
+)
+
+const maxScratchKeyLen = 128
+
+// ScratchStore is the per-chat storage behind KVTool and NotesTool.
+// memory.Memory satisfies it.
+type ScratchStore interface {
+	ScratchSet(ctx context.Context, table memory.Scratch, chatID, key, value string) error
+	ScratchGet(ctx context.Context, table memory.Scratch, chatID, key string) (value string, ok bool, err error)
+	ScratchDelete(ctx context.Context, table memory.Scratch, chatID, key string) error
+	ScratchList(ctx context.Context, table memory.Scratch, chatID string) ([]memory.ScratchEntry, error)
+}
+
+// scratchTool implements the actions KVTool and NotesTool share over one
+// memory.Scratch table: set, get, delete, list, and optionally append,
+// all scoped to the current chat and capped in count and size.
+type scratchTool struct {
+	mu         sync.Mutex // serializes read-modify-write updates
+	store      ScratchStore
+	table      memory.Scratch
+	noun       string // "key" or "note", for messages
+	appendable bool
+	maxEntries int
+	maxValue   int // bytes per entry; 0 for no limit
+	maxTotal   int // bytes across a chat's entries; 0 for no limit
+	// listEntry renders one entry for 'list'.
+	listEntry func(memory.ScratchEntry) string
+}
+
+func (t *scratchTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
+	var params struct {
+		Action string `json:"action"`
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+	}
+	chatID := ChatIDFromContext(ctx)
+
+	if params.Action == "list" {
+		return t.list(ctx, chatID)
+	}
+
+	if params.Key == "" {
+		return &Result{Error: "key is required for " + params.Action, IsError: true}, nil
+	}
+	if len(params.Key) > maxScratchKeyLen {
+		return &Result{Error: fmt.Sprintf("key too long (max %d bytes)", maxScratchKeyLen), IsError: true}, nil
+	}
+
+	switch {
+	case params.Action == "set":
+		return t.write(ctx, chatID, params.Key, func(string) string { return params.Value })
+	case params.Action == "append" && t.appendable:
+		return t.write(ctx, chatID, params.Key, func(old string) string {
+			if old == "" {
+				return params.Value
+			}
+			return old + "\n" + params.Value
+		})
+	case params.Action == "get":
+		value, ok, err := t.store.ScratchGet(ctx, t.table, chatID, params.Key)
+		if err != nil {
+			return &Result{Error: fmt.Sprintf("failed to get %s: %v", t.noun, err), IsError: true}, nil
+		}
+		if !ok {
+			return &Result{Error: t.noun + " not found: " + params.Key, IsError: true}, nil
+		}
+		return &Result{Output: value}, nil
+	case params.Action == "delete":
+		if err := t.store.ScratchDelete(ctx, t.table, chatID, params.Key); err != nil {
+			return &Result{Error: fmt.Sprintf("failed to delete %s: %v", t.noun, err), IsError: true}, nil
+		}
+		return &Result{Output: "Deleted " + params.Key}, nil
+	default:
+		return &Result{Error: "unknown action: " + params.Action, IsError: true}, nil
+	}
+}
+
+func (t *scratchTool) list(ctx context.Context, chatID string) (*Result, error) {
+	entries, err := t.store.ScratchList(ctx, t.table, chatID)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to list %ss: %v", t.noun, err), IsError: true}, nil
+	}
+	if len(entries) == 0 {
+		return &Result{Output: "(no " + t.noun + "s)"}, nil
+	}
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = t.listEntry(e)
+	}
+	return &Result{Output: strings.Join(lines, "\n")}, nil
+}
+
+// write replaces the entry under key with update(old), where old is "" for
+// a new entry, if the chat's entries stay within the limits.
+func (t *scratchTool) write(ctx context.Context, chatID, key string, update func(old string) string) (*Result, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.store.ScratchList(ctx, t.table, chatID)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to save %s: %v", t.noun, err), IsError: true}, nil
+	}
+	var old string
+	exists := false
+	total := 0
+	for _, e := range entries {
+		if e.Key == key {
+			old, exists = e.Value, true
+			continue
+		}
+		total += len(e.Value)
+	}
+	if !exists && len(entries) >= t.maxEntries {
+		return &Result{Error: fmt.Sprintf("too many %ss (max %d); delete some first", t.noun, t.maxEntries), IsError: true}, nil
+	}
+
+	value := update(old)
+	if t.maxValue > 0 && len(value) > t.maxValue {
+		return &Result{Error: fmt.Sprintf("value too large: %d bytes (max %d)", len(value), t.maxValue), IsError: true}, nil
+	}
+	if t.maxTotal > 0 && total+len(value) > t.maxTotal {
+		return &Result{Error: fmt.Sprintf("%ss would take %d bytes (max %d); shorten or delete some first", t.noun, total+len(value), t.maxTotal), IsError: true}, nil
+	}
+	if err := t.store.ScratchSet(ctx, t.table, chatID, key, value); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to save %s: %v", t.noun, err), IsError: true}, nil
+	}
+	return &Result{Output: fmt.Sprintf("Saved %s (%d bytes)", key, len(value))}, nil
+}
@@ -1,13 +1,18 @@
 package tool
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -100,6 +105,9 @@ type ShellTool struct {
 	timeoutSecs    int
 	maxOutputChars int
 	sandboxEnabled bool
+	allowed        map[string]bool
+	denyPatterns   []*regexp.Regexp // built-in plus configured extras
+	allowPatterns  []*regexp.Regexp
 }
 
 // ShellConfig configures the shell tool.
@@ -108,22 +116,61 @@ type ShellConfig struct {
 	TimeoutSecs    int
 	MaxOutputChars int
 	SandboxEnabled bool
+	// AllowedCommands, when non-empty, is the only set of programs that may
+	// run. It takes precedence over the denylist.
+	AllowedCommands []string
+	// ExtraDenyPatterns are regexes blocked in addition to the built-in ones.
+	ExtraDenyPatterns []string
+	// AllowPatterns override the denylist: a simple command (no chaining or
+	// substitution) matching one of them runs even if a deny pattern matches.
+	AllowPatterns []string
 }
 
-// NewShellTool creates a new shell tool.
-func NewShellTool(cfg ShellConfig) *ShellTool {
+// NewShellTool creates a new shell tool. It fails if a configured pattern is
+// not a valid regular expression.
+func NewShellTool(cfg ShellConfig) (*ShellTool, error) {
 	if cfg.TimeoutSecs <= 0 {
 		cfg.TimeoutSecs = 60
 	}
 	if cfg.MaxOutputChars <= 0 {
 		cfg.MaxOutputChars = 10000
 	}
+	var allowed map[string]bool
+	if len(cfg.AllowedCommands) > 0 {
+		allowed = make(map[string]bool, len(cfg.AllowedCommands))
+		for _, name := range cfg.AllowedCommands {
+			allowed[strings.TrimSpace(name)] = true
+		}
+	}
+	extra, err := compilePatterns(cfg.ExtraDenyPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny pattern: %w", err)
+	}
+	allowPatterns, err := compilePatterns(cfg.AllowPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow pattern: %w", err)
+	}
 	return &ShellTool{
 		workspaceDir:   cfg.WorkspaceDir,
 		timeoutSecs:    cfg.TimeoutSecs,
 		maxOutputChars: cfg.MaxOutputChars,
 		sandboxEnabled: cfg.SandboxEnabled,
+		allowed:        allowed,
+		denyPatterns:   append(slices.Clip(denyPatterns), extra...),
+		allowPatterns:  allowPatterns,
+	}, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
 	}
+	return compiled, nil
 }
 
 func (t *ShellTool) Name() string { return "shell" }
@@ -145,74 +192,411 @@ func (t *ShellTool) Parameters() json.RawMessage {
 }
 
 func (t *ShellTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
+	return t.run(ctx, args, nil)
+}
+
+// ExecuteStream runs the command like Execute, also passing each line of
+// stdout and stderr to progress as it is produced.
+func (t *ShellTool) ExecuteStream(ctx context.Context, args json.RawMessage, progress func(line string)) (*Result, error) {
+	return t.run(ctx, args, progress)
+}
+
+func (t *ShellTool) run(ctx context.Context, args json.RawMessage, progress func(line string)) (*Result, error) {
+	command, err := t.parseCommand(args)
+	if err != nil {
+		return &Result{Error: err.Error(), IsError: true}, nil
+	}
+
+	timeout := time.Duration(t.timeoutSecs) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := t.command(ctx, command)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if progress != nil {
+		lines := &progressLines{progress: progress, remaining: t.maxOutputChars}
+		outLines, errLines := &progressStream{lines: lines}, &progressStream{lines: lines}
+		cmd.Stdout = io.MultiWriter(&stdout, outLines)
+		cmd.Stderr = io.MultiWriter(&stderr, errLines)
+		defer errLines.flush()
+		defer outLines.flush()
+	}
+	err = cmd.Run()
+
+	result := &Result{Output: t.formatStreams(stdout.String(), stderr.String())}
+	if err != nil {
+		result.IsError = true
+		result.ExitCode, result.Error = exitStatus(err)
+	}
+	return result, nil
+}
+
+// progressLines reports the lines of a command's output, from stdout and
+// stderr, until limit characters have been reported.
+type progressLines struct {
+	mu        sync.Mutex
+	progress  func(line string)
+	remaining int
+	truncated bool
+}
+
+func (p *progressLines) report(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.truncated {
+		return
+	}
+	p.remaining -= len(line) + 1
+	if p.remaining < 0 {
+		p.truncated = true
+		p.progress("... (output truncated)")
+		return
+	}
+	p.progress(line)
+}
+
+func (p *progressLines) left() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.remaining
+}
+
+// progressStream splits one output stream into lines for progressLines.
+type progressStream struct {
+	lines   *progressLines
+	pending []byte
+}
+
+func (s *progressStream) Write(b []byte) (int, error) {
+	s.pending = append(s.pending, b...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			break
+		}
+		s.lines.report(strings.TrimRight(string(s.pending[:i]), "\r"))
+		s.pending = s.pending[i+1:]
+	}
+	// A line longer than the whole limit can't be reported anyway.
+	if len(s.pending) > s.lines.left() {
+		s.lines.report(string(s.pending))
+		s.pending = nil
+	}
+	return len(b), nil
+}
+
+// flush reports a last line that has no newline.
+func (s *progressStream) flush() {
+	if len(s.pending) > 0 {
+		s.lines.report(string(s.pending))
+		s.pending = nil
+	}
+}
+
+// exitStatus converts the error from running a command into an exit code
+// and message. Commands killed by a signal (e.g. the timeout) or that failed
+// to start report -1.
+func exitStatus(err error) (int, string) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return exitErr.ExitCode(), fmt.Sprintf("exit code %d", exitErr.ExitCode())
+	}
+	return -1, err.Error()
+}
+
+// formatStreams labels stdout and stderr, each truncated separately. Output
+// that is only stdout is returned as is.
+func (t *ShellTool) formatStreams(stdout, stderr string) string {
+	truncate := func(s string) string {
+		if len(s) > t.maxOutputChars {
+			return s[:t.maxOutputChars] + "\n... (output truncated)"
+		}
+		return s
+	}
+	stdout, stderr = truncate(stdout), truncate(stderr)
+	if stderr == "" {
+		return stdout
+	}
+	var b strings.Builder
+	if stdout != "" {
+		b.WriteString("[stdout]\n" + stdout)
+		if !strings.HasSuffix(stdout, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString("[stderr]\n" + stderr)
+	return b.String()
+}
+
+// parseCommand decodes the tool arguments and applies the sandbox checks.
+func (t *ShellTool) parseCommand(args json.RawMessage) (string, error) {
 	var params struct {
 		Command string `json:"command"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
-		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
+		return "", fmt.Errorf("invalid arguments: %w", err)
 	}
 
 	if params.Command == "" {
-		return &Result{Error: "command is required", IsError: true}, nil
+		return "", fmt.Errorf("command is required")
+	}
+
+	// An allowlist applies even without the sandbox and replaces the denylist.
+	if t.allowed != nil {
+		if reason := t.checkAllowList(params.Command); reason != "" {
+			return "", fmt.Errorf("command blocked by allowlist: %s", reason)
+		}
 	}
 
 	// Sandbox checks
 	if t.sandboxEnabled {
-		if reason := t.checkDenyList(params.Command); reason != "" {
-			return &Result{
-				Error:   fmt.Sprintf("command blocked by sandbox: %s", reason),
-				IsError: true,
-			}, nil
+		if t.allowed == nil {
+			if reason := t.checkDenyList(params.Command); reason != "" {
+				return "", fmt.Errorf("command blocked by sandbox: %s", reason)
+			}
 		}
 		// Block path traversal
 		if strings.Contains(params.Command, "../") {
-			return &Result{Error: "command blocked: path traversal detected", IsError: true}, nil
+			return "", fmt.Errorf("command blocked: path traversal detected")
 		}
 		// Block absolute paths outside workspace to limit filesystem reach
 		if t.workspaceDir != "" && containsAbsolutePathOutsideWorkspace(params.Command, t.workspaceDir) {
-			return &Result{Error: "command blocked: absolute path outside workspace", IsError: true}, nil
+			return "", fmt.Errorf("command blocked: absolute path outside workspace")
 		}
 	}
 
-	timeout := time.Duration(t.timeoutSecs) * time.Second
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	return params.Command, nil
+}
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", params.Command)
+func (t *ShellTool) command(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	if t.workspaceDir != "" {
 		cmd.Dir = t.workspaceDir
 	}
+	return cmd
+}
 
-	output, err := cmd.CombinedOutput()
-	result := string(output)
+// checkDenyList matches the deny patterns against the whole command, each
+// command in a chain or pipeline, and the body of every $(...), `...`, <(...)
+// or >(...) substitution, so nothing dangerous hides behind ; or && or $(.
+// Allow patterns exempt only simple commands, so they can't be used to
+// smuggle a chained command past the denylist.
+func (t *ShellTool) checkDenyList(command string) string {
+	for _, part := range denyCandidates(command) {
+		// Normalize whitespace to prevent multi-space bypass
+		normalized := collapseWhitespace(part)
+		for _, pattern := range t.denyPatterns {
+			if pattern.MatchString(normalized) && !t.allowOverride(normalized) {
+				return fmt.Sprintf("matches deny pattern: %s", pattern.String())
+			}
+		}
+	}
+	return ""
+}
 
-	// Truncate if needed
-	if len(result) > t.maxOutputChars {
-		result = result[:t.maxOutputChars] + "\n... (output truncated)"
+// allowOverride reports whether a simple command matches an allow pattern.
+func (t *ShellTool) allowOverride(command string) bool {
+	if len(splitCommands(command)) > 1 || len(substitutions(command)) > 0 {
+		return false
 	}
+	command = strings.TrimSpace(command)
+	for _, pattern := range t.allowPatterns {
+		if pattern.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
 
-	if err != nil {
-		return &Result{
-			Output:  result,
-			Error:   err.Error(),
-			IsError: true,
-		}, nil
+// denyCandidates returns command, its segments, and the same recursively for
+// each substitution it contains.
+func denyCandidates(command string) []string {
+	parts := []string{command}
+	parts = append(parts, splitCommands(command)...)
+	for _, sub := range substitutions(command) {
+		parts = append(parts, denyCandidates(sub)...)
 	}
+	return parts
+}
 
-	return &Result{Output: result}, nil
+// substitutions returns the bodies of the outermost $(...), <(...), >(...)
+// and `...` substitutions in command. Quotes are ignored on purpose: checking
+// a body that would not actually run only makes the deny list stricter.
+func substitutions(command string) []string {
+	var subs []string
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != '`' {
+				end++
+			}
+			subs = append(subs, string(runes[i+1:min(end, len(runes))]))
+			i = end
+		case (runes[i] == '$' || runes[i] == '<' || runes[i] == '>') && i+1 < len(runes) && runes[i+1] == '(':
+			depth := 1
+			end := i + 2
+			for ; end < len(runes) && depth > 0; end++ {
+				switch runes[end] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+			// An unclosed substitution still runs to the end of the input.
+			body := runes[i+2 : end]
+			if depth == 0 {
+				body = runes[i+2 : end-1]
+			}
+			subs = append(subs, string(body))
+			i = end - 1
+		}
+	}
+	return subs
 }
 
-func (t *ShellTool) checkDenyList(command string) string {
-	// Normalize whitespace to prevent multi-space bypass
-	normalized := collapseWhitespace(command)
-	for _, pattern := range denyPatterns {
-		if pattern.MatchString(normalized) {
-			return fmt.Sprintf("matches deny pattern: %s", pattern.String())
+// checkAllowList requires every command in a chain or pipeline to start with
+// an allowed program. Command and process substitutions could run anything,
+// so they are refused; <(...) and >(...) run wherever /bin/sh is bash.
+func (t *ShellTool) checkAllowList(command string) string {
+	if strings.Contains(command, "$(") || strings.Contains(command, "`") ||
+		strings.Contains(command, "<(") || strings.Contains(command, ">(") {
+		return "command substitution is not allowed"
+	}
+	segments := splitCommands(command)
+	if len(segments) == 0 {
+		return "no command found"
+	}
+	for _, seg := range segments {
+		name := programName(seg)
+		if name == "" {
+			return fmt.Sprintf("no program in %q", seg)
+		}
+		if !t.allowed[name] {
+			return fmt.Sprintf("%s is not an allowed command", name)
 		}
 	}
 	return ""
 }
 
+// splitCommands splits a command line on unquoted ;, &, |, &&, ||, and
+// newlines, returning the non-empty segments.
+func splitCommands(command string) []string {
+	var segments []string
+	var cur strings.Builder
+	flush := func() {
+		if seg := strings.TrimSpace(cur.String()); seg != "" {
+			segments = append(segments, seg)
+		}
+		cur.Reset()
+	}
+
+	var quote rune
+	escaped := false
+	runes := []rune(command)
+	for i, ch := range runes {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '&' && isRedirect(runes, i):
+			// 2>&1 and &> are redirections, not separators
+		case ch == ';' || ch == '&' || ch == '|' || ch == '\n':
+			flush()
+			continue
+		}
+		cur.WriteRune(ch)
+	}
+	flush()
+	return segments
+}
+
+// isRedirect reports whether the & at runes[i] belongs to a redirection
+// operator (>&, <&, &>) rather than separating commands.
+func isRedirect(runes []rune, i int) bool {
+	return (i > 0 && (runes[i-1] == '>' || runes[i-1] == '<')) ||
+		(i+1 < len(runes) && runes[i+1] == '>')
+}
+
+// programName returns the program a simple command runs, skipping leading
+// environment assignments such as FOO=bar. Quotes around the name are removed.
+func programName(segment string) string {
+	for _, word := range shellWords(segment) {
+		if isAssignment(word) {
+			continue
+		}
+		return word
+	}
+	return ""
+}
+
+// shellWords splits s on unquoted whitespace and strips quotes and escapes.
+func shellWords(s string) []string {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, ch := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(ch)
+			escaped = false
+		case ch == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(ch)
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			inWord = true
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words
+}
+
+// isAssignment reports whether word is a NAME=value environment assignment.
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, ch := range name {
+		if ch != '_' && !(ch >= 'a' && ch <= 'z') && !(ch >= 'A' && ch <= 'Z') && (i == 0 || !(ch >= '0' && ch <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
 // collapseWhitespace replaces multiple whitespace chars with a single space.
 func collapseWhitespace(s string) string {
 	var b strings.Builder
@@ -0,0 +1,275 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShellExecuteStreamIncremental(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10})
+
+	args, _ := json.Marshal(map[string]string{"command": "echo one; sleep 0.5; echo two"})
+	start := time.Now()
+	var got []string
+	var firstAt time.Duration
+	res, err := st.ExecuteStream(context.Background(), args, func(line string) {
+		if len(got) == 0 {
+			firstAt = time.Since(start)
+		}
+		got = append(got, line)
+	})
+	doneAt := time.Since(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Fatalf("expected [one two], got %v", got)
+	}
+	if doneAt-firstAt < 300*time.Millisecond {
+		t.Fatalf("first line arrived at %v, command finished at %v; expected incremental output", firstAt, doneAt)
+	}
+	if res.IsError || res.Output != "one\ntwo\n" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestShellExecuteStreamResultMatchesExecute(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10, MaxOutputChars: 1000})
+
+	args, _ := json.Marshal(map[string]string{"command": "echo partial; echo oops >&2; exit 3"})
+	var got []string
+	var mu sync.Mutex
+	streamed, err := st.ExecuteStream(context.Background(), args, func(line string) {
+		mu.Lock()
+		got = append(got, line)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plain, _ := st.Execute(context.Background(), args)
+
+	if *streamed != *plain {
+		t.Fatalf("streamed result %+v differs from %+v", streamed, plain)
+	}
+	if !streamed.IsError || streamed.ExitCode != 3 || streamed.Error != "exit code 3" ||
+		streamed.Output != "[stdout]\npartial\n[stderr]\noops\n" {
+		t.Fatalf("unexpected result: %+v", streamed)
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "oops" || got[1] != "partial" {
+		t.Fatalf("expected both streams reported, got %v", got)
+	}
+}
+
+func TestShellExecuteStreamTruncatesProgress(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10, MaxOutputChars: 20})
+
+	args, _ := json.Marshal(map[string]string{"command": "for i in 1 2 3 4 5 6 7 8 9; do echo line$i; done"})
+	var got []string
+	st.ExecuteStream(context.Background(), args, func(line string) { got = append(got, line) })
+	if len(got) == 0 || got[len(got)-1] != "... (output truncated)" || len(got) > 4 {
+		t.Fatalf("expected progress cut off after 20 chars, got %v", got)
+	}
+}
+
+func TestShellExecuteStreamSandbox(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), SandboxEnabled: true})
+
+	args, _ := json.Marshal(map[string]string{"command": "shutdown now"})
+	res, err := st.ExecuteStream(context.Background(), args, func(string) { t.Error("blocked command produced output") })
+	if err != nil || !res.IsError {
+		t.Fatalf("expected sandbox to block the command, got %+v, %v", res, err)
+	}
+}
+
+func TestShellAllowList(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{
+		WorkspaceDir:    t.TempDir(),
+		SandboxEnabled:  true,
+		AllowedCommands: []string{"echo", "ls", "grep"},
+	})
+
+	allowed := []string{
+		"echo hello",
+		"ls -la | grep go",
+		"FOO=bar echo $FOO",
+		`GREETING="hello world" A=1 echo "$GREETING"`,
+		"echo one && echo two; ls",
+		"ls missing 2>&1",
+		"echo 'a; rm -rf x'",
+	}
+	for _, cmd := range allowed {
+		args, _ := json.Marshal(map[string]string{"command": cmd})
+		if _, err := st.parseCommand(args); err != nil {
+			t.Errorf("%q: expected allowed, got %v", cmd, err)
+		}
+	}
+
+	denied := []string{
+		"cat secrets.txt",
+		"FOO=bar cat x",
+		"echo ok; curl example.com",
+		"ls || rm x",
+		"ls | sh",
+		"echo $(whoami)",
+		"echo `whoami`",
+		"echo hi <(cat secrets.txt)",
+		"echo hi >(rm -rf ~)",
+		"echo hi > >(cat)",
+		"/bin/echo hi",
+		"FOO=bar",
+	}
+	for _, cmd := range denied {
+		args, _ := json.Marshal(map[string]string{"command": cmd})
+		if _, err := st.parseCommand(args); err == nil {
+			t.Errorf("%q: expected blocked by allowlist", cmd)
+		}
+	}
+}
+
+func TestShellAllowListOverridesDenyList(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{
+		WorkspaceDir:    t.TempDir(),
+		SandboxEnabled:  true,
+		AllowedCommands: []string{"nohup"},
+	})
+	args, _ := json.Marshal(map[string]string{"command": "nohup true"})
+	if _, err := st.parseCommand(args); err != nil {
+		t.Fatalf("allowlisted command should skip the denylist, got %v", err)
+	}
+}
+
+func TestProgramName(t *testing.T) {
+	tests := map[string]string{
+		"ls -la":                      "ls",
+		"FOO=bar ls":                  "ls",
+		`A="x y" B='z' git status`:    "git",
+		`"my tool" --flag`:            "my tool",
+		"  X_1=2   python3 script.py": "python3",
+		"FOO=bar":                     "",
+	}
+	for in, want := range tests {
+		if got := programName(in); got != want {
+			t.Errorf("programName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestShellDenyListChaining(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), SandboxEnabled: true})
+
+	blocked := []string{
+		"a && rm -rf /",
+		"x; shutdown",
+		"echo $(dd if=/dev/zero of=/dev/sda)",
+		"echo ok || reboot",
+		"ls | xargs rm",
+		"echo `mkfs.ext4 disk.img`",
+		"echo $(echo $(shred file))",
+		"diff <(shutdown) x",
+		"echo $(nohup true",
+	}
+	for _, cmd := range blocked {
+		if reason := st.checkDenyList(cmd); reason == "" {
+			t.Errorf("%q: expected deny list to block", cmd)
+		}
+	}
+
+	for _, cmd := range []string{"echo ok; ls -la", "echo $(date) && pwd", "cat a.txt | grep rm"} {
+		if reason := st.checkDenyList(cmd); reason != "" {
+			t.Errorf("%q: unexpectedly blocked: %s", cmd, reason)
+		}
+	}
+}
+
+func TestSubstitutions(t *testing.T) {
+	got := substitutions("a $(b $(c)) `d` <(e) x")
+	want := []string{"b $(c)", "d", "e"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestShellExecuteExitCodeAndStreams(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10})
+
+	args, _ := json.Marshal(map[string]string{"command": "echo out; echo err >&2; exit 3"})
+	res, err := st.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError || res.ExitCode != 3 || res.Error != "exit code 3" {
+		t.Fatalf("expected exit code 3, got %+v", res)
+	}
+	if res.Output != "[stdout]\nout\n[stderr]\nerr\n" {
+		t.Fatalf("expected labeled streams, got %q", res.Output)
+	}
+
+	args, _ = json.Marshal(map[string]string{"command": "echo plain"})
+	res, _ = st.Execute(context.Background(), args)
+	if res.IsError || res.ExitCode != 0 || res.Output != "plain\n" {
+		t.Fatalf("expected plain stdout, got %+v", res)
+	}
+}
+
+func TestShellExecuteTruncatesPerStream(t *testing.T) {
+	st, _ := NewShellTool(ShellConfig{WorkspaceDir: t.TempDir(), TimeoutSecs: 10, MaxOutputChars: 5})
+
+	args, _ := json.Marshal(map[string]string{"command": "echo 1234567890; echo abcdefghij >&2"})
+	res, _ := st.Execute(context.Background(), args)
+	want := "[stdout]\n12345\n... (output truncated)\n[stderr]\nabcde\n... (output truncated)"
+	if res.Output != want {
+		t.Fatalf("got %q, want %q", res.Output, want)
+	}
+}
+
+func TestShellConfigurablePatterns(t *testing.T) {
+	st, err := NewShellTool(ShellConfig{
+		WorkspaceDir:      t.TempDir(),
+		SandboxEnabled:    true,
+		ExtraDenyPatterns: []string{`(?i)\bterraform\s+destroy\b`},
+		AllowPatterns:     []string{`^git push --force\b`, `^pip install --user\b`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range []string{"git push --force origin main", "pip  install --user requests"} {
+		if reason := st.checkDenyList(cmd); reason != "" {
+			t.Errorf("%q: allow pattern should override deny, got %s", cmd, reason)
+		}
+	}
+	blocked := []string{
+		"terraform destroy -auto-approve", // extra deny pattern
+		"nohup sleep 1",                   // built-ins still apply
+		"git push --force; shutdown",      // allow doesn't cover chained commands
+		"git push --force $(reboot)",
+	}
+	for _, cmd := range blocked {
+		if reason := st.checkDenyList(cmd); reason == "" {
+			t.Errorf("%q: expected to be blocked", cmd)
+		}
+	}
+
+	// The built-in list is untouched for other tools
+	plain, _ := NewShellTool(ShellConfig{SandboxEnabled: true})
+	if reason := plain.checkDenyList("terraform destroy"); reason != "" {
+		t.Errorf("extra pattern leaked into another tool: %s", reason)
+	}
+}
+
+func TestShellInvalidPattern(t *testing.T) {
+	if _, err := NewShellTool(ShellConfig{ExtraDenyPatterns: []string{"(unclosed"}}); err == nil {
+		t.Error("expected error for invalid deny pattern")
+	}
+	if _, err := NewShellTool(ShellConfig{AllowPatterns: []string{"[z-a]"}}); err == nil {
+		t.Error("expected error for invalid allow pattern")
+	}
+}
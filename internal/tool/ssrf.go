@@ -0,0 +1,88 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// resolveTimeout bounds the DNS lookup made before a hostname is allowed.
+const resolveTimeout = 3 * time.Second
+
+// hostResolver looks up the addresses of a hostname. *net.Resolver satisfies it.
+type hostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// urlGuard applies SSRF protection to URLs the agent asks to open.
+type urlGuard struct {
+	resolver     hostResolver
+	allowPrivate bool // skip the private address checks (trusted internal use)
+}
+
+func newURLGuard(allowPrivate bool) urlGuard {
+	return urlGuard{resolver: net.DefaultResolver, allowPrivate: allowPrivate}
+}
+
+// check parses rawURL and rejects non-http(s) schemes and hosts that are, or
+// resolve to, private, loopback, or link-local addresses. A hostname is
+// rejected if any of its addresses is private, since the client may pick any.
+func (g urlGuard) check(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	// Only allow http and https
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("only http/https schemes are allowed, got: %s", u.Scheme)
+	}
+
+	if g.allowPrivate {
+		return u, nil
+	}
+
+	host := u.Hostname()
+	if isPrivateHost(host) {
+		return nil, fmt.Errorf("access to private/loopback addresses is denied: %s", host)
+	}
+	if net.ParseIP(host) != nil {
+		return u, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve host %s: %w", host, err)
+	}
+	for _, a := range addrs {
+		if isPrivateIP(a.IP) {
+			return nil, fmt.Errorf("access to private/loopback addresses is denied: %s resolves to %s", host, a.IP)
+		}
+	}
+	return u, nil
+}
+
+// isPrivateHost returns true for localhost names and literal private,
+// loopback, and link-local addresses.
+func isPrivateHost(host string) bool {
+	// Check common localhost names
+	lower := strings.ToLower(host)
+	if lower == "localhost" || strings.HasSuffix(lower, ".localhost") || lower == "ip6-localhost" || lower == "ip6-loopback" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && isPrivateIP(ip)
+}
+
+// isPrivateIP returns true for loopback, private, link-local, and unspecified addresses.
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
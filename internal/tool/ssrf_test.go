@@ -0,0 +1,116 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"open-dan/internal/config"
+)
+
+// stubResolver resolves hostnames from a fixed table instead of DNS.
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host: %s", host)
+	}
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+// publicResolver answers every test hostname with a public address.
+var publicResolver = stubResolver{
+	"example.com":       {"93.184.215.14"},
+	"shop.example.com":  {"93.184.215.14"},
+	"other.example.org": {"93.184.215.14"},
+	"other.com":         {"93.184.215.15"},
+	"evil.com":          {"93.184.215.16"},
+	"sub.evil.com":      {"93.184.215.16"},
+}
+
+func TestURLGuardResolvesHostnames(t *testing.T) {
+	g := urlGuard{resolver: stubResolver{
+		"example.com":       {"93.184.215.14"},
+		"metadata.internal": {"169.254.169.254"},
+		"intranet.corp":     {"10.0.0.8"},
+		"mixed.example":     {"93.184.215.14", "127.0.0.1"},
+		"v6.example":        {"fd00::1"},
+	}}
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{"https://example.com/page", false},
+		{"http://metadata.internal/latest/meta-data", true},
+		{"https://intranet.corp/", true},
+		{"https://mixed.example/", true},
+		{"https://v6.example/", true},
+		{"https://unknown.example/", true},
+		{"http://app.localhost/", true},
+		{"http://0.0.0.0/", true},
+	}
+	for _, tt := range tests {
+		_, err := g.check(context.Background(), tt.url)
+		if tt.blocked && err == nil {
+			t.Errorf("expected %s to be blocked", tt.url)
+		}
+		if !tt.blocked && err != nil {
+			t.Errorf("unexpected error for %s: %v", tt.url, err)
+		}
+	}
+
+	g.allowPrivate = true
+	for _, u := range []string{"http://metadata.internal/", "http://127.0.0.1:8080/", "http://localhost/"} {
+		if _, err := g.check(context.Background(), u); err != nil {
+			t.Errorf("allow_private_network should permit %s: %v", u, err)
+		}
+	}
+	if _, err := g.check(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("allow_private_network must still reject non-http schemes")
+	}
+}
+
+func TestBrowserRejectsHostResolvingToPrivateIP(t *testing.T) {
+	bt := NewBrowserTool(config.BrowserConfig{})
+	bt.guard.resolver = stubResolver{"rebind.example": {"192.168.0.10"}}
+
+	err := bt.validateURL(context.Background(), "https://rebind.example/admin")
+	if err == nil || !strings.Contains(err.Error(), "192.168.0.10") {
+		t.Fatalf("expected private resolution to be denied, got %v", err)
+	}
+}
+
+func TestFetchAllowPrivateNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal ok"))
+	}))
+	defer srv.Close()
+	args, _ := json.Marshal(map[string]string{"url": srv.URL})
+
+	res, err := NewFetchTool(config.FetchConfig{}).Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError {
+		t.Fatal("expected loopback fetch to be blocked by default")
+	}
+
+	res, err = NewFetchTool(config.FetchConfig{AllowPrivateNetwork: true}).Execute(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || !strings.Contains(res.Output, "internal ok") {
+		t.Fatalf("expected fetch to succeed with allow_private_network, got %+v", res)
+	}
+}
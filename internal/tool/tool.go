@@ -13,9 +13,51 @@ type Tool interface {
 	Execute(ctx context.Context, args json.RawMessage) (*Result, error)
 }
 
+// StreamingTool is a Tool that can report output incrementally while it runs.
+// Lines go to a callback rather than a returned channel so that the call
+// still ends in a Result: a bare channel of lines would lose the labelled
+// streams, per-stream caps, and exit code the model is given.
+type StreamingTool interface {
+	Tool
+	// ExecuteStream runs the tool like Execute and returns the same Result,
+	// also passing each line of output to progress as it is produced.
+	ExecuteStream(ctx context.Context, args json.RawMessage, progress func(line string)) (*Result, error)
+}
+
+// CacheableTool is a Tool whose result depends only on its arguments for a
+// while, such as a search or an HTTP GET, so repeating a call may reuse the
+// earlier result. Tools with side effects must not implement it.
+type CacheableTool interface {
+	Tool
+	Cacheable() bool
+}
+
+// IsCacheable reports whether t declares its results cacheable. Tools are
+// not cacheable unless they say so.
+func IsCacheable(t Tool) bool {
+	c, ok := t.(CacheableTool)
+	return ok && c.Cacheable()
+}
+
 // Result is the output of a tool execution.
 type Result struct {
 	Output  string `json:"output"`
 	Error   string `json:"error,omitempty"`
 	IsError bool   `json:"is_error"`
+	// ExitCode is the process exit status for tools that run a process,
+	// or -1 if it was killed or never started.
+	ExitCode int `json:"exit_code,omitempty"`
+}
+
+type chatIDKey struct{}
+
+// WithChatID returns a context carrying the chat a tool call belongs to.
+func WithChatID(ctx context.Context, chatID string) context.Context {
+	return context.WithValue(ctx, chatIDKey{}, chatID)
+}
+
+// ChatIDFromContext returns the chat ID set by WithChatID, or "".
+func ChatIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(chatIDKey{}).(string)
+	return id
 }
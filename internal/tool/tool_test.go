@@ -0,0 +1,22 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// execTool runs tool with args marshalled to JSON, failing the test if
+// Execute returns an error rather than an error Result.
+func execTool(t *testing.T, ctx context.Context, tool Tool, args any) *Result {
+	t.Helper()
+	raw, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tool.Execute(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
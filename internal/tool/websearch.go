@@ -6,20 +6,61 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"strings"
 	"time"
+
+	"golang.org/x/net/html"
+
+	"open-dan/internal/config"
 )
 
-// WebSearchTool provides web search capability using DuckDuckGo HTML.
-type WebSearchTool struct{}
+// WebSearchTool provides web search through a configurable backend
+// (DuckDuckGo HTML, a SearXNG instance, or the Brave Search API).
+type WebSearchTool struct {
+	cfg     config.WebSearchConfig
+	client  *http.Client
+	backend searchBackend
+	err     error // backend misconfiguration, reported on use
+}
+
+// searchBackend fetches a results page and parses it into results.
+// Each backend lives in its own websearch_<name>.go file.
+type searchBackend interface {
+	fetch(ctx context.Context, client *http.Client, query string, max int) ([]byte, error)
+	parse(body []byte, max int) ([]searchResult, error)
+}
 
-func NewWebSearchTool() *WebSearchTool {
-	return &WebSearchTool{}
+func NewWebSearchTool(cfg config.WebSearchConfig) *WebSearchTool {
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultSearchResults
+	}
+	t := &WebSearchTool{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+	switch cfg.Backend {
+	case "", "duckduckgo":
+		t.backend = duckDuckGoBackend{}
+	case "searxng":
+		if cfg.SearxngURL == "" {
+			t.err = fmt.Errorf("searxng backend requires searxng_url")
+		}
+		t.backend = searxngBackend{baseURL: strings.TrimRight(cfg.SearxngURL, "/")}
+	case "brave":
+		if cfg.BraveAPIKey == "" {
+			t.err = fmt.Errorf("brave backend requires brave_api_key")
+		}
+		t.backend = braveBackend{apiKey: cfg.BraveAPIKey}
+	default:
+		t.err = fmt.Errorf("unknown search backend: %s", cfg.Backend)
+	}
+	return t
 }
 
-func (t *WebSearchTool) Name() string        { return "web_search" }
-func (t *WebSearchTool) Description() string  {
-	return "Search the web for information. Returns search results with titles and URLs."
+func (t *WebSearchTool) Name() string    { return "web_search" }
+func (t *WebSearchTool) Cacheable() bool { return true }
+func (t *WebSearchTool) Description() string {
+	return "Search the web for information. Returns a JSON list of results with title, url, and snippet."
 }
 
 func (t *WebSearchTool) Parameters() json.RawMessage {
@@ -29,6 +70,14 @@ func (t *WebSearchTool) Parameters() json.RawMessage {
 			"query": {
 				"type": "string",
 				"description": "The search query"
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Maximum number of results to return (default 8)"
+			},
+			"raw": {
+				"type": "boolean",
+				"description": "Return the raw backend response instead of parsed results (for debugging)"
 			}
 		},
 		"required": ["query"]
@@ -37,7 +86,9 @@ func (t *WebSearchTool) Parameters() json.RawMessage {
 
 func (t *WebSearchTool) Execute(ctx context.Context, args json.RawMessage) (*Result, error) {
 	var params struct {
-		Query string `json:"query"`
+		Query      string `json:"query"`
+		MaxResults int    `json:"max_results"`
+		Raw        bool   `json:"raw"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return &Result{Error: "invalid arguments: " + err.Error(), IsError: true}, nil
@@ -47,31 +98,98 @@ func (t *WebSearchTool) Execute(ctx context.Context, args json.RawMessage) (*Res
 		return &Result{Error: "query is required", IsError: true}, nil
 	}
 
-	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(params.Query))
+	if t.err != nil {
+		return &Result{Error: t.err.Error(), IsError: true}, nil
+	}
+	if params.MaxResults <= 0 {
+		params.MaxResults = t.cfg.MaxResults
+	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	body, err := t.backend.fetch(ctx, t.client, params.Query, params.MaxResults)
 	if err != nil {
-		return &Result{Error: "failed to create request: " + err.Error(), IsError: true}, nil
+		return &Result{Error: "search request failed: " + err.Error(), IsError: true}, nil
+	}
+
+	if params.Raw {
+		output := string(body)
+		if len(output) > 10000 {
+			output = output[:10000] + "\n... (truncated)"
+		}
+		return &Result{Output: output}, nil
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; OpenDan/1.0)")
 
+	results, err := t.backend.parse(body, params.MaxResults)
+	if err != nil {
+		return &Result{Error: "failed to parse search results: " + err.Error(), IsError: true}, nil
+	}
+	if len(results) == 0 {
+		return &Result{Output: "No results found."}, nil
+	}
+
+	output, _ := json.MarshalIndent(results, "", "  ")
+	return &Result{Output: string(output)}, nil
+}
+
+const defaultSearchResults = 8
+
+// maxSearchResponseBytes bounds how much of a backend response is read.
+const maxSearchResponseBytes = 512 * 1024
+
+// doSearchRequest sends req and returns the body, treating HTTP errors as failures.
+func doSearchRequest(client *http.Client, req *http.Request) ([]byte, error) {
 	resp, err := client.Do(req)
 	if err != nil {
-		return &Result{Error: "search request failed: " + err.Error(), IsError: true}, nil
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 100000))
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSearchResponseBytes))
 	if err != nil {
-		return &Result{Error: "failed to read response: " + err.Error(), IsError: true}, nil
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return body, nil
+}
 
-	// Return raw HTML for the LLM to parse — simple and effective
-	output := string(body)
-	if len(output) > 10000 {
-		output = output[:10000] + "\n... (truncated)"
+// searchResult is a single parsed web search hit.
+type searchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
 	}
+	return false
+}
 
-	return &Result{Output: output}, nil
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the whitespace-normalized text content of n.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
 }
@@ -0,0 +1,64 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const braveSearchURL = "https://api.search.brave.com/res/v1/web/search"
+
+// braveBackend uses the Brave Search API, which requires a subscription token.
+type braveBackend struct {
+	apiKey string
+}
+
+func (b braveBackend) fetch(ctx context.Context, client *http.Client, query string, max int) ([]byte, error) {
+	q := url.Values{"q": {query}, "count": {strconv.Itoa(min(max, 20))}}
+	req, err := http.NewRequestWithContext(ctx, "GET", braveSearchURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+	return doSearchRequest(client, req)
+}
+
+func (braveBackend) parse(body []byte, max int) ([]searchResult, error) {
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	var results []searchResult
+	for _, r := range resp.Web.Results {
+		if len(results) >= max {
+			break
+		}
+		// Descriptions highlight matches with <strong> tags.
+		results = append(results, searchResult{Title: stripTags(r.Title), URL: r.URL, Snippet: stripTags(r.Description)})
+	}
+	return results, nil
+}
+
+// stripTags returns the text content of an HTML fragment.
+func stripTags(s string) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	return nodeText(doc)
+}
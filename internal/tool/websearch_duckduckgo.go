@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// duckDuckGoBackend scrapes the DuckDuckGo HTML results page. It needs no
+// API key but may be rate-limited.
+type duckDuckGoBackend struct{}
+
+func (duckDuckGoBackend) fetch(ctx context.Context, client *http.Client, query string, _ int) ([]byte, error) {
+	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; OpenDan/1.0)")
+	return doSearchRequest(client, req)
+}
+
+func (duckDuckGoBackend) parse(body []byte, max int) ([]searchResult, error) {
+	return parseDDGResults(bytes.NewReader(body), max)
+}
+
+// parseDDGResults extracts results from a DuckDuckGo HTML results page.
+// Each hit is a result__a anchor (title and link) followed by a
+// result__snippet element. Ads, which link through y.js, are skipped.
+func parseDDGResults(r io.Reader, max int) ([]searchResult, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []searchResult
+	var current *searchResult
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case hasClass(n, "result__a"):
+				current = nil
+				link := decodeDDGURL(attr(n, "href"))
+				if link == "" || len(results) >= max {
+					return
+				}
+				results = append(results, searchResult{Title: nodeText(n), URL: link})
+				current = &results[len(results)-1]
+				return
+			case hasClass(n, "result__snippet"):
+				if current != nil && current.Snippet == "" {
+					current.Snippet = nodeText(n)
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return results, nil
+}
+
+// decodeDDGURL unwraps DuckDuckGo's /l/?uddg= redirect links to the real
+// destination. It returns "" for ad links and anything that isn't http(s).
+func decodeDDGURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if strings.HasSuffix(u.Hostname(), "duckduckgo.com") || u.Host == "" {
+		if u.Path == "/y.js" {
+			return ""
+		}
+		if dest := u.Query().Get("uddg"); dest != "" {
+			if u, err = url.Parse(dest); err != nil {
+				return ""
+			}
+		}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,46 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// searxngBackend queries a (usually self-hosted) SearXNG instance through
+// its JSON API. The instance must have the json format enabled.
+type searxngBackend struct {
+	baseURL string
+}
+
+func (b searxngBackend) fetch(ctx context.Context, client *http.Client, query string, _ int) ([]byte, error) {
+	q := url.Values{"q": {query}, "format": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return doSearchRequest(client, req)
+}
+
+func (searxngBackend) parse(body []byte, max int) ([]searchResult, error) {
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	var results []searchResult
+	for _, r := range resp.Results {
+		if len(results) >= max {
+			break
+		}
+		results = append(results, searchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
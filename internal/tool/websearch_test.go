@@ -0,0 +1,131 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"open-dan/internal/config"
+)
+
+func TestParseDDGResults(t *testing.T) {
+	f, err := os.Open("testdata/ddg_results.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseDDGResults(f, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []searchResult{
+		{
+			Title:   "html package - golang.org/x/net/html - Go Packages",
+			URL:     "https://pkg.go.dev/golang.org/x/net/html",
+			Snippet: "Package html implements an HTML5-compliant tokenizer and parser.",
+		},
+		{
+			Title:   "net/html at master · golang/net · GitHub",
+			URL:     "https://github.com/golang/net/tree/master/html?tab=readme&x=1",
+			Snippet: "[mirror] Go supplementary network libraries. Contribute to golang/net development on GitHub.",
+		},
+		{
+			Title: "Golang parse HTML, extract all content with <body> </body> tags",
+			URL:   "https://stackoverflow.com/questions/30109061/golang-parse-html",
+		},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results (ad skipped), got %d: %+v", len(want), len(results), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d:\n got %+v\nwant %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestParseDDGResultsLimit(t *testing.T) {
+	f, err := os.Open("testdata/ddg_results.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, _ := parseDDGResults(f, 1)
+	if len(results) != 1 || results[0].URL != "https://pkg.go.dev/golang.org/x/net/html" {
+		t.Fatalf("expected only the first result, got %+v", results)
+	}
+}
+
+func TestDecodeDDGURL(t *testing.T) {
+	cases := map[string]string{
+		"//duckduckgo.com/l/?uddg=https%3A%2F%2Fexample.com%2Fa%3Fb%3Dc&rut=x": "https://example.com/a?b=c",
+		"/l/?uddg=http%3A%2F%2Fexample.org%2F":                                 "http://example.org/",
+		"https://example.net/direct":                                           "https://example.net/direct",
+		"https://duckduckgo.com/y.js?ad_domain=ads.example":                    "",
+		"//duckduckgo.com/l/?uddg=javascript%3Aalert(1)":                       "",
+	}
+	for in, want := range cases {
+		if got := decodeDDGURL(in); got != want {
+			t.Errorf("decodeDDGURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseSearxngResults(t *testing.T) {
+	body, err := os.ReadFile("testdata/searxng_results.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := searxngBackend{}.parse(body, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []searchResult{
+		{Title: "html package - golang.org/x/net/html", URL: "https://pkg.go.dev/golang.org/x/net/html", Snippet: "Package html implements an HTML5-compliant tokenizer and parser."},
+		{Title: "goquery", URL: "https://github.com/PuerkitoBio/goquery", Snippet: "A little like that j-thing, only in Go."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got %+v, want %+v", results, want)
+	}
+}
+
+func TestParseBraveResults(t *testing.T) {
+	body, err := os.ReadFile("testdata/brave_results.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := braveBackend{}.parse(body, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []searchResult{
+		{Title: "html package - golang.org/x/net/html", URL: "https://pkg.go.dev/golang.org/x/net/html", Snippet: "Package html implements an HTML5-compliant tokenizer and parser."},
+		{Title: "goquery", URL: "https://github.com/PuerkitoBio/goquery", Snippet: "A little like that j-thing, only in Go."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got %+v, want %+v", results, want)
+	}
+}
+
+func TestNewWebSearchToolMisconfigured(t *testing.T) {
+	for _, cfg := range []config.WebSearchConfig{
+		{Backend: "searxng"},
+		{Backend: "brave"},
+		{Backend: "altavista"},
+	} {
+		res, err := NewWebSearchTool(cfg).Execute(context.Background(), json.RawMessage(`{"query":"x"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.IsError {
+			t.Errorf("backend %q: expected error result", cfg.Backend)
+		}
+	}
+}